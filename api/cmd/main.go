@@ -10,9 +10,9 @@ import (
 	"syscall"
 
 	"github.com/KennyMacCormik/common/log"
+	"github.com/KennyMacCormik/otel/backend/pkg/gin/gin_rate_limiter"
 	otelInit "github.com/KennyMacCormik/otel/backend/pkg/otel/init"
 
-	"github.com/KennyMacCormik/otel/api/internal/client/client_impl"
 	initApp "github.com/KennyMacCormik/otel/api/internal/init"
 	"github.com/KennyMacCormik/otel/api/internal/service/service_impl"
 
@@ -55,7 +55,7 @@ func main() {
 		}
 	}()
 
-	httpCache, err := cache.NewCache()
+	httpCache, err := cache.NewCache(conf)
 	if err != nil {
 		log.Error("failed to initialize cache", "error", err)
 		gracefulStop()
@@ -69,11 +69,30 @@ func main() {
 		}
 	}()
 
-	httpClient := client_impl.NewBackendClient(conf.Client.Endpoint, conf.Client.RequestTimeout)
+	backendClient, closeClient, err := initApp.InitClient(context.Background(), conf)
+	if err != nil {
+		log.Error("failed to initialize backend client", "error", err)
+		gracefulStop()
+	}
+	defer closeClient()
+	log.Info("backend client initialized", "transport", conf.Client.Transport)
 
-	svc := service_impl.NewServiceLayer(httpCache, httpClient)
+	svc := service_impl.NewServiceLayer(httpCache, backendClient)
+
+	rm, err := gin_rate_limiter.NewRateLimiterFromConf(conf.RateLimiter)
+	if err != nil {
+		log.Error("failed to initialize rate limiter", "error", err)
+		gracefulStop()
+	}
+	defer func() {
+		err = rm.Close(context.Background())
+		if err != nil {
+			log.Warn("failed to close rate limiter", "error", err)
+		}
+	}()
+	log.Info("rate limiter initialized", "mode", rm.Mode())
 
-	httpSvr := initApp.InitServer(conf, svc)
+	httpSvr := initApp.InitServer(conf, svc, rm)
 	log.Info("http server initialized")
 	defer func() {
 		err = httpSvr.Close(conf.Http.ShutdownTimeout)
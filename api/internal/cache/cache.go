@@ -2,12 +2,17 @@ package cache
 
 import (
 	"github.com/KennyMacCormik/otel/backend/pkg/cache"
+	"github.com/KennyMacCormik/otel/backend/pkg/cache/impl/http_storage"
 	"github.com/KennyMacCormik/otel/backend/pkg/cache/impl/sync_map"
 	"github.com/KennyMacCormik/otel/backend/pkg/cache/wrappers/sharded_cache"
+	"github.com/KennyMacCormik/otel/backend/pkg/cache/wrappers/tiered_cache"
 	"github.com/KennyMacCormik/otel/backend/pkg/cache/wrappers/ttl_cache"
+
+	initApp "github.com/KennyMacCormik/otel/api/internal/init"
 )
 
-func NewCache() (cache.CacheInterface, error) {
+// NewCache returns local cache. If remote cache is configured, local cache is used as L1 in front of it.
+func NewCache(conf *initApp.Config) (cache.CacheInterface, error) {
 	fn := func() cache.CacheInterface {
 		c, _ := ttl_cache.NewTtlCache(sync_map.NewSyncMapCache())
 		return c
@@ -18,5 +23,21 @@ func NewCache() (cache.CacheInterface, error) {
 		return nil, err
 	}
 
-	return c, nil
+	if conf.RemoteCache.Endpoint == "" {
+		return c, nil
+	}
+
+	remote, err := http_storage.NewHttpStorage(
+		conf.RemoteCache.Endpoint,
+		http_storage.WithOverrideDefaults(conf.RemoteCache.RequestTimeout),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return tiered_cache.NewTieredCache(
+		c,
+		remote,
+		tiered_cache.WithOverrideDefaults(tiered_cache.WritePolicy(conf.RemoteCache.WritePolicy)),
+	)
 }
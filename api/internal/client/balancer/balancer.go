@@ -0,0 +1,418 @@
+package balancer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/KennyMacCormik/common/log"
+)
+
+type Policy string
+
+const (
+	PolicyRoundRobin       Policy = "round-robin"
+	PolicyLeastOutstanding Policy = "least-outstanding"
+	PolicyConsistentHash   Policy = "consistent-hash"
+)
+
+const (
+	defaultRefreshInterval     = 30 * time.Second
+	defaultHealthCheckInterval = 5 * time.Second
+	defaultHealthCheckTimeout  = time.Second
+	defaultUnhealthyThreshold  = 3
+	defaultHealthyThreshold    = 2
+	virtualNodes               = 100
+)
+
+var ErrNoEndpoints = errors.New("no backend endpoints")
+var ErrUnknownPolicy = errors.New("unknown balancing policy")
+
+// Endpoint is a single backend. Endpoints start healthy and keep their state across resolver refreshes.
+type Endpoint struct {
+	URL string
+
+	healthy     atomic.Bool
+	outstanding atomic.Int64
+
+	// accessed by the health checker only
+	successes, failures int
+}
+
+func (e *Endpoint) Healthy() bool {
+	return e.healthy.Load()
+}
+
+func (e *Endpoint) Outstanding() int64 {
+	return e.outstanding.Load()
+}
+
+type ringEntry struct {
+	hash     uint64
+	endpoint *Endpoint
+}
+
+// endpointSet is an immutable snapshot of endpoints replaced on every resolver refresh
+type endpointSet struct {
+	endpoints []*Endpoint
+	ring      []ringEntry
+}
+
+// Balancer picks backend endpoint for every request.
+// Endpoints are periodically re-resolved and actively health checked, unhealthy endpoints are ejected.
+// If all endpoints are unhealthy, Balancer picks from all of them rather than failing requests.
+type Balancer struct {
+	policy   Policy
+	resolver Resolver
+
+	refreshInterval                      time.Duration
+	healthPath                           string
+	healthInterval, healthTimeout        time.Duration
+	unhealthyThreshold, healthyThreshold int
+	healthClient                         *http.Client
+
+	set atomic.Pointer[endpointSet]
+	rr  atomic.Uint64
+
+	closeOnce sync.Once
+	closeCh   chan struct{}
+	wg        sync.WaitGroup
+}
+
+type InitOptions func(b *Balancer)
+
+// WithRefreshInterval sets how often endpoints are re-resolved. Static endpoints are never refreshed.
+func WithRefreshInterval(interval time.Duration) InitOptions {
+	return func(b *Balancer) {
+		if interval <= 0 {
+			interval = defaultRefreshInterval
+		}
+
+		b.refreshInterval = interval
+	}
+}
+
+// WithHealthCheck enables active health checking with GET requests to path relative to the endpoint host.
+// Endpoint is ejected after unhealthyThreshold consecutive failures and returned after healthyThreshold successes.
+func WithHealthCheck(path string, interval, timeout time.Duration, unhealthyThreshold, healthyThreshold int) InitOptions {
+	return func(b *Balancer) {
+		if interval <= 0 {
+			interval = defaultHealthCheckInterval
+		}
+
+		if timeout <= 0 {
+			timeout = defaultHealthCheckTimeout
+		}
+
+		if unhealthyThreshold < 1 {
+			unhealthyThreshold = defaultUnhealthyThreshold
+		}
+
+		if healthyThreshold < 1 {
+			healthyThreshold = defaultHealthyThreshold
+		}
+
+		b.healthPath = path
+		b.healthInterval = interval
+		b.healthTimeout = timeout
+		b.unhealthyThreshold = unhealthyThreshold
+		b.healthyThreshold = healthyThreshold
+	}
+}
+
+// NewBalancer resolves endpoints and starts background refresh and health checks. Close stops them.
+func NewBalancer(ctx context.Context, policy Policy, resolver Resolver, opts ...InitOptions) (*Balancer, error) {
+	const wrap = "NewBalancer"
+
+	switch policy {
+	case PolicyRoundRobin, PolicyLeastOutstanding, PolicyConsistentHash:
+	default:
+		return nil, fmt.Errorf("%s: %w: %s", wrap, ErrUnknownPolicy, policy)
+	}
+
+	b := &Balancer{
+		policy:          policy,
+		resolver:        resolver,
+		refreshInterval: defaultRefreshInterval,
+		closeCh:         make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	if err := b.refresh(ctx); err != nil {
+		return nil, fmt.Errorf("%s: %w", wrap, err)
+	}
+
+	if _, ok := resolver.(*staticResolver); !ok {
+		b.wg.Add(1)
+		go b.refreshLoop()
+	}
+
+	if b.healthPath != "" {
+		b.healthClient = &http.Client{Timeout: b.healthTimeout}
+		b.wg.Add(1)
+		go b.healthLoop()
+	}
+
+	return b, nil
+}
+
+// Pick returns endpoint for the request. key is only used by the consistent hashing policy.
+// Excluded endpoints are never returned, ErrNoEndpoints is returned if there is no other endpoint.
+// Returned function must be called once the request is finished.
+func (b *Balancer) Pick(key string, exclude ...*Endpoint) (*Endpoint, func(), error) {
+	set := b.set.Load()
+
+	all := make([]*Endpoint, 0, len(set.endpoints))
+	candidates := make([]*Endpoint, 0, len(set.endpoints))
+	for _, e := range set.endpoints {
+		if slices.Contains(exclude, e) {
+			continue
+		}
+
+		all = append(all, e)
+		if e.Healthy() {
+			candidates = append(candidates, e)
+		}
+	}
+
+	if len(all) == 0 {
+		return nil, nil, ErrNoEndpoints
+	}
+
+	anyHealthy := len(candidates) > 0
+	if !anyHealthy {
+		candidates = all
+	}
+
+	var e *Endpoint
+
+	switch b.policy {
+	case PolicyRoundRobin:
+		e = candidates[b.rr.Add(1)%uint64(len(candidates))]
+	case PolicyLeastOutstanding:
+		// start from the next endpoint to spread requests between equally loaded ones
+		start := int(b.rr.Add(1) % uint64(len(candidates)))
+		e = candidates[start]
+		for i := 1; i < len(candidates); i++ {
+			c := candidates[(start+i)%len(candidates)]
+			if c.Outstanding() < e.Outstanding() {
+				e = c
+			}
+		}
+	case PolicyConsistentHash:
+		e = set.lookup(hashString(key), func(e *Endpoint) bool {
+			return slices.Contains(candidates, e)
+		})
+	}
+
+	e.outstanding.Add(1)
+
+	return e, func() { e.outstanding.Add(-1) }, nil
+}
+
+// Endpoints returns current endpoints
+func (b *Balancer) Endpoints() []*Endpoint {
+	return slices.Clone(b.set.Load().endpoints)
+}
+
+func (b *Balancer) Close() {
+	b.closeOnce.Do(func() {
+		close(b.closeCh)
+		b.wg.Wait()
+	})
+}
+
+// lookup returns the first endpoint clockwise from hash accepted by fn. fn must accept at least one endpoint.
+func (s *endpointSet) lookup(hash uint64, fn func(e *Endpoint) bool) *Endpoint {
+	i, _ := slices.BinarySearchFunc(s.ring, hash, func(e ringEntry, h uint64) int {
+		switch {
+		case e.hash < h:
+			return -1
+		case e.hash > h:
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	for n := 0; n < len(s.ring); n++ {
+		e := s.ring[(i+n)%len(s.ring)].endpoint
+		if fn(e) {
+			return e
+		}
+	}
+
+	return nil
+}
+
+func (b *Balancer) refresh(ctx context.Context) error {
+	urls, err := b.resolver.Resolve(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(urls) == 0 {
+		return ErrNoEndpoints
+	}
+
+	existing := make(map[string]*Endpoint)
+	if old := b.set.Load(); old != nil {
+		for _, e := range old.endpoints {
+			existing[e.URL] = e
+		}
+	}
+
+	set := &endpointSet{
+		endpoints: make([]*Endpoint, 0, len(urls)),
+		ring:      make([]ringEntry, 0, len(urls)*virtualNodes),
+	}
+
+	for _, u := range urls {
+		e, ok := existing[u]
+		if !ok {
+			e = &Endpoint{URL: u}
+			e.healthy.Store(true)
+		}
+
+		set.endpoints = append(set.endpoints, e)
+
+		for i := 0; i < virtualNodes; i++ {
+			set.ring = append(set.ring, ringEntry{hash: hashString(u + "#" + strconv.Itoa(i)), endpoint: e})
+		}
+	}
+
+	slices.SortFunc(set.ring, func(a, b ringEntry) int {
+		switch {
+		case a.hash < b.hash:
+			return -1
+		case a.hash > b.hash:
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	b.set.Store(set)
+
+	return nil
+}
+
+func (b *Balancer) refreshLoop() {
+	defer b.wg.Done()
+
+	ticker := time.NewTicker(b.refreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), b.refreshInterval)
+			if err := b.refresh(ctx); err != nil {
+				log.Warn("failed to refresh backend endpoints, keeping previous ones", "err", err)
+			}
+			cancel()
+		case <-b.closeCh:
+			return
+		}
+	}
+}
+
+func (b *Balancer) healthLoop() {
+	defer b.wg.Done()
+
+	ticker := time.NewTicker(b.healthInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			b.checkAll()
+		case <-b.closeCh:
+			return
+		}
+	}
+}
+
+func (b *Balancer) checkAll() {
+	var wg sync.WaitGroup
+
+	for _, e := range b.set.Load().endpoints {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b.updateHealth(e, b.check(e))
+		}()
+	}
+
+	wg.Wait()
+}
+
+func (b *Balancer) check(e *Endpoint) error {
+	u, err := url.Parse(e.URL)
+	if err != nil {
+		return err
+	}
+
+	u.Path, u.RawPath, u.RawQuery = b.healthPath, "", ""
+
+	resp, err := b.healthClient.Get(u.String())
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("health check status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+func (b *Balancer) updateHealth(e *Endpoint, err error) {
+	if err == nil {
+		e.failures = 0
+		e.successes++
+
+		if !e.Healthy() && e.successes >= b.healthyThreshold {
+			e.healthy.Store(true)
+			log.Info("backend endpoint is healthy", "endpoint", e.URL)
+		}
+
+		return
+	}
+
+	e.successes = 0
+	e.failures++
+
+	if e.Healthy() && e.failures >= b.unhealthyThreshold {
+		e.healthy.Store(false)
+		log.Warn("backend endpoint is unhealthy", "endpoint", e.URL, "err", err)
+	}
+}
+
+// hashString returns FNV-1a hash finalized with murmur3 fmix64,
+// as FNV alone distributes similar virtual node names unevenly over the ring
+func hashString(s string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(s))
+
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+
+	return x
+}
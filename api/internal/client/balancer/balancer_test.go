@@ -0,0 +1,259 @@
+package balancer
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeResolver returns endpoints set by the test, err takes precedence
+type fakeResolver struct {
+	mtx       sync.Mutex
+	endpoints []string
+	err       error
+}
+
+func (f *fakeResolver) Resolve(_ context.Context) ([]string, error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	return f.endpoints, f.err
+}
+
+func (f *fakeResolver) set(endpoints []string, err error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	f.endpoints, f.err = endpoints, err
+}
+
+func newTestBalancer(t *testing.T, policy Policy, endpoints ...string) *Balancer {
+	b, err := NewBalancer(context.Background(), policy, NewStaticResolver(endpoints))
+	require.NoError(t, err, "expect no error with valid configuration")
+	t.Cleanup(b.Close)
+
+	return b
+}
+
+// pick returns URL of the picked endpoint releasing it at once
+func pick(t *testing.T, b *Balancer, key string, exclude ...*Endpoint) string {
+	e, done, err := b.Pick(key, exclude...)
+	require.NoError(t, err, "expect endpoint to be picked")
+	done()
+
+	return e.URL
+}
+
+func TestNewBalancer(t *testing.T) {
+	_, err := NewBalancer(context.Background(), "random", NewStaticResolver([]string{"http://a"}))
+	assert.ErrorIs(t, err, ErrUnknownPolicy, "expect unknown policy to be rejected")
+
+	_, err = NewBalancer(context.Background(), PolicyRoundRobin, NewStaticResolver(nil))
+	assert.ErrorIs(t, err, ErrNoEndpoints, "expect empty endpoints to be rejected")
+
+	_, err = NewBalancer(context.Background(), PolicyRoundRobin, &fakeResolver{err: assert.AnError})
+	assert.ErrorIs(t, err, assert.AnError, "expect resolver error")
+}
+
+func TestPick_RoundRobin(t *testing.T) {
+	b := newTestBalancer(t, PolicyRoundRobin, "http://a", "http://b", "http://c")
+
+	picked := map[string]int{}
+	for i := 0; i < 30; i++ {
+		picked[pick(t, b, "key")]++
+	}
+
+	assert.Equal(t, map[string]int{"http://a": 10, "http://b": 10, "http://c": 10}, picked, "expect even rotation")
+}
+
+func TestPick_LeastOutstanding(t *testing.T) {
+	b := newTestBalancer(t, PolicyLeastOutstanding, "http://a", "http://b")
+
+	busy, done, err := b.Pick("key")
+	require.NoError(t, err, "expect endpoint to be picked")
+	assert.Equal(t, int64(1), busy.Outstanding(), "expect picked endpoint to count outstanding request")
+
+	for i := 0; i < 4; i++ {
+		assert.NotEqual(t, busy.URL, pick(t, b, "key"), "expect idle endpoint to be picked")
+	}
+
+	done()
+	assert.Equal(t, int64(0), busy.Outstanding(), "expect done to release the request")
+
+	picked := map[string]int{}
+	for i := 0; i < 4; i++ {
+		picked[pick(t, b, "key")]++
+	}
+	assert.Equal(t, map[string]int{"http://a": 2, "http://b": 2}, picked, "expect equally loaded endpoints to share requests")
+}
+
+func TestPick_ConsistentHash(t *testing.T) {
+	const keys = 3000
+
+	r := &fakeResolver{endpoints: []string{"http://a", "http://b", "http://c"}}
+	b, err := NewBalancer(context.Background(), PolicyConsistentHash, r)
+	require.NoError(t, err, "expect no error with valid configuration")
+	t.Cleanup(b.Close)
+
+	before := make(map[string]string, keys)
+	counts := map[string]int{}
+	for i := 0; i < keys; i++ {
+		key := "key-" + strconv.Itoa(i)
+		before[key] = pick(t, b, key)
+		counts[before[key]]++
+		require.Equal(t, before[key], pick(t, b, key), "expect key to stick to its endpoint")
+	}
+
+	for url, n := range counts {
+		assert.InDelta(t, keys/3, n, keys/10, "expect keys to be spread evenly, endpoint %s", url)
+	}
+
+	r.set([]string{"http://a", "http://b"}, nil)
+	require.NoError(t, b.refresh(context.Background()), "expect refresh to succeed")
+
+	for key, url := range before {
+		if url != "http://c" {
+			assert.Equal(t, url, pick(t, b, key), "expect keys of remaining endpoints not to move")
+		}
+	}
+}
+
+func TestPick_ConsistentHashFallback(t *testing.T) {
+	b := newTestBalancer(t, PolicyConsistentHash, "http://a", "http://b", "http://c")
+
+	url := pick(t, b, "key")
+	for _, e := range b.Endpoints() {
+		if e.URL == url {
+			e.healthy.Store(false)
+		}
+	}
+
+	next := pick(t, b, "key")
+	assert.NotEqual(t, url, next, "expect unhealthy endpoint to be skipped")
+	assert.Equal(t, next, pick(t, b, "key"), "expect key to stick to the next endpoint on the ring")
+}
+
+func TestPick_Exclude(t *testing.T) {
+	for _, policy := range []Policy{PolicyRoundRobin, PolicyLeastOutstanding, PolicyConsistentHash} {
+		t.Run(string(policy), func(t *testing.T) {
+			b := newTestBalancer(t, policy, "http://a", "http://b")
+			endpoints := b.Endpoints()
+
+			for i := 0; i < 4; i++ {
+				assert.Equal(t, "http://b", pick(t, b, strconv.Itoa(i), endpoints[0]), "expect excluded endpoint not to be picked")
+			}
+
+			_, _, err := b.Pick("key", endpoints...)
+			assert.ErrorIs(t, err, ErrNoEndpoints, "expect error if every endpoint is excluded")
+		})
+	}
+}
+
+func TestPick_Unhealthy(t *testing.T) {
+	b := newTestBalancer(t, PolicyRoundRobin, "http://a", "http://b")
+	endpoints := b.Endpoints()
+
+	endpoints[0].healthy.Store(false)
+	for i := 0; i < 4; i++ {
+		assert.Equal(t, "http://b", pick(t, b, "key"), "expect unhealthy endpoint to be ejected")
+	}
+
+	endpoints[1].healthy.Store(false)
+	picked := map[string]int{}
+	for i := 0; i < 4; i++ {
+		picked[pick(t, b, "key")]++
+	}
+	assert.Len(t, picked, 2, "expect all endpoints to be used if none is healthy")
+}
+
+func TestUpdateHealth(t *testing.T) {
+	b := &Balancer{unhealthyThreshold: 3, healthyThreshold: 2}
+	e := &Endpoint{URL: "http://a"}
+	e.healthy.Store(true)
+
+	testCases := []struct {
+		name    string
+		err     error
+		healthy bool
+	}{
+		{"first failure", assert.AnError, true},
+		{"second failure", assert.AnError, true},
+		{"success resets failures", nil, true},
+		{"failure after reset", assert.AnError, true},
+		{"second failure after reset", assert.AnError, true},
+		{"unhealthy threshold", assert.AnError, false},
+		{"first success", nil, false},
+		{"failure resets successes", assert.AnError, false},
+		{"success after reset", nil, false},
+		{"healthy threshold", nil, true},
+	}
+
+	// cases are consecutive checks of the same endpoint
+	for _, tc := range testCases {
+		b.updateHealth(e, tc.err)
+		assert.Equal(t, tc.healthy, e.Healthy(), "Unexpected health after %s", tc.name)
+	}
+}
+
+func TestHealthCheck(t *testing.T) {
+	var failing atomic.Bool
+	var path atomic.Value
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path.Store(r.URL.Path)
+		if failing.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(srv.Close)
+
+	b, err := NewBalancer(context.Background(), PolicyRoundRobin, NewStaticResolver([]string{srv.URL + "/storage?x=1"}),
+		WithHealthCheck("/health", 10*time.Millisecond, time.Second, 2, 2))
+	require.NoError(t, err, "expect no error with valid configuration")
+	t.Cleanup(b.Close)
+
+	e := b.Endpoints()[0]
+
+	failing.Store(true)
+	require.Eventually(t, func() bool { return !e.Healthy() }, time.Second, time.Millisecond, "expect failing endpoint to be ejected")
+	assert.Equal(t, "/health", path.Load(), "expect health path to replace the endpoint path")
+
+	failing.Store(false)
+	require.Eventually(t, e.Healthy, time.Second, time.Millisecond, "expect recovered endpoint to return")
+}
+
+func TestRefresh(t *testing.T) {
+	r := &fakeResolver{endpoints: []string{"http://a", "http://b"}}
+	b, err := NewBalancer(context.Background(), PolicyRoundRobin, r, WithRefreshInterval(10*time.Millisecond))
+	require.NoError(t, err, "expect no error with valid configuration")
+	t.Cleanup(b.Close)
+
+	b.Endpoints()[0].healthy.Store(false)
+
+	r.set([]string{"http://a", "http://c"}, nil)
+	require.Eventually(t, func() bool {
+		endpoints := b.Endpoints()
+		return len(endpoints) == 2 && endpoints[1].URL == "http://c"
+	}, time.Second, time.Millisecond, "expect endpoints to be re-resolved")
+
+	endpoints := b.Endpoints()
+	assert.False(t, endpoints[0].Healthy(), "expect existing endpoint to keep its state")
+	assert.True(t, endpoints[1].Healthy(), "expect new endpoint to start healthy")
+
+	r.set(nil, errors.New("dns failure"))
+	assert.Error(t, b.refresh(context.Background()), "expect resolver error")
+	r.set(nil, nil)
+	assert.ErrorIs(t, b.refresh(context.Background()), ErrNoEndpoints, "expect empty result to be rejected")
+	assert.Equal(t, endpoints, b.Endpoints(), "expect previous endpoints to be kept on failure")
+}
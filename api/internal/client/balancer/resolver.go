@@ -0,0 +1,92 @@
+package balancer
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Resolver returns base URLs of backend endpoints
+type Resolver interface {
+	Resolve(ctx context.Context) ([]string, error)
+}
+
+type staticResolver struct {
+	endpoints []string
+}
+
+// NewStaticResolver returns Resolver of the fixed list of endpoints
+func NewStaticResolver(endpoints []string) Resolver {
+	return &staticResolver{endpoints: slices.Clone(endpoints)}
+}
+
+func (s *staticResolver) Resolve(_ context.Context) ([]string, error) {
+	return s.endpoints, nil
+}
+
+type dnsResolver struct {
+	template *url.URL
+	srv      bool
+	resolver *net.Resolver
+}
+
+// NewDNSResolver returns Resolver of the template URL host.
+// With srv set, the host is a SRV record name, e.g. http://_http._tcp.backend/storage, and ports are taken from SRV records.
+// Otherwise the host is resolved to A/AAAA records and the template port is used.
+// Scheme and path of the template are kept.
+func NewDNSResolver(template string, srv bool) (Resolver, error) {
+	u, err := url.Parse(template)
+	if err != nil {
+		return nil, fmt.Errorf("NewDNSResolver: %w", err)
+	}
+
+	return &dnsResolver{template: u, srv: srv, resolver: net.DefaultResolver}, nil
+}
+
+func (d *dnsResolver) Resolve(ctx context.Context) ([]string, error) {
+	const wrap = "dnsResolver/Resolve"
+
+	var hosts []string
+
+	if d.srv {
+		_, records, err := d.resolver.LookupSRV(ctx, "", "", d.template.Hostname())
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", wrap, err)
+		}
+
+		for _, r := range records {
+			hosts = append(hosts, net.JoinHostPort(strings.TrimSuffix(r.Target, "."), strconv.Itoa(int(r.Port))))
+		}
+	} else {
+		addrs, err := d.resolver.LookupHost(ctx, d.template.Hostname())
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", wrap, err)
+		}
+
+		for _, addr := range addrs {
+			if port := d.template.Port(); port != "" {
+				hosts = append(hosts, net.JoinHostPort(addr, port))
+			} else if strings.Contains(addr, ":") {
+				hosts = append(hosts, "["+addr+"]")
+			} else {
+				hosts = append(hosts, addr)
+			}
+		}
+	}
+
+	// stable order keeps consistent hashing ring stable between refreshes
+	slices.Sort(hosts)
+
+	result := make([]string, 0, len(hosts))
+	for _, host := range hosts {
+		u := *d.template
+		u.Host = host
+		result = append(result, u.String())
+	}
+
+	return result, nil
+}
@@ -0,0 +1,45 @@
+package balancer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStaticResolver(t *testing.T) {
+	endpoints := []string{"http://b", "http://a"}
+	r := NewStaticResolver(endpoints)
+	endpoints[0] = "http://c"
+
+	got, err := r.Resolve(context.Background())
+	require.NoError(t, err, "expect no error")
+	assert.Equal(t, []string{"http://b", "http://a"}, got, "expect endpoints to be copied in order")
+}
+
+func TestDNSResolver(t *testing.T) {
+	testCases := []struct {
+		name     string
+		template string
+		expected string
+	}{
+		{"with port", "http://localhost:8080/storage", "http://127.0.0.1:8080/storage"},
+		{"without port", "https://localhost/storage", "https://127.0.0.1/storage"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := NewDNSResolver(tc.template, false)
+			require.NoError(t, err, "expect valid template")
+
+			got, err := r.Resolve(context.Background())
+			require.NoError(t, err, "expect localhost to be resolved")
+			assert.Contains(t, got, tc.expected, "expect template scheme, port and path to be kept")
+			assert.IsNonDecreasing(t, got, "expect stable order")
+		})
+	}
+
+	_, err := NewDNSResolver("://invalid", false)
+	assert.Error(t, err, "expect invalid template to be rejected")
+}
@@ -0,0 +1,371 @@
+package circuit_breaker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/KennyMacCormik/common/log"
+	cacheErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/cache"
+	httpErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/http"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/KennyMacCormik/otel/api/internal/client"
+)
+
+type State int
+
+const (
+	StateClosed State = iota
+	StateHalfOpen
+	StateOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateHalfOpen:
+		return "half-open"
+	case StateOpen:
+		return "open"
+	default:
+		return "unknown"
+	}
+}
+
+const (
+	defaultWindowSize       = 100
+	defaultMinRequests      = 20
+	defaultErrorRate        = 0.5
+	defaultSlowCallDuration = 500 * time.Millisecond
+	defaultSlowCallRate     = 0.8
+	defaultOpenTimeout      = 5 * time.Second
+	defaultHalfOpenMaxCalls = 5
+)
+
+// outcome of a single call in the sliding window
+type outcome struct {
+	failed, slow bool
+}
+
+// circuitBreaker wraps client.BackendClientInterface and stops calling the backend
+// once the error rate or the slow call rate over the last windowSize calls exceeds the threshold.
+// After openTimeout it lets halfOpenMaxCalls probes through, the circuit closes if all of them succeed.
+// Not found responses and calls cancelled by the caller are not counted as failures.
+type circuitBreaker struct {
+	impl client.BackendClientInterface
+
+	windowSize, minRequests, halfOpenMaxCalls int
+	errorRate, slowCallRate                   float64
+	slowCallDuration, openTimeout             time.Duration
+
+	mtx             sync.Mutex
+	state           State
+	generation      uint64
+	openedAt        time.Time
+	window          []outcome
+	next, count     int
+	failures, slows int
+	halfOpenCalls   int
+	halfOpenSuccess int
+
+	registerer     prometheus.Registerer
+	metricState    prometheus.Gauge
+	metricRejected prometheus.Counter
+	metricOpened   prometheus.Counter
+
+	now func() time.Time
+}
+
+type InitOptions func(cb *circuitBreaker)
+
+// WithOverrideDefaults sets thresholds. Invalid values fall back to defaults.
+// Rates are fractions of failed or slow calls in the window, e.g. 0.5.
+func WithOverrideDefaults(windowSize, minRequests int, errorRate float64,
+	slowCallDuration time.Duration, slowCallRate float64, openTimeout time.Duration, halfOpenMaxCalls int) InitOptions {
+	return func(cb *circuitBreaker) {
+		if windowSize < 1 {
+			windowSize = defaultWindowSize
+		}
+
+		if minRequests < 1 || minRequests > windowSize {
+			minRequests = min(defaultMinRequests, windowSize)
+		}
+
+		if errorRate <= 0 || errorRate > 1 {
+			errorRate = defaultErrorRate
+		}
+
+		if slowCallDuration <= 0 {
+			slowCallDuration = defaultSlowCallDuration
+		}
+
+		if slowCallRate <= 0 || slowCallRate > 1 {
+			slowCallRate = defaultSlowCallRate
+		}
+
+		if openTimeout <= 0 {
+			openTimeout = defaultOpenTimeout
+		}
+
+		if halfOpenMaxCalls < 1 {
+			halfOpenMaxCalls = defaultHalfOpenMaxCalls
+		}
+
+		cb.windowSize = windowSize
+		cb.minRequests = minRequests
+		cb.errorRate = errorRate
+		cb.slowCallDuration = slowCallDuration
+		cb.slowCallRate = slowCallRate
+		cb.openTimeout = openTimeout
+		cb.halfOpenMaxCalls = halfOpenMaxCalls
+	}
+}
+
+// WithRegisterer registers metrics with reg instead of prometheus.DefaultRegisterer
+func WithRegisterer(reg prometheus.Registerer) InitOptions {
+	return func(cb *circuitBreaker) {
+		if reg != nil {
+			cb.registerer = reg
+		}
+	}
+}
+
+// NewCircuitBreaker returns initialized circuit breaker.
+// Circuit breakers registered with the same registerer share their metrics.
+func NewCircuitBreaker(impl client.BackendClientInterface, opts ...InitOptions) client.BackendClientInterface {
+	cb := &circuitBreaker{
+		impl:             impl,
+		windowSize:       defaultWindowSize,
+		minRequests:      defaultMinRequests,
+		errorRate:        defaultErrorRate,
+		slowCallDuration: defaultSlowCallDuration,
+		slowCallRate:     defaultSlowCallRate,
+		openTimeout:      defaultOpenTimeout,
+		halfOpenMaxCalls: defaultHalfOpenMaxCalls,
+		registerer:       prometheus.DefaultRegisterer,
+		now:              time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(cb)
+	}
+
+	cb.window = make([]outcome, cb.windowSize)
+
+	reg := cb.registerer
+	f := promauto.With(nil)
+
+	cb.metricState = registerMetric(reg, f.NewGauge(prometheus.GaugeOpts{
+		Name: "backend_circuit_breaker_state",
+		Help: "Current circuit breaker state: 0 closed, 1 half-open, 2 open",
+	}))
+	cb.metricRejected = registerMetric(reg, f.NewCounter(prometheus.CounterOpts{
+		Name: "backend_circuit_breaker_rejected_requests",
+		Help: "Total number of backend requests rejected by the open circuit",
+	}))
+	cb.metricOpened = registerMetric(reg, f.NewCounter(prometheus.CounterOpts{
+		Name: "backend_circuit_breaker_opened",
+		Help: "Total number of times the circuit was opened",
+	}))
+
+	return cb
+}
+
+func (cb *circuitBreaker) Get(ctx context.Context, key, requestId string) (any, error) {
+	var val any
+
+	err := cb.call(ctx, func() error {
+		var err error
+		val, err = cb.impl.Get(ctx, key, requestId)
+		return err
+	})
+
+	return val, err
+}
+
+func (cb *circuitBreaker) Set(ctx context.Context, key string, value any, requestId string) (int, error) {
+	var code int
+
+	err := cb.call(ctx, func() error {
+		var err error
+		code, err = cb.impl.Set(ctx, key, value, requestId)
+		return err
+	})
+
+	return code, err
+}
+
+func (cb *circuitBreaker) Delete(ctx context.Context, key, requestId string) error {
+	return cb.call(ctx, func() error {
+		return cb.impl.Delete(ctx, key, requestId)
+	})
+}
+
+func (cb *circuitBreaker) call(ctx context.Context, fn func() error) error {
+	span := trace.SpanFromContext(ctx)
+
+	generation, state, ok := cb.allow()
+	span.SetAttributes(attribute.String("circuit_breaker.state", state.String()))
+
+	if !ok {
+		cb.metricRejected.Inc()
+		span.AddEvent("circuit breaker rejected request")
+		return client.ErrCircuitOpen
+	}
+
+	start := cb.now()
+	err := fn()
+	elapsed := cb.now().Sub(start)
+
+	if ctx.Err() != nil {
+		cb.release(generation)
+		return err
+	}
+
+	cb.record(generation, outcome{
+		failed: isFailure(err),
+		slow:   elapsed >= cb.slowCallDuration,
+	})
+
+	return err
+}
+
+// allow returns the generation of the state the call was admitted in
+func (cb *circuitBreaker) allow() (uint64, State, bool) {
+	cb.mtx.Lock()
+	defer cb.mtx.Unlock()
+
+	if cb.state == StateOpen && cb.now().Sub(cb.openedAt) >= cb.openTimeout {
+		cb.setState(StateHalfOpen)
+	}
+
+	switch cb.state {
+	case StateOpen:
+		return cb.generation, cb.state, false
+	case StateHalfOpen:
+		if cb.halfOpenCalls >= cb.halfOpenMaxCalls {
+			return cb.generation, cb.state, false
+		}
+		cb.halfOpenCalls++
+	}
+
+	return cb.generation, cb.state, true
+}
+
+// release frees the half-open slot of a call whose outcome is unknown
+func (cb *circuitBreaker) release(generation uint64) {
+	cb.mtx.Lock()
+	defer cb.mtx.Unlock()
+
+	if generation == cb.generation && cb.state == StateHalfOpen {
+		cb.halfOpenCalls--
+	}
+}
+
+func (cb *circuitBreaker) record(generation uint64, o outcome) {
+	cb.mtx.Lock()
+	defer cb.mtx.Unlock()
+
+	// outcome belongs to the previous state
+	if generation != cb.generation {
+		return
+	}
+
+	switch cb.state {
+	case StateHalfOpen:
+		if o.failed || o.slow {
+			cb.setState(StateOpen)
+			return
+		}
+
+		cb.halfOpenSuccess++
+		if cb.halfOpenSuccess >= cb.halfOpenMaxCalls {
+			cb.setState(StateClosed)
+		}
+	case StateClosed:
+		cb.push(o)
+
+		if cb.count < cb.minRequests {
+			return
+		}
+
+		if float64(cb.failures)/float64(cb.count) >= cb.errorRate ||
+			float64(cb.slows)/float64(cb.count) >= cb.slowCallRate {
+			cb.setState(StateOpen)
+		}
+	}
+}
+
+// push adds outcome to the sliding window evicting the oldest one
+func (cb *circuitBreaker) push(o outcome) {
+	if cb.count == cb.windowSize {
+		old := cb.window[cb.next]
+		if old.failed {
+			cb.failures--
+		}
+		if old.slow {
+			cb.slows--
+		}
+	} else {
+		cb.count++
+	}
+
+	cb.window[cb.next] = o
+	cb.next = (cb.next + 1) % cb.windowSize
+
+	if o.failed {
+		cb.failures++
+	}
+	if o.slow {
+		cb.slows++
+	}
+}
+
+// setState must be called with mtx held
+func (cb *circuitBreaker) setState(state State) {
+	log.Warn("backend circuit breaker state changed", "from", cb.state.String(), "to", state.String())
+
+	cb.state = state
+	cb.generation++
+	cb.halfOpenCalls, cb.halfOpenSuccess = 0, 0
+	cb.next, cb.count, cb.failures, cb.slows = 0, 0, 0, 0
+
+	if state == StateOpen {
+		cb.openedAt = cb.now()
+		cb.metricOpened.Inc()
+	}
+
+	cb.metricState.Set(float64(state))
+}
+
+// isFailure reports whether err indicates unhealthy backend.
+// Errors caused by the request itself and policy rejections are not failures.
+func isFailure(err error) bool {
+	return err != nil && !errors.Is(err, cacheErrors.ErrNotFound) && !errors.Is(err, httpErrors.ErrBadRequest) &&
+		!client.IsRejected(err)
+}
+
+// registerMetric registers c with reg. If an equal metric is already registered, it is returned instead.
+func registerMetric[T prometheus.Collector](reg prometheus.Registerer, c T) T {
+	err := reg.Register(c)
+	if err == nil {
+		return c
+	}
+
+	var are prometheus.AlreadyRegisteredError
+	if errors.As(err, &are) {
+		if existing, ok := are.ExistingCollector.(T); ok {
+			return existing
+		}
+	}
+
+	log.Error("failed to register circuit breaker metric", "err", err)
+
+	return c
+}
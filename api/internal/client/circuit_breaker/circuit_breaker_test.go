@@ -0,0 +1,260 @@
+package circuit_breaker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	cacheErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/cache"
+	httpErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/http"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KennyMacCormik/otel/api/internal/client"
+)
+
+const (
+	testSlowCall    = 100 * time.Millisecond
+	testOpenTimeout = time.Second
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+// fakeClient returns err of the next call, every call takes latency on the fake clock
+type fakeClient struct {
+	clock   *fakeClock
+	err     error
+	latency time.Duration
+	calls   int
+}
+
+func (f *fakeClient) call() error {
+	f.calls++
+	f.clock.Advance(f.latency)
+	return f.err
+}
+
+func (f *fakeClient) Get(context.Context, string, string) (any, error) {
+	return "value", f.call()
+}
+
+func (f *fakeClient) Set(context.Context, string, any, string) (int, error) {
+	return 201, f.call()
+}
+
+func (f *fakeClient) Delete(context.Context, string, string) error {
+	return f.call()
+}
+
+// newTestBreaker returns breaker with window of 10 calls, 4 min requests, 0.5 error and slow call rates
+// and 2 half-open probes
+func newTestBreaker(t *testing.T) (*circuitBreaker, *fakeClient, *fakeClock) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	impl := &fakeClient{clock: clock}
+
+	c := NewCircuitBreaker(impl,
+		WithOverrideDefaults(10, 4, 0.5, testSlowCall, 0.5, testOpenTimeout, 2),
+		WithRegisterer(prometheus.NewRegistry()),
+	)
+	cb, ok := c.(*circuitBreaker)
+	require.True(t, ok, "expect *circuitBreaker")
+	cb.now = clock.Now
+
+	return cb, impl, clock
+}
+
+func getState(cb *circuitBreaker) State {
+	cb.mtx.Lock()
+	defer cb.mtx.Unlock()
+
+	return cb.state
+}
+
+// run makes a call for every error, nil means success
+func run(cb *circuitBreaker, impl *fakeClient, errs ...error) {
+	for _, err := range errs {
+		impl.err = err
+		_, _ = cb.Get(context.Background(), "key", "id")
+	}
+	impl.err = nil
+}
+
+func TestCircuitBreaker_Closed(t *testing.T) {
+	testCases := []struct {
+		name    string
+		errs    []error
+		latency time.Duration
+		state   State
+	}{
+		{"success", []error{nil, nil, nil, nil, nil}, 0, StateClosed},
+		{"below min requests", []error{assert.AnError, assert.AnError, assert.AnError}, 0, StateClosed},
+		{"below error rate", []error{assert.AnError, nil, nil, nil, assert.AnError, nil}, 0, StateClosed},
+		{"error rate", []error{nil, assert.AnError, nil, assert.AnError}, 0, StateOpen},
+		{"not found", []error{cacheErrors.ErrNotFound, cacheErrors.ErrNotFound, cacheErrors.ErrNotFound, cacheErrors.ErrNotFound}, 0, StateClosed},
+		{"bad request", []error{httpErrors.ErrBadRequest, httpErrors.ErrBadRequest, httpErrors.ErrBadRequest, httpErrors.ErrBadRequest}, 0, StateClosed},
+		{"quota exceeded", []error{cacheErrors.ErrQuotaExceeded, cacheErrors.ErrQuotaExceeded, cacheErrors.ErrQuotaExceeded, cacheErrors.ErrQuotaExceeded}, 0, StateClosed},
+		{"write limited", []error{cacheErrors.ErrWriteRateLimited, cacheErrors.ErrWriteRateLimited, cacheErrors.ErrWriteRateLimited, cacheErrors.ErrWriteRateLimited}, 0, StateClosed},
+		{"slow calls", []error{nil, nil, nil, nil}, testSlowCall, StateOpen},
+		{"fast calls", []error{nil, nil, nil, nil}, testSlowCall - time.Millisecond, StateClosed},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cb, impl, _ := newTestBreaker(t)
+			impl.latency = tc.latency
+
+			run(cb, impl, tc.errs...)
+			assert.Equal(t, tc.state, getState(cb), "Unexpected state")
+		})
+	}
+}
+
+func TestCircuitBreaker_SlidingWindow(t *testing.T) {
+	cb, impl, _ := newTestBreaker(t)
+
+	run(cb, impl, assert.AnError, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	require.Equal(t, StateClosed, getState(cb), "expect 1 error of 10 calls not to open the circuit")
+
+	// window is full, the first error is evicted while new ones are added
+	run(cb, impl, assert.AnError, assert.AnError, assert.AnError, assert.AnError)
+	assert.Equal(t, StateClosed, getState(cb), "expect evicted error not to count")
+
+	run(cb, impl, assert.AnError)
+	assert.Equal(t, StateOpen, getState(cb), "expect 5 errors of the last 10 calls to open the circuit")
+}
+
+func TestCircuitBreaker_Open(t *testing.T) {
+	cb, impl, clock := newTestBreaker(t)
+	run(cb, impl, assert.AnError, assert.AnError, assert.AnError, assert.AnError)
+	require.Equal(t, StateOpen, getState(cb), "expect circuit to open")
+
+	calls := impl.calls
+	_, err := cb.Get(context.Background(), "key", "id")
+	assert.ErrorIs(t, err, client.ErrCircuitOpen, "expect open circuit to reject calls")
+	assert.ErrorIs(t, err, httpErrors.ErrUnavailable, "expect rejection to be reported as unavailable")
+	assert.Equal(t, calls, impl.calls, "expect backend not to be called")
+	assert.Equal(t, 1.0, testutil.ToFloat64(cb.metricRejected), "expect rejection to be counted")
+	assert.Equal(t, 1.0, testutil.ToFloat64(cb.metricOpened), "expect opening to be counted")
+
+	clock.Advance(testOpenTimeout - time.Millisecond)
+	_, err = cb.Get(context.Background(), "key", "id")
+	assert.ErrorIs(t, err, client.ErrCircuitOpen, "expect circuit to stay open until the timeout")
+
+	clock.Advance(time.Millisecond)
+	_, err = cb.Get(context.Background(), "key", "id")
+	assert.NoError(t, err, "expect probe after the timeout")
+	assert.Equal(t, StateHalfOpen, getState(cb), "expect circuit to be half-open")
+	assert.Equal(t, float64(StateHalfOpen), testutil.ToFloat64(cb.metricState), "expect state gauge to follow the state")
+}
+
+func TestCircuitBreaker_HalfOpen(t *testing.T) {
+	testCases := []struct {
+		name    string
+		errs    []error
+		latency time.Duration
+		state   State
+	}{
+		{"probes succeed", []error{nil, nil}, 0, StateClosed},
+		{"probe fails", []error{nil, assert.AnError}, 0, StateOpen},
+		{"probe is slow", []error{nil}, testSlowCall, StateOpen},
+		{"probe not found", []error{cacheErrors.ErrNotFound, nil}, 0, StateClosed},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cb, impl, clock := newTestBreaker(t)
+			run(cb, impl, assert.AnError, assert.AnError, assert.AnError, assert.AnError)
+			clock.Advance(testOpenTimeout)
+
+			impl.latency = tc.latency
+			run(cb, impl, tc.errs...)
+			assert.Equal(t, tc.state, getState(cb), "Unexpected state")
+		})
+	}
+}
+
+func TestCircuitBreaker_HalfOpenMaxCalls(t *testing.T) {
+	cb, impl, clock := newTestBreaker(t)
+	run(cb, impl, assert.AnError, assert.AnError, assert.AnError, assert.AnError)
+	clock.Advance(testOpenTimeout)
+
+	// probes are admitted but haven't completed yet
+	gen1, _, ok := cb.allow()
+	require.True(t, ok, "expect first probe to be admitted")
+	gen2, _, ok := cb.allow()
+	require.True(t, ok, "expect second probe to be admitted")
+
+	_, err := cb.Get(context.Background(), "key", "id")
+	assert.ErrorIs(t, err, client.ErrCircuitOpen, "expect calls above half-open limit to be rejected")
+
+	// probe cancelled by the caller frees its slot
+	cb.release(gen1)
+	_, _, ok = cb.allow()
+	assert.True(t, ok, "expect released slot to be reused")
+
+	cb.record(gen2, outcome{})
+	assert.Equal(t, StateHalfOpen, getState(cb), "expect circuit to wait for all probes")
+}
+
+func TestCircuitBreaker_Generation(t *testing.T) {
+	cb, impl, _ := newTestBreaker(t)
+
+	// call admitted while closed completes after the circuit opened
+	gen, _, ok := cb.allow()
+	require.True(t, ok, "expect call to be admitted")
+
+	run(cb, impl, assert.AnError, assert.AnError, assert.AnError, assert.AnError)
+	require.Equal(t, StateOpen, getState(cb), "expect circuit to open")
+
+	cb.mtx.Lock()
+	cb.setState(StateHalfOpen)
+	cb.mtx.Unlock()
+	cb.record(gen, outcome{failed: true})
+	assert.Equal(t, StateHalfOpen, getState(cb), "expect outcome of the previous state to be ignored")
+
+	cb.release(gen)
+	cb.mtx.Lock()
+	assert.Equal(t, 0, cb.halfOpenCalls, "expect release of the previous state to be ignored")
+	cb.mtx.Unlock()
+}
+
+func TestCircuitBreaker_Cancelled(t *testing.T) {
+	cb, impl, _ := newTestBreaker(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	impl.err = context.Canceled
+	for i := 0; i < 10; i++ {
+		_, err := cb.Get(ctx, "key", "id")
+		assert.ErrorIs(t, err, context.Canceled, "expect caller error")
+	}
+
+	assert.Equal(t, StateClosed, getState(cb), "expect cancelled calls not to count")
+}
+
+func TestCircuitBreaker_Registerer(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	impl := &fakeClient{clock: &fakeClock{}}
+
+	assert.NotPanics(t, func() {
+		NewCircuitBreaker(impl, WithRegisterer(reg))
+		NewCircuitBreaker(impl, WithRegisterer(reg))
+	}, "expect breakers to share a registerer")
+
+	families, err := reg.Gather()
+	require.NoError(t, err, "expect metrics to be gathered")
+	assert.Len(t, families, 3, "expect metrics to be registered once")
+}
@@ -7,14 +7,16 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
 	"strings"
 	"time"
 
-	"github.com/KennyMacCormik/common/conv"
+	"github.com/KennyMacCormik/otel/backend/pkg/gin/gin_deadline"
 	"github.com/KennyMacCormik/otel/backend/pkg/gin/gin_request_id"
 	cacheErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/cache"
+	httpErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/http"
 	httpModels "github.com/KennyMacCormik/otel/backend/pkg/models/http"
 	otelHelpers "github.com/KennyMacCormik/otel/backend/pkg/otel/helpers"
 	"go.opentelemetry.io/otel"
@@ -22,20 +24,41 @@ import (
 	"go.opentelemetry.io/otel/propagation"
 
 	"github.com/KennyMacCormik/otel/api/internal/client"
+	"github.com/KennyMacCormik/otel/api/internal/client/balancer"
 )
 
 type clientImpl struct {
-	client  *http.Client
-	timeout time.Duration
-	backend string
+	client    *http.Client
+	timeout   time.Duration
+	balancer  *balancer.Balancer
+	retry     RetryPolicy
+	latencies *latencyTracker // nil if hedging is disabled
 }
 
-func NewBackendClient(backend string, timeout time.Duration) client.BackendClientInterface {
-	return &clientImpl{
-		backend: backend,
-		timeout: timeout,
-		client:  &http.Client{Timeout: timeout},
+// attemptResult is the outcome of a single request to backend
+type attemptResult struct {
+	body       []byte
+	code       int
+	retryAfter time.Duration
+	err        error
+}
+
+// NewBackendClient returns client of the backend storage. Every attempt is sent to the endpoint picked by the balancer.
+// timeout limits every single attempt, it is shrunk to the remaining deadline of the request context.
+func NewBackendClient(balancer *balancer.Balancer, timeout time.Duration, retry RetryPolicy, hedging HedgingPolicy,
+	transport TransportPolicy) client.BackendClientInterface {
+	c := &clientImpl{
+		balancer: balancer,
+		timeout:  timeout,
+		client:   &http.Client{Transport: newTransport(transport)},
+		retry:    retry,
 	}
+
+	if hedging.Enabled {
+		c.latencies = newLatencyTracker(hedging)
+	}
+
+	return c
 }
 
 func (c *clientImpl) Get(ctx context.Context, key, requestId string) (any, error) {
@@ -53,17 +76,14 @@ func (c *clientImpl) Get(ctx context.Context, key, requestId string) (any, error
 		return nil, err
 	}
 
-	span.SetAttributes(attribute.String("http.url", r.URL.String()))
-
 	r.Header.Set(gin_request_id.RequestIDKey, requestId)
-	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(r.Header))
 
-	b, _, err := c.invoke(r)
+	b, _, err := c.invoke(r, key)
 	if err != nil {
 		if errors.Is(err, cacheErrors.ErrNotFound) {
 			return nil, err
 		}
-		err = fmt.Errorf("%s %s: %s: %w", http.MethodPut, r.URL, spanName+".invoke", err)
+		err = fmt.Errorf("%s %s: %s: %w", http.MethodGet, r.URL, spanName+".invoke", err)
 		otelHelpers.SetSpanExceptionWithErr(span, err)
 		return nil, err
 	}
@@ -75,7 +95,7 @@ func (c *clientImpl) Get(ctx context.Context, key, requestId string) (any, error
 		return "", err
 	}
 
-	return response.Val, validateResponse(b)
+	return response.Val, nil
 }
 
 func (c *clientImpl) Set(ctx context.Context, key string, value any, requestId string) (int, error) {
@@ -94,12 +114,9 @@ func (c *clientImpl) Set(ctx context.Context, key string, value any, requestId s
 		return 0, err
 	}
 
-	span.SetAttributes(attribute.String("http.url", r.URL.String()))
-
 	r.Header.Set(gin_request_id.RequestIDKey, requestId)
-	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(r.Header))
 
-	_, code, err := c.invoke(r)
+	_, code, err := c.invoke(r, key)
 	if err != nil {
 		err = fmt.Errorf("%s %s: %s: %w", http.MethodPut, r.URL, spanName+".invoke", err)
 		otelHelpers.SetSpanExceptionWithErr(span, err)
@@ -126,12 +143,9 @@ func (c *clientImpl) Delete(ctx context.Context, key string, requestId string) e
 		return err
 	}
 
-	span.SetAttributes(attribute.String("http.url", r.URL.String()))
-
 	r.Header.Set(gin_request_id.RequestIDKey, requestId)
-	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(r.Header))
 
-	_, _, err = c.invoke(r)
+	_, _, err = c.invoke(r, key)
 	if err != nil {
 		err = fmt.Errorf("%s: %w", spanName+".invoke", err)
 		otelHelpers.SetSpanExceptionWithErr(span, err)
@@ -152,52 +166,199 @@ func (c *clientImpl) prepareWithBody(ctx context.Context, method, key string, va
 
 	reader := bytes.NewReader(jsonBody)
 
-	return http.NewRequestWithContext(ctx, method, c.backend, reader)
+	// URL is relative to the backend endpoint, it is resolved on every attempt
+	return http.NewRequestWithContext(ctx, method, "", reader)
 }
 
 func (c *clientImpl) prepareWithUrlPath(ctx context.Context, method, key string) (*http.Request, error) {
 	encodedKey := url.QueryEscape(key)
 
-	path, err := url.JoinPath(c.backend, encodedKey)
+	// URL is relative to the backend endpoint, it is resolved on every attempt
+	return http.NewRequestWithContext(ctx, method, encodedKey, nil)
+}
+
+// resolveURL appends relative path of ref to the endpoint URL
+func resolveURL(endpoint string, ref *url.URL) (*url.URL, error) {
+	u, err := url.Parse(endpoint)
 	if err != nil {
 		return nil, err
 	}
 
-	return http.NewRequestWithContext(ctx, method, path, nil)
+	if ref.Path == "" {
+		return u, nil
+	}
+
+	u.RawPath = strings.TrimSuffix(u.EscapedPath(), "/") + "/" + ref.EscapedPath()
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + ref.Path
+
+	return u, nil
+}
+
+// invoke sends request retrying transient failures according to the retry policy.
+// Every attempt is recorded as a child span of the request context span.
+// key is used by the balancer to pick backend endpoint.
+func (c *clientImpl) invoke(r *http.Request, key string) ([]byte, int, error) {
+	maxAttempts := 1
+	if c.retry.canRetry(r.Method) {
+		maxAttempts = c.retry.MaxAttempts
+	}
+
+	if c.retry.UseIdempotencyKeys {
+		r.Header.Set(IdempotencyKeyHeader, r.Header.Get(gin_request_id.RequestIDKey))
+	}
+
+	for attempt := 1; ; attempt++ {
+		res := c.send(r, key, attempt)
+		if attempt >= maxAttempts || !isRetryable(r.Context(), res.code, res.body, res.err) {
+			return handleResult(res.body, res.code, res.err)
+		}
+
+		if !sleep(r.Context(), c.retry.delay(attempt, res.retryAfter)) {
+			return handleResult(res.body, res.code, res.err)
+		}
+	}
 }
 
-func (c *clientImpl) invoke(r *http.Request) ([]byte, int, error) {
-	resp, err := c.client.Do(r)
+// send performs a single attempt, which is hedged for Get requests if hedging is enabled
+func (c *clientImpl) send(r *http.Request, key string, attempt int) attemptResult {
+	if c.latencies != nil && r.Method == http.MethodGet {
+		return c.hedge(r, key, attempt)
+	}
+
+	endpoint, done, err := c.balancer.Pick(key)
 	if err != nil {
-		return nil, 0, err
+		return attemptResult{err: err}
 	}
-	defer func() { _ = resp.Body.Close() }()
+	defer done()
+
+	return c.attempt(r.Context(), r, attempt, endpoint, "")
+}
+
+// attempt sends request to the endpoint. ctx is derived from the request context and is cancelled for hedging losers.
+func (c *clientImpl) attempt(ctx context.Context, r *http.Request, attempt int, endpoint *balancer.Endpoint, hedgeRole string) attemptResult {
+	const (
+		spanName = "client.attempt"
+	)
+
+	ctx, span := otelHelpers.StartSpanWithCtx(ctx, spanName, spanName)
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.Int("http.request.resend_count", attempt-1),
+		attribute.String("backend.endpoint", endpoint.URL),
+	)
+
+	if hedgeRole != "" {
+		span.SetAttributes(attribute.String("client.hedge.role", hedgeRole))
+	}
+
+	budget := c.budget(ctx)
+	span.SetAttributes(attribute.Int64("client.timeout_ms", budget.Milliseconds()))
+	if budget <= 0 {
+		otelHelpers.SetSpanExceptionWithErr(span, context.DeadlineExceeded)
+		return attemptResult{err: context.DeadlineExceeded}
+	}
+
+	reqCtx, cancel := context.WithTimeout(ctx, budget)
+	defer cancel()
+
+	var err error
 
-	if resp.StatusCode == http.StatusNotFound {
-		return nil, 0, cacheErrors.ErrNotFound
+	req := r.Clone(reqCtx)
+	req.Header.Set(gin_deadline.TimeoutHeader, gin_deadline.FormatTimeout(budget))
+	req.URL, err = resolveURL(endpoint.URL, r.URL)
+	if err != nil {
+		otelHelpers.SetSpanExceptionWithErr(span, err)
+		return attemptResult{err: err}
 	}
-	if resp.StatusCode == http.StatusInternalServerError {
-		return nil, 0, errors.New("internal server error")
+
+	span.SetAttributes(attribute.String("http.url", req.URL.String()))
+
+	if r.GetBody != nil {
+		body, err := r.GetBody()
+		if err != nil {
+			otelHelpers.SetSpanExceptionWithErr(span, err)
+			return attemptResult{err: err}
+		}
+		req.Body = body
+	}
+
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil && r.Context().Err() == nil {
+			span.SetAttributes(attribute.Bool("client.hedge.cancelled", true))
+			return attemptResult{err: err}
+		}
+		otelHelpers.SetSpanExceptionWithErr(span, err)
+		return attemptResult{err: err}
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+
+	retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	if ok {
+		span.SetAttributes(attribute.String("http.retry_after", retryAfter.String()))
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, 0, err
+		otelHelpers.SetSpanExceptionWithErr(span, err)
+		return attemptResult{err: err}
 	}
 
-	return body, resp.StatusCode, nil
+	if resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests {
+		otelHelpers.SetSpanExceptionWithErr(span, errors.New(http.StatusText(resp.StatusCode)))
+	}
+
+	return attemptResult{body: body, code: resp.StatusCode, retryAfter: retryAfter}
 }
 
-func validateResponse(b []byte) error {
-	str := conv.BytesToStr(b)
-	if strings.Contains(str, "malformed request") {
-		return errors.New("malformed request")
+// budget returns attempt timeout shrunk to the remaining deadline of ctx
+func (c *clientImpl) budget(ctx context.Context) time.Duration {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return c.timeout
 	}
-	if strings.Contains(str, "internal server error") {
-		return errors.New("internal server error")
+
+	return min(c.timeout, time.Until(deadline))
+}
+
+// isRetryable reports whether attempt failed due to transient error.
+// Errors caused by the request context and policy rejections are final.
+func isRetryable(ctx context.Context, code int, body []byte, err error) bool {
+	if err != nil {
+		return ctx.Err() == nil
 	}
-	if strings.Contains(str, "not found") {
-		return cacheErrors.ErrNotFound
+
+	if !isRetryableStatus(code) {
+		return false
 	}
-	return nil
+
+	return code != http.StatusTooManyRequests || !client.IsRejected(httpErrors.ParseErrStatus(code, body))
+}
+
+// handleResult returns httpErrors.ErrStatus for every non-2xx status.
+// It unwraps to the sentinel error of the status, e.g. cacheErrors.ErrNotFound for 404.
+// Transport errors are wrapped with httpErrors.ErrTimeout or httpErrors.ErrUnavailable.
+func handleResult(b []byte, code int, err error) ([]byte, int, error) {
+	if err != nil {
+		var netErr net.Error
+		switch {
+		case errors.Is(err, context.Canceled):
+			return nil, 0, err
+		case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+			return nil, 0, fmt.Errorf("%w: %w", httpErrors.ErrTimeout, err)
+		default:
+			return nil, 0, fmt.Errorf("%w: %w", httpErrors.ErrUnavailable, err)
+		}
+	}
+
+	if code < http.StatusOK || code >= http.StatusMultipleChoices {
+		return nil, 0, httpErrors.ParseErrStatus(code, b)
+	}
+
+	return b, code, nil
 }
@@ -0,0 +1,157 @@
+package client_impl
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/KennyMacCormik/otel/api/internal/client/balancer"
+)
+
+const (
+	hedgingWindow       = 1000
+	hedgingRecalcPeriod = 100
+
+	hedgeRolePrimary = "primary"
+	hedgeRoleHedge   = "hedge"
+)
+
+// HedgingPolicy configures hedged Get requests. If the first request hasn't answered
+// within the Percentile of recent Get latencies, the second one is sent to another endpoint.
+// The first definitive response wins and the other request is cancelled.
+type HedgingPolicy struct {
+	Enabled bool
+	// Percentile of latencies used as hedging delay, e.g. 95
+	Percentile float64
+	// MinDelay is the lower bound of hedging delay, it prevents doubling load when latencies are uniformly low
+	MinDelay time.Duration
+}
+
+// latencyTracker keeps the last hedgingWindow latencies and recalculates the delay every hedgingRecalcPeriod samples
+type latencyTracker struct {
+	percentile float64
+	minDelay   time.Duration
+
+	mtx     sync.Mutex
+	samples []time.Duration
+	next    int
+	pending int
+
+	delay atomic.Int64
+}
+
+func newLatencyTracker(p HedgingPolicy) *latencyTracker {
+	return &latencyTracker{
+		percentile: min(max(p.Percentile, 0), 100),
+		minDelay:   p.MinDelay,
+		samples:    make([]time.Duration, 0, hedgingWindow),
+	}
+}
+
+// getDelay returns hedging delay. It returns false until enough latencies are recorded.
+func (l *latencyTracker) getDelay() (time.Duration, bool) {
+	d := time.Duration(l.delay.Load())
+	return d, d > 0
+}
+
+func (l *latencyTracker) record(d time.Duration) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if len(l.samples) < hedgingWindow {
+		l.samples = append(l.samples, d)
+	} else {
+		l.samples[l.next] = d
+		l.next = (l.next + 1) % hedgingWindow
+	}
+
+	l.pending++
+	if l.pending < hedgingRecalcPeriod {
+		return
+	}
+	l.pending = 0
+
+	sorted := slices.Clone(l.samples)
+	slices.Sort(sorted)
+
+	idx := int(float64(len(sorted)-1) * l.percentile / 100)
+	l.delay.Store(int64(max(sorted[idx], l.minDelay, 1)))
+}
+
+type hedgeResult struct {
+	attemptResult
+	role string
+}
+
+// hedge sends request to the picked endpoint and, once hedging delay passes, to another one.
+// The first definitive result wins, the loser is cancelled. The winner is recorded on the parent span.
+// Every attempt records its latency from its own start, see recordLatency.
+func (c *clientImpl) hedge(r *http.Request, key string, attempt int) attemptResult {
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	primary, done, err := c.balancer.Pick(key)
+	if err != nil {
+		return attemptResult{err: err}
+	}
+
+	results := make(chan hedgeResult, 2)
+	launch := func(e *balancer.Endpoint, done func(), role string) {
+		start := time.Now()
+		go func() {
+			defer done()
+			res := c.attempt(ctx, r, attempt, e, role)
+			c.recordLatency(r.Context(), ctx, role, res, time.Since(start))
+			results <- hedgeResult{attemptResult: res, role: role}
+		}()
+	}
+
+	launch(primary, done, hedgeRolePrimary)
+	pending := 1
+
+	var timerCh <-chan time.Time
+	if delay, ok := c.latencies.getDelay(); ok {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		timerCh = timer.C
+	}
+
+	var res hedgeResult
+	for pending > 0 {
+		select {
+		case <-timerCh:
+			timerCh = nil
+			if e, done, err := c.balancer.Pick(key, primary); err == nil {
+				launch(e, done, hedgeRoleHedge)
+				pending++
+			}
+		case res = <-results:
+			pending--
+			// failed result only wins if there is nothing else to wait for
+			if pending > 0 && isRetryable(r.Context(), res.code, res.body, res.err) {
+				continue
+			}
+			pending = 0
+		}
+	}
+
+	trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("client.hedge.winner", res.role))
+
+	return res.attemptResult
+}
+
+// recordLatency records latency of the attempt. Primary cancelled as the loser is recorded with the time it ran,
+// which is a lower bound of its latency: dropping it would hide the slow tail hedging cuts off and shrink the delay.
+// Other failed attempts are not recorded, as their latency says nothing about the backend.
+func (c *clientImpl) recordLatency(parent, ctx context.Context, role string, res attemptResult, d time.Duration) {
+	lost := ctx.Err() != nil && parent.Err() == nil
+	if res.err == nil || (role == hedgeRolePrimary && lost) {
+		c.latencies.record(d)
+	}
+}
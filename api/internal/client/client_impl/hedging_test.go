@@ -0,0 +1,152 @@
+package client_impl
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KennyMacCormik/otel/api/internal/client/balancer"
+)
+
+func TestLatencyTracker(t *testing.T) {
+	l := newLatencyTracker(HedgingPolicy{Enabled: true, Percentile: 90, MinDelay: 5 * time.Millisecond})
+
+	for i := 1; i < hedgingRecalcPeriod; i++ {
+		l.record(time.Duration(i) * time.Millisecond)
+	}
+	_, ok := l.getDelay()
+	assert.False(t, ok, "expect no delay until enough latencies are recorded")
+
+	l.record(hedgingRecalcPeriod * time.Millisecond)
+	d, ok := l.getDelay()
+	require.True(t, ok, "expect delay after recalculation period")
+	assert.Equal(t, 90*time.Millisecond, d, "expect percentile of recorded latencies")
+
+	for i := 0; i < hedgingWindow; i++ {
+		l.record(time.Millisecond)
+	}
+	d, ok = l.getDelay()
+	require.True(t, ok, "expect delay")
+	assert.Equal(t, 5*time.Millisecond, d, "expect old latencies to leave the window and delay to be capped by min delay")
+	assert.Len(t, l.samples, hedgingWindow, "expect window size to be bounded")
+}
+
+func TestLatencyTracker_Percentile(t *testing.T) {
+	testCases := []struct {
+		name       string
+		percentile float64
+		delay      time.Duration
+	}{
+		{"median", 50, 50 * time.Millisecond},
+		{"max", 100, 100 * time.Millisecond},
+		{"above max", 150, 100 * time.Millisecond},
+		{"below min", -1, time.Millisecond},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			l := newLatencyTracker(HedgingPolicy{Enabled: true, Percentile: tc.percentile})
+			for i := 1; i <= hedgingRecalcPeriod; i++ {
+				l.record(time.Duration(i) * time.Millisecond)
+			}
+
+			d, ok := l.getDelay()
+			require.True(t, ok, "expect delay")
+			assert.Equal(t, tc.delay, d, "Unexpected delay")
+		})
+	}
+}
+
+// newHedgingClient returns client balancing between the servers round-robin.
+// The first pick returns the last endpoint.
+func newHedgingClient(t *testing.T, delay time.Duration, endpoints ...string) *clientImpl {
+	b, err := balancer.NewBalancer(context.Background(), balancer.PolicyRoundRobin, balancer.NewStaticResolver(endpoints))
+	require.NoError(t, err, "expect no error with valid configuration")
+	t.Cleanup(b.Close)
+
+	c, ok := NewBackendClient(b, time.Second, RetryPolicy{}, HedgingPolicy{Enabled: true, Percentile: 95}, TransportPolicy{}).(*clientImpl)
+	require.True(t, ok, "expect *clientImpl")
+	c.latencies.delay.Store(int64(delay))
+
+	return c
+}
+
+func newServer(t *testing.T, latency time.Duration, code int) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-time.After(latency):
+		}
+		w.WriteHeader(code)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func getSamples(l *latencyTracker) []time.Duration {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	return append([]time.Duration(nil), l.samples...)
+}
+
+func TestHedge(t *testing.T) {
+	const delay = 20 * time.Millisecond
+
+	fast := newServer(t, 0, http.StatusOK)
+	slow := newServer(t, 5*time.Second, http.StatusOK)
+	c := newHedgingClient(t, delay, fast.URL, slow.URL)
+
+	r, err := c.prepareWithUrlPath(context.Background(), http.MethodGet, "key")
+	require.NoError(t, err, "expect no error on prepare")
+
+	start := time.Now()
+	res := c.hedge(r, "key", 1)
+	require.NoError(t, res.err, "expect hedge to succeed")
+	assert.Equal(t, http.StatusOK, res.code, "expect response of the hedge")
+	assert.Less(t, time.Since(start), time.Second, "expect slow primary not to be awaited")
+
+	// primary is recorded once it is cancelled, the hedge before its result is returned
+	require.Eventually(t, func() bool { return len(getSamples(c.latencies)) == 2 }, time.Second, time.Millisecond,
+		"expect both attempts to be recorded")
+
+	samples := getSamples(c.latencies)
+	hedged, primary := samples[0], samples[1]
+	assert.Less(t, hedged, primary, "expect every attempt to be measured from its own start")
+	assert.GreaterOrEqual(t, primary, delay, "expect primary to be recorded with the time it ran")
+}
+
+func TestHedge_PrimaryWins(t *testing.T) {
+	fast := newServer(t, 0, http.StatusOK)
+	other := newServer(t, 0, http.StatusOK)
+	c := newHedgingClient(t, time.Second, other.URL, fast.URL)
+
+	r, err := c.prepareWithUrlPath(context.Background(), http.MethodGet, "key")
+	require.NoError(t, err, "expect no error on prepare")
+
+	res := c.hedge(r, "key", 1)
+	require.NoError(t, res.err, "expect primary to succeed")
+	assert.Len(t, getSamples(c.latencies), 1, "expect only primary to be sent and recorded")
+}
+
+func TestHedge_FailedPrimary(t *testing.T) {
+	const delay = 20 * time.Millisecond
+
+	failing := newServer(t, 50*time.Millisecond, http.StatusServiceUnavailable)
+	hedge := newServer(t, 100*time.Millisecond, http.StatusOK)
+	c := newHedgingClient(t, delay, hedge.URL, failing.URL)
+
+	r, err := c.prepareWithUrlPath(context.Background(), http.MethodGet, "key")
+	require.NoError(t, err, "expect no error on prepare")
+
+	res := c.hedge(r, "key", 1)
+	require.NoError(t, res.err, "expect no transport error")
+	assert.Equal(t, http.StatusOK, res.code, "expect retryable failure to wait for the hedge")
+}
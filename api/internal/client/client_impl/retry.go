@@ -0,0 +1,111 @@
+package client_impl
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// RetryPolicy configures retries of failed backend requests.
+// Methods idempotent by RFC 9110 are always retried. Other methods are only retried
+// if UseIdempotencyKeys is set, in which case every request carries IdempotencyKeyHeader.
+// Retry-After of the failed response replaces the backoff if it is longer, up to MaxDelay.
+type RetryPolicy struct {
+	// MaxAttempts includes the first attempt. Values below 2 disable retries.
+	MaxAttempts int
+	// BaseDelay is the backoff of the first retry, it doubles with every next one up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+
+	UseIdempotencyKeys bool
+}
+
+func (p RetryPolicy) canRetry(method string) bool {
+	if p.MaxAttempts < 2 {
+		return false
+	}
+
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return p.UseIdempotencyKeys
+	}
+}
+
+// backoff returns random delay between zero and the exponential backoff of the attempt (full jitter).
+// attempt is the number of the failed attempt starting from 1.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.MaxDelay
+	if shift := attempt - 1; shift < 32 && p.BaseDelay<<shift > 0 && p.BaseDelay<<shift < p.MaxDelay {
+		d = p.BaseDelay << shift
+	}
+
+	if d <= 0 {
+		return 0
+	}
+
+	return rand.N(d + 1)
+}
+
+// delay returns wait before the retry of the failed attempt. Retry-After longer than the backoff is honored
+// up to MaxDelay, so a misbehaving backend can't stall the caller for its whole deadline.
+func (p RetryPolicy) delay(attempt int, retryAfter time.Duration) time.Duration {
+	return max(p.backoff(attempt), min(retryAfter, p.MaxDelay))
+}
+
+// isRetryableStatus reports whether status indicates transient failure
+func isRetryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// parseRetryAfter returns delay from Retry-After header, which is either seconds or HTTP date
+func parseRetryAfter(h string, now time.Time) (time.Duration, bool) {
+	if h == "" {
+		return 0, false
+	}
+
+	if s, err := strconv.Atoi(h); err == nil {
+		if s < 0 {
+			return 0, false
+		}
+		return time.Duration(s) * time.Second, true
+	}
+
+	t, err := http.ParseTime(h)
+	if err != nil {
+		return 0, false
+	}
+
+	if d := t.Sub(now); d > 0 {
+		return d, true
+	}
+
+	return 0, true
+}
+
+// sleep waits for d or ctx to be done. It returns false without waiting if d exceeds ctx deadline.
+func sleep(ctx context.Context, d time.Duration) bool {
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < d {
+		return false
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
@@ -0,0 +1,241 @@
+package client_impl
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/KennyMacCormik/otel/backend/pkg/gin/gin_request_id"
+	cacheErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/cache"
+	httpErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KennyMacCormik/otel/api/internal/client/balancer"
+)
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 3, BaseDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond}
+
+	testCases := []struct {
+		name    string
+		attempt int
+		max     time.Duration
+	}{
+		{"first", 1, 10 * time.Millisecond},
+		{"second", 2, 20 * time.Millisecond},
+		{"third", 3, 40 * time.Millisecond},
+		{"capped", 4, 50 * time.Millisecond},
+		{"overflow", 64, 50 * time.Millisecond},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			for i := 0; i < 100; i++ {
+				d := p.backoff(tc.attempt)
+				require.GreaterOrEqual(t, d, time.Duration(0), "expect non-negative backoff")
+				require.LessOrEqual(t, d, tc.max, "expect backoff within exponential bound")
+			}
+		})
+	}
+
+	assert.Zero(t, RetryPolicy{}.backoff(1), "expect no backoff without delays")
+}
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 50 * time.Millisecond}
+
+	assert.LessOrEqual(t, p.delay(1, 0), time.Millisecond, "expect backoff without Retry-After")
+	assert.Equal(t, 30*time.Millisecond, p.delay(1, 30*time.Millisecond), "expect longer Retry-After to replace backoff")
+	assert.Equal(t, 50*time.Millisecond, p.delay(1, time.Hour), "expect Retry-After to be capped by max delay")
+}
+
+func TestRetryPolicy_CanRetry(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 3}
+
+	assert.True(t, p.canRetry(http.MethodGet), "expect GET to be retried")
+	assert.True(t, p.canRetry(http.MethodPut), "expect PUT to be retried")
+	assert.True(t, p.canRetry(http.MethodDelete), "expect DELETE to be retried")
+	assert.False(t, p.canRetry(http.MethodPost), "expect POST not to be retried without idempotency keys")
+	assert.True(t, RetryPolicy{MaxAttempts: 3, UseIdempotencyKeys: true}.canRetry(http.MethodPost),
+		"expect POST to be retried with idempotency keys")
+	assert.False(t, RetryPolicy{MaxAttempts: 1}.canRetry(http.MethodGet), "expect single attempt to disable retries")
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name   string
+		header string
+		delay  time.Duration
+		ok     bool
+	}{
+		{"empty", "", 0, false},
+		{"seconds", "3", 3 * time.Second, true},
+		{"zero", "0", 0, true},
+		{"negative", "-1", 0, false},
+		{"date", now.Add(90 * time.Second).Format(http.TimeFormat), 90 * time.Second, true},
+		{"past date", now.Add(-time.Minute).Format(http.TimeFormat), 0, true},
+		{"invalid", "soon", 0, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			d, ok := parseRetryAfter(tc.header, now)
+			assert.Equal(t, tc.ok, ok, "Unexpected ok")
+			assert.Equal(t, tc.delay, d, "Unexpected delay")
+		})
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	quota := []byte(`{"code":"` + httpErrors.CodeQuotaExceeded + `","message":"quota exceeded"}`)
+	writeLimited := []byte(`{"code":"` + httpErrors.CodeWriteLimited + `","message":"write limited"}`)
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	testCases := []struct {
+		name      string
+		ctx       context.Context
+		code      int
+		body      []byte
+		err       error
+		retryable bool
+	}{
+		{"transport error", context.Background(), 0, nil, errors.New("connection refused"), true},
+		{"cancelled", cancelled, 0, nil, context.Canceled, false},
+		{"ok", context.Background(), http.StatusOK, nil, nil, false},
+		{"not found", context.Background(), http.StatusNotFound, nil, nil, false},
+		{"internal error", context.Background(), http.StatusInternalServerError, nil, nil, false},
+		{"bad gateway", context.Background(), http.StatusBadGateway, nil, nil, true},
+		{"unavailable", context.Background(), http.StatusServiceUnavailable, nil, nil, true},
+		{"gateway timeout", context.Background(), http.StatusGatewayTimeout, nil, nil, true},
+		{"rate limited", context.Background(), http.StatusTooManyRequests, []byte(`{"error":"too many requests"}`), nil, true},
+		{"quota exceeded", context.Background(), http.StatusTooManyRequests, quota, nil, false},
+		{"write limited", context.Background(), http.StatusTooManyRequests, writeLimited, nil, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.retryable, isRetryable(tc.ctx, tc.code, tc.body, tc.err), "Unexpected retryable")
+		})
+	}
+}
+
+// newRetryClient returns client without hedging sending every request to the server
+func newRetryClient(t *testing.T, retry RetryPolicy, endpoint string) *clientImpl {
+	b, err := balancer.NewBalancer(context.Background(), balancer.PolicyRoundRobin, balancer.NewStaticResolver([]string{endpoint}))
+	require.NoError(t, err, "expect no error with valid configuration")
+	t.Cleanup(b.Close)
+
+	c, ok := NewBackendClient(b, time.Second, retry, HedgingPolicy{}, TransportPolicy{}).(*clientImpl)
+	require.True(t, ok, "expect *clientImpl")
+
+	return c
+}
+
+// newSequenceServer responds with the codes in order, the last one is repeated
+func newSequenceServer(t *testing.T, header http.Header, body string, codes ...int) (*httptest.Server, *atomic.Int64) {
+	var calls atomic.Int64
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(calls.Add(1))
+		for k, v := range header {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(codes[min(n, len(codes))-1])
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, &calls
+}
+
+func TestInvoke(t *testing.T) {
+	retry := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+
+	testCases := []struct {
+		name   string
+		method string
+		retry  RetryPolicy
+		header http.Header
+		body   string
+		codes  []int
+		calls  int64
+		code   int
+		err    error
+	}{
+		{"success", http.MethodGet, retry, nil, "", []int{http.StatusOK}, 1, http.StatusOK, nil},
+		{"recovered", http.MethodGet, retry, nil, "", []int{http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusOK}, 3, http.StatusOK, nil},
+		{"exhausted", http.MethodGet, retry, nil, "", []int{http.StatusServiceUnavailable}, 3, 0, httpErrors.ErrUnavailable},
+		{"not retryable", http.MethodGet, retry, nil, "", []int{http.StatusNotFound, http.StatusOK}, 1, 0, cacheErrors.ErrNotFound},
+		{"retries disabled", http.MethodGet, RetryPolicy{MaxAttempts: 1}, nil, "", []int{http.StatusServiceUnavailable, http.StatusOK}, 1, 0, httpErrors.ErrUnavailable},
+		{"post", http.MethodPost, retry, nil, "", []int{http.StatusServiceUnavailable, http.StatusOK}, 1, 0, httpErrors.ErrUnavailable},
+		{"post with idempotency keys", http.MethodPost, RetryPolicy{MaxAttempts: 3, UseIdempotencyKeys: true}, nil, "", []int{http.StatusServiceUnavailable, http.StatusOK}, 2, http.StatusOK, nil},
+		{"quota exceeded", http.MethodPut, retry, nil, `{"code":"quota_exceeded","message":"quota exceeded"}`, []int{http.StatusTooManyRequests, http.StatusOK}, 1, 0, cacheErrors.ErrQuotaExceeded},
+		{"long Retry-After", http.MethodGet, retry, http.Header{"Retry-After": {"3600"}}, "", []int{http.StatusTooManyRequests, http.StatusOK}, 2, http.StatusOK, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv, calls := newSequenceServer(t, tc.header, tc.body, tc.codes...)
+			c := newRetryClient(t, tc.retry, srv.URL)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			r, err := c.prepareWithUrlPath(ctx, tc.method, "key")
+			require.NoError(t, err, "expect no error on prepare")
+
+			_, code, err := c.invoke(r, "key")
+			assert.Equal(t, tc.calls, calls.Load(), "Unexpected number of attempts")
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err, "Unexpected error")
+				return
+			}
+			require.NoError(t, err, "expect no error")
+			assert.Equal(t, tc.code, code, "Unexpected status")
+		})
+	}
+}
+
+func TestInvoke_IdempotencyKey(t *testing.T) {
+	var keys []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get(IdempotencyKeyHeader))
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(srv.Close)
+
+	c := newRetryClient(t, RetryPolicy{MaxAttempts: 2, UseIdempotencyKeys: true}, srv.URL)
+
+	r, err := c.prepareWithUrlPath(context.Background(), http.MethodPost, "key")
+	require.NoError(t, err, "expect no error on prepare")
+	r.Header.Set(gin_request_id.RequestIDKey, "request-1")
+
+	_, _, _ = c.invoke(r, "key")
+	assert.Equal(t, []string{"request-1", "request-1"}, keys, "expect every attempt to carry the same idempotency key")
+}
+
+func TestInvoke_DeadlineBeforeRetry(t *testing.T) {
+	srv, calls := newSequenceServer(t, http.Header{"Retry-After": {"1"}}, "", http.StatusServiceUnavailable, http.StatusOK)
+	c := newRetryClient(t, RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Second}, srv.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	r, err := c.prepareWithUrlPath(ctx, http.MethodGet, "key")
+	require.NoError(t, err, "expect no error on prepare")
+
+	start := time.Now()
+	_, _, err = c.invoke(r, "key")
+	assert.ErrorIs(t, err, httpErrors.ErrUnavailable, "expect the last failure to be returned")
+	assert.Equal(t, int64(1), calls.Load(), "expect no retry past the deadline")
+	assert.Less(t, time.Since(start), 100*time.Millisecond, "expect no wait for a retry that can't happen")
+}
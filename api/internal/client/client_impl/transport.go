@@ -0,0 +1,211 @@
+package client_impl
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/KennyMacCormik/common/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"golang.org/x/net/http2"
+)
+
+// TransportPolicy configures connections to backend replicas
+type TransportPolicy struct {
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	// MaxConnsPerHost limits dialing, in-flight and idle connections per replica. Zero means no limit.
+	MaxConnsPerHost       int
+	IdleConnTimeout       time.Duration
+	KeepAlive             time.Duration
+	DialTimeout           time.Duration
+	TLSHandshakeTimeout   time.Duration
+	ResponseHeaderTimeout time.Duration
+	// HTTP2 negotiates HTTP/2 over TLS for https endpoints and uses HTTP/2 with prior knowledge (h2c) for http ones.
+	// Backend must have h2c enabled for the latter. h2c requests are multiplexed by http2.Transport, which has no
+	// per host limits: MaxIdleConnsPerHost, MaxConnsPerHost and ResponseHeaderTimeout don't apply to them,
+	// requests are still limited by the client timeout.
+	HTTP2 bool
+	// Registerer registers connection metrics, nil means prometheus.DefaultRegisterer.
+	// Transports registered with the same registerer share their metrics.
+	Registerer prometheus.Registerer
+}
+
+// transportMetrics track connections of transports registered with the same registerer
+type transportMetrics struct {
+	connsOpened   prometheus.Counter
+	connsClosed   prometheus.Counter
+	connsOpen     prometheus.Gauge
+	dialErrors    prometheus.Counter
+	dialDuration  prometheus.Histogram
+	connsAcquired *prometheus.CounterVec
+}
+
+func newTransportMetrics(reg prometheus.Registerer) *transportMetrics {
+	if reg == nil {
+		reg = prometheus.DefaultRegisterer
+	}
+	f := promauto.With(nil)
+
+	return &transportMetrics{
+		connsOpened: registerMetric(reg, f.NewCounter(prometheus.CounterOpts{
+			Name: "backend_client_connections_opened",
+			Help: "Total number of connections opened to backend replicas",
+		})),
+		connsClosed: registerMetric(reg, f.NewCounter(prometheus.CounterOpts{
+			Name: "backend_client_connections_closed",
+			Help: "Total number of connections to backend replicas closed",
+		})),
+		connsOpen: registerMetric(reg, f.NewGauge(prometheus.GaugeOpts{
+			Name: "backend_client_connections_open",
+			Help: "Current number of open connections to backend replicas",
+		})),
+		dialErrors: registerMetric(reg, f.NewCounter(prometheus.CounterOpts{
+			Name: "backend_client_dial_errors",
+			Help: "Total number of failed dials to backend replicas",
+		})),
+		dialDuration: registerMetric(reg, f.NewHistogram(prometheus.HistogramOpts{
+			Name:    "backend_client_dial_duration_seconds",
+			Help:    "Duration of successful dials to backend replicas",
+			Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		})),
+		connsAcquired: registerMetric(reg, f.NewCounterVec(prometheus.CounterOpts{
+			Name: "backend_client_connections_acquired",
+			Help: "Total number of connections acquired for backend requests by whether the connection was reused",
+		}, []string{"reused"})),
+	}
+}
+
+// newTransport returns transport tracking connections in metrics
+func newTransport(policy TransportPolicy) http.RoundTripper {
+	metrics := newTransportMetrics(policy.Registerer)
+	dialer := &net.Dialer{Timeout: policy.DialTimeout, KeepAlive: policy.KeepAlive}
+
+	dial := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		start := time.Now()
+
+		conn, err := dialer.DialContext(ctx, network, addr)
+		if err != nil {
+			metrics.dialErrors.Inc()
+			return nil, err
+		}
+
+		metrics.dialDuration.Observe(time.Since(start).Seconds())
+		metrics.connsOpened.Inc()
+		metrics.connsOpen.Inc()
+
+		return &trackedConn{Conn: conn, metrics: metrics}, nil
+	}
+
+	t := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dial,
+		ForceAttemptHTTP2:     policy.HTTP2,
+		MaxIdleConns:          policy.MaxIdleConns,
+		MaxIdleConnsPerHost:   policy.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       policy.MaxConnsPerHost,
+		IdleConnTimeout:       policy.IdleConnTimeout,
+		TLSHandshakeTimeout:   policy.TLSHandshakeTimeout,
+		ResponseHeaderTimeout: policy.ResponseHeaderTimeout,
+		ExpectContinueTimeout: time.Second,
+	}
+
+	var rt http.RoundTripper = t
+
+	if policy.HTTP2 {
+		h2c := &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				return dial(ctx, network, addr)
+			},
+			IdleConnTimeout: policy.IdleConnTimeout,
+			ReadIdleTimeout: policy.KeepAlive,
+		}
+		rt = &h2cTransport{https: t, h2c: h2c}
+	}
+
+	return &instrumentedTransport{base: rt, metrics: metrics}
+}
+
+// h2cTransport sends requests to http endpoints over HTTP/2 with prior knowledge
+type h2cTransport struct {
+	https *http.Transport
+	h2c   *http2.Transport
+}
+
+func (t *h2cTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if r.URL.Scheme == "http" {
+		return t.h2c.RoundTrip(r)
+	}
+
+	return t.https.RoundTrip(r)
+}
+
+func (t *h2cTransport) CloseIdleConnections() {
+	t.https.CloseIdleConnections()
+	t.h2c.CloseIdleConnections()
+}
+
+// instrumentedTransport records whether requests reused connections
+type instrumentedTransport struct {
+	base    http.RoundTripper
+	metrics *transportMetrics
+}
+
+func (t *instrumentedTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.metrics.connsAcquired.WithLabelValues(strconv.FormatBool(info.Reused)).Inc()
+		},
+	}
+
+	return t.base.RoundTrip(r.WithContext(httptrace.WithClientTrace(r.Context(), trace)))
+}
+
+func (t *instrumentedTransport) CloseIdleConnections() {
+	if c, ok := t.base.(interface{ CloseIdleConnections() }); ok {
+		c.CloseIdleConnections()
+	}
+}
+
+type trackedConn struct {
+	net.Conn
+
+	metrics   *transportMetrics
+	closeOnce sync.Once
+}
+
+func (c *trackedConn) Close() error {
+	c.closeOnce.Do(func() {
+		c.metrics.connsClosed.Inc()
+		c.metrics.connsOpen.Dec()
+	})
+
+	return c.Conn.Close()
+}
+
+// registerMetric registers c with reg. If an equal metric is already registered, it is returned instead.
+func registerMetric[T prometheus.Collector](reg prometheus.Registerer, c T) T {
+	err := reg.Register(c)
+	if err == nil {
+		return c
+	}
+
+	var are prometheus.AlreadyRegisteredError
+	if errors.As(err, &are) {
+		if existing, ok := are.ExistingCollector.(T); ok {
+			return existing
+		}
+	}
+
+	log.Error("failed to register backend client metric", "err", err)
+
+	return c
+}
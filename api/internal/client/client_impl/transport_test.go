@@ -0,0 +1,128 @@
+package client_impl
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+func newTestTransport(t *testing.T, enableHTTP2 bool) *instrumentedTransport {
+	rt := newTransport(TransportPolicy{
+		MaxIdleConns:        10,
+		MaxIdleConnsPerHost: 10,
+		IdleConnTimeout:     time.Minute,
+		KeepAlive:           time.Minute,
+		DialTimeout:         time.Second,
+		TLSHandshakeTimeout: time.Second,
+		HTTP2:               enableHTTP2,
+		Registerer:          prometheus.NewRegistry(),
+	}).(*instrumentedTransport)
+	t.Cleanup(rt.CloseIdleConnections)
+
+	return rt
+}
+
+// protoHandler replies with the protocol of the request
+func protoHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.Proto)
+	})
+}
+
+// get returns body of the response to GET url
+func get(t *testing.T, rt http.RoundTripper, url string) string {
+	resp, err := (&http.Client{Transport: rt}).Get(url)
+	require.NoError(t, err, "expect request to succeed")
+	defer func() { _ = resp.Body.Close() }()
+
+	b, err := io.ReadAll(resp.Body)
+	require.NoError(t, err, "expect body to be read")
+
+	return string(b)
+}
+
+func TestTransport_Metrics(t *testing.T) {
+	srv := httptest.NewServer(protoHandler())
+	defer srv.Close()
+
+	rt := newTestTransport(t, false)
+	m := rt.metrics
+
+	get(t, rt, srv.URL)
+	get(t, rt, srv.URL)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.connsOpened), "expect one connection to be opened")
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.connsOpen), "expect connection to stay open")
+	assert.Equal(t, 1, testutil.CollectAndCount(m.dialDuration), "expect dial duration to be observed")
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.connsAcquired.WithLabelValues("false")), "expect first request to open connection")
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.connsAcquired.WithLabelValues("true")), "expect second request to reuse connection")
+
+	rt.CloseIdleConnections()
+	require.Eventually(t, func() bool { return testutil.ToFloat64(m.connsOpen) == 0 }, time.Second, time.Millisecond, "expect idle connection to close")
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.connsClosed), "expect closed connection to be counted")
+
+	closed := httptest.NewServer(protoHandler())
+	closed.Close()
+
+	_, err := (&http.Client{Transport: rt}).Get(closed.URL)
+	assert.Error(t, err, "expect request to closed server to fail")
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.dialErrors), "expect dial error to be counted")
+}
+
+func TestTransport_SharedMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+
+	first := newTransportMetrics(reg)
+	second := newTransportMetrics(reg)
+
+	assert.Same(t, first.connsOpened, second.connsOpened, "expect transports of one registerer to share metrics")
+	assert.NotSame(t, first.connsOpened, newTransportMetrics(prometheus.NewRegistry()).connsOpened,
+		"expect separate metrics for another registerer")
+}
+
+func TestTransport_HTTP2(t *testing.T) {
+	h2cSrv := httptest.NewServer(h2c.NewHandler(protoHandler(), &http2.Server{}))
+	defer h2cSrv.Close()
+
+	tlsSrv := httptest.NewUnstartedServer(protoHandler())
+	tlsSrv.EnableHTTP2 = true
+	tlsSrv.StartTLS()
+	defer tlsSrv.Close()
+
+	testCases := []struct {
+		name        string
+		enableHTTP2 bool
+		srv         *httptest.Server
+		proto       string
+	}{
+		{"http", false, h2cSrv, "HTTP/1.1"},
+		{"h2c", true, h2cSrv, "HTTP/2.0"},
+		{"https", false, tlsSrv, "HTTP/1.1"},
+		{"https with http2", true, tlsSrv, "HTTP/2.0"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := newTestTransport(t, tc.enableHTTP2)
+
+			https := rt.base
+			if h, ok := rt.base.(*h2cTransport); ok {
+				https = h.https
+			}
+			https.(*http.Transport).TLSClientConfig = tc.srv.Client().Transport.(*http.Transport).TLSClientConfig
+
+			assert.Equal(t, tc.proto, get(t, rt, tc.srv.URL), "Unexpected protocol")
+			assert.Equal(t, tc.proto, get(t, rt, tc.srv.URL), "Unexpected protocol of reused connection")
+			assert.Equal(t, 1.0, testutil.ToFloat64(rt.metrics.connsOpened), "expect dialed connection to be tracked and reused")
+		})
+	}
+}
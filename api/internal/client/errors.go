@@ -0,0 +1,19 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+
+	cacheErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/cache"
+	httpErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/http"
+)
+
+// ErrCircuitOpen is returned without calling the backend while the circuit breaker is open.
+// It wraps httpErrors.ErrUnavailable, so it is reported to the api clients as 503.
+var ErrCircuitOpen = fmt.Errorf("circuit breaker is open: %w", httpErrors.ErrUnavailable)
+
+// IsRejected reports whether err is the backend refusing the request by policy: exceeded tenant quota
+// or key write limit. Such errors say nothing about the backend health and aren't resolved by retrying.
+func IsRejected(err error) bool {
+	return errors.Is(err, cacheErrors.ErrQuotaExceeded) || errors.Is(err, cacheErrors.ErrWriteRateLimited)
+}
@@ -0,0 +1,141 @@
+package grpc_client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/KennyMacCormik/otel/backend/pkg/gin/gin_request_id"
+	cacheErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/cache"
+	grpcErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/grpc"
+	otelHelpers "github.com/KennyMacCormik/otel/backend/pkg/otel/helpers"
+	"github.com/KennyMacCormik/otel/backend/pkg/proto/storage_pb"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/KennyMacCormik/otel/api/internal/client"
+)
+
+// roundRobinServiceConfig spreads RPCs over all addresses the target resolves to
+const roundRobinServiceConfig = `{"loadBalancingConfig": [{"round_robin": {}}]}`
+
+// GrpcClient implements client.BackendClientInterface over the backend gRPC storage service.
+// Errors are converted to httpErrors.ErrStatus, so they are handled the same way as errors of the HTTP client.
+type GrpcClient struct {
+	conn    *grpc.ClientConn
+	client  storage_pb.StorageClient
+	timeout time.Duration
+}
+
+var _ client.BackendClientInterface = (*GrpcClient)(nil)
+
+// NewGrpcClient returns gRPC client of the backend storage. target uses gRPC name syntax, e.g. dns:///backend:9090,
+// RPCs are balanced over all addresses it resolves to. timeout limits every RPC.
+func NewGrpcClient(target string, timeout time.Duration) (*GrpcClient, error) {
+	conn, err := grpc.NewClient(target,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+		grpc.WithDefaultServiceConfig(roundRobinServiceConfig),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("NewGrpcClient: %w", err)
+	}
+
+	return &GrpcClient{
+		conn:    conn,
+		client:  storage_pb.NewStorageClient(conn),
+		timeout: timeout,
+	}, nil
+}
+
+func (c *GrpcClient) Get(ctx context.Context, key, requestId string) (any, error) {
+	const (
+		spanName = "client.get"
+	)
+
+	ctx, span := otelHelpers.StartSpanWithCtx(ctx, spanName, spanName)
+	defer span.End()
+
+	ctx, cancel := c.prepare(ctx, requestId)
+	defer cancel()
+
+	resp, err := c.client.Get(ctx, &storage_pb.GetRequest{Key: key})
+	if err != nil {
+		err = grpcErrors.FromStatus(err)
+		if errors.Is(err, cacheErrors.ErrNotFound) {
+			return nil, err
+		}
+		err = fmt.Errorf("%s: %w", spanName+".invoke", err)
+		otelHelpers.SetSpanExceptionWithErr(span, err)
+		return nil, err
+	}
+
+	return resp.GetValue(), nil
+}
+
+// Set returns HTTP status matching storage_pb.SetResult, so callers don't depend on the transport
+func (c *GrpcClient) Set(ctx context.Context, key string, value any, requestId string) (int, error) {
+	const (
+		spanName = "client.set"
+	)
+
+	ctx, span := otelHelpers.StartSpanWithCtx(ctx, spanName, spanName)
+	defer span.End()
+
+	ctx, cancel := c.prepare(ctx, requestId)
+	defer cancel()
+
+	resp, err := c.client.Set(ctx, &storage_pb.SetRequest{Key: key, Value: fmt.Sprintf("%v", value)})
+	if err != nil {
+		err = fmt.Errorf("%s: %w", spanName+".invoke", grpcErrors.FromStatus(err))
+		otelHelpers.SetSpanExceptionWithErr(span, err)
+		return 0, err
+	}
+
+	switch resp.GetResult() {
+	case storage_pb.SetResult_SET_RESULT_CREATED:
+		return http.StatusCreated, nil
+	case storage_pb.SetResult_SET_RESULT_UNCHANGED:
+		return http.StatusNoContent, nil
+	default:
+		return http.StatusOK, nil
+	}
+}
+
+func (c *GrpcClient) Delete(ctx context.Context, key string, requestId string) error {
+	const (
+		spanName = "client.delete"
+	)
+
+	ctx, span := otelHelpers.StartSpanWithCtx(ctx, spanName, spanName)
+	defer span.End()
+
+	ctx, cancel := c.prepare(ctx, requestId)
+	defer cancel()
+
+	_, err := c.client.Delete(ctx, &storage_pb.DeleteRequest{Key: key})
+	if err != nil {
+		err = fmt.Errorf("%s: %w", spanName+".invoke", grpcErrors.FromStatus(err))
+		otelHelpers.SetSpanExceptionWithErr(span, err)
+		return err
+	}
+
+	return nil
+}
+
+// Close closes the connection. Pending RPCs fail with codes.Canceled.
+func (c *GrpcClient) Close() error {
+	return c.conn.Close()
+}
+
+// prepare limits ctx with timeout and passes request ID in metadata.
+// The earlier of timeout and ctx deadline is sent to the backend as the RPC deadline.
+func (c *GrpcClient) prepare(ctx context.Context, requestId string) (context.Context, context.CancelFunc) {
+	ctx = metadata.AppendToOutgoingContext(ctx, gin_request_id.RequestIDKey, requestId)
+
+	return context.WithTimeout(ctx, c.timeout)
+}
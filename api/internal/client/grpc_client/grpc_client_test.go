@@ -0,0 +1,202 @@
+package grpc_client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/KennyMacCormik/otel/backend/pkg/gin/gin_request_id"
+	cacheErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/cache"
+	grpcErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/grpc"
+	httpErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/http"
+	"github.com/KennyMacCormik/otel/backend/pkg/proto/storage_pb"
+)
+
+// testServer replies to every RPC with err converted by grpcErrors.ToStatus, or with result if err is nil
+type testServer struct {
+	storage_pb.UnimplementedStorageServer
+
+	err       error
+	result    storage_pb.SetResult
+	delay     time.Duration
+	requestId chan string
+}
+
+func (s *testServer) Get(ctx context.Context, req *storage_pb.GetRequest) (*storage_pb.GetResponse, error) {
+	if err := s.reply(ctx); err != nil {
+		return nil, err
+	}
+
+	return &storage_pb.GetResponse{Key: req.GetKey(), Value: "v"}, nil
+}
+
+func (s *testServer) Set(ctx context.Context, _ *storage_pb.SetRequest) (*storage_pb.SetResponse, error) {
+	if err := s.reply(ctx); err != nil {
+		return nil, err
+	}
+
+	return &storage_pb.SetResponse{Result: s.result}, nil
+}
+
+func (s *testServer) Delete(ctx context.Context, _ *storage_pb.DeleteRequest) (*storage_pb.DeleteResponse, error) {
+	if err := s.reply(ctx); err != nil {
+		return nil, err
+	}
+
+	return &storage_pb.DeleteResponse{}, nil
+}
+
+func (s *testServer) reply(ctx context.Context) error {
+	if s.requestId != nil {
+		md, _ := metadata.FromIncomingContext(ctx)
+		s.requestId <- md.Get(gin_request_id.RequestIDKey)[0]
+	}
+
+	if s.delay > 0 {
+		select {
+		case <-time.After(s.delay):
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		}
+	}
+
+	if s.err != nil {
+		return grpcErrors.ToStatus(s.err)
+	}
+
+	return nil
+}
+
+// newTestClient serves srv over bufconn and returns GrpcClient connected to it
+func newTestClient(t *testing.T, srv *testServer, timeout time.Duration) *GrpcClient {
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer()
+	storage_pb.RegisterStorageServer(s, srv)
+	go func() { _ = s.Serve(lis) }()
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err, "expect client to be created")
+
+	c := &GrpcClient{conn: conn, client: storage_pb.NewStorageClient(conn), timeout: timeout}
+	t.Cleanup(func() { _ = c.Close() })
+
+	return c
+}
+
+func TestGrpcClient_Errors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		err      error
+		status   int
+		code     string
+		sentinel error
+	}{
+		{"not found", cacheErrors.ErrNotFound, http.StatusNotFound, httpErrors.CodeNotFound, cacheErrors.ErrNotFound},
+		{"bad request", httpErrors.ErrBadRequest, http.StatusBadRequest, httpErrors.CodeBadRequest, httpErrors.ErrBadRequest},
+		{"quota exceeded", cacheErrors.ErrQuotaExceeded, http.StatusTooManyRequests, httpErrors.CodeQuotaExceeded, cacheErrors.ErrQuotaExceeded},
+		{"write limited", cacheErrors.NewErrWriteLimited("k", time.Second), http.StatusTooManyRequests, httpErrors.CodeWriteLimited, cacheErrors.ErrWriteRateLimited},
+		{"unavailable", cacheErrors.ErrCacheClosed, http.StatusServiceUnavailable, httpErrors.CodeUnavailable, nil},
+		{"internal", errors.New("secret details"), http.StatusInternalServerError, httpErrors.CodeInternal, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newTestClient(t, &testServer{err: tc.err}, time.Second)
+
+			calls := map[string]func() error{
+				"get": func() error {
+					_, err := c.Get(context.Background(), "k", "id")
+					return err
+				},
+				"set": func() error {
+					_, err := c.Set(context.Background(), "k", "v", "id")
+					return err
+				},
+				"delete": func() error {
+					return c.Delete(context.Background(), "k", "id")
+				},
+			}
+
+			for name, call := range calls {
+				err := call()
+				var errStatus *httpErrors.ErrStatus
+				require.ErrorAs(t, err, &errStatus, "expect %s to return ErrStatus", name)
+				assert.Equal(t, tc.status, errStatus.GetStatus(), "Unexpected %s HTTP status", name)
+				assert.Equal(t, tc.code, errStatus.GetCode(), "Unexpected %s error code", name)
+				assert.NotContains(t, err.Error(), "secret details", "expect internal details not to leak")
+				if tc.sentinel != nil {
+					assert.ErrorIs(t, err, tc.sentinel, "expect %s error to match sentinel", name)
+				}
+			}
+		})
+	}
+}
+
+func TestGrpcClient_SetResult(t *testing.T) {
+	testCases := []struct {
+		name   string
+		result storage_pb.SetResult
+		code   int
+	}{
+		{"created", storage_pb.SetResult_SET_RESULT_CREATED, http.StatusCreated},
+		{"unchanged", storage_pb.SetResult_SET_RESULT_UNCHANGED, http.StatusNoContent},
+		{"updated", storage_pb.SetResult_SET_RESULT_UPDATED, http.StatusOK},
+		{"unspecified", storage_pb.SetResult_SET_RESULT_UNSPECIFIED, http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newTestClient(t, &testServer{result: tc.result}, time.Second)
+
+			code, err := c.Set(context.Background(), "k", "v", "id")
+			require.NoError(t, err, "expect set to succeed")
+			assert.Equal(t, tc.code, code, "Unexpected HTTP code")
+		})
+	}
+}
+
+func TestGrpcClient_Metadata(t *testing.T) {
+	srv := &testServer{requestId: make(chan string, 1)}
+	c := newTestClient(t, srv, time.Second)
+
+	val, err := c.Get(context.Background(), "k", "request-1")
+	require.NoError(t, err, "expect get to succeed")
+	assert.Equal(t, "v", val, "expect value from server")
+	assert.Equal(t, "request-1", <-srv.requestId, "expect request ID in metadata")
+}
+
+func TestGrpcClient_Deadline(t *testing.T) {
+	t.Run("timeout", func(t *testing.T) {
+		c := newTestClient(t, &testServer{delay: time.Second}, 10*time.Millisecond)
+
+		_, err := c.Get(context.Background(), "k", "id")
+		var errStatus *httpErrors.ErrStatus
+		require.ErrorAs(t, err, &errStatus, "expect timeout to return ErrStatus")
+		assert.Equal(t, http.StatusGatewayTimeout, errStatus.GetStatus(), "expect timeout to map to gateway timeout")
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		c := newTestClient(t, &testServer{delay: time.Second}, time.Second)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(10*time.Millisecond, cancel)
+
+		err := c.Delete(ctx, "k", "id")
+		assert.ErrorIs(t, err, context.Canceled, "expect cancellation to return context.Canceled")
+	})
+}
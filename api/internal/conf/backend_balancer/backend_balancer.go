@@ -0,0 +1,134 @@
+package backend_balancer
+
+import (
+	"time"
+
+	"github.com/KennyMacCormik/common/log"
+	"github.com/KennyMacCormik/common/val"
+	"github.com/spf13/viper"
+
+	"github.com/KennyMacCormik/otel/api/internal/conf"
+)
+
+type backendBalancerConf struct {
+	BalancerDiscovery         string        `mapstructure:"backend_client_discovery" validate:"oneof=static dns-a dns-srv"`
+	BalancerDiscoveryInterval time.Duration `mapstructure:"backend_client_discovery_interval" validate:"min=1s,max=1h"`
+	BalancerPolicy            string        `mapstructure:"backend_client_balancer" validate:"oneof=round-robin least-outstanding consistent-hash"`
+
+	BalancerHealthCheckEnabled            bool          `mapstructure:"backend_client_health_check_enabled"`
+	BalancerHealthCheckPath               string        `mapstructure:"backend_client_health_check_path" validate:"required,startswith=/"`
+	BalancerHealthCheckInterval           time.Duration `mapstructure:"backend_client_health_check_interval" validate:"min=100ms,max=1m"`
+	BalancerHealthCheckTimeout            time.Duration `mapstructure:"backend_client_health_check_timeout" validate:"min=10ms,max=10s"`
+	BalancerHealthCheckUnhealthyThreshold int           `mapstructure:"backend_client_health_check_unhealthy_threshold" validate:"min=1,max=100"`
+	BalancerHealthCheckHealthyThreshold   int           `mapstructure:"backend_client_health_check_healthy_threshold" validate:"min=1,max=100"`
+}
+
+func NewBackendBalancerConf() conf.BackendBalancerConf {
+	c := &backendBalancerConf{}
+
+	viper.SetDefault("backend_client_discovery", "static")
+	err := viper.BindEnv("backend_client_discovery")
+	if err != nil {
+		log.Error("Failed to bind backend_client_discovery")
+	}
+
+	viper.SetDefault("backend_client_discovery_interval", "30s")
+	err = viper.BindEnv("backend_client_discovery_interval")
+	if err != nil {
+		log.Error("Failed to bind backend_client_discovery_interval")
+	}
+
+	viper.SetDefault("backend_client_balancer", "round-robin")
+	err = viper.BindEnv("backend_client_balancer")
+	if err != nil {
+		log.Error("Failed to bind backend_client_balancer")
+	}
+
+	viper.SetDefault("backend_client_health_check_enabled", true)
+	err = viper.BindEnv("backend_client_health_check_enabled")
+	if err != nil {
+		log.Error("Failed to bind backend_client_health_check_enabled")
+	}
+
+	viper.SetDefault("backend_client_health_check_path", "/health")
+	err = viper.BindEnv("backend_client_health_check_path")
+	if err != nil {
+		log.Error("Failed to bind backend_client_health_check_path")
+	}
+
+	viper.SetDefault("backend_client_health_check_interval", "5s")
+	err = viper.BindEnv("backend_client_health_check_interval")
+	if err != nil {
+		log.Error("Failed to bind backend_client_health_check_interval")
+	}
+
+	viper.SetDefault("backend_client_health_check_timeout", "1s")
+	err = viper.BindEnv("backend_client_health_check_timeout")
+	if err != nil {
+		log.Error("Failed to bind backend_client_health_check_timeout")
+	}
+
+	viper.SetDefault("backend_client_health_check_unhealthy_threshold", 3)
+	err = viper.BindEnv("backend_client_health_check_unhealthy_threshold")
+	if err != nil {
+		log.Error("Failed to bind backend_client_health_check_unhealthy_threshold")
+	}
+
+	viper.SetDefault("backend_client_health_check_healthy_threshold", 2)
+	err = viper.BindEnv("backend_client_health_check_healthy_threshold")
+	if err != nil {
+		log.Error("Failed to bind backend_client_health_check_healthy_threshold")
+	}
+
+	err = viper.Unmarshal(c)
+	if err != nil {
+		log.Error("Failed to unmarshal backendBalancerConf")
+	}
+
+	err = val.ValidateStruct(c)
+	if err != nil {
+		log.Error("Failed to validate backendBalancerConf", "err", err)
+	}
+
+	if err != nil {
+		return nil
+	}
+
+	return c
+}
+
+func (l *backendBalancerConf) Discovery() string {
+	return l.BalancerDiscovery
+}
+
+func (l *backendBalancerConf) DiscoveryInterval() time.Duration {
+	return l.BalancerDiscoveryInterval
+}
+
+func (l *backendBalancerConf) Policy() string {
+	return l.BalancerPolicy
+}
+
+func (l *backendBalancerConf) HealthCheckEnabled() bool {
+	return l.BalancerHealthCheckEnabled
+}
+
+func (l *backendBalancerConf) HealthCheckPath() string {
+	return l.BalancerHealthCheckPath
+}
+
+func (l *backendBalancerConf) HealthCheckInterval() time.Duration {
+	return l.BalancerHealthCheckInterval
+}
+
+func (l *backendBalancerConf) HealthCheckTimeout() time.Duration {
+	return l.BalancerHealthCheckTimeout
+}
+
+func (l *backendBalancerConf) UnhealthyThreshold() int {
+	return l.BalancerHealthCheckUnhealthyThreshold
+}
+
+func (l *backendBalancerConf) HealthyThreshold() int {
+	return l.BalancerHealthCheckHealthyThreshold
+}
@@ -11,24 +11,152 @@ import (
 )
 
 type backendClientConf struct {
-	ClientEndpoint       string        `mapstructure:"backend_client_endpoint" validate:"required,urlprefix,url"`
+	ClientTransport      string        `mapstructure:"backend_client_transport" validate:"oneof=http grpc"`
+	ClientEndpoints      []string      `mapstructure:"backend_client_endpoint" validate:"required_if=ClientTransport http,dive,urlprefix,url"`
+	ClientGrpcTarget     string        `mapstructure:"backend_client_grpc_target" validate:"required_if=ClientTransport grpc"`
 	ClientRequestTimeout time.Duration `mapstructure:"backend_client_request_timeout" validate:"min=100ms,max=1s"`
+
+	ClientRetryMaxAttempts     int           `mapstructure:"backend_client_retry_max_attempts" validate:"min=1,max=10"`
+	ClientRetryBaseDelay       time.Duration `mapstructure:"backend_client_retry_base_delay" validate:"min=1ms,max=1s"`
+	ClientRetryMaxDelay        time.Duration `mapstructure:"backend_client_retry_max_delay" validate:"min=1ms,max=10s,gtefield=ClientRetryBaseDelay"`
+	ClientRetryIdempotencyKeys bool          `mapstructure:"backend_client_retry_idempotency_keys"`
+
+	ClientHedgingEnabled    bool          `mapstructure:"backend_client_hedging_enabled"`
+	ClientHedgingPercentile float64       `mapstructure:"backend_client_hedging_percentile" validate:"gt=0,max=100"`
+	ClientHedgingMinDelay   time.Duration `mapstructure:"backend_client_hedging_min_delay" validate:"min=1ms,max=1s"`
+
+	ClientMaxIdleConns          int           `mapstructure:"backend_client_max_idle_conns" validate:"min=1,max=10000"`
+	ClientMaxIdleConnsPerHost   int           `mapstructure:"backend_client_max_idle_conns_per_host" validate:"min=1,max=10000"`
+	ClientMaxConnsPerHost       int           `mapstructure:"backend_client_max_conns_per_host" validate:"min=0,max=10000"`
+	ClientIdleConnTimeout       time.Duration `mapstructure:"backend_client_idle_conn_timeout" validate:"min=1s,max=10m"`
+	ClientKeepAlive             time.Duration `mapstructure:"backend_client_keep_alive" validate:"min=1s,max=10m"`
+	ClientDialTimeout           time.Duration `mapstructure:"backend_client_dial_timeout" validate:"min=10ms,max=10s"`
+	ClientTLSHandshakeTimeout   time.Duration `mapstructure:"backend_client_tls_handshake_timeout" validate:"min=10ms,max=10s"`
+	ClientResponseHeaderTimeout time.Duration `mapstructure:"backend_client_response_header_timeout" validate:"min=0,max=10s"`
+	ClientHTTP2                 bool          `mapstructure:"backend_client_http2"`
 }
 
 func NewBackendClientConf() conf.BackendClientConf {
 	c := &backendClientConf{}
 
-	err := viper.BindEnv("backend_client_endpoint")
+	viper.SetDefault("backend_client_transport", "http")
+	err := viper.BindEnv("backend_client_transport")
+	if err != nil {
+		log.Error("Failed to bind backend_client_transport")
+	}
+
+	err = viper.BindEnv("backend_client_endpoint")
 	if err != nil {
 		log.Error("Failed to bind backend_client_endpoint")
 	}
 
+	err = viper.BindEnv("backend_client_grpc_target")
+	if err != nil {
+		log.Error("Failed to bind backend_client_grpc_target")
+	}
+
 	viper.SetDefault("backend_client_request_timeout", "200ms")
 	err = viper.BindEnv("backend_client_request_timeout")
 	if err != nil {
 		log.Error("Failed to bind backend_client_request_timeout")
 	}
 
+	viper.SetDefault("backend_client_retry_max_attempts", 3)
+	err = viper.BindEnv("backend_client_retry_max_attempts")
+	if err != nil {
+		log.Error("Failed to bind backend_client_retry_max_attempts")
+	}
+
+	viper.SetDefault("backend_client_retry_base_delay", "20ms")
+	err = viper.BindEnv("backend_client_retry_base_delay")
+	if err != nil {
+		log.Error("Failed to bind backend_client_retry_base_delay")
+	}
+
+	viper.SetDefault("backend_client_retry_max_delay", "200ms")
+	err = viper.BindEnv("backend_client_retry_max_delay")
+	if err != nil {
+		log.Error("Failed to bind backend_client_retry_max_delay")
+	}
+
+	viper.SetDefault("backend_client_retry_idempotency_keys", false)
+	err = viper.BindEnv("backend_client_retry_idempotency_keys")
+	if err != nil {
+		log.Error("Failed to bind backend_client_retry_idempotency_keys")
+	}
+
+	viper.SetDefault("backend_client_hedging_enabled", false)
+	err = viper.BindEnv("backend_client_hedging_enabled")
+	if err != nil {
+		log.Error("Failed to bind backend_client_hedging_enabled")
+	}
+
+	viper.SetDefault("backend_client_hedging_percentile", 95)
+	err = viper.BindEnv("backend_client_hedging_percentile")
+	if err != nil {
+		log.Error("Failed to bind backend_client_hedging_percentile")
+	}
+
+	viper.SetDefault("backend_client_hedging_min_delay", "10ms")
+	err = viper.BindEnv("backend_client_hedging_min_delay")
+	if err != nil {
+		log.Error("Failed to bind backend_client_hedging_min_delay")
+	}
+
+	viper.SetDefault("backend_client_max_idle_conns", 100)
+	err = viper.BindEnv("backend_client_max_idle_conns")
+	if err != nil {
+		log.Error("Failed to bind backend_client_max_idle_conns")
+	}
+
+	viper.SetDefault("backend_client_max_idle_conns_per_host", 100)
+	err = viper.BindEnv("backend_client_max_idle_conns_per_host")
+	if err != nil {
+		log.Error("Failed to bind backend_client_max_idle_conns_per_host")
+	}
+
+	viper.SetDefault("backend_client_max_conns_per_host", 0)
+	err = viper.BindEnv("backend_client_max_conns_per_host")
+	if err != nil {
+		log.Error("Failed to bind backend_client_max_conns_per_host")
+	}
+
+	viper.SetDefault("backend_client_idle_conn_timeout", "90s")
+	err = viper.BindEnv("backend_client_idle_conn_timeout")
+	if err != nil {
+		log.Error("Failed to bind backend_client_idle_conn_timeout")
+	}
+
+	viper.SetDefault("backend_client_keep_alive", "30s")
+	err = viper.BindEnv("backend_client_keep_alive")
+	if err != nil {
+		log.Error("Failed to bind backend_client_keep_alive")
+	}
+
+	viper.SetDefault("backend_client_dial_timeout", "500ms")
+	err = viper.BindEnv("backend_client_dial_timeout")
+	if err != nil {
+		log.Error("Failed to bind backend_client_dial_timeout")
+	}
+
+	viper.SetDefault("backend_client_tls_handshake_timeout", "1s")
+	err = viper.BindEnv("backend_client_tls_handshake_timeout")
+	if err != nil {
+		log.Error("Failed to bind backend_client_tls_handshake_timeout")
+	}
+
+	viper.SetDefault("backend_client_response_header_timeout", "0s")
+	err = viper.BindEnv("backend_client_response_header_timeout")
+	if err != nil {
+		log.Error("Failed to bind backend_client_response_header_timeout")
+	}
+
+	viper.SetDefault("backend_client_http2", false)
+	err = viper.BindEnv("backend_client_http2")
+	if err != nil {
+		log.Error("Failed to bind backend_client_http2")
+	}
+
 	err = viper.Unmarshal(c)
 	if err != nil {
 		log.Error("Failed to unmarshal backendClientConf")
@@ -46,10 +174,82 @@ func NewBackendClientConf() conf.BackendClientConf {
 	return c
 }
 
-func (l *backendClientConf) Endpoint() string {
-	return l.ClientEndpoint
+func (l *backendClientConf) Transport() string {
+	return l.ClientTransport
+}
+
+func (l *backendClientConf) GrpcTarget() string {
+	return l.ClientGrpcTarget
+}
+
+func (l *backendClientConf) Endpoints() []string {
+	return l.ClientEndpoints
 }
 
 func (l *backendClientConf) RequestTimeout() time.Duration {
 	return l.ClientRequestTimeout
 }
+
+func (l *backendClientConf) RetryMaxAttempts() int {
+	return l.ClientRetryMaxAttempts
+}
+
+func (l *backendClientConf) RetryBaseDelay() time.Duration {
+	return l.ClientRetryBaseDelay
+}
+
+func (l *backendClientConf) RetryMaxDelay() time.Duration {
+	return l.ClientRetryMaxDelay
+}
+
+func (l *backendClientConf) RetryIdempotencyKeys() bool {
+	return l.ClientRetryIdempotencyKeys
+}
+
+func (l *backendClientConf) HedgingEnabled() bool {
+	return l.ClientHedgingEnabled
+}
+
+func (l *backendClientConf) HedgingPercentile() float64 {
+	return l.ClientHedgingPercentile
+}
+
+func (l *backendClientConf) HedgingMinDelay() time.Duration {
+	return l.ClientHedgingMinDelay
+}
+
+func (l *backendClientConf) MaxIdleConns() int {
+	return l.ClientMaxIdleConns
+}
+
+func (l *backendClientConf) MaxIdleConnsPerHost() int {
+	return l.ClientMaxIdleConnsPerHost
+}
+
+func (l *backendClientConf) MaxConnsPerHost() int {
+	return l.ClientMaxConnsPerHost
+}
+
+func (l *backendClientConf) IdleConnTimeout() time.Duration {
+	return l.ClientIdleConnTimeout
+}
+
+func (l *backendClientConf) KeepAlive() time.Duration {
+	return l.ClientKeepAlive
+}
+
+func (l *backendClientConf) DialTimeout() time.Duration {
+	return l.ClientDialTimeout
+}
+
+func (l *backendClientConf) TLSHandshakeTimeout() time.Duration {
+	return l.ClientTLSHandshakeTimeout
+}
+
+func (l *backendClientConf) ResponseHeaderTimeout() time.Duration {
+	return l.ClientResponseHeaderTimeout
+}
+
+func (l *backendClientConf) HTTP2() bool {
+	return l.ClientHTTP2
+}
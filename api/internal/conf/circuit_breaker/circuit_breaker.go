@@ -0,0 +1,122 @@
+package circuit_breaker
+
+import (
+	"time"
+
+	"github.com/KennyMacCormik/common/log"
+	"github.com/KennyMacCormik/common/val"
+	"github.com/spf13/viper"
+
+	"github.com/KennyMacCormik/otel/api/internal/conf"
+)
+
+type circuitBreakerConf struct {
+	CbEnabled          bool          `mapstructure:"circuit_breaker_enabled"`
+	CbWindowSize       int           `mapstructure:"circuit_breaker_window_size" validate:"min=1,max=100000"`
+	CbMinRequests      int           `mapstructure:"circuit_breaker_min_requests" validate:"min=1,ltefield=CbWindowSize"`
+	CbErrorRate        float64       `mapstructure:"circuit_breaker_error_rate" validate:"gt=0,lte=1"`
+	CbSlowCallDuration time.Duration `mapstructure:"circuit_breaker_slow_call_duration" validate:"min=1ms,max=10s"`
+	CbSlowCallRate     float64       `mapstructure:"circuit_breaker_slow_call_rate" validate:"gt=0,lte=1"`
+	CbOpenTimeout      time.Duration `mapstructure:"circuit_breaker_open_timeout" validate:"min=100ms,max=5m"`
+	CbHalfOpenMaxCalls int           `mapstructure:"circuit_breaker_half_open_max_calls" validate:"min=1,max=1000"`
+}
+
+func NewCircuitBreakerConf() conf.CircuitBreakerConf {
+	c := &circuitBreakerConf{}
+
+	viper.SetDefault("circuit_breaker_enabled", true)
+	err := viper.BindEnv("circuit_breaker_enabled")
+	if err != nil {
+		log.Error("Failed to bind circuit_breaker_enabled")
+	}
+
+	viper.SetDefault("circuit_breaker_window_size", 100)
+	err = viper.BindEnv("circuit_breaker_window_size")
+	if err != nil {
+		log.Error("Failed to bind circuit_breaker_window_size")
+	}
+
+	viper.SetDefault("circuit_breaker_min_requests", 20)
+	err = viper.BindEnv("circuit_breaker_min_requests")
+	if err != nil {
+		log.Error("Failed to bind circuit_breaker_min_requests")
+	}
+
+	viper.SetDefault("circuit_breaker_error_rate", 0.5)
+	err = viper.BindEnv("circuit_breaker_error_rate")
+	if err != nil {
+		log.Error("Failed to bind circuit_breaker_error_rate")
+	}
+
+	viper.SetDefault("circuit_breaker_slow_call_duration", "500ms")
+	err = viper.BindEnv("circuit_breaker_slow_call_duration")
+	if err != nil {
+		log.Error("Failed to bind circuit_breaker_slow_call_duration")
+	}
+
+	viper.SetDefault("circuit_breaker_slow_call_rate", 0.8)
+	err = viper.BindEnv("circuit_breaker_slow_call_rate")
+	if err != nil {
+		log.Error("Failed to bind circuit_breaker_slow_call_rate")
+	}
+
+	viper.SetDefault("circuit_breaker_open_timeout", "5s")
+	err = viper.BindEnv("circuit_breaker_open_timeout")
+	if err != nil {
+		log.Error("Failed to bind circuit_breaker_open_timeout")
+	}
+
+	viper.SetDefault("circuit_breaker_half_open_max_calls", 5)
+	err = viper.BindEnv("circuit_breaker_half_open_max_calls")
+	if err != nil {
+		log.Error("Failed to bind circuit_breaker_half_open_max_calls")
+	}
+
+	err = viper.Unmarshal(c)
+	if err != nil {
+		log.Error("Failed to unmarshal circuitBreakerConf")
+	}
+
+	err = val.ValidateStruct(c)
+	if err != nil {
+		log.Error("Failed to validate circuitBreakerConf", "err", err)
+	}
+
+	if err != nil {
+		return nil
+	}
+
+	return c
+}
+
+func (l *circuitBreakerConf) Enabled() bool {
+	return l.CbEnabled
+}
+
+func (l *circuitBreakerConf) WindowSize() int {
+	return l.CbWindowSize
+}
+
+func (l *circuitBreakerConf) MinRequests() int {
+	return l.CbMinRequests
+}
+
+func (l *circuitBreakerConf) ErrorRate() float64 {
+	return l.CbErrorRate
+}
+
+func (l *circuitBreakerConf) SlowCallDuration() time.Duration {
+	return l.CbSlowCallDuration
+}
+
+func (l *circuitBreakerConf) SlowCallRate() float64 {
+	return l.CbSlowCallRate
+}
+
+func (l *circuitBreakerConf) OpenTimeout() time.Duration {
+	return l.CbOpenTimeout
+}
+
+func (l *circuitBreakerConf) HalfOpenMaxCalls() int {
+	return l.CbHalfOpenMaxCalls
+}
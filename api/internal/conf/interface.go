@@ -3,6 +3,53 @@ package conf
 import "time"
 
 type BackendClientConf interface {
+	Transport() string
+	Endpoints() []string
+	GrpcTarget() string
+	RequestTimeout() time.Duration
+	RetryMaxAttempts() int
+	RetryBaseDelay() time.Duration
+	RetryMaxDelay() time.Duration
+	RetryIdempotencyKeys() bool
+	HedgingEnabled() bool
+	HedgingPercentile() float64
+	HedgingMinDelay() time.Duration
+	MaxIdleConns() int
+	MaxIdleConnsPerHost() int
+	MaxConnsPerHost() int
+	IdleConnTimeout() time.Duration
+	KeepAlive() time.Duration
+	DialTimeout() time.Duration
+	TLSHandshakeTimeout() time.Duration
+	ResponseHeaderTimeout() time.Duration
+	HTTP2() bool
+}
+
+type RemoteCacheConf interface {
 	Endpoint() string
 	RequestTimeout() time.Duration
+	WritePolicy() string
+}
+
+type CircuitBreakerConf interface {
+	Enabled() bool
+	WindowSize() int
+	MinRequests() int
+	ErrorRate() float64
+	SlowCallDuration() time.Duration
+	SlowCallRate() float64
+	OpenTimeout() time.Duration
+	HalfOpenMaxCalls() int
+}
+
+type BackendBalancerConf interface {
+	Discovery() string
+	DiscoveryInterval() time.Duration
+	Policy() string
+	HealthCheckEnabled() bool
+	HealthCheckPath() string
+	HealthCheckInterval() time.Duration
+	HealthCheckTimeout() time.Duration
+	UnhealthyThreshold() int
+	HealthyThreshold() int
 }
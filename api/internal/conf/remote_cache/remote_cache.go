@@ -0,0 +1,66 @@
+package remote_cache
+
+import (
+	"time"
+
+	"github.com/KennyMacCormik/common/log"
+	"github.com/KennyMacCormik/common/val"
+	"github.com/spf13/viper"
+
+	"github.com/KennyMacCormik/otel/api/internal/conf"
+)
+
+type remoteCacheConf struct {
+	CacheEndpoint       string        `mapstructure:"remote_cache_endpoint" validate:"omitempty,urlprefix,url"`
+	CacheRequestTimeout time.Duration `mapstructure:"remote_cache_request_timeout" validate:"min=10ms,max=1s"`
+	CacheWritePolicy    string        `mapstructure:"remote_cache_write_policy" validate:"oneof=write-through write-around"`
+}
+
+func NewRemoteCacheConf() conf.RemoteCacheConf {
+	c := &remoteCacheConf{}
+
+	err := viper.BindEnv("remote_cache_endpoint")
+	if err != nil {
+		log.Error("Failed to bind remote_cache_endpoint")
+	}
+
+	viper.SetDefault("remote_cache_request_timeout", "100ms")
+	err = viper.BindEnv("remote_cache_request_timeout")
+	if err != nil {
+		log.Error("Failed to bind remote_cache_request_timeout")
+	}
+
+	viper.SetDefault("remote_cache_write_policy", "write-through")
+	err = viper.BindEnv("remote_cache_write_policy")
+	if err != nil {
+		log.Error("Failed to bind remote_cache_write_policy")
+	}
+
+	err = viper.Unmarshal(c)
+	if err != nil {
+		log.Error("Failed to unmarshal remoteCacheConf")
+	}
+
+	err = val.ValidateStruct(c)
+	if err != nil {
+		log.Error("Failed to validate remoteCacheConf", "err", err)
+	}
+
+	if err != nil {
+		return nil
+	}
+
+	return c
+}
+
+func (l *remoteCacheConf) Endpoint() string {
+	return l.CacheEndpoint
+}
+
+func (l *remoteCacheConf) RequestTimeout() time.Duration {
+	return l.CacheRequestTimeout
+}
+
+func (l *remoteCacheConf) WritePolicy() string {
+	return l.CacheWritePolicy
+}
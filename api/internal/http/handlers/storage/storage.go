@@ -18,6 +18,7 @@ import (
 
 	"github.com/KennyMacCormik/otel/backend/pkg/gin/gin_request_id"
 	cacheErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/cache"
+	httpErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/http"
 	httpModels "github.com/KennyMacCormik/otel/backend/pkg/models/http"
 
 	"github.com/KennyMacCormik/otel/api/internal/service"
@@ -49,11 +50,11 @@ func (s *StorageHandler) ginGet() func(c *gin.Context) {
 		defer span.End()
 		defer lg.Info("request finished")
 		if reqId == "" {
-			err := errors.New("no request ID provided")
+			err := fmt.Errorf("%w: no request ID provided", httpErrors.ErrBadRequest)
 			setSpanErr(span, err)
 			lg.Error("malformed request", "error", err.Error())
 
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			writeError(c, err)
 			return
 		}
 
@@ -62,7 +63,7 @@ func (s *StorageHandler) ginGet() func(c *gin.Context) {
 			setSpanErr(span, err)
 			lg.Error("malformed request", "error", err.Error())
 
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			writeError(c, err)
 			return
 		}
 
@@ -71,13 +72,13 @@ func (s *StorageHandler) ginGet() func(c *gin.Context) {
 			if errors.Is(err, cacheErrors.ErrNotFound) {
 				lg.Warn("key not found", "key", key)
 
-				c.Status(http.StatusNotFound)
+				writeError(c, err)
 				return
 			}
 
 			lg.Error("failed to get value", "key", key, "error", err.Error())
 
-			c.Status(http.StatusInternalServerError)
+			writeError(c, err)
 			return
 		}
 
@@ -99,11 +100,11 @@ func (s *StorageHandler) ginSet() func(c *gin.Context) {
 		defer span.End()
 		defer lg.Info("request finished")
 		if reqId == "" {
-			err := errors.New("no request ID provided")
+			err := fmt.Errorf("%w: no request ID provided", httpErrors.ErrBadRequest)
 			setSpanErr(span, err)
 			lg.Error("malformed request", "error", err.Error())
 
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			writeError(c, err)
 			return
 		}
 
@@ -114,7 +115,7 @@ func (s *StorageHandler) ginSet() func(c *gin.Context) {
 			setSpanErr(span, err)
 			lg.Error("failed read request body", "error", err.Error())
 
-			c.Status(http.StatusBadRequest)
+			writeError(c, fmt.Errorf("%w: %w", httpErrors.ErrBadRequest, err))
 			return
 		}
 
@@ -125,7 +126,7 @@ func (s *StorageHandler) ginSet() func(c *gin.Context) {
 		if err != nil {
 			lg.Error("failed to set value", "key", b.Key, "value", b.Val, "error", err.Error())
 
-			c.Status(http.StatusInternalServerError)
+			writeError(c, err)
 			return
 		}
 
@@ -143,11 +144,11 @@ func (s *StorageHandler) ginDel() func(c *gin.Context) {
 		defer span.End()
 		defer lg.Info("request finished")
 		if reqId == "" {
-			err := errors.New("no request ID provided")
+			err := fmt.Errorf("%w: no request ID provided", httpErrors.ErrBadRequest)
 			setSpanErr(span, err)
 			lg.Error("malformed request", "error", err.Error())
 
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			writeError(c, err)
 			return
 		}
 
@@ -156,7 +157,7 @@ func (s *StorageHandler) ginDel() func(c *gin.Context) {
 			setSpanErr(span, err)
 			lg.Error("malformed request", "error", err.Error())
 
-			c.Status(http.StatusBadRequest)
+			writeError(c, err)
 			return
 		}
 
@@ -164,7 +165,7 @@ func (s *StorageHandler) ginDel() func(c *gin.Context) {
 		if err != nil {
 			lg.Error("failed to delete value", "key", key, "error", err.Error())
 
-			c.Status(http.StatusInternalServerError)
+			writeError(c, err)
 			return
 		}
 
@@ -172,6 +173,13 @@ func (s *StorageHandler) ginDel() func(c *gin.Context) {
 	}
 }
 
+// writeError responds with status and httpModels.ErrorBody matching err.
+// Errors returned by the backend are passed through with their status.
+func writeError(c *gin.Context, err error) {
+	errStatus := httpErrors.FromError(err)
+	c.JSON(errStatus.GetStatus(), errStatus.GetBody())
+}
+
 func setSpanErr(span trace.Span, err error) {
 	span.SetStatus(codes.Error, err.Error())
 	span.RecordError(err)
@@ -181,11 +189,11 @@ func setSpanErr(span trace.Span, err error) {
 func getKey(c *gin.Context) (string, error) {
 	key := c.Param("key")
 	if key == "" {
-		return "", errors.New("no key provided")
+		return "", fmt.Errorf("%w: no key provided", httpErrors.ErrBadRequest)
 	}
 
 	if !isUrlEncoded(strings.TrimPrefix(c.Request.RequestURI, "/storage/"), key) {
-		return "", errors.New("key must be URL-encoded")
+		return "", fmt.Errorf("%w: key must be URL-encoded", httpErrors.ErrBadRequest)
 	}
 
 	return key, nil
@@ -0,0 +1,42 @@
+package init
+
+import (
+	"context"
+
+	"github.com/KennyMacCormik/otel/api/internal/client/balancer"
+)
+
+// InitBalancer resolves backend endpoints and starts health checks
+func InitBalancer(ctx context.Context, conf *Config) (*balancer.Balancer, error) {
+	var (
+		resolver balancer.Resolver
+		err      error
+	)
+
+	switch conf.Client.Balancer.Discovery {
+	case "dns-a":
+		resolver, err = balancer.NewDNSResolver(conf.Client.Endpoints[0], false)
+	case "dns-srv":
+		resolver, err = balancer.NewDNSResolver(conf.Client.Endpoints[0], true)
+	default:
+		resolver = balancer.NewStaticResolver(conf.Client.Endpoints)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []balancer.InitOptions{balancer.WithRefreshInterval(conf.Client.Balancer.DiscoveryInterval)}
+
+	if conf.Client.Balancer.HealthCheck {
+		opts = append(opts, balancer.WithHealthCheck(
+			conf.Client.Balancer.HealthCheckPath,
+			conf.Client.Balancer.HealthInterval,
+			conf.Client.Balancer.HealthTimeout,
+			conf.Client.Balancer.UnhealthyThreshold,
+			conf.Client.Balancer.HealthyThreshold,
+		))
+	}
+
+	return balancer.NewBalancer(ctx, balancer.Policy(conf.Client.Balancer.Policy), resolver, opts...)
+}
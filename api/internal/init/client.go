@@ -0,0 +1,68 @@
+package init
+
+import (
+	"context"
+
+	"github.com/KennyMacCormik/common/log"
+
+	"github.com/KennyMacCormik/otel/api/internal/client"
+	"github.com/KennyMacCormik/otel/api/internal/client/circuit_breaker"
+	"github.com/KennyMacCormik/otel/api/internal/client/client_impl"
+	"github.com/KennyMacCormik/otel/api/internal/client/grpc_client"
+)
+
+// InitClient returns backend client of the configured transport, wrapped with the circuit breaker if it is enabled.
+// closeFn releases the balancer or the connection of the client, it is safe to call on error.
+func InitClient(ctx context.Context, conf *Config) (c client.BackendClientInterface, closeFn func(), err error) {
+	closeFn = func() {}
+
+	if conf.Client.Transport == "grpc" {
+		grpcClient, err := grpc_client.NewGrpcClient(conf.Client.GrpcTarget, conf.Client.RequestTimeout)
+		if err != nil {
+			return nil, closeFn, err
+		}
+
+		c = grpcClient
+		closeFn = func() {
+			if err := grpcClient.Close(); err != nil {
+				log.Warn("failed to close grpc client", "error", err)
+			}
+		}
+	} else {
+		backends, err := InitBalancer(ctx, conf)
+		if err != nil {
+			return nil, closeFn, err
+		}
+		log.Info("backend balancer initialized", "endpoints", len(backends.Endpoints()))
+
+		c = client_impl.NewBackendClient(backends, conf.Client.RequestTimeout, client_impl.RetryPolicy{
+			MaxAttempts:        conf.Client.Retry.MaxAttempts,
+			BaseDelay:          conf.Client.Retry.BaseDelay,
+			MaxDelay:           conf.Client.Retry.MaxDelay,
+			UseIdempotencyKeys: conf.Client.Retry.IdempotencyKeys,
+		}, client_impl.HedgingPolicy{
+			Enabled:    conf.Client.Hedging.Enabled,
+			Percentile: conf.Client.Hedging.Percentile,
+			MinDelay:   conf.Client.Hedging.MinDelay,
+		}, client_impl.TransportPolicy{
+			MaxIdleConns:          conf.Client.Connections.MaxIdleConns,
+			MaxIdleConnsPerHost:   conf.Client.Connections.MaxIdleConnsPerHost,
+			MaxConnsPerHost:       conf.Client.Connections.MaxConnsPerHost,
+			IdleConnTimeout:       conf.Client.Connections.IdleConnTimeout,
+			KeepAlive:             conf.Client.Connections.KeepAlive,
+			DialTimeout:           conf.Client.Connections.DialTimeout,
+			TLSHandshakeTimeout:   conf.Client.Connections.TLSHandshakeTimeout,
+			ResponseHeaderTimeout: conf.Client.Connections.ResponseHeaderTimeout,
+			HTTP2:                 conf.Client.Connections.HTTP2,
+		})
+		closeFn = backends.Close
+	}
+
+	if cb := conf.Client.CircuitBreaker; cb.Enabled {
+		c = circuit_breaker.NewCircuitBreaker(c, circuit_breaker.WithOverrideDefaults(
+			cb.WindowSize, cb.MinRequests, cb.ErrorRate, cb.SlowCallDuration, cb.SlowCallRate, cb.OpenTimeout, cb.HalfOpenMaxCalls,
+		))
+	}
+
+	return c, closeFn, nil
+}
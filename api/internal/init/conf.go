@@ -1,32 +1,109 @@
 package init
 
 import (
+	"fmt"
 	"time"
 
+	"github.com/KennyMacCormik/common/log"
+	"github.com/KennyMacCormik/otel/backend/pkg/conf"
+	"github.com/KennyMacCormik/otel/backend/pkg/conf/admin_conf"
 	"github.com/KennyMacCormik/otel/backend/pkg/conf/gin_conf"
 	"github.com/KennyMacCormik/otel/backend/pkg/conf/http_conf"
 	"github.com/KennyMacCormik/otel/backend/pkg/conf/logger_conf"
 	"github.com/KennyMacCormik/otel/backend/pkg/conf/otel_config"
 	"github.com/KennyMacCormik/otel/backend/pkg/conf/rate_limiter_conf"
 
+	"github.com/KennyMacCormik/otel/api/internal/conf/backend_balancer"
 	"github.com/KennyMacCormik/otel/api/internal/conf/backend_client"
+	"github.com/KennyMacCormik/otel/api/internal/conf/circuit_breaker"
+	"github.com/KennyMacCormik/otel/api/internal/conf/remote_cache"
 )
 
 type Config struct {
 	Log         Log
 	OTel        OTel
-	RateLimiter RateLimiter
+	RateLimiter conf.RateLimiterConf
 	Http        Http
 	Gin         Gin
+	Admin       Admin
 	Client      Client
+	RemoteCache RemoteCache
 }
 type Client struct {
+	// Transport is either http or grpc. Balancer, Retry, Hedging and Connections only apply to http.
+	Transport      string
+	Endpoints      []string
+	GrpcTarget     string
+	RequestTimeout time.Duration
+	Balancer       Balancer
+	Retry          Retry
+	Hedging        Hedging
+	Connections    Connections
+	CircuitBreaker CircuitBreaker
+}
+type Retry struct {
+	MaxAttempts     int
+	BaseDelay       time.Duration
+	MaxDelay        time.Duration
+	IdempotencyKeys bool
+}
+type Hedging struct {
+	Enabled    bool
+	Percentile float64
+	MinDelay   time.Duration
+}
+type Connections struct {
+	MaxIdleConns          int
+	MaxIdleConnsPerHost   int
+	MaxConnsPerHost       int
+	IdleConnTimeout       time.Duration
+	KeepAlive             time.Duration
+	DialTimeout           time.Duration
+	TLSHandshakeTimeout   time.Duration
+	ResponseHeaderTimeout time.Duration
+	HTTP2                 bool
+}
+
+// RemoteCache is a shared L2 tier behind the local cache. Empty Endpoint disables it.
+type RemoteCache struct {
 	Endpoint       string
 	RequestTimeout time.Duration
+	WritePolicy    string
+}
+type Balancer struct {
+	Discovery          string
+	DiscoveryInterval  time.Duration
+	Policy             string
+	HealthCheck        bool
+	HealthCheckPath    string
+	HealthInterval     time.Duration
+	HealthTimeout      time.Duration
+	UnhealthyThreshold int
+	HealthyThreshold   int
+}
+type CircuitBreaker struct {
+	Enabled          bool
+	WindowSize       int
+	MinRequests      int
+	ErrorRate        float64
+	SlowCallDuration time.Duration
+	SlowCallRate     float64
+	OpenTimeout      time.Duration
+	HalfOpenMaxCalls int
 }
 type Gin struct {
 	Mode string
 }
+type Admin struct {
+	// Token authenticates admin endpoints, they reject every request if it is empty
+	Token string
+}
+
+// String keeps the token out of the logged config
+func (a Admin) String() string {
+	return fmt.Sprintf("{Token:%s}", redact(a.Token))
+}
+
 type Log struct {
 	Format string
 	Level  string
@@ -35,17 +112,13 @@ type OTel struct {
 	Endpoint        string
 	ShutdownTimeout time.Duration
 }
-type RateLimiter struct {
-	MaxRunning int64
-	MaxWait    int64
-	RetryAfter int64
-}
 type Http struct {
 	Endpoint        string
 	ReadTimeout     time.Duration
 	WriteTimeout    time.Duration
 	IdleTimeout     time.Duration
 	ShutdownTimeout time.Duration
+	H2C             bool
 }
 
 func GetConfig() *Config {
@@ -57,7 +130,11 @@ func GetConfig() *Config {
 		cfg.getOTelConfig,
 		cfg.getRateLimiterConfig,
 		cfg.getGinConfig,
+		cfg.getAdminConfig,
 		cfg.getBackendClientConfig,
+		cfg.getBackendBalancerConfig,
+		cfg.getRemoteCacheConfig,
+		cfg.getCircuitBreakerConfig,
 	}
 
 	for _, fn := range fns {
@@ -69,6 +146,17 @@ func GetConfig() *Config {
 	return cfg
 }
 
+func (c *Config) getAdminConfig() bool {
+	i := admin_conf.NewAdminConf()
+	if i == nil {
+		return false
+	}
+
+	c.Admin.Token = i.Token()
+
+	return true
+}
+
 func (c *Config) getGinConfig() bool {
 	i := gin_conf.NewGinConf()
 	if i == nil {
@@ -103,6 +191,7 @@ func (c *Config) getHttpConfig() bool {
 	c.Http.ReadTimeout = i.ReadTimeout()
 	c.Http.WriteTimeout = i.WriteTimeout()
 	c.Http.ShutdownTimeout = i.ShutdownTimeout()
+	c.Http.H2C = i.H2CEnabled()
 
 	return true
 }
@@ -125,9 +214,7 @@ func (c *Config) getRateLimiterConfig() bool {
 		return false
 	}
 
-	c.RateLimiter.RetryAfter = i.RetryAfter()
-	c.RateLimiter.MaxRunning = i.MaxRunning()
-	c.RateLimiter.MaxWait = i.MaxWaiting()
+	c.RateLimiter = i
 
 	return true
 }
@@ -138,8 +225,90 @@ func (c *Config) getBackendClientConfig() bool {
 		return false
 	}
 
-	c.Client.Endpoint = i.Endpoint()
+	c.Client.Transport = i.Transport()
+	c.Client.Endpoints = i.Endpoints()
+	c.Client.GrpcTarget = i.GrpcTarget()
 	c.Client.RequestTimeout = i.RequestTimeout()
+	c.Client.Retry.MaxAttempts = i.RetryMaxAttempts()
+	c.Client.Retry.BaseDelay = i.RetryBaseDelay()
+	c.Client.Retry.MaxDelay = i.RetryMaxDelay()
+	c.Client.Retry.IdempotencyKeys = i.RetryIdempotencyKeys()
+	c.Client.Hedging.Enabled = i.HedgingEnabled()
+	c.Client.Hedging.Percentile = i.HedgingPercentile()
+	c.Client.Hedging.MinDelay = i.HedgingMinDelay()
+	c.Client.Connections.MaxIdleConns = i.MaxIdleConns()
+	c.Client.Connections.MaxIdleConnsPerHost = i.MaxIdleConnsPerHost()
+	c.Client.Connections.MaxConnsPerHost = i.MaxConnsPerHost()
+	c.Client.Connections.IdleConnTimeout = i.IdleConnTimeout()
+	c.Client.Connections.KeepAlive = i.KeepAlive()
+	c.Client.Connections.DialTimeout = i.DialTimeout()
+	c.Client.Connections.TLSHandshakeTimeout = i.TLSHandshakeTimeout()
+	c.Client.Connections.ResponseHeaderTimeout = i.ResponseHeaderTimeout()
+	c.Client.Connections.HTTP2 = i.HTTP2()
+
+	return true
+}
+
+func (c *Config) getRemoteCacheConfig() bool {
+	i := remote_cache.NewRemoteCacheConf()
+	if i == nil {
+		return false
+	}
+
+	c.RemoteCache.Endpoint = i.Endpoint()
+	c.RemoteCache.RequestTimeout = i.RequestTimeout()
+	c.RemoteCache.WritePolicy = i.WritePolicy()
+
+	return true
+}
+
+func (c *Config) getCircuitBreakerConfig() bool {
+	i := circuit_breaker.NewCircuitBreakerConf()
+	if i == nil {
+		return false
+	}
+
+	c.Client.CircuitBreaker.Enabled = i.Enabled()
+	c.Client.CircuitBreaker.WindowSize = i.WindowSize()
+	c.Client.CircuitBreaker.MinRequests = i.MinRequests()
+	c.Client.CircuitBreaker.ErrorRate = i.ErrorRate()
+	c.Client.CircuitBreaker.SlowCallDuration = i.SlowCallDuration()
+	c.Client.CircuitBreaker.SlowCallRate = i.SlowCallRate()
+	c.Client.CircuitBreaker.OpenTimeout = i.OpenTimeout()
+	c.Client.CircuitBreaker.HalfOpenMaxCalls = i.HalfOpenMaxCalls()
+
+	return true
+}
+
+func (c *Config) getBackendBalancerConfig() bool {
+	i := backend_balancer.NewBackendBalancerConf()
+	if i == nil {
+		return false
+	}
+
+	c.Client.Balancer.Discovery = i.Discovery()
+	c.Client.Balancer.DiscoveryInterval = i.DiscoveryInterval()
+	c.Client.Balancer.Policy = i.Policy()
+	c.Client.Balancer.HealthCheck = i.HealthCheckEnabled()
+	c.Client.Balancer.HealthCheckPath = i.HealthCheckPath()
+	c.Client.Balancer.HealthInterval = i.HealthCheckInterval()
+	c.Client.Balancer.HealthTimeout = i.HealthCheckTimeout()
+	c.Client.Balancer.UnhealthyThreshold = i.UnhealthyThreshold()
+	c.Client.Balancer.HealthyThreshold = i.HealthyThreshold()
+
+	if c.Client.Transport == "http" && c.Client.Balancer.Discovery != "static" && len(c.Client.Endpoints) != 1 {
+		log.Error("exactly one backend_client_endpoint is required with DNS discovery", "endpoints", c.Client.Endpoints)
+		return false
+	}
 
 	return true
 }
+
+// redact hides secret, empty secret is kept to show it is not set
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+
+	return "[REDACTED]"
+}
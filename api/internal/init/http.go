@@ -2,6 +2,8 @@ package init
 
 import (
 	"github.com/KennyMacCormik/common/gin_factory"
+	"github.com/KennyMacCormik/otel/backend/pkg/gin/gin_admin_auth"
+	"github.com/KennyMacCormik/otel/backend/pkg/gin/gin_deadline"
 	httpWithGin "github.com/KennyMacCormik/otel/backend/pkg/gin/gin_http"
 	"github.com/KennyMacCormik/otel/backend/pkg/gin/gin_rate_limiter"
 	"github.com/KennyMacCormik/otel/backend/pkg/gin/gin_request_id"
@@ -13,30 +15,37 @@ import (
 
 const otelGinMiddlewareName = "api"
 
-func InitServer(conf *Config, svc service.ServiceInterface) *httpWithGin.GinServer {
+func InitServer(conf *Config, svc service.ServiceInterface, rm *gin_rate_limiter.RateLimiter) *httpWithGin.GinServer {
+	var opts []httpWithGin.InitOptions
+	if conf.Http.H2C {
+		opts = append(opts, httpWithGin.WithH2C())
+	}
+
 	return httpWithGin.NewHttpServer(
 		conf.Http.Endpoint,
-		initRouter(conf, svc),
+		initRouter(svc, rm, conf.Admin.Token),
 		conf.Http.ReadTimeout,
 		conf.Http.ReadTimeout,
 		conf.Http.ReadTimeout,
+		opts...,
 	)
 }
 
-func initRouter(conf *Config, svc service.ServiceInterface) *gin_factory.GinFactory {
+func initRouter(svc service.ServiceInterface, rm *gin_rate_limiter.RateLimiter, adminToken string) *gin_factory.GinFactory {
 	ginFactory := gin_factory.NewGinFactory()
 
 	ginFactory.AddMiddleware(
 		otelgin.Middleware(otelGinMiddlewareName),
 		gin_request_id.RequestIDMiddleware(),
-		gin_rate_limiter.NewRateLimiter(
-			conf.RateLimiter.MaxRunning,
-			conf.RateLimiter.MaxWait,
-			conf.RateLimiter.RetryAfter,
-		).GetRateLimiter(),
+		gin_deadline.DeadlineMiddleware(),
+		rm.GetRateLimiter(),
 	)
 
-	ginFactory.AddHandlers(storageHandlers.NewStorageHandler(svc).GetGinHandler())
+	ginFactory.AddHandlers(
+		storageHandlers.NewStorageHandler(svc).GetGinHandler(),
+		rm.GetRateLimiterMetricsEndpoint(),
+		rm.GetRateLimiterAdminEndpoint(gin_admin_auth.AdminAuth(adminToken)),
+	)
 
 	return ginFactory
 }
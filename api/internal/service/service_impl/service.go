@@ -7,8 +7,10 @@ import (
 	"log/slog"
 
 	"github.com/KennyMacCormik/otel/backend/pkg/cache"
+	"github.com/KennyMacCormik/otel/backend/pkg/cache/wrappers/ttl_cache"
 	cacheErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/cache"
 	otelHelpers "github.com/KennyMacCormik/otel/backend/pkg/otel/helpers"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/KennyMacCormik/otel/api/internal/client"
 	"github.com/KennyMacCormik/otel/api/internal/service"
@@ -37,12 +39,12 @@ func (l *serviceLayer) Get(ctx context.Context, key, requestId string, lg *slog.
 			span.AddEvent("cache miss")
 			lg.Debug("cache miss")
 
-			return l.invokeClientAndStoreValue(ctx, key, requestId)
+			return l.invokeClientAndStoreValue(ctx, key, requestId, err)
 		}
 		otelHelpers.SetSpanExceptionWithoutErr(span, err)
 		lg.Warn("cache error", "error", err)
 
-		return l.invokeClientAndStoreValue(ctx, key, requestId)
+		return l.invokeClientAndStoreValue(ctx, key, requestId, err)
 	}
 
 	span.AddEvent("cache hit")
@@ -83,9 +85,17 @@ func (l *serviceLayer) Delete(ctx context.Context, key, requestId string, lg *sl
 	return l.client.Delete(ctx, key, requestId)
 }
 
-func (l *serviceLayer) invokeClientAndStoreValue(ctx context.Context, key, requestId string) (any, error) {
+// invokeClientAndStoreValue gets value from backend. If backend circuit is open,
+// expired value from cacheErr is returned instead, as stale data is better than no data during incidents.
+func (l *serviceLayer) invokeClientAndStoreValue(ctx context.Context, key, requestId string, cacheErr error) (any, error) {
 	val, err := l.client.Get(ctx, key, requestId)
 	if err != nil {
+		var errTimeout *ttl_cache.ErrTimeout
+		if errors.Is(err, client.ErrCircuitOpen) && errors.As(cacheErr, &errTimeout) && errTimeout.GetStaleValue() != nil {
+			trace.SpanFromContext(ctx).AddEvent("stale cache hit")
+			return errTimeout.GetStaleValue(), nil
+		}
+
 		return nil, err
 	}
 
@@ -13,6 +13,7 @@ import (
 
 	initApp "github.com/KennyMacCormik/otel/backend/internal/init"
 	"github.com/KennyMacCormik/otel/backend/internal/storage"
+	"github.com/KennyMacCormik/otel/backend/pkg/gin/gin_rate_limiter"
 	otelInit "github.com/KennyMacCormik/otel/backend/pkg/otel/init"
 )
 
@@ -52,10 +53,33 @@ func main() {
 		}
 	}()
 
-	st := storage.NewStorage()
+	st, usage, err := storage.NewStorage(conf)
+	if err != nil {
+		log.Error("failed to initialize cache", "error", err)
+		gracefulStop()
+	}
+	defer func() {
+		err = st.Close(context.Background())
+		if err != nil {
+			log.Warn("failed to close cache", "error", err)
+		}
+	}()
 	log.Info("cache initialized")
 
-	httpSvr := initApp.HttpServer(conf, st)
+	rm, err := gin_rate_limiter.NewRateLimiterFromConf(conf.RateLimiter)
+	if err != nil {
+		log.Error("failed to initialize rate limiter", "error", err)
+		gracefulStop()
+	}
+	defer func() {
+		err = rm.Close(context.Background())
+		if err != nil {
+			log.Warn("failed to close rate limiter", "error", err)
+		}
+	}()
+	log.Info("rate limiter initialized", "mode", rm.Mode())
+
+	httpSvr := initApp.HttpServer(conf, st, usage, rm)
 	log.Info("http server initialized")
 	defer func() {
 		err = httpSvr.Close(conf.Http.ShutdownTimeout)
@@ -75,6 +99,66 @@ func main() {
 	}()
 	log.Info("server started")
 
+	if conf.Grpc.Enabled {
+		grpcSvr := initApp.GrpcServer(conf, st)
+		log.Info("grpc server initialized")
+		defer func() {
+			err = grpcSvr.Close(conf.Grpc.ShutdownTimeout)
+			if err != nil {
+				log.Warn("failed to shutdown grpc server", "error", err)
+			}
+		}()
+
+		go func() {
+			err = grpcSvr.Start()
+			if err != nil {
+				log.Error("Failed to start grpc server", "error", err)
+				gracefulStop()
+			}
+		}()
+		log.Info("grpc server started")
+	}
+
+	if conf.Resp.Enabled {
+		respSvr := initApp.RespServer(conf, st)
+		log.Info("resp server initialized")
+		defer func() {
+			err = respSvr.Close(conf.Resp.ShutdownTimeout)
+			if err != nil {
+				log.Warn("failed to shutdown resp server", "error", err)
+			}
+		}()
+
+		go func() {
+			err = respSvr.Start()
+			if err != nil {
+				log.Error("Failed to start resp server", "error", err)
+				gracefulStop()
+			}
+		}()
+		log.Info("resp server started")
+	}
+
+	if conf.Memcache.Enabled {
+		memcacheSvr := initApp.MemcacheServer(conf, st)
+		log.Info("memcache server initialized")
+		defer func() {
+			err = memcacheSvr.Close(conf.Memcache.ShutdownTimeout)
+			if err != nil {
+				log.Warn("failed to shutdown memcache server", "error", err)
+			}
+		}()
+
+		go func() {
+			err = memcacheSvr.Start()
+			if err != nil {
+				log.Error("Failed to start memcache server", "error", err)
+				gracefulStop()
+			}
+		}()
+		log.Info("memcache server started")
+	}
+
 	quit := make(chan os.Signal, 1)
 	defer close(quit)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
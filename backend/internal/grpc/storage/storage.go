@@ -0,0 +1,291 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/KennyMacCormik/common/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/KennyMacCormik/otel/backend/pkg/cache"
+	"github.com/KennyMacCormik/otel/backend/pkg/cache/wrappers/watch_cache"
+	"github.com/KennyMacCormik/otel/backend/pkg/gin/gin_request_id"
+	cacheErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/cache"
+	grpcErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/grpc"
+	httpErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/http"
+	watchCacheModels "github.com/KennyMacCormik/otel/backend/pkg/models/watch_cache"
+	otelHelpers "github.com/KennyMacCormik/otel/backend/pkg/otel/helpers"
+	"github.com/KennyMacCormik/otel/backend/pkg/proto/storage_pb"
+)
+
+// StorageServer serves storage_pb.StorageServer over the same storage as the HTTP handlers.
+// Watch requires storage or one of its layers, see cache.As, to implement watch_cache.Watcher.
+type StorageServer struct {
+	storage_pb.UnimplementedStorageServer
+
+	st cache.CacheInterface
+}
+
+func NewStorageServer(st cache.CacheInterface) *StorageServer {
+	return &StorageServer{st: st}
+}
+
+// Register registers the server as storage_pb.StorageServer
+func (s *StorageServer) Register(r grpc.ServiceRegistrar) {
+	storage_pb.RegisterStorageServer(r, s)
+}
+
+func (s *StorageServer) Get(ctx context.Context, req *storage_pb.GetRequest) (*storage_pb.GetResponse, error) {
+	const (
+		spanName = "grpc.get"
+	)
+
+	ctx, lg, span := getLogAndSpan(ctx, spanName)
+	defer span.End()
+	defer lg.Info("request finished")
+
+	if err := validateKey(req.GetKey()); err != nil {
+		return nil, handleErr(span, lg, "malformed request", err)
+	}
+
+	val, err := s.st.Get(ctx, req.GetKey())
+	if err != nil {
+		if errors.Is(err, cacheErrors.ErrNotFound) {
+			lg.Warn("key not found", "key", req.GetKey())
+			return nil, grpcErrors.ToStatus(err)
+		}
+		return nil, handleErr(span, lg, "failed to get value", err, "key", req.GetKey())
+	}
+
+	return &storage_pb.GetResponse{Key: req.GetKey(), Value: fmt.Sprintf("%v", val)}, nil
+}
+
+func (s *StorageServer) Set(ctx context.Context, req *storage_pb.SetRequest) (*storage_pb.SetResponse, error) {
+	const (
+		spanName = "grpc.set"
+	)
+
+	ctx, lg, span := getLogAndSpan(ctx, spanName)
+	defer span.End()
+	defer lg.Info("request finished")
+
+	result, err := s.set(ctx, req)
+	if err != nil {
+		return nil, handleErr(span, lg, "failed to set value", err, "key", req.GetKey())
+	}
+
+	return &storage_pb.SetResponse{Result: result}, nil
+}
+
+func (s *StorageServer) Delete(ctx context.Context, req *storage_pb.DeleteRequest) (*storage_pb.DeleteResponse, error) {
+	const (
+		spanName = "grpc.delete"
+	)
+
+	ctx, lg, span := getLogAndSpan(ctx, spanName)
+	defer span.End()
+	defer lg.Info("request finished")
+
+	if err := s.delete(ctx, req.GetKey()); err != nil {
+		return nil, handleErr(span, lg, "failed to delete value", err, "key", req.GetKey())
+	}
+
+	return &storage_pb.DeleteResponse{}, nil
+}
+
+func (s *StorageServer) BatchGet(ctx context.Context, req *storage_pb.BatchGetRequest) (*storage_pb.BatchGetResponse, error) {
+	const (
+		spanName = "grpc.batch_get"
+	)
+
+	ctx, lg, span := getLogAndSpan(ctx, spanName)
+	defer span.End()
+	defer lg.Info("request finished")
+
+	span.SetAttributes(attribute.Int("batch.size", len(req.GetKeys())))
+
+	items := make([]*storage_pb.BatchGetItem, 0, len(req.GetKeys()))
+	for _, key := range req.GetKeys() {
+		if err := validateKey(key); err != nil {
+			return nil, handleErr(span, lg, "malformed request", err)
+		}
+
+		val, err := s.st.Get(ctx, key)
+		if errors.Is(err, cacheErrors.ErrNotFound) {
+			items = append(items, &storage_pb.BatchGetItem{Key: key})
+			continue
+		}
+		if err != nil {
+			return nil, handleErr(span, lg, "failed to get value", err, "key", key)
+		}
+
+		items = append(items, &storage_pb.BatchGetItem{Key: key, Value: fmt.Sprintf("%v", val), Found: true})
+	}
+
+	return &storage_pb.BatchGetResponse{Items: items}, nil
+}
+
+func (s *StorageServer) BatchSet(ctx context.Context, req *storage_pb.BatchSetRequest) (*storage_pb.BatchSetResponse, error) {
+	const (
+		spanName = "grpc.batch_set"
+	)
+
+	ctx, lg, span := getLogAndSpan(ctx, spanName)
+	defer span.End()
+	defer lg.Info("request finished")
+
+	span.SetAttributes(attribute.Int("batch.size", len(req.GetItems())))
+
+	results := make([]storage_pb.SetResult, 0, len(req.GetItems()))
+	for _, item := range req.GetItems() {
+		result, err := s.set(ctx, item)
+		if err != nil {
+			return nil, handleErr(span, lg, "failed to set value", err, "key", item.GetKey())
+		}
+
+		results = append(results, result)
+	}
+
+	return &storage_pb.BatchSetResponse{Results: results}, nil
+}
+
+func (s *StorageServer) BatchDelete(ctx context.Context, req *storage_pb.BatchDeleteRequest) (*storage_pb.BatchDeleteResponse, error) {
+	const (
+		spanName = "grpc.batch_delete"
+	)
+
+	ctx, lg, span := getLogAndSpan(ctx, spanName)
+	defer span.End()
+	defer lg.Info("request finished")
+
+	span.SetAttributes(attribute.Int("batch.size", len(req.GetKeys())))
+
+	for _, key := range req.GetKeys() {
+		if err := s.delete(ctx, key); err != nil {
+			return nil, handleErr(span, lg, "failed to delete value", err, "key", key)
+		}
+	}
+
+	return &storage_pb.BatchDeleteResponse{}, nil
+}
+
+func (s *StorageServer) Watch(req *storage_pb.WatchRequest, stream grpc.ServerStreamingServer[storage_pb.WatchEvent]) error {
+	const (
+		spanName = "grpc.watch"
+	)
+
+	ctx, lg, span := getLogAndSpan(stream.Context(), spanName)
+	defer span.End()
+	defer lg.Info("request finished")
+
+	span.SetAttributes(attribute.String("watch.prefix", req.GetPrefix()))
+
+	watcher, ok := cache.As[watch_cache.Watcher](s.st)
+	if !ok {
+		return status.Error(codes.Unimplemented, "storage doesn't support watch")
+	}
+
+	sub, err := watcher.Watch(ctx, req.GetPrefix())
+	if err != nil {
+		return handleErr(span, lg, "failed to watch", err)
+	}
+
+	for e := range sub.Events() {
+		if err = stream.Send(toWatchEvent(e)); err != nil {
+			// client is gone, subscription ends with the stream context
+			return err
+		}
+	}
+
+	err = sub.Err()
+	if ctx.Err() != nil {
+		return status.FromContextError(ctx.Err()).Err()
+	}
+
+	otelHelpers.SetSpanExceptionWithErr(span, err)
+	lg.Warn("watch ended", "error", err.Error())
+
+	if errors.Is(err, cacheErrors.ErrCacheClosed) {
+		return grpcErrors.ToStatus(err)
+	}
+
+	// watcher fell behind, client is expected to re-read keys and watch again
+	return status.Error(codes.Aborted, err.Error())
+}
+
+func (s *StorageServer) set(ctx context.Context, req *storage_pb.SetRequest) (storage_pb.SetResult, error) {
+	if err := validateKey(req.GetKey()); err != nil {
+		return storage_pb.SetResult_SET_RESULT_UNSPECIFIED, err
+	}
+
+	code, err := s.st.Set(ctx, req.GetKey(), req.GetValue())
+	if err != nil {
+		return storage_pb.SetResult_SET_RESULT_UNSPECIFIED, err
+	}
+
+	switch code {
+	case 201:
+		return storage_pb.SetResult_SET_RESULT_CREATED, nil
+	case 204:
+		return storage_pb.SetResult_SET_RESULT_UNCHANGED, nil
+	default:
+		return storage_pb.SetResult_SET_RESULT_UPDATED, nil
+	}
+}
+
+func (s *StorageServer) delete(ctx context.Context, key string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+
+	return s.st.Delete(ctx, key)
+}
+
+func toWatchEvent(e watchCacheModels.Event) *storage_pb.WatchEvent {
+	if e.Type == watchCacheModels.EventDelete {
+		return &storage_pb.WatchEvent{Type: storage_pb.EventType_EVENT_TYPE_DELETE, Key: e.Key}
+	}
+
+	return &storage_pb.WatchEvent{Type: storage_pb.EventType_EVENT_TYPE_SET, Key: e.Key, Value: fmt.Sprintf("%v", e.Value)}
+}
+
+func validateKey(key string) error {
+	if key == "" {
+		return fmt.Errorf("%w: no key provided", httpErrors.ErrBadRequest)
+	}
+
+	return nil
+}
+
+// handleErr records err and converts it to gRPC status
+func handleErr(span trace.Span, lg *slog.Logger, msg string, err error, args ...any) error {
+	otelHelpers.SetSpanExceptionWithErr(span, err)
+	lg.Error(msg, append(args, "error", err.Error())...)
+
+	return grpcErrors.ToStatus(err)
+}
+
+func getLogAndSpan(ctx context.Context, spanName string) (context.Context, *slog.Logger, trace.Span) {
+	ctx, span := otelHelpers.StartSpanWithCtx(ctx, spanName, spanName)
+
+	lg := log.CopyLogger().With("Method", spanName)
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	if reqIds := md.Get(gin_request_id.RequestIDKey); len(reqIds) > 0 {
+		span.SetAttributes(attribute.String("request_id", reqIds[0]))
+		lg = lg.With("request_id", reqIds[0])
+	} else {
+		span.SetAttributes(attribute.String("request_id", "N/A"))
+	}
+
+	lg.Info("request trace ID", "trace_id", span.SpanContext().TraceID().String())
+
+	return ctx, lg, span
+}
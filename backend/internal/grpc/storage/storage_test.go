@@ -0,0 +1,341 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/KennyMacCormik/otel/backend/pkg/cache"
+	"github.com/KennyMacCormik/otel/backend/pkg/cache/impl/sync_map"
+	mockCache "github.com/KennyMacCormik/otel/backend/pkg/cache/mocks"
+	"github.com/KennyMacCormik/otel/backend/pkg/cache/wrappers/watch_cache"
+	cacheErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/cache"
+	grpcErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/grpc"
+	httpErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/http"
+	"github.com/KennyMacCormik/otel/backend/pkg/proto/storage_pb"
+)
+
+// newTestClient serves st over bufconn and returns client connected to it
+func newTestClient(t *testing.T, st cache.CacheInterface, opts ...grpc.ServerOption) storage_pb.StorageClient {
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(opts...)
+	NewStorageServer(st).Register(srv)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err, "expect client to be created")
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return storage_pb.NewStorageClient(conn)
+}
+
+func newWatchStorage(t *testing.T, opts ...watch_cache.InitOptions) cache.CacheInterface {
+	st, err := watch_cache.NewWatchCache(sync_map.NewSyncMapCache(), opts...)
+	require.NoError(t, err, "expect no error with valid configuration")
+	t.Cleanup(func() { _ = st.Close(context.Background()) })
+
+	return st
+}
+
+// startWatch watches all keys and returns once the server has subscribed
+func startWatch(t *testing.T, ctx context.Context, client storage_pb.StorageClient, st cache.CacheInterface) storage_pb.Storage_WatchClient {
+	stream, err := client.Watch(ctx, &storage_pb.WatchRequest{})
+	require.NoError(t, err, "expect watch to start")
+
+	received := make(chan struct{})
+	go func() {
+		if _, err := stream.Recv(); err == nil {
+			close(received)
+		}
+	}()
+
+	i := 0
+	require.Eventually(t, func() bool {
+		i++
+		_, err := st.Set(context.Background(), "ready", strconv.Itoa(i))
+		require.NoError(t, err, "expect set to succeed")
+
+		select {
+		case <-received:
+			return true
+		default:
+			return false
+		}
+	}, time.Second, 10*time.Millisecond, "expect watch to deliver events")
+
+	return stream
+}
+
+// recvErr reads the stream until it ends and returns its error
+func recvErr(t *testing.T, stream storage_pb.Storage_WatchClient) error {
+	for {
+		if _, err := stream.Recv(); err != nil {
+			return err
+		}
+	}
+}
+
+func TestStorageServer_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	testCases := []struct {
+		name    string
+		setup   func(st *mockCache.MockCacheInterface)
+		call    func(client storage_pb.StorageClient) error
+		code    codes.Code
+		errCode string
+	}{
+		{
+			name: "empty key",
+			call: func(client storage_pb.StorageClient) error {
+				_, err := client.Get(ctx, &storage_pb.GetRequest{})
+				return err
+			},
+			code:    codes.InvalidArgument,
+			errCode: httpErrors.CodeBadRequest,
+		},
+		{
+			name: "not found",
+			setup: func(st *mockCache.MockCacheInterface) {
+				st.EXPECT().Get(mock.Anything, "k").Return(nil, cacheErrors.ErrNotFound)
+			},
+			call: func(client storage_pb.StorageClient) error {
+				_, err := client.Get(ctx, &storage_pb.GetRequest{Key: "k"})
+				return err
+			},
+			code:    codes.NotFound,
+			errCode: httpErrors.CodeNotFound,
+		},
+		{
+			name: "quota exceeded",
+			setup: func(st *mockCache.MockCacheInterface) {
+				st.EXPECT().Set(mock.Anything, "k", "v").Return(0, cacheErrors.ErrQuotaExceeded)
+			},
+			call: func(client storage_pb.StorageClient) error {
+				_, err := client.Set(ctx, &storage_pb.SetRequest{Key: "k", Value: "v"})
+				return err
+			},
+			code:    codes.ResourceExhausted,
+			errCode: httpErrors.CodeQuotaExceeded,
+		},
+		{
+			name: "write limited",
+			setup: func(st *mockCache.MockCacheInterface) {
+				st.EXPECT().Set(mock.Anything, "k", "v").Return(0, cacheErrors.NewErrWriteLimited("k", time.Second))
+			},
+			call: func(client storage_pb.StorageClient) error {
+				_, err := client.Set(ctx, &storage_pb.SetRequest{Key: "k", Value: "v"})
+				return err
+			},
+			code:    codes.ResourceExhausted,
+			errCode: httpErrors.CodeWriteLimited,
+		},
+		{
+			name: "cache closed",
+			setup: func(st *mockCache.MockCacheInterface) {
+				st.EXPECT().Delete(mock.Anything, "k").Return(cacheErrors.ErrCacheClosed)
+			},
+			call: func(client storage_pb.StorageClient) error {
+				_, err := client.Delete(ctx, &storage_pb.DeleteRequest{Key: "k"})
+				return err
+			},
+			code:    codes.Unavailable,
+			errCode: httpErrors.CodeUnavailable,
+		},
+		{
+			name: "internal",
+			setup: func(st *mockCache.MockCacheInterface) {
+				st.EXPECT().Get(mock.Anything, "k").Return(nil, errors.New("secret details"))
+			},
+			call: func(client storage_pb.StorageClient) error {
+				_, err := client.Get(ctx, &storage_pb.GetRequest{Key: "k"})
+				return err
+			},
+			code:    codes.Internal,
+			errCode: httpErrors.CodeInternal,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			st := mockCache.NewMockCacheInterface(t)
+			if tc.setup != nil {
+				tc.setup(st)
+			}
+
+			err := tc.call(newTestClient(t, st))
+			require.Error(t, err, "expect RPC to fail")
+			assert.Equal(t, tc.code, status.Code(err), "Unexpected status code")
+			assert.NotContains(t, err.Error(), "secret details", "expect internal details not to leak")
+
+			var errStatus *httpErrors.ErrStatus
+			require.ErrorAs(t, grpcErrors.FromStatus(err), &errStatus, "expect status to convert back")
+			assert.Equal(t, tc.errCode, errStatus.GetCode(), "expect error code to survive the round trip")
+		})
+	}
+}
+
+func TestStorageServer_SetResult(t *testing.T) {
+	ctx := context.Background()
+	client := newTestClient(t, sync_map.NewSyncMapCache())
+
+	testCases := []struct {
+		name   string
+		value  string
+		result storage_pb.SetResult
+	}{
+		{"created", "v1", storage_pb.SetResult_SET_RESULT_CREATED},
+		{"unchanged", "v1", storage_pb.SetResult_SET_RESULT_UNCHANGED},
+		{"updated", "v2", storage_pb.SetResult_SET_RESULT_UPDATED},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := client.Set(ctx, &storage_pb.SetRequest{Key: "k", Value: tc.value})
+			require.NoError(t, err, "expect set to succeed")
+			assert.Equal(t, tc.result, resp.GetResult(), "Unexpected set result")
+		})
+	}
+
+	resp, err := client.Get(ctx, &storage_pb.GetRequest{Key: "k"})
+	require.NoError(t, err, "expect get to succeed")
+	assert.Equal(t, "v2", resp.GetValue(), "expect last value")
+}
+
+func TestStorageServer_Batch(t *testing.T) {
+	ctx := context.Background()
+	client := newTestClient(t, sync_map.NewSyncMapCache())
+
+	setResp, err := client.BatchSet(ctx, &storage_pb.BatchSetRequest{Items: []*storage_pb.SetRequest{
+		{Key: "a", Value: "1"},
+		{Key: "b", Value: "2"},
+		{Key: "a", Value: "1"},
+	}})
+	require.NoError(t, err, "expect batch set to succeed")
+	assert.Equal(t, []storage_pb.SetResult{
+		storage_pb.SetResult_SET_RESULT_CREATED,
+		storage_pb.SetResult_SET_RESULT_CREATED,
+		storage_pb.SetResult_SET_RESULT_UNCHANGED,
+	}, setResp.GetResults(), "expect result per item")
+
+	getResp, err := client.BatchGet(ctx, &storage_pb.BatchGetRequest{Keys: []string{"a", "missing"}})
+	require.NoError(t, err, "expect batch get to succeed")
+	require.Len(t, getResp.GetItems(), 2, "expect item per key")
+	assert.True(t, getResp.GetItems()[0].GetFound(), "expect stored key to be found")
+	assert.Equal(t, "1", getResp.GetItems()[0].GetValue(), "expect stored value")
+	assert.False(t, getResp.GetItems()[1].GetFound(), "expect missing key not to fail the batch")
+
+	_, err = client.BatchDelete(ctx, &storage_pb.BatchDeleteRequest{Keys: []string{"a", "b"}})
+	require.NoError(t, err, "expect batch delete to succeed")
+	_, err = client.Get(ctx, &storage_pb.GetRequest{Key: "b"})
+	assert.Equal(t, codes.NotFound, status.Code(err), "expect deleted key to be gone")
+
+	testCases := []struct {
+		name string
+		call func() error
+	}{
+		{"batch get", func() error {
+			_, err := client.BatchGet(ctx, &storage_pb.BatchGetRequest{Keys: []string{"a", ""}})
+			return err
+		}},
+		{"batch set", func() error {
+			_, err := client.BatchSet(ctx, &storage_pb.BatchSetRequest{Items: []*storage_pb.SetRequest{{Key: "", Value: "v"}}})
+			return err
+		}},
+		{"batch delete", func() error {
+			_, err := client.BatchDelete(ctx, &storage_pb.BatchDeleteRequest{Keys: []string{""}})
+			return err
+		}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, codes.InvalidArgument, status.Code(tc.call()), "expect empty key to be rejected")
+		})
+	}
+}
+
+func TestStorageServer_Watch(t *testing.T) {
+	t.Run("events", func(t *testing.T) {
+		st := newWatchStorage(t)
+		stream := startWatch(t, context.Background(), newTestClient(t, st), st)
+
+		_, err := st.Set(context.Background(), "k", "v")
+		require.NoError(t, err, "expect set to succeed")
+		require.NoError(t, st.Delete(context.Background(), "k"), "expect delete to succeed")
+
+		var events []*storage_pb.WatchEvent
+		for len(events) < 2 {
+			e, err := stream.Recv()
+			require.NoError(t, err, "expect watch to be active")
+			if e.GetKey() == "k" {
+				events = append(events, e)
+			}
+		}
+		assert.Equal(t, storage_pb.EventType_EVENT_TYPE_SET, events[0].GetType(), "expect set event")
+		assert.Equal(t, "v", events[0].GetValue(), "expect value of set event")
+		assert.Equal(t, storage_pb.EventType_EVENT_TYPE_DELETE, events[1].GetType(), "expect delete event")
+	})
+
+	t.Run("fell behind", func(t *testing.T) {
+		st := newWatchStorage(t, watch_cache.WithOverrideDefaults(1))
+		stream := startWatch(t, context.Background(), newTestClient(t, st), st)
+
+		// large values fill the flow control window, so the server stops draining the subscription
+		value := strings.Repeat("v", 256<<10)
+		for i := range 20 {
+			_, err := st.Set(context.Background(), "big"+strconv.Itoa(i), value)
+			require.NoError(t, err, "expect set to succeed")
+		}
+
+		assert.Equal(t, codes.Aborted, status.Code(recvErr(t, stream)), "expect watch to end with Aborted")
+	})
+
+	t.Run("cache closed", func(t *testing.T) {
+		st := newWatchStorage(t)
+		stream := startWatch(t, context.Background(), newTestClient(t, st), st)
+
+		require.NoError(t, st.Close(context.Background()), "expect cache to close")
+		assert.Equal(t, codes.Unavailable, status.Code(recvErr(t, stream)), "expect watch to end with Unavailable")
+	})
+
+	t.Run("client cancel", func(t *testing.T) {
+		served := make(chan error, 1)
+		interceptor := func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			err := handler(srv, ss)
+			served <- err
+			return err
+		}
+
+		st := newWatchStorage(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		stream := startWatch(t, ctx, newTestClient(t, st, grpc.StreamInterceptor(interceptor)), st)
+
+		cancel()
+		assert.Equal(t, codes.Canceled, status.Code(recvErr(t, stream)), "expect client to see cancellation")
+
+		select {
+		case err := <-served:
+			assert.Equal(t, codes.Canceled, status.Code(err), "expect server to end watch with Canceled")
+		case <-time.After(time.Second):
+			require.Fail(t, "expect server to end watch")
+		}
+	})
+}
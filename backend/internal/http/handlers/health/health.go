@@ -0,0 +1,42 @@
+package health
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/KennyMacCormik/otel/backend/pkg/cache"
+	customGinImpl "github.com/KennyMacCormik/otel/backend/pkg/gin"
+	cacheErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/cache"
+)
+
+// Path is the route of the health check endpoint
+const Path = "/health"
+
+type HealthHandler struct {
+	st cache.CacheInterface
+}
+
+// NewHealthHandler returns handler of the health check endpoint used by clients to eject unhealthy replicas
+func NewHealthHandler(st cache.CacheInterface) customGinImpl.GinHandler {
+	return &HealthHandler{st: st}
+}
+
+func (h *HealthHandler) GetGinHandler() func(*gin.Engine) {
+	return func(router *gin.Engine) {
+		router.GET(Path, h.ginHealth())
+	}
+}
+
+func (h *HealthHandler) ginHealth() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		// storage is the only dependency, other errors such as unsupported operation don't affect serving requests
+		if _, err := h.st.GetLength(); errors.Is(err, cacheErrors.ErrCacheClosed) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"status": "unavailable", "error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	}
+}
@@ -0,0 +1,53 @@
+package quota
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/KennyMacCormik/otel/backend/pkg/cache/wrappers/quota_cache"
+	customGinImpl "github.com/KennyMacCormik/otel/backend/pkg/gin"
+	cacheErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/cache"
+	httpErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/http"
+)
+
+const AdminPath = "/admin/quota"
+
+type QuotaHandler struct {
+	usage quota_cache.UsageGetter
+	auth  gin.HandlerFunc
+}
+
+// NewQuotaHandler returns handler of the admin endpoint reporting storage usage per tenant, auth guards it
+func NewQuotaHandler(usage quota_cache.UsageGetter, auth gin.HandlerFunc) customGinImpl.GinHandler {
+	return &QuotaHandler{usage: usage, auth: auth}
+}
+
+func (h *QuotaHandler) GetGinHandler() func(*gin.Engine) {
+	return func(router *gin.Engine) {
+		router.GET(AdminPath, h.auth, h.ginGetAll())
+		router.GET(AdminPath+"/:tenant", h.auth, h.ginGet())
+	}
+}
+
+func (h *QuotaHandler) ginGetAll() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, h.usage.GetUsage())
+	}
+}
+
+func (h *QuotaHandler) ginGet() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		t := c.Param("tenant")
+
+		u, ok := h.usage.GetUsage()[t]
+		if !ok {
+			errStatus := httpErrors.FromError(fmt.Errorf("tenant %s: %w", t, cacheErrors.ErrNotFound))
+			c.JSON(errStatus.GetStatus(), errStatus.GetBody())
+			return
+		}
+
+		c.JSON(http.StatusOK, u)
+	}
+}
@@ -4,8 +4,10 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"math"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 
 	"github.com/KennyMacCormik/common/log"
@@ -17,7 +19,9 @@ import (
 	customGinImpl "github.com/KennyMacCormik/otel/backend/pkg/gin"
 	"github.com/KennyMacCormik/otel/backend/pkg/gin/gin_request_id"
 	cacheErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/cache"
+	httpErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/http"
 	httpModels "github.com/KennyMacCormik/otel/backend/pkg/models/http"
+	"github.com/KennyMacCormik/otel/backend/pkg/models/tenant"
 	otelHelpers "github.com/KennyMacCormik/otel/backend/pkg/otel/helpers"
 )
 
@@ -51,7 +55,7 @@ func (s *StorageHandler) ginGet() func(c *gin.Context) {
 		if err != nil {
 			otelHelpers.SetSpanExceptionWithErr(span, err)
 			lg.Error("malformed request", "error", err.Error())
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			writeError(c, err)
 			return
 		}
 
@@ -59,12 +63,12 @@ func (s *StorageHandler) ginGet() func(c *gin.Context) {
 		if err != nil {
 			if errors.Is(err, cacheErrors.ErrNotFound) {
 				lg.Warn("key not found", "key", key)
-				c.Status(http.StatusNotFound)
+				writeError(c, err)
 				return
 			}
 			otelHelpers.SetSpanExceptionWithErr(span, err)
 			lg.Error("failed to get value", "key", key, "error", err.Error())
-			c.Status(http.StatusInternalServerError)
+			writeError(c, err)
 			return
 		}
 
@@ -91,7 +95,7 @@ func (s *StorageHandler) ginSet() func(c *gin.Context) {
 		if err != nil {
 			otelHelpers.SetSpanExceptionWithErr(span, err)
 			lg.Error("failed read request body", "error", err.Error())
-			c.Status(http.StatusBadRequest)
+			writeError(c, fmt.Errorf("%w: %w", httpErrors.ErrBadRequest, err))
 			return
 		}
 
@@ -99,10 +103,20 @@ func (s *StorageHandler) ginSet() func(c *gin.Context) {
 		lg.Debug("request value", "value", b.Val)
 
 		code, err := s.st.Set(c.Request.Context(), b.Key, b.Val)
+		if errors.Is(err, cacheErrors.ErrWriteRateLimited) {
+			lg.Warn("key write rate limit exceeded", "key", b.Key)
+			writeError(c, err)
+			return
+		}
+		if errors.Is(err, cacheErrors.ErrQuotaExceeded) {
+			lg.Warn("tenant quota exceeded", "key", b.Key, "tenant", tenant.FromContext(c.Request.Context()))
+			writeError(c, err)
+			return
+		}
 		if err != nil {
 			otelHelpers.SetSpanExceptionWithErr(span, err)
 			lg.Error("failed to set value", "key", b.Key, "value", b.Val, "error", err.Error())
-			c.Status(http.StatusInternalServerError)
+			writeError(c, err)
 			return
 		}
 
@@ -124,7 +138,7 @@ func (s *StorageHandler) ginDel() func(c *gin.Context) {
 		if err != nil {
 			otelHelpers.SetSpanExceptionWithErr(span, err)
 			lg.Error("malformed request", "error", err.Error())
-			c.Status(http.StatusBadRequest)
+			writeError(c, err)
 			return
 		}
 
@@ -132,7 +146,7 @@ func (s *StorageHandler) ginDel() func(c *gin.Context) {
 		if err != nil {
 			otelHelpers.SetSpanExceptionWithErr(span, err)
 			lg.Error("failed to delete value", "key", key, "error", err.Error())
-			c.Status(http.StatusInternalServerError)
+			writeError(c, err)
 			return
 		}
 
@@ -140,14 +154,27 @@ func (s *StorageHandler) ginDel() func(c *gin.Context) {
 	}
 }
 
+// writeError responds with status and httpModels.ErrorBody matching err.
+// Writes rejected by the key write limit carry Retry-After.
+func writeError(c *gin.Context, err error) {
+	var errLimited *cacheErrors.ErrWriteLimited
+	if errors.As(err, &errLimited) {
+		retryAfter := max(1, int64(math.Ceil(errLimited.GetRetryAfter().Seconds())))
+		c.Header("Retry-After", strconv.FormatInt(retryAfter, 10))
+	}
+
+	errStatus := httpErrors.FromError(err)
+	c.JSON(errStatus.GetStatus(), errStatus.GetBody())
+}
+
 func getKey(c *gin.Context) (string, error) {
 	key := c.Param("key")
 	if key == "" {
-		return "", errors.New("no key provided")
+		return "", fmt.Errorf("%w: no key provided", httpErrors.ErrBadRequest)
 	}
 
 	if !isUrlEncoded(strings.TrimPrefix(c.Request.RequestURI, "/storage/"), key) {
-		return "", errors.New("key must be URL-encoded")
+		return "", fmt.Errorf("%w: key must be URL-encoded", httpErrors.ErrBadRequest)
 	}
 
 	return key, nil
@@ -1,27 +1,97 @@
 package init
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/KennyMacCormik/common/log"
 
+	"github.com/KennyMacCormik/otel/backend/pkg/cache/wrappers/encrypted_cache"
+	"github.com/KennyMacCormik/otel/backend/pkg/cache/wrappers/quota_cache"
+	"github.com/KennyMacCormik/otel/backend/pkg/conf"
+	"github.com/KennyMacCormik/otel/backend/pkg/conf/admin_conf"
+	"github.com/KennyMacCormik/otel/backend/pkg/conf/compression_conf"
+	"github.com/KennyMacCormik/otel/backend/pkg/conf/encryption_conf"
 	"github.com/KennyMacCormik/otel/backend/pkg/conf/gin_conf"
+	"github.com/KennyMacCormik/otel/backend/pkg/conf/grpc_conf"
 	"github.com/KennyMacCormik/otel/backend/pkg/conf/http_conf"
 	"github.com/KennyMacCormik/otel/backend/pkg/conf/logger_conf"
+	"github.com/KennyMacCormik/otel/backend/pkg/conf/memcache_conf"
 	"github.com/KennyMacCormik/otel/backend/pkg/conf/otel_config"
+	"github.com/KennyMacCormik/otel/backend/pkg/conf/quota_conf"
 	"github.com/KennyMacCormik/otel/backend/pkg/conf/rate_limiter_conf"
+	"github.com/KennyMacCormik/otel/backend/pkg/conf/resp_conf"
+	"github.com/KennyMacCormik/otel/backend/pkg/conf/write_limit_conf"
+	"github.com/KennyMacCormik/otel/backend/pkg/gin/gin_tenant"
+	"github.com/KennyMacCormik/otel/backend/pkg/models/tenant"
 )
 
 type Config struct {
 	Log         Log
 	OTel        OTel
-	RateLimiter RateLimiter
+	RateLimiter conf.RateLimiterConf
 	Http        Http
+	Grpc        Grpc
+	Resp        Resp
+	Memcache    Memcache
 	Gin         Gin
+	Admin       Admin
+	Compression Compression
+	Encryption  Encryption
+	WriteLimit  WriteLimit
+	Quota       Quota
+}
+type Quota struct {
+	Enabled bool
+	// TenantHeader carries API key of the tenant
+	TenantHeader string
+	// Tenants maps tenant to its API key, requests without key belong to the default tenant
+	Tenants map[string]string
+	// Default applies to tenants without their own quota
+	Default quota_cache.Quota
+	Quotas  map[string]quota_cache.Quota
+}
+
+// String keeps tenant API keys out of the logged config
+func (q Quota) String() string {
+	tenants := make(map[string]string, len(q.Tenants))
+	for t, key := range q.Tenants {
+		tenants[t] = redact(key)
+	}
+
+	return fmt.Sprintf("{Enabled:%t TenantHeader:%s Tenants:%v Default:%+v Quotas:%+v}",
+		q.Enabled, q.TenantHeader, tenants, q.Default, q.Quotas)
+}
+
+type WriteLimit struct {
+	// Limit of writes per key per Window, zero disables it
+	Limit  int64
+	Window time.Duration
+	Burst  int64
+	// MaxValueSize in bytes, zero disables it
+	MaxValueSize int64
+}
+type Encryption struct {
+	// Keyring is nil if encryption is disabled
+	Keyring *encrypted_cache.Keyring
+}
+type Compression struct {
+	Algorithm string
+	Threshold int64
 }
 type Gin struct {
 	Mode string
 }
+type Admin struct {
+	// Token authenticates admin endpoints, they reject every request if it is empty
+	Token string
+}
+
+// String keeps the token out of the logged config
+func (a Admin) String() string {
+	return fmt.Sprintf("{Token:%s}", redact(a.Token))
+}
+
 type Log struct {
 	Format string
 	Level  string
@@ -30,17 +100,31 @@ type OTel struct {
 	Endpoint        string
 	ShutdownTimeout time.Duration
 }
-type RateLimiter struct {
-	MaxRunning int64
-	MaxWait    int64
-	RetryAfter int64
-}
 type Http struct {
 	Endpoint        string
 	ReadTimeout     time.Duration
 	WriteTimeout    time.Duration
 	IdleTimeout     time.Duration
 	ShutdownTimeout time.Duration
+	H2C             bool
+}
+type Grpc struct {
+	Enabled         bool
+	Endpoint        string
+	ShutdownTimeout time.Duration
+	WatchBufferSize int
+}
+type Resp struct {
+	Enabled         bool
+	Endpoint        string
+	ShutdownTimeout time.Duration
+	IdleTimeout     time.Duration
+}
+type Memcache struct {
+	Enabled         bool
+	Endpoint        string
+	ShutdownTimeout time.Duration
+	IdleTimeout     time.Duration
 }
 
 func GetConfig() *Config {
@@ -49,9 +133,17 @@ func GetConfig() *Config {
 	fns := []func() bool{
 		cfg.getLoggingConfig,
 		cfg.getHttpConfig,
+		cfg.getGrpcConfig,
+		cfg.getRespConfig,
+		cfg.getMemcacheConfig,
 		cfg.getOTelConfig,
 		cfg.getRateLimiterConfig,
 		cfg.getGinConfig,
+		cfg.getAdminConfig,
+		cfg.getCompressionConfig,
+		cfg.getEncryptionConfig,
+		cfg.getWriteLimitConfig,
+		cfg.getQuotaConfig,
 	}
 
 	for _, fn := range fns {
@@ -63,6 +155,17 @@ func GetConfig() *Config {
 	return cfg
 }
 
+func (c *Config) getAdminConfig() bool {
+	i := admin_conf.NewAdminConf()
+	if i == nil {
+		return false
+	}
+
+	c.Admin.Token = i.Token()
+
+	return true
+}
+
 func (c *Config) getGinConfig() bool {
 	i := gin_conf.NewGinConf()
 	if i == nil {
@@ -103,6 +206,49 @@ func (c *Config) getHttpConfig() bool {
 	c.Http.ReadTimeout = i.ReadTimeout()
 	c.Http.WriteTimeout = i.WriteTimeout()
 	c.Http.ShutdownTimeout = i.ShutdownTimeout()
+	c.Http.H2C = i.H2CEnabled()
+
+	return true
+}
+
+func (c *Config) getGrpcConfig() bool {
+	i := grpc_conf.NewGrpcConf()
+	if i == nil {
+		return false
+	}
+
+	c.Grpc.Enabled = i.Enabled()
+	c.Grpc.Endpoint = i.Endpoint()
+	c.Grpc.ShutdownTimeout = i.ShutdownTimeout()
+	c.Grpc.WatchBufferSize = i.WatchBufferSize()
+
+	return true
+}
+
+func (c *Config) getRespConfig() bool {
+	i := resp_conf.NewRespConf()
+	if i == nil {
+		return false
+	}
+
+	c.Resp.Enabled = i.Enabled()
+	c.Resp.Endpoint = i.Endpoint()
+	c.Resp.ShutdownTimeout = i.ShutdownTimeout()
+	c.Resp.IdleTimeout = i.IdleTimeout()
+
+	return true
+}
+
+func (c *Config) getMemcacheConfig() bool {
+	i := memcache_conf.NewMemcacheConf()
+	if i == nil {
+		return false
+	}
+
+	c.Memcache.Enabled = i.Enabled()
+	c.Memcache.Endpoint = i.Endpoint()
+	c.Memcache.ShutdownTimeout = i.ShutdownTimeout()
+	c.Memcache.IdleTimeout = i.IdleTimeout()
 
 	return true
 }
@@ -125,9 +271,99 @@ func (c *Config) getRateLimiterConfig() bool {
 		return false
 	}
 
-	c.RateLimiter.RetryAfter = i.RetryAfter()
-	c.RateLimiter.MaxRunning = i.MaxRunning()
-	c.RateLimiter.MaxWait = i.MaxWaiting()
+	c.RateLimiter = i
+
+	return true
+}
+
+func (c *Config) getCompressionConfig() bool {
+	i := compression_conf.NewCompressionConf()
+	if i == nil {
+		return false
+	}
+
+	c.Compression.Algorithm = i.Algorithm()
+	c.Compression.Threshold = i.Threshold()
+
+	return true
+}
+
+func (c *Config) getWriteLimitConfig() bool {
+	i := write_limit_conf.NewWriteLimitConf()
+	if i == nil {
+		return false
+	}
+
+	c.WriteLimit.Limit = i.Limit()
+	c.WriteLimit.Window = i.Window()
+	c.WriteLimit.Burst = i.Burst()
+	c.WriteLimit.MaxValueSize = i.MaxValueSize()
+
+	return true
+}
+
+func (c *Config) getQuotaConfig() bool {
+	i := quota_conf.NewQuotaConf()
+	if i == nil {
+		return false
+	}
+
+	c.Quota.Enabled = i.Enabled()
+	c.Quota.TenantHeader = i.TenantHeader()
+	c.Quota.Default = quota_cache.Quota{MaxKeys: i.MaxKeys(), MaxBytes: i.MaxBytes()}
+
+	var err error
+
+	c.Quota.Tenants, err = gin_tenant.ParseKeys(i.Tenants())
+	if err != nil {
+		log.Error("Failed to parse quota tenants", "err", err)
+		return false
+	}
+
+	c.Quota.Quotas, err = quota_cache.ParseQuotas(i.Limits())
+	if err != nil {
+		log.Error("Failed to parse quota limits", "err", err)
+		return false
+	}
+
+	for t := range c.Quota.Quotas {
+		if _, ok := c.Quota.Tenants[t]; !ok && t != tenant.Default {
+			log.Error("Failed to validate quota limits", "err", "tenant has no API key", "tenant", t)
+			return false
+		}
+	}
 
 	return true
 }
+
+func (c *Config) getEncryptionConfig() bool {
+	i := encryption_conf.NewEncryptionConf()
+	if i == nil {
+		return false
+	}
+
+	var err error
+
+	switch {
+	case i.Keys() != "":
+		c.Encryption.Keyring, err = encrypted_cache.ParseKeyring(i.Keys())
+	case i.KeysFile() != "":
+		c.Encryption.Keyring, err = encrypted_cache.LoadKeyringFromFile(i.KeysFile())
+	}
+
+	if err != nil {
+		log.Error("Failed to load encryption keyring", "err", err)
+		return false
+	}
+
+	return true
+}
+
+// redact hides secret, empty secret is kept to show it is not set
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+
+	return "[REDACTED]"
+}
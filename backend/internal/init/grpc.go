@@ -0,0 +1,26 @@
+package init
+
+import (
+	"google.golang.org/grpc"
+
+	grpcStorage "github.com/KennyMacCormik/otel/backend/internal/grpc/storage"
+	"github.com/KennyMacCormik/otel/backend/pkg/cache"
+	"github.com/KennyMacCormik/otel/backend/pkg/grpc/grpc_server"
+	"github.com/KennyMacCormik/otel/backend/pkg/grpc/grpc_tenant"
+)
+
+func GrpcServer(conf *Config, st cache.CacheInterface) *grpc_server.GrpcServer {
+	var opts []grpc.ServerOption
+	if conf.Quota.Enabled {
+		opts = append(opts,
+			grpc.ChainUnaryInterceptor(grpc_tenant.UnaryServerInterceptor(conf.Quota.TenantHeader, conf.Quota.Tenants)),
+			grpc.ChainStreamInterceptor(grpc_tenant.StreamServerInterceptor(conf.Quota.TenantHeader, conf.Quota.Tenants)),
+		)
+	}
+
+	return grpc_server.NewGrpcServer(
+		conf.Grpc.Endpoint,
+		grpcStorage.NewStorageServer(st).Register,
+		opts...,
+	)
+}
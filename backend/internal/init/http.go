@@ -2,48 +2,75 @@ package init
 
 import (
 	"github.com/KennyMacCormik/common/gin_factory"
+	"github.com/gin-gonic/gin"
 	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 
+	healthHandlers "github.com/KennyMacCormik/otel/backend/internal/http/handlers/health"
+	quotaHandlers "github.com/KennyMacCormik/otel/backend/internal/http/handlers/quota"
 	storageHandlers "github.com/KennyMacCormik/otel/backend/internal/http/handlers/storage"
 	"github.com/KennyMacCormik/otel/backend/pkg/cache"
+	"github.com/KennyMacCormik/otel/backend/pkg/cache/wrappers/quota_cache"
+	"github.com/KennyMacCormik/otel/backend/pkg/gin/gin_admin_auth"
+	"github.com/KennyMacCormik/otel/backend/pkg/gin/gin_deadline"
 	"github.com/KennyMacCormik/otel/backend/pkg/gin/gin_get_trace_parent"
 	httpWithGin "github.com/KennyMacCormik/otel/backend/pkg/gin/gin_http"
 	"github.com/KennyMacCormik/otel/backend/pkg/gin/gin_rate_limiter"
 	"github.com/KennyMacCormik/otel/backend/pkg/gin/gin_request_id"
+	"github.com/KennyMacCormik/otel/backend/pkg/gin/gin_tenant"
 )
 
 const otelGinMiddlewareName = "backend"
 
-func HttpServer(conf *Config, st cache.CacheInterface) *httpWithGin.GinServer {
+// HttpServer serves st, usage is nil if quotas are disabled
+func HttpServer(conf *Config, st cache.CacheInterface, usage quota_cache.UsageGetter, rm *gin_rate_limiter.RateLimiter) *httpWithGin.GinServer {
+	var opts []httpWithGin.InitOptions
+	if conf.Http.H2C {
+		opts = append(opts, httpWithGin.WithH2C())
+	}
+
 	return httpWithGin.NewHttpServer(
 		conf.Http.Endpoint,
-		initRouter(conf, st),
+		initRouter(st, usage, rm, conf),
 		conf.Http.ReadTimeout,
 		conf.Http.ReadTimeout,
 		conf.Http.ReadTimeout,
+		opts...,
 	)
 }
 
-func initRouter(conf *Config, st cache.CacheInterface) *gin_factory.GinFactory {
+func initRouter(st cache.CacheInterface, usage quota_cache.UsageGetter, rm *gin_rate_limiter.RateLimiter, conf *Config) *gin_factory.GinFactory {
 	ginFactory := gin_factory.NewGinFactory()
 
-	rm := gin_rate_limiter.NewRateLimiter(
-		conf.RateLimiter.MaxRunning,
-		conf.RateLimiter.MaxWait,
-		conf.RateLimiter.RetryAfter,
-	)
-
-	ginFactory.AddMiddleware(
+	middleware := []gin.HandlerFunc{
 		gin_get_trace_parent.GetTraceParent(),
 		otelgin.Middleware(otelGinMiddlewareName),
 		gin_request_id.RequestIDMiddleware(),
-		rm.GetRateLimiter(),
-	)
+		gin_deadline.DeadlineMiddleware(),
+	}
+
+	// tenant is resolved before the rate limiter, so unknown API keys don't take its slots
+	if conf.Quota.Enabled {
+		middleware = append(middleware, gin_tenant.TenantMiddleware(conf.Quota.TenantHeader, conf.Quota.Tenants))
+	}
 
-	ginFactory.AddHandlers(
+	ginFactory.AddMiddleware(append(middleware, rm.GetRateLimiter())...)
+	// health checks are answered during overload, otherwise clients eject replicas which are just busy
+	rm.Exempt(healthHandlers.Path)
+
+	adminAuth := gin_admin_auth.AdminAuth(conf.Admin.Token)
+
+	handlers := []func(*gin.Engine){
 		storageHandlers.NewStorageHandler(st).GetGinHandler(),
+		healthHandlers.NewHealthHandler(st).GetGinHandler(),
 		rm.GetRateLimiterMetricsEndpoint(),
-	)
+		rm.GetRateLimiterAdminEndpoint(adminAuth),
+	}
+
+	if usage != nil {
+		handlers = append(handlers, quotaHandlers.NewQuotaHandler(usage, adminAuth).GetGinHandler())
+	}
+
+	ginFactory.AddHandlers(handlers...)
 
 	return ginFactory
 }
@@ -0,0 +1,18 @@
+package init
+
+import (
+	memcacheStorage "github.com/KennyMacCormik/otel/backend/internal/memcache/storage"
+	"github.com/KennyMacCormik/otel/backend/pkg/cache/wrappers/meta_cache"
+	"github.com/KennyMacCormik/otel/backend/pkg/memcache"
+)
+
+func MemcacheServer(conf *Config, st meta_cache.MetaCache) *memcache.Server {
+	var opts []memcacheStorage.InitOptions
+	if conf.Quota.Enabled {
+		// memcached clients can't authenticate, so their writes can't be attributed to a tenant
+		opts = append(opts, memcacheStorage.WithReadOnly())
+	}
+
+	handler := memcacheStorage.NewStorageHandler(st, opts...)
+	return memcache.NewServer(conf.Memcache.Endpoint, handler.Handle, conf.Memcache.IdleTimeout)
+}
@@ -0,0 +1,35 @@
+package init
+
+import (
+	"context"
+
+	respStorage "github.com/KennyMacCormik/otel/backend/internal/resp/storage"
+	"github.com/KennyMacCormik/otel/backend/pkg/cache/wrappers/meta_cache"
+	"github.com/KennyMacCormik/otel/backend/pkg/models/tenant"
+	"github.com/KennyMacCormik/otel/backend/pkg/resp"
+)
+
+func RespServer(conf *Config, st meta_cache.MetaCache) *resp.Server {
+	handler := respStorage.NewStorageHandler(st)
+
+	var opts []resp.InitOptions
+	if conf.Quota.Enabled {
+		opts = append(opts, resp.WithAuth(tenantAuth(conf.Quota.Tenants)))
+	}
+
+	return resp.NewServer(conf.Resp.Endpoint, handler.Handle, conf.Resp.IdleTimeout, opts...)
+}
+
+// tenantAuth resolves tenant of the connection from API key sent as AUTH password
+func tenantAuth(keys map[string]string) resp.AuthFunc {
+	resolver := tenant.NewResolver(keys)
+
+	return func(ctx context.Context, password string) (context.Context, bool) {
+		t, ok := resolver.Resolve(password)
+		if !ok {
+			return nil, false
+		}
+
+		return tenant.NewContext(ctx, t), true
+	}
+}
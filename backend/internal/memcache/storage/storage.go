@@ -0,0 +1,413 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KennyMacCormik/common/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/KennyMacCormik/otel/backend/pkg/cache/wrappers/meta_cache"
+	"github.com/KennyMacCormik/otel/backend/pkg/memcache"
+	cacheErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/cache"
+	httpErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/http"
+	otelHelpers "github.com/KennyMacCormik/otel/backend/pkg/otel/helpers"
+)
+
+const (
+	maxKeyLen = 250
+	// exptime above 30 days is an absolute unix time
+	maxRelativeExptime = 60 * 60 * 24 * 30
+)
+
+// replyError is written to the client as is
+type replyError string
+
+func (e replyError) Error() string {
+	return string(e)
+}
+
+const (
+	errBadCommandLine replyError = "CLIENT_ERROR bad command line format"
+	errInvalidDelta   replyError = "CLIENT_ERROR invalid numeric delta argument"
+	errNonNumeric     replyError = "CLIENT_ERROR cannot increment or decrement non-numeric value"
+	errReadOnly       replyError = "SERVER_ERROR writes are disabled"
+)
+
+// StorageHandler maps memcached commands onto the same storage as the HTTP handlers.
+// Flags and expirations are kept by the storage, a write over any other transport clears them.
+// Cas unique is derived from the value and flags, so it changes with every write changing them whatever transport
+// it came from, while writing back the same value keeps it. Commands changing a key are applied with
+// meta_cache.MetaCache.Update, which makes cas, incr and decr atomic against writes over every transport.
+type StorageHandler struct {
+	st       meta_cache.MetaCache
+	readOnly bool
+
+	commands map[string]command
+}
+
+type command func(ctx context.Context, w *memcache.Writer, req *memcache.Request) error
+
+type InitOptions func(h *StorageHandler)
+
+// WithReadOnly rejects all commands but get and gets, e.g. when writes must be attributed to a tenant,
+// as memcached protocol can't authenticate one.
+func WithReadOnly() InitOptions {
+	return func(h *StorageHandler) {
+		h.readOnly = true
+	}
+}
+
+func NewStorageHandler(st meta_cache.MetaCache, opts ...InitOptions) *StorageHandler {
+	h := &StorageHandler{st: st}
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	h.commands = map[string]command{
+		"get":     h.get,
+		"gets":    h.get,
+		"set":     h.store,
+		"add":     h.store,
+		"replace": h.store,
+		"append":  h.store,
+		"prepend": h.store,
+		"cas":     h.store,
+		"delete":  h.delete,
+		"incr":    h.incr,
+		"decr":    h.incr,
+		"touch":   h.touch,
+	}
+
+	if h.readOnly {
+		for name := range h.commands {
+			if name != "get" && name != "gets" {
+				h.commands[name] = rejectWrite
+			}
+		}
+	}
+
+	return h
+}
+
+func rejectWrite(context.Context, *memcache.Writer, *memcache.Request) error {
+	return errReadOnly
+}
+
+// Handle implements memcache.HandlerFunc
+func (h *StorageHandler) Handle(ctx context.Context, w *memcache.Writer, req *memcache.Request) {
+	spanName := "memcache." + req.Command
+
+	fn, ok := h.commands[req.Command]
+	if !ok {
+		w.WriteLine("ERROR")
+		return
+	}
+
+	ctx, span := otelHelpers.StartSpanWithCtx(ctx, spanName, spanName)
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("db.system", "memcached"),
+		attribute.String("db.operation.name", req.Command),
+		attribute.Bool("memcache.noreply", req.NoReply),
+	)
+
+	lg := log.CopyLogger().With("Method", spanName)
+	lg.Debug("request trace ID", "trace_id", span.SpanContext().TraceID().String())
+
+	for _, key := range keysOf(req) {
+		if len(key) > maxKeyLen || strings.ContainsFunc(key, func(r rune) bool { return r < ' ' || r == 0x7f }) {
+			handleErr(w, span, lg, errBadCommandLine)
+			return
+		}
+	}
+
+	if err := fn(ctx, w, req); err != nil {
+		handleErr(w, span, lg, err)
+	}
+}
+
+func keysOf(req *memcache.Request) []string {
+	if req.Command == "get" || req.Command == "gets" {
+		return req.Args
+	}
+
+	if len(req.Args) > 0 {
+		return req.Args[:1]
+	}
+
+	return nil
+}
+
+// get writes found items, expired and missing keys are skipped
+func (h *StorageHandler) get(ctx context.Context, w *memcache.Writer, req *memcache.Request) error {
+	if len(req.Args) == 0 {
+		return replyError("ERROR")
+	}
+
+	for _, key := range req.Args {
+		item, err := h.st.GetItem(ctx, key)
+		if errors.Is(err, cacheErrors.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		val := toString(item.Value)
+		w.WriteValue(key, item.Flags, val, casOf(val, item.Flags), req.Command == "gets")
+	}
+
+	w.WriteLine("END")
+
+	return nil
+}
+
+// store handles set, add, replace, append, prepend and cas
+func (h *StorageHandler) store(ctx context.Context, w *memcache.Writer, req *memcache.Request) error {
+	argc := 4
+	if req.Command == "cas" {
+		argc = 5
+	}
+	if len(req.Args) != argc {
+		return errBadCommandLine
+	}
+
+	key := req.Args[0]
+
+	flags, err := strconv.ParseUint(req.Args[1], 10, 32)
+	if err != nil {
+		return errBadCommandLine
+	}
+
+	exptime, err := strconv.ParseInt(req.Args[2], 10, 64)
+	if err != nil {
+		return errBadCommandLine
+	}
+
+	var casUnique uint64
+	if req.Command == "cas" {
+		if casUnique, err = strconv.ParseUint(req.Args[4], 10, 64); err != nil {
+			return errBadCommandLine
+		}
+	}
+
+	reply := "STORED"
+	err = h.st.Update(ctx, key, func(item meta_cache.Item, exists bool) (meta_cache.Item, meta_cache.Action, error) {
+		val := toString(item.Value)
+		data := req.Data
+
+		switch req.Command {
+		case "add":
+			if exists {
+				reply = "NOT_STORED"
+				return item, meta_cache.Keep, nil
+			}
+		case "replace":
+			if !exists {
+				reply = "NOT_STORED"
+				return item, meta_cache.Keep, nil
+			}
+		case "append", "prepend":
+			if !exists {
+				reply = "NOT_STORED"
+				return item, meta_cache.Keep, nil
+			}
+			if req.Command == "append" {
+				data = val + data
+			} else {
+				data = data + val
+			}
+			// append and prepend ignore flags and exptime
+			return meta_cache.Item{Value: data, Meta: item.Meta}, meta_cache.Store, nil
+		case "cas":
+			if !exists {
+				reply = "NOT_FOUND"
+				return item, meta_cache.Keep, nil
+			}
+			if casOf(val, item.Flags) != casUnique {
+				reply = "EXISTS"
+				return item, meta_cache.Keep, nil
+			}
+		}
+
+		deadline, expired := toDeadline(exptime)
+		if expired {
+			return item, meta_cache.Remove, nil
+		}
+
+		return meta_cache.Item{Value: data, Meta: meta_cache.Meta{Deadline: deadline, Flags: uint32(flags)}}, meta_cache.Store, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	w.WriteLine(reply)
+
+	return nil
+}
+
+func (h *StorageHandler) delete(ctx context.Context, w *memcache.Writer, req *memcache.Request) error {
+	// legacy clients may send zero hold time
+	if len(req.Args) != 1 && (len(req.Args) != 2 || req.Args[1] != "0") {
+		return errBadCommandLine
+	}
+
+	key := req.Args[0]
+
+	reply := "DELETED"
+	err := h.st.Update(ctx, key, func(item meta_cache.Item, exists bool) (meta_cache.Item, meta_cache.Action, error) {
+		if !exists {
+			reply = "NOT_FOUND"
+		}
+		return item, meta_cache.Remove, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	w.WriteLine(reply)
+
+	return nil
+}
+
+// incr wraps around 64-bit unsigned integer, decr stops at zero. Flags and expiration are kept.
+func (h *StorageHandler) incr(ctx context.Context, w *memcache.Writer, req *memcache.Request) error {
+	if len(req.Args) != 2 {
+		return errBadCommandLine
+	}
+
+	key := req.Args[0]
+
+	delta, err := strconv.ParseUint(req.Args[1], 10, 64)
+	if err != nil {
+		return errInvalidDelta
+	}
+
+	reply := "NOT_FOUND"
+	err = h.st.Update(ctx, key, func(item meta_cache.Item, exists bool) (meta_cache.Item, meta_cache.Action, error) {
+		if !exists {
+			return item, meta_cache.Keep, nil
+		}
+
+		n, err := strconv.ParseUint(strings.TrimSpace(toString(item.Value)), 10, 64)
+		if err != nil {
+			return item, meta_cache.Keep, errNonNumeric
+		}
+
+		switch {
+		case req.Command == "incr":
+			n += delta
+		case delta > n:
+			n = 0
+		default:
+			n -= delta
+		}
+
+		reply = strconv.FormatUint(n, 10)
+		item.Value = reply
+
+		return item, meta_cache.Store, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	w.WriteLine(reply)
+
+	return nil
+}
+
+func (h *StorageHandler) touch(ctx context.Context, w *memcache.Writer, req *memcache.Request) error {
+	if len(req.Args) != 2 {
+		return errBadCommandLine
+	}
+
+	key := req.Args[0]
+
+	exptime, err := strconv.ParseInt(req.Args[1], 10, 64)
+	if err != nil {
+		return errBadCommandLine
+	}
+
+	reply := "TOUCHED"
+	err = h.st.Update(ctx, key, func(item meta_cache.Item, exists bool) (meta_cache.Item, meta_cache.Action, error) {
+		if !exists {
+			reply = "NOT_FOUND"
+			return item, meta_cache.Keep, nil
+		}
+
+		var expired bool
+		if item.Deadline, expired = toDeadline(exptime); expired {
+			return item, meta_cache.Remove, nil
+		}
+
+		return item, meta_cache.Store, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	w.WriteLine(reply)
+
+	return nil
+}
+
+// toDeadline converts exptime to deadline, zero deadline means the item never expires
+func toDeadline(exptime int64) (time.Time, bool) {
+	switch {
+	case exptime == 0:
+		return time.Time{}, false
+	case exptime < 0:
+		return time.Time{}, true
+	case exptime > maxRelativeExptime:
+		deadline := time.Unix(exptime, 0)
+		return deadline, !time.Now().Before(deadline)
+	default:
+		return time.Now().Add(time.Duration(min(exptime, math.MaxInt64/int64(time.Second))) * time.Second), false
+	}
+}
+
+// casOf derives cas unique from value and flags, zero is never returned as clients treat it as no cas
+func casOf(val string, flags uint32) uint64 {
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(val))
+	_, _ = hash.Write([]byte{byte(flags >> 24), byte(flags >> 16), byte(flags >> 8), byte(flags)})
+
+	return max(hash.Sum64(), 1)
+}
+
+func toString(value any) string {
+	return fmt.Sprintf("%v", value)
+}
+
+// handleErr writes error reply. Command errors are written as is, storage errors are mapped like HTTP errors.
+func handleErr(w *memcache.Writer, span trace.Span, lg *slog.Logger, err error) {
+	var reply replyError
+	if errors.As(err, &reply) {
+		otelHelpers.SetSpanExceptionWithoutErr(span, err)
+		w.WriteLine(reply.Error())
+		return
+	}
+
+	status := httpErrors.FromError(err)
+	if status.GetStatus() >= 500 {
+		otelHelpers.SetSpanExceptionWithErr(span, err)
+		lg.Error("command failed", "err", err)
+		w.WriteLine("SERVER_ERROR " + status.GetMessage())
+		return
+	}
+
+	otelHelpers.SetSpanExceptionWithoutErr(span, err)
+	lg.Warn("command failed", "err", err)
+	w.WriteLine("CLIENT_ERROR " + status.GetMessage())
+}
@@ -0,0 +1,185 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KennyMacCormik/otel/backend/pkg/cache/impl/sync_map"
+	"github.com/KennyMacCormik/otel/backend/pkg/cache/wrappers/meta_cache"
+	"github.com/KennyMacCormik/otel/backend/pkg/memcache"
+)
+
+func newTestHandler(t *testing.T, opts ...InitOptions) (*StorageHandler, meta_cache.MetaCache) {
+	st, err := meta_cache.NewMetaCache(sync_map.NewSyncMapCache())
+	require.NoError(t, err, "expect no error with valid configuration")
+	t.Cleanup(func() { _ = st.Close(context.Background()) })
+
+	return NewStorageHandler(st, opts...), st
+}
+
+// do runs the command, data is the data block of storage commands
+func do(t *testing.T, h *StorageHandler, line string, data ...string) string {
+	fields := strings.Fields(line)
+	req := &memcache.Request{Command: fields[0], Args: fields[1:]}
+	if len(data) > 0 {
+		req.Data = data[0]
+	}
+
+	var buf bytes.Buffer
+	w := memcache.NewWriter(&buf)
+	h.Handle(context.Background(), w, req)
+	require.NoError(t, w.Flush(), "expect reply to be written")
+
+	return buf.String()
+}
+
+// gets returns cas unique of the key
+func gets(t *testing.T, h *StorageHandler, key string) string {
+	reply := do(t, h, "gets "+key)
+	header, _, ok := strings.Cut(reply, "\r\n")
+	require.True(t, ok, "expect value of %s", key)
+
+	fields := strings.Fields(header)
+	require.Len(t, fields, 5, "expect cas unique in %s", header)
+
+	return fields[4]
+}
+
+func TestStorageHandler_Store(t *testing.T) {
+	h, _ := newTestHandler(t)
+
+	assert.Equal(t, "END\r\n", do(t, h, "get k"), "expect no value for missing key")
+	assert.Equal(t, "STORED\r\n", do(t, h, "set k 5 0 1", "v"), "expect set to succeed")
+	assert.Equal(t, "VALUE k 5 1\r\nv\r\nEND\r\n", do(t, h, "get k"), "expect value with flags")
+
+	assert.Equal(t, "NOT_STORED\r\n", do(t, h, "add k 0 0 1", "x"), "expect add to skip existing key")
+	assert.Equal(t, "STORED\r\n", do(t, h, "add n 0 0 1", "x"), "expect add to store missing key")
+	assert.Equal(t, "NOT_STORED\r\n", do(t, h, "replace missing 0 0 1", "x"), "expect replace to skip missing key")
+	assert.Equal(t, "STORED\r\n", do(t, h, "replace n 0 0 1", "y"), "expect replace to store existing key")
+
+	assert.Equal(t, "NOT_STORED\r\n", do(t, h, "append missing 0 0 1", "x"), "expect append to skip missing key")
+	assert.Equal(t, "STORED\r\n", do(t, h, "append k 9 0 2", ">>"), "expect append to succeed")
+	assert.Equal(t, "STORED\r\n", do(t, h, "prepend k 9 0 2", "<<"), "expect prepend to succeed")
+	assert.Equal(t, "VALUE k 5 5\r\n<<v>>\r\nEND\r\n", do(t, h, "get k"), "expect append and prepend to keep flags")
+
+	assert.Equal(t, "VALUE k 5 5\r\n<<v>>\r\nVALUE n 0 1\r\ny\r\nEND\r\n", do(t, h, "get k missing n"), "expect found keys only")
+
+	assert.Equal(t, "DELETED\r\n", do(t, h, "delete k"), "expect delete to succeed")
+	assert.Equal(t, "NOT_FOUND\r\n", do(t, h, "delete k"), "expect missing key not to be found")
+
+	assert.Equal(t, "CLIENT_ERROR bad command line format\r\n", do(t, h, "set k x 0 1", "v"), "expect error for invalid flags")
+	assert.Equal(t, "CLIENT_ERROR bad command line format\r\n", do(t, h, "get "+strings.Repeat("k", maxKeyLen+1)), "expect error for long key")
+}
+
+func TestStorageHandler_ReadOnly(t *testing.T) {
+	h, st := newTestHandler(t, WithReadOnly())
+
+	_, err := st.Set(context.Background(), "k", "v")
+	require.NoError(t, err, "expect set to succeed")
+	assert.Equal(t, "VALUE k 0 1\r\nv\r\nEND\r\n", do(t, h, "get k"), "expect reads to be served")
+
+	testCases := []struct {
+		name string
+		line string
+		data []string
+	}{
+		{"set", "set k 0 0 1", []string{"x"}},
+		{"append", "append k 0 0 1", []string{"x"}},
+		{"delete", "delete k", nil},
+		{"incr", "incr k 1", nil},
+		{"touch", "touch k 10", nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, "SERVER_ERROR writes are disabled\r\n", do(t, h, tc.line, tc.data...), "expect write to be rejected")
+		})
+	}
+
+	assert.Equal(t, "VALUE k 0 1\r\nv\r\nEND\r\n", do(t, h, "get k"), "expect value to be kept")
+}
+
+func TestStorageHandler_Cas(t *testing.T) {
+	h, st := newTestHandler(t)
+	ctx := context.Background()
+
+	assert.Equal(t, "NOT_FOUND\r\n", do(t, h, "cas k 0 0 1 1", "v"), "expect cas of missing key not to be found")
+
+	do(t, h, "set k 0 0 1", "a")
+	unique := gets(t, h, "k")
+	assert.Equal(t, unique, gets(t, h, "k"), "expect cas unique to be stable")
+
+	assert.Equal(t, "STORED\r\n", do(t, h, "cas k 0 0 1 "+unique, "b"), "expect cas with current unique to succeed")
+	assert.Equal(t, "EXISTS\r\n", do(t, h, "cas k 0 0 1 "+unique, "c"), "expect cas with stale unique to fail")
+
+	unique = gets(t, h, "k")
+	_, err := st.Set(ctx, "k", "foreign")
+	require.NoError(t, err, "expect no error on set")
+	assert.Equal(t, "EXISTS\r\n", do(t, h, "cas k 0 0 1 "+unique, "c"), "expect write over other transport to change cas unique")
+
+	unique = gets(t, h, "k")
+	do(t, h, "set k 1 0 7", "foreign")
+	assert.NotEqual(t, unique, gets(t, h, "k"), "expect flags to change cas unique")
+
+	assert.Equal(t, "CLIENT_ERROR bad command line format\r\n", do(t, h, "cas k 0 0 1", "v"), "expect error without unique")
+}
+
+func TestStorageHandler_Incr(t *testing.T) {
+	h, _ := newTestHandler(t)
+
+	assert.Equal(t, "NOT_FOUND\r\n", do(t, h, "incr n 1"), "expect missing key not to be found")
+
+	do(t, h, "set n 3 0 2", "10")
+	assert.Equal(t, "15\r\n", do(t, h, "incr n 5"), "expect incr to add delta")
+	assert.Equal(t, "0\r\n", do(t, h, "decr n 20"), "expect decr to stop at zero")
+	assert.Equal(t, "VALUE n 3 1\r\n0\r\nEND\r\n", do(t, h, "get n"), "expect flags to be kept")
+
+	do(t, h, "set n 0 0 20", strconv.FormatUint(1<<64-1, 10))
+	assert.Equal(t, "1\r\n", do(t, h, "incr n 2"), "expect incr to wrap around")
+
+	do(t, h, "set s 0 0 3", "abc")
+	assert.Equal(t, "CLIENT_ERROR cannot increment or decrement non-numeric value\r\n", do(t, h, "incr s 1"), "expect error for non-numeric value")
+	assert.Equal(t, "CLIENT_ERROR invalid numeric delta argument\r\n", do(t, h, "incr n -1"), "expect error for invalid delta")
+}
+
+func TestStorageHandler_Exptime(t *testing.T) {
+	h, st := newTestHandler(t)
+	ctx := context.Background()
+
+	do(t, h, "set k 0 100 1", "v")
+	item, err := st.GetItem(ctx, "k")
+	require.NoError(t, err, "expect no error on get")
+	assert.WithinDuration(t, time.Now().Add(100*time.Second), item.Deadline, time.Second, "expect relative exptime")
+
+	deadline := time.Now().Add(time.Hour).Truncate(time.Second)
+	do(t, h, "set k 0 "+strconv.FormatInt(deadline.Unix(), 10)+" 1", "v")
+	item, err = st.GetItem(ctx, "k")
+	require.NoError(t, err, "expect no error on get")
+	assert.True(t, deadline.Equal(item.Deadline), "expect exptime above 30 days to be absolute")
+
+	assert.Equal(t, "STORED\r\n", do(t, h, "set k 0 -1 1", "v"), "expect set with negative exptime to succeed")
+	assert.Equal(t, "END\r\n", do(t, h, "get k"), "expect negative exptime to expire key at once")
+
+	do(t, h, "set k 0 0 1", "v")
+	assert.Equal(t, "TOUCHED\r\n", do(t, h, "touch k 100"), "expect touch to succeed")
+	item, err = st.GetItem(ctx, "k")
+	require.NoError(t, err, "expect no error on get")
+	assert.False(t, item.Deadline.IsZero(), "expect touch to set expiration")
+
+	_, err = st.Set(ctx, "k", "foreign")
+	require.NoError(t, err, "expect no error on set")
+	item, err = st.GetItem(ctx, "k")
+	require.NoError(t, err, "expect no error on get")
+	assert.True(t, item.Deadline.IsZero(), "expect write over other transport to clear expiration")
+
+	assert.Equal(t, "TOUCHED\r\n", do(t, h, "touch k -1"), "expect touch to succeed")
+	assert.Equal(t, "END\r\n", do(t, h, "get k"), "expect touch with negative exptime to expire key")
+	assert.Equal(t, "NOT_FOUND\r\n", do(t, h, "touch k 1"), "expect missing key not to be found")
+}
@@ -0,0 +1,610 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KennyMacCormik/common/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/KennyMacCormik/otel/backend/pkg/cache/wrappers/meta_cache"
+	cacheErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/cache"
+	httpErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/http"
+	otelHelpers "github.com/KennyMacCormik/otel/backend/pkg/otel/helpers"
+	"github.com/KennyMacCormik/otel/backend/pkg/resp"
+)
+
+const defaultScanCount = 10
+
+// replyError is written to the client as is
+type replyError string
+
+func (e replyError) Error() string {
+	return string(e)
+}
+
+const (
+	errSyntax        replyError = "ERR syntax error"
+	errNotInteger    replyError = "ERR value is not an integer or out of range"
+	errInvalidTTL    replyError = "ERR invalid expire time in 'set' command"
+	errInvalidCursor replyError = "ERR invalid cursor"
+	errOverflow      replyError = "ERR increment or decrement would overflow"
+)
+
+// StorageHandler maps Redis commands onto the same storage as the HTTP handlers.
+// Expirations are kept by the storage, so writes over other transports reset them as SET does.
+// Commands changing a key are applied with meta_cache.MetaCache.Update, which makes INCR atomic
+// against writes over every transport.
+type StorageHandler struct {
+	st meta_cache.MetaCache
+
+	commands map[string]command
+}
+
+func NewStorageHandler(st meta_cache.MetaCache) *StorageHandler {
+	h := &StorageHandler{st: st}
+
+	h.commands = map[string]command{
+		"PING":    ping,
+		"ECHO":    echo,
+		"SELECT":  selectDB,
+		"COMMAND": emptyArray,
+		"CLIENT":  ok,
+		"GET":     h.get,
+		"SET":     h.set,
+		"DEL":     h.del,
+		"EXISTS":  h.exists,
+		"EXPIRE":  h.expire,
+		"PEXPIRE": h.expire,
+		"TTL":     h.ttl,
+		"KEYS":    h.keys,
+		"SCAN":    h.scan,
+		"INCR":    h.incr,
+		"INCRBY":  h.incr,
+	}
+
+	return h
+}
+
+// Handle implements resp.HandlerFunc
+func (h *StorageHandler) Handle(ctx context.Context, w *resp.Writer, args []string) {
+	cmd := strings.ToUpper(args[0])
+	spanName := "resp." + strings.ToLower(cmd)
+
+	ctx, span := otelHelpers.StartSpanWithCtx(ctx, spanName, spanName)
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("db.system", "redis"),
+		attribute.String("db.operation.name", cmd),
+		attribute.Int("resp.proto", w.Proto()),
+	)
+
+	lg := log.CopyLogger().With("Method", spanName)
+	lg.Debug("request trace ID", "trace_id", span.SpanContext().TraceID().String())
+
+	fn, ok := h.commands[cmd]
+	if !ok {
+		w.WriteError(fmt.Sprintf("ERR unknown command '%s', with args beginning with: %s", args[0], strings.Join(args[1:], " ")))
+		return
+	}
+
+	if err := fn(ctx, w, args); err != nil {
+		handleErr(w, span, lg, err, args)
+	}
+}
+
+type command func(ctx context.Context, w *resp.Writer, args []string) error
+
+func ping(_ context.Context, w *resp.Writer, args []string) error {
+	switch len(args) {
+	case 1:
+		w.WriteSimpleString("PONG")
+	case 2:
+		w.WriteBulkString(args[1])
+	default:
+		resp.WrongArgs(w, args[0])
+	}
+
+	return nil
+}
+
+func echo(_ context.Context, w *resp.Writer, args []string) error {
+	if len(args) != 2 {
+		resp.WrongArgs(w, args[0])
+		return nil
+	}
+
+	w.WriteBulkString(args[1])
+
+	return nil
+}
+
+// selectDB only accepts database 0, as storage has single keyspace
+func selectDB(_ context.Context, w *resp.Writer, args []string) error {
+	if len(args) != 2 {
+		resp.WrongArgs(w, args[0])
+		return nil
+	}
+
+	if args[1] != "0" {
+		w.WriteError("ERR DB index is out of range")
+		return nil
+	}
+
+	w.WriteSimpleString("OK")
+
+	return nil
+}
+
+// emptyArray answers introspection commands sent by clients on connect
+func emptyArray(_ context.Context, w *resp.Writer, _ []string) error {
+	w.WriteArrayHeader(0)
+	return nil
+}
+
+func ok(_ context.Context, w *resp.Writer, _ []string) error {
+	w.WriteSimpleString("OK")
+	return nil
+}
+
+func (h *StorageHandler) get(ctx context.Context, w *resp.Writer, args []string) error {
+	if len(args) != 2 {
+		resp.WrongArgs(w, args[0])
+		return nil
+	}
+
+	item, err := h.st.GetItem(ctx, args[1])
+	if errors.Is(err, cacheErrors.ErrNotFound) {
+		w.WriteNull()
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	w.WriteBulkString(toString(item.Value))
+
+	return nil
+}
+
+// set supports EX, PX, NX, XX and KEEPTTL options
+func (h *StorageHandler) set(ctx context.Context, w *resp.Writer, args []string) error {
+	if len(args) < 3 {
+		resp.WrongArgs(w, args[0])
+		return nil
+	}
+
+	key, value := args[1], args[2]
+
+	var ttl time.Duration
+	var nx, xx, keepTTL bool
+
+	for i := 3; i < len(args); i++ {
+		switch opt := strings.ToUpper(args[i]); opt {
+		case "NX":
+			nx = true
+		case "XX":
+			xx = true
+		case "KEEPTTL":
+			keepTTL = true
+		case "EX", "PX":
+			if ttl != 0 || i+1 == len(args) {
+				return errSyntax
+			}
+			i++
+			n, err := strconv.ParseInt(args[i], 10, 64)
+			if err != nil {
+				return errNotInteger
+			}
+			if n <= 0 || (opt == "EX" && n > math.MaxInt64/int64(time.Second)) || n > math.MaxInt64/int64(time.Millisecond) {
+				return errInvalidTTL
+			}
+			if opt == "EX" {
+				ttl = time.Duration(n) * time.Second
+			} else {
+				ttl = time.Duration(n) * time.Millisecond
+			}
+		default:
+			return errSyntax
+		}
+	}
+
+	if (nx && xx) || (keepTTL && ttl != 0) {
+		return errSyntax
+	}
+
+	var stored bool
+	err := h.st.Update(ctx, key, func(item meta_cache.Item, exists bool) (meta_cache.Item, meta_cache.Action, error) {
+		if (nx && exists) || (xx && !exists) {
+			return item, meta_cache.Keep, nil
+		}
+
+		next := meta_cache.Item{Value: value}
+		switch {
+		case ttl != 0:
+			next.Deadline = time.Now().Add(ttl)
+		case keepTTL:
+			next.Meta = item.Meta
+		}
+		stored = true
+
+		return next, meta_cache.Store, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if !stored {
+		w.WriteNull()
+		return nil
+	}
+
+	w.WriteSimpleString("OK")
+
+	return nil
+}
+
+// del returns number of deleted keys
+func (h *StorageHandler) del(ctx context.Context, w *resp.Writer, args []string) error {
+	if len(args) < 2 {
+		resp.WrongArgs(w, args[0])
+		return nil
+	}
+
+	var n int64
+	for _, key := range args[1:] {
+		err := h.st.Update(ctx, key, func(item meta_cache.Item, exists bool) (meta_cache.Item, meta_cache.Action, error) {
+			if exists {
+				n++
+			}
+			return item, meta_cache.Remove, nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	w.WriteInteger(n)
+
+	return nil
+}
+
+// exists returns number of existing keys, the same key is counted as many times as it is passed
+func (h *StorageHandler) exists(ctx context.Context, w *resp.Writer, args []string) error {
+	if len(args) < 2 {
+		resp.WrongArgs(w, args[0])
+		return nil
+	}
+
+	var n int64
+	for _, key := range args[1:] {
+		_, err := h.st.GetItem(ctx, key)
+		if errors.Is(err, cacheErrors.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		n++
+	}
+
+	w.WriteInteger(n)
+
+	return nil
+}
+
+// expire returns 1 if timeout was set and 0 if key doesn't exist. Non-positive timeout deletes the key.
+func (h *StorageHandler) expire(ctx context.Context, w *resp.Writer, args []string) error {
+	if len(args) != 3 {
+		resp.WrongArgs(w, args[0])
+		return nil
+	}
+
+	unit := time.Second
+	if strings.EqualFold(args[0], "PEXPIRE") {
+		unit = time.Millisecond
+	}
+
+	timeout, err := strconv.ParseInt(args[2], 10, 64)
+	if err != nil || timeout > math.MaxInt64/int64(unit) {
+		return errNotInteger
+	}
+
+	var found bool
+	err = h.st.Update(ctx, args[1], func(item meta_cache.Item, exists bool) (meta_cache.Item, meta_cache.Action, error) {
+		found = exists
+		switch {
+		case !exists:
+			return item, meta_cache.Keep, nil
+		case timeout <= 0:
+			return item, meta_cache.Remove, nil
+		}
+
+		item.Deadline = time.Now().Add(time.Duration(timeout) * unit)
+
+		return item, meta_cache.Store, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if !found {
+		w.WriteInteger(0)
+		return nil
+	}
+
+	w.WriteInteger(1)
+
+	return nil
+}
+
+// ttl returns remaining seconds, -1 for key without timeout and -2 for missing key
+func (h *StorageHandler) ttl(ctx context.Context, w *resp.Writer, args []string) error {
+	if len(args) != 2 {
+		resp.WrongArgs(w, args[0])
+		return nil
+	}
+
+	item, err := h.st.GetItem(ctx, args[1])
+	if errors.Is(err, cacheErrors.ErrNotFound) {
+		w.WriteInteger(-2)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if item.Deadline.IsZero() {
+		w.WriteInteger(-1)
+		return nil
+	}
+
+	w.WriteInteger(int64((time.Until(item.Deadline) + time.Second/2) / time.Second))
+
+	return nil
+}
+
+func (h *StorageHandler) keys(ctx context.Context, w *resp.Writer, args []string) error {
+	if len(args) != 2 {
+		resp.WrongArgs(w, args[0])
+		return nil
+	}
+
+	keys, err := h.st.GetKeys(ctx)
+	if err != nil {
+		return err
+	}
+
+	matched := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if matchGlob(args[1], key) {
+			matched = append(matched, key)
+		}
+	}
+
+	w.WriteStrings(matched)
+
+	return nil
+}
+
+// scan iterates over sorted snapshot of keys, cursor is the position in it.
+// Keys added or removed during iteration may be missed or returned twice.
+func (h *StorageHandler) scan(ctx context.Context, w *resp.Writer, args []string) error {
+	if len(args) < 2 || len(args)%2 != 0 {
+		resp.WrongArgs(w, args[0])
+		return nil
+	}
+
+	cursor, err := strconv.Atoi(args[1])
+	if err != nil || cursor < 0 {
+		return errInvalidCursor
+	}
+
+	pattern, count := "*", defaultScanCount
+	for i := 2; i < len(args); i += 2 {
+		switch strings.ToUpper(args[i]) {
+		case "MATCH":
+			pattern = args[i+1]
+		case "COUNT":
+			count, err = strconv.Atoi(args[i+1])
+			if err != nil || count < 1 {
+				return errSyntax
+			}
+		case "TYPE":
+			// all values are strings
+			if !strings.EqualFold(args[i+1], "string") {
+				w.WriteArrayHeader(2)
+				w.WriteBulkString("0")
+				w.WriteArrayHeader(0)
+				return nil
+			}
+		default:
+			return errSyntax
+		}
+	}
+
+	keys, err := h.st.GetKeys(ctx)
+	if err != nil {
+		return err
+	}
+	sort.Strings(keys)
+
+	end := min(cursor+count, len(keys))
+	next := end
+	if end == len(keys) {
+		next = 0
+	}
+
+	var page []string
+	if cursor < end {
+		for _, key := range keys[cursor:end] {
+			if matchGlob(pattern, key) {
+				page = append(page, key)
+			}
+		}
+	}
+
+	w.WriteArrayHeader(2)
+	w.WriteBulkString(strconv.Itoa(next))
+	w.WriteStrings(page)
+
+	return nil
+}
+
+// incr keeps key timeout like Redis does
+func (h *StorageHandler) incr(ctx context.Context, w *resp.Writer, args []string) error {
+	byDelta := strings.EqualFold(args[0], "INCRBY")
+	if (!byDelta && len(args) != 2) || (byDelta && len(args) != 3) {
+		resp.WrongArgs(w, args[0])
+		return nil
+	}
+
+	delta := int64(1)
+	if byDelta {
+		var err error
+		if delta, err = strconv.ParseInt(args[2], 10, 64); err != nil {
+			return errNotInteger
+		}
+	}
+
+	var n int64
+	err := h.st.Update(ctx, args[1], func(item meta_cache.Item, exists bool) (meta_cache.Item, meta_cache.Action, error) {
+		if exists {
+			var err error
+			if n, err = strconv.ParseInt(toString(item.Value), 10, 64); err != nil {
+				return item, meta_cache.Keep, errNotInteger
+			}
+		}
+
+		if (delta > 0 && n > math.MaxInt64-delta) || (delta < 0 && n < math.MinInt64-delta) {
+			return item, meta_cache.Keep, errOverflow
+		}
+		n += delta
+		item.Value = strconv.FormatInt(n, 10)
+
+		return item, meta_cache.Store, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	w.WriteInteger(n)
+
+	return nil
+}
+
+func toString(value any) string {
+	return fmt.Sprintf("%v", value)
+}
+
+// handleErr writes error reply. Command errors are written as is, storage errors are mapped like HTTP errors.
+func handleErr(w *resp.Writer, span trace.Span, lg *slog.Logger, err error, args []string) {
+	var reply replyError
+	if errors.As(err, &reply) {
+		otelHelpers.SetSpanExceptionWithoutErr(span, err)
+		w.WriteError(reply.Error())
+		return
+	}
+
+	status := httpErrors.FromError(err)
+	if status.GetStatus() >= 500 {
+		otelHelpers.SetSpanExceptionWithErr(span, err)
+		lg.Error("command failed", "args", len(args), "err", err)
+	} else {
+		otelHelpers.SetSpanExceptionWithoutErr(span, err)
+		lg.Warn("command failed", "args", len(args), "err", err)
+	}
+
+	w.WriteError("ERR " + status.GetMessage())
+}
+
+// matchGlob matches key against Redis glob pattern supporting *, ?, [...], [^...] and \ escapes
+func matchGlob(pattern, key string) bool {
+	if pattern == "*" {
+		return true
+	}
+
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 1 && pattern[1] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := 0; i <= len(key); i++ {
+				if matchGlob(pattern[1:], key[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(key) == 0 {
+				return false
+			}
+			key = key[1:]
+			pattern = pattern[1:]
+		case '[':
+			if len(key) == 0 {
+				return false
+			}
+			end := strings.IndexByte(pattern[1:], ']')
+			if end < 0 {
+				// unterminated class matches literally
+				if key[0] != '[' {
+					return false
+				}
+				key, pattern = key[1:], pattern[1:]
+				continue
+			}
+			class := pattern[1 : end+1]
+			negate := len(class) > 0 && class[0] == '^'
+			if negate {
+				class = class[1:]
+			}
+			if matchClass(class, key[0]) == negate {
+				return false
+			}
+			key, pattern = key[1:], pattern[end+2:]
+		case '\\':
+			if len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if len(key) == 0 || key[0] != pattern[0] {
+				return false
+			}
+			key, pattern = key[1:], pattern[1:]
+		}
+	}
+
+	return len(key) == 0
+}
+
+func matchClass(class string, c byte) bool {
+	for i := 0; i < len(class); i++ {
+		if i+2 < len(class) && class[i+1] == '-' {
+			if class[i] <= c && c <= class[i+2] {
+				return true
+			}
+			i += 2
+			continue
+		}
+		if class[i] == c {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,200 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KennyMacCormik/otel/backend/pkg/cache/impl/sync_map"
+	"github.com/KennyMacCormik/otel/backend/pkg/cache/wrappers/meta_cache"
+	"github.com/KennyMacCormik/otel/backend/pkg/resp"
+)
+
+func newTestHandler(t *testing.T) (*StorageHandler, meta_cache.MetaCache) {
+	st, err := meta_cache.NewMetaCache(sync_map.NewSyncMapCache())
+	require.NoError(t, err, "expect no error with valid configuration")
+	t.Cleanup(func() { _ = st.Close(context.Background()) })
+
+	return NewStorageHandler(st), st
+}
+
+// do runs the command and returns its reply as read by resp.Reader
+func do(t *testing.T, h *StorageHandler, args ...string) any {
+	var buf bytes.Buffer
+	w := resp.NewWriter(&buf)
+	h.Handle(context.Background(), w, args)
+	require.NoError(t, w.Flush(), "expect reply to be written")
+
+	reply, err := resp.NewReader(&buf).ReadReply()
+	require.NoError(t, err, "expect valid reply")
+
+	return reply
+}
+
+func TestStorageHandler_Set(t *testing.T) {
+	h, _ := newTestHandler(t)
+
+	assert.Nil(t, do(t, h, "GET", "k"), "expect null for missing key")
+	assert.Equal(t, "OK", do(t, h, "SET", "k", "v"), "expect SET to succeed")
+	assert.Equal(t, "v", do(t, h, "GET", "k"), "expect stored value")
+
+	assert.Nil(t, do(t, h, "SET", "k", "x", "NX"), "expect NX to skip existing key")
+	assert.Equal(t, "v", do(t, h, "GET", "k"), "expect value kept by NX")
+	assert.Equal(t, "OK", do(t, h, "SET", "n", "x", "NX"), "expect NX to set missing key")
+
+	assert.Nil(t, do(t, h, "SET", "missing", "x", "XX"), "expect XX to skip missing key")
+	assert.Nil(t, do(t, h, "GET", "missing"), "expect XX not to create key")
+	assert.Equal(t, "OK", do(t, h, "SET", "k", "x", "XX"), "expect XX to set existing key")
+	assert.Equal(t, "x", do(t, h, "GET", "k"), "expect value set by XX")
+
+	testCases := []struct {
+		name  string
+		args  []string
+		reply resp.ReplyError
+	}{
+		{"NX with XX", []string{"SET", "k", "v", "NX", "XX"}, resp.ReplyError(errSyntax)},
+		{"EX with KEEPTTL", []string{"SET", "k", "v", "EX", "1", "KEEPTTL"}, resp.ReplyError(errSyntax)},
+		{"EX with PX", []string{"SET", "k", "v", "EX", "1", "PX", "1"}, resp.ReplyError(errSyntax)},
+		{"EX without value", []string{"SET", "k", "v", "EX"}, resp.ReplyError(errSyntax)},
+		{"zero EX", []string{"SET", "k", "v", "EX", "0"}, resp.ReplyError(errInvalidTTL)},
+		{"EX overflow", []string{"SET", "k", "v", "EX", strconv.FormatInt(1<<62, 10)}, resp.ReplyError(errInvalidTTL)},
+		{"non integer EX", []string{"SET", "k", "v", "EX", "x"}, resp.ReplyError(errNotInteger)},
+		{"unknown option", []string{"SET", "k", "v", "GET"}, resp.ReplyError(errSyntax)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.reply, do(t, h, tc.args...), "Unexpected reply")
+		})
+	}
+}
+
+func TestStorageHandler_TTL(t *testing.T) {
+	h, st := newTestHandler(t)
+	ctx := context.Background()
+
+	assert.Equal(t, int64(-2), do(t, h, "TTL", "k"), "expect -2 for missing key")
+
+	do(t, h, "SET", "k", "v", "EX", "100")
+	assert.Equal(t, int64(100), do(t, h, "TTL", "k"), "expect TTL set by EX")
+
+	do(t, h, "SET", "k", "5", "KEEPTTL")
+	assert.Equal(t, int64(100), do(t, h, "TTL", "k"), "expect KEEPTTL to keep TTL")
+
+	assert.Equal(t, int64(6), do(t, h, "INCR", "k"), "expect INCR to succeed")
+	assert.Equal(t, int64(100), do(t, h, "TTL", "k"), "expect INCR to keep TTL")
+
+	do(t, h, "SET", "k", "v")
+	assert.Equal(t, int64(-1), do(t, h, "TTL", "k"), "expect SET to clear TTL")
+
+	assert.Equal(t, int64(0), do(t, h, "EXPIRE", "missing", "10"), "expect 0 for missing key")
+	assert.Equal(t, int64(1), do(t, h, "EXPIRE", "k", "10"), "expect EXPIRE to set TTL")
+	assert.Equal(t, int64(10), do(t, h, "TTL", "k"), "expect TTL set by EXPIRE")
+
+	_, err := st.Set(ctx, "k", "foreign")
+	require.NoError(t, err, "expect no error on set")
+	assert.Equal(t, int64(-1), do(t, h, "TTL", "k"), "expect write over other transport to clear TTL")
+
+	assert.Equal(t, int64(1), do(t, h, "EXPIRE", "k", "-1"), "expect non-positive EXPIRE to succeed")
+	assert.Nil(t, do(t, h, "GET", "k"), "expect non-positive EXPIRE to delete key")
+
+	do(t, h, "SET", "k", "v", "PX", "1")
+	time.Sleep(5 * time.Millisecond)
+	assert.Nil(t, do(t, h, "GET", "k"), "expect expired key not to be found")
+	assert.Equal(t, int64(0), do(t, h, "EXISTS", "k"), "expect expired key not to exist")
+}
+
+func TestStorageHandler_Incr(t *testing.T) {
+	h, _ := newTestHandler(t)
+
+	assert.Equal(t, int64(1), do(t, h, "INCR", "n"), "expect missing key to start at zero")
+	assert.Equal(t, int64(-9), do(t, h, "INCRBY", "n", "-10"), "expect INCRBY to add delta")
+	assert.Equal(t, "-9", do(t, h, "GET", "n"), "expect value stored as string")
+
+	do(t, h, "SET", "s", "abc")
+	assert.Equal(t, resp.ReplyError(errNotInteger), do(t, h, "INCR", "s"), "expect error for non integer value")
+	assert.Equal(t, resp.ReplyError(errNotInteger), do(t, h, "INCRBY", "n", "x"), "expect error for non integer delta")
+
+	do(t, h, "SET", "max", strconv.FormatInt(1<<63-1, 10))
+	assert.Equal(t, resp.ReplyError(errOverflow), do(t, h, "INCR", "max"), "expect overflow error")
+	do(t, h, "SET", "min", strconv.FormatInt(-1<<63, 10))
+	assert.Equal(t, resp.ReplyError(errOverflow), do(t, h, "INCRBY", "min", "-1"), "expect underflow error")
+	assert.Equal(t, strconv.FormatInt(-1<<63, 10), do(t, h, "GET", "min"), "expect failed INCRBY to keep value")
+}
+
+func TestStorageHandler_DelExists(t *testing.T) {
+	h, _ := newTestHandler(t)
+
+	do(t, h, "SET", "a", "1")
+	do(t, h, "SET", "b", "1")
+
+	assert.Equal(t, int64(3), do(t, h, "EXISTS", "a", "a", "b", "c"), "expect every passed existing key to be counted")
+	assert.Equal(t, int64(2), do(t, h, "DEL", "a", "b", "c"), "expect existing keys to be counted")
+	assert.Equal(t, int64(0), do(t, h, "EXISTS", "a", "b"), "expect deleted keys not to exist")
+}
+
+func TestStorageHandler_Scan(t *testing.T) {
+	h, _ := newTestHandler(t)
+
+	for i := range 25 {
+		do(t, h, "SET", fmt.Sprintf("key:%02d", i), "v")
+	}
+	do(t, h, "SET", "other", "v")
+	do(t, h, "SET", "gone", "v", "PX", "1")
+	time.Sleep(5 * time.Millisecond)
+
+	var keys []any
+	cursor := "0"
+	for i := 0; ; i++ {
+		require.Less(t, i, 10, "expect iteration to finish")
+
+		reply, ok := do(t, h, "SCAN", cursor, "MATCH", "key:*", "COUNT", "10").([]any)
+		require.True(t, ok, "expect array reply")
+		require.Len(t, reply, 2, "expect cursor and keys")
+
+		cursor = reply[0].(string)
+		keys = append(keys, reply[1].([]any)...)
+		if cursor == "0" {
+			break
+		}
+	}
+	assert.Len(t, keys, 25, "expect every matching key to be returned once")
+
+	assert.Equal(t, []any{"0", []any{}}, do(t, h, "SCAN", "0", "TYPE", "hash"), "expect no keys of other types")
+	assert.Equal(t, resp.ReplyError(errInvalidCursor), do(t, h, "SCAN", "x"), "expect invalid cursor error")
+	assert.Equal(t, resp.ReplyError(errSyntax), do(t, h, "SCAN", "0", "COUNT", "0"), "expect invalid count error")
+
+	assert.Equal(t, []any{"other"}, do(t, h, "KEYS", "o*"), "expect KEYS to match pattern")
+	assert.Empty(t, do(t, h, "KEYS", "gone"), "expect expired key to be skipped")
+}
+
+func TestMatchGlob(t *testing.T) {
+	testCases := []struct {
+		pattern, key string
+		match        bool
+	}{
+		{"*", "anything", true},
+		{"k*", "key", true},
+		{"k*y", "key", true},
+		{"k*z", "key", false},
+		{"k?y", "key", true},
+		{"k?y", "ky", false},
+		{"k[a-f]y", "key", true},
+		{"k[^a-f]y", "key", false},
+		{"k[xe]y", "key", true},
+		{`k\*y`, "k*y", true},
+		{`k\*y`, "key", false},
+		{"k[ey", "k[ey", true},
+		{"", "", true},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.match, matchGlob(tc.pattern, tc.key), "Unexpected match of [%s] against [%s]", tc.key, tc.pattern)
+	}
+}
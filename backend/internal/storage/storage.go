@@ -1,10 +1,94 @@
 package storage
 
 import (
-	"github.com/KennyMacCormik/otel/backend/pkg/cache"
+	"github.com/prometheus/client_golang/prometheus"
+
+	initApp "github.com/KennyMacCormik/otel/backend/internal/init"
 	"github.com/KennyMacCormik/otel/backend/pkg/cache/impl/sync_map"
+	"github.com/KennyMacCormik/otel/backend/pkg/cache/wrappers/compressed_cache"
+	"github.com/KennyMacCormik/otel/backend/pkg/cache/wrappers/encrypted_cache"
+	"github.com/KennyMacCormik/otel/backend/pkg/cache/wrappers/meta_cache"
+	"github.com/KennyMacCormik/otel/backend/pkg/cache/wrappers/quota_cache"
+	"github.com/KennyMacCormik/otel/backend/pkg/cache/wrappers/watch_cache"
+	"github.com/KennyMacCormik/otel/backend/pkg/cache/wrappers/write_limit_cache"
 )
 
-func NewStorage() cache.CacheInterface {
-	return sync_map.NewSyncMapCache()
+// NewStorage builds storage according to conf.
+// Values are compressed before they are encrypted, as ciphertext doesn't compress.
+// Quotas and write limits apply to values as they were written, rejected writes don't reach watchers.
+// Write limits are checked first, so rate limited writes don't touch quota usage.
+// Changes are watched above every layer changing values, so watchers get values as they were written.
+// Expirations and flags are kept on top of the watched layer, so removal of expired keys reaches watchers.
+// Usage is nil if quotas are disabled, otherwise it is exported to prometheus.DefaultRegisterer.
+// Compression stats are exported there as well if compression is enabled.
+func NewStorage(conf *initApp.Config) (meta_cache.MetaCache, quota_cache.UsageGetter, error) {
+	var err error
+	var usage quota_cache.UsageGetter
+
+	st := sync_map.NewSyncMapCache()
+
+	if conf.Encryption.Keyring != nil {
+		st, err = encrypted_cache.NewEncryptedCache(st, conf.Encryption.Keyring)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if compressed_cache.Algorithm(conf.Compression.Algorithm) != compressed_cache.AlgorithmNone {
+		st, err = compressed_cache.NewCompressedCache(st,
+			compressed_cache.WithOverrideDefaults(
+				compressed_cache.Algorithm(conf.Compression.Algorithm),
+				conf.Compression.Threshold,
+			),
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		err = prometheus.Register(compressed_cache.NewCollector(st.(compressed_cache.StatsGetter)))
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if conf.Quota.Enabled {
+		st, err = quota_cache.NewQuotaCache(st, quota_cache.WithQuotas(conf.Quota.Default, conf.Quota.Quotas))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		usage = st.(quota_cache.UsageGetter)
+		err = prometheus.Register(quota_cache.NewCollector(usage))
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if conf.WriteLimit.Limit > 0 || conf.WriteLimit.MaxValueSize > 0 {
+		st, err = write_limit_cache.NewWriteLimitCache(st,
+			write_limit_cache.WithOverrideDefaults(
+				conf.WriteLimit.Limit,
+				conf.WriteLimit.Window,
+				conf.WriteLimit.Burst,
+				conf.WriteLimit.MaxValueSize,
+			),
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if conf.Grpc.Enabled {
+		st, err = watch_cache.NewWatchCache(st, watch_cache.WithOverrideDefaults(conf.Grpc.WatchBufferSize))
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	mc, err := meta_cache.NewMetaCache(st)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return mc, usage, nil
 }
@@ -0,0 +1,54 @@
+package cache
+
+import (
+	cacheErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/cache"
+)
+
+const (
+	codecTagString byte = 's'
+	codecTagBytes  byte = 'b'
+)
+
+type defaultCodec struct{}
+
+// NewDefaultCodec returns Codec that supports string and []byte values.
+// Serialized data is prefixed with a single type tag, so the original type is restored by Unmarshal.
+func NewDefaultCodec() Codec {
+	return defaultCodec{}
+}
+
+func (defaultCodec) Marshal(value any) ([]byte, error) {
+	const wrap = "defaultCodec/Marshal"
+
+	switch v := value.(type) {
+	case string:
+		b := make([]byte, 0, len(v)+1)
+		b = append(b, codecTagString)
+		return append(b, v...), nil
+	case []byte:
+		b := make([]byte, 0, len(v)+1)
+		b = append(b, codecTagBytes)
+		return append(b, v...), nil
+	default:
+		return nil, cacheErrors.NewErrInvalidValue(value, cacheErrors.ErrUnsupportedType, wrap)
+	}
+}
+
+func (defaultCodec) Unmarshal(data []byte) (any, error) {
+	const wrap = "defaultCodec/Unmarshal"
+
+	if len(data) == 0 {
+		return nil, cacheErrors.NewErrInvalidValue(data, cacheErrors.ErrMalformedData, wrap)
+	}
+
+	switch data[0] {
+	case codecTagString:
+		return string(data[1:]), nil
+	case codecTagBytes:
+		b := make([]byte, len(data)-1)
+		copy(b, data[1:])
+		return b, nil
+	default:
+		return nil, cacheErrors.NewErrInvalidValue(data, cacheErrors.ErrMalformedData, wrap)
+	}
+}
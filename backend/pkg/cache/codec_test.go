@@ -0,0 +1,53 @@
+package cache
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	cache2 "github.com/KennyMacCormik/otel/backend/pkg/models/errors/cache"
+)
+
+func TestDefaultCodec_String(t *testing.T) {
+	c := NewDefaultCodec()
+
+	b, err := c.Marshal("value")
+	require.NoError(t, err, "Marshal should not return an error for a string")
+
+	val, err := c.Unmarshal(b)
+	require.NoError(t, err, "Unmarshal should not return an error for a marshaled string")
+	assert.Equal(t, "value", val, "Unmarshal should restore the original string")
+}
+
+func TestDefaultCodec_Bytes(t *testing.T) {
+	c := NewDefaultCodec()
+
+	b, err := c.Marshal([]byte("value"))
+	require.NoError(t, err, "Marshal should not return an error for []byte")
+
+	val, err := c.Unmarshal(b)
+	require.NoError(t, err, "Unmarshal should not return an error for marshaled []byte")
+	assert.Equal(t, []byte("value"), val, "Unmarshal should restore the original []byte")
+
+	b[len(b)-1] = 'X'
+	assert.Equal(t, []byte("value"), val, "Unmarshal should not retain the input slice")
+}
+
+func TestDefaultCodec_UnsupportedType(t *testing.T) {
+	_, err := NewDefaultCodec().Marshal(42)
+	require.Error(t, err, "Marshal should return an error for unsupported type")
+	assert.ErrorIs(t, err, cache2.ErrUnsupportedType, "Error should indicate unsupported type")
+}
+
+func TestDefaultCodec_MalformedData(t *testing.T) {
+	c := NewDefaultCodec()
+
+	_, err := c.Unmarshal(nil)
+	require.Error(t, err, "Unmarshal should return an error for empty data")
+	assert.ErrorIs(t, err, cache2.ErrMalformedData, "Error should indicate malformed data")
+
+	_, err = c.Unmarshal([]byte("?value"))
+	require.Error(t, err, "Unmarshal should return an error for unknown type tag")
+	assert.ErrorIs(t, err, cache2.ErrMalformedData, "Error should indicate malformed data")
+}
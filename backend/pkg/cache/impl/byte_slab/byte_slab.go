@@ -0,0 +1,500 @@
+package byte_slab
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/KennyMacCormik/otel/backend/pkg/cache"
+	byteSlabErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/byte_slab"
+	cacheErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/cache"
+)
+
+const (
+	defaultShardNumber   int64 = 16
+	defaultShardSize     int64 = 1 << 20 // 1 MiB
+	defaultTTL                 = time.Duration(0)
+	defaultCleanInterval       = 30 * time.Second
+
+	maxShardSize int64 = math.MaxUint32
+	maxKeyLength       = math.MaxUint16
+)
+
+// entry header layout: expiresAt (8) | hash (8) | keyLen (2) | valLen (4)
+const (
+	expiresAtOffset = 0
+	hashOffset      = 8
+	keyLenOffset    = 16
+	valLenOffset    = 18
+	headerSize      = 22
+)
+
+// byteSlab stores serialized values in pre-allocated byte slabs.
+// Each shard keeps its entries in a FIFO ring buffer and indexes them with map[uint64]uint32,
+// which contains no pointers and therefore isn't scanned by GC.
+// When a shard runs out of space, the oldest entries are evicted.
+// Hash collisions are resolved in favour of the latest written key.
+type byteSlab struct {
+	shards []*shard
+	codec  cache.Codec
+
+	shardNumber, shardSize int64
+	ttl, cleanInterval     time.Duration
+
+	ticker    *time.Ticker
+	closeOnce sync.Once
+	closed    atomic.Bool
+	closeCh   chan struct{}
+}
+
+type shard struct {
+	mtx sync.RWMutex
+
+	buf   []byte
+	index map[uint64]uint32
+
+	// head points at the oldest entry, tail at the next write position.
+	// wrapAt marks the end of valid data when tail has wrapped around to the start of buf.
+	head, tail, wrapAt uint32
+	entries            int64
+}
+
+type InitOptions func(b *byteSlab)
+
+// WithOverrideDefaults sets number of shards, size of each shard in bytes,
+// entries ttl and expired entries clean interval. Zero ttl means entries never expire.
+func WithOverrideDefaults(shardNumber, shardSize int64, ttl, cleanInterval time.Duration) InitOptions {
+	return func(b *byteSlab) {
+		if shardNumber < 1 {
+			shardNumber = defaultShardNumber
+		}
+
+		if shardSize <= headerSize || shardSize > maxShardSize {
+			shardSize = defaultShardSize
+		}
+
+		if ttl < 0 {
+			ttl = defaultTTL
+		}
+
+		if cleanInterval <= 0 {
+			cleanInterval = defaultCleanInterval
+		}
+
+		b.shardNumber = shardNumber
+		b.shardSize = shardSize
+		b.ttl = ttl
+		b.cleanInterval = cleanInterval
+	}
+}
+
+// WithCodec replaces cache.NewDefaultCodec used to serialize values
+func WithCodec(codec cache.Codec) InitOptions {
+	return func(b *byteSlab) {
+		if codec != nil {
+			b.codec = codec
+		}
+	}
+}
+
+func NewByteSlabCache(opts ...InitOptions) cache.CacheInterface {
+	b := &byteSlab{
+		codec:         cache.NewDefaultCodec(),
+		shardNumber:   defaultShardNumber,
+		shardSize:     defaultShardSize,
+		ttl:           defaultTTL,
+		cleanInterval: defaultCleanInterval,
+		closeCh:       make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	b.shards = make([]*shard, b.shardNumber)
+	for i := range b.shards {
+		b.shards[i] = newShard(b.shardSize)
+	}
+
+	if b.ttl > 0 {
+		b.ticker = time.NewTicker(b.cleanInterval)
+		go b.cleanUp()
+	}
+
+	return b
+}
+
+func newShard(size int64) *shard {
+	return &shard{
+		buf:    make([]byte, size),
+		index:  make(map[uint64]uint32),
+		wrapAt: uint32(size),
+	}
+}
+
+func (b *byteSlab) Get(ctx context.Context, key string) (any, error) {
+	const wrap = "byteSlab/Get"
+
+	if err := cache.ValidateInput(
+		cache.WithClosedValidation(&b.closed, wrap),
+		cache.WithCtxValidation(ctx, wrap),
+		cache.WithKeyValidation(key, wrap),
+	); err != nil {
+		return nil, err
+	}
+
+	hash := hashKey(key)
+	s := b.getShard(hash)
+	now := getTime().UnixNano()
+
+	s.mtx.RLock()
+	data, ok, expired := s.get(key, hash, now)
+	var (
+		val any
+		err error
+	)
+	if ok {
+		// data points into the slab, so it must be decoded before the lock is released
+		val, err = b.codec.Unmarshal(data)
+	}
+	s.mtx.RUnlock()
+
+	if expired {
+		s.mtx.Lock()
+		s.deleteExpired(key, hash, now)
+		s.mtx.Unlock()
+	}
+
+	if !ok {
+		return nil, cacheErrors.NewErrKeyNotFound(key)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", wrap, err)
+	}
+
+	return val, nil
+}
+
+func (b *byteSlab) Set(ctx context.Context, key string, value any) (int, error) {
+	const wrap = "byteSlab/Set"
+
+	if err := cache.ValidateInput(
+		cache.WithClosedValidation(&b.closed, wrap),
+		cache.WithCtxValidation(ctx, wrap),
+		cache.WithKeyValidation(key, wrap),
+		cache.WithValueValidation(value, wrap),
+	); err != nil {
+		return 0, err
+	}
+
+	if len(key) > maxKeyLength {
+		return 0, cacheErrors.NewErrInvalidValue(key, byteSlabErrors.ErrKeyTooLong, wrap)
+	}
+
+	data, err := b.codec.Marshal(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", wrap, err)
+	}
+
+	if int64(headerSize+len(key)+len(data)) > b.shardSize {
+		return 0, cacheErrors.NewErrInvalidValue(key, byteSlabErrors.ErrEntryTooLarge, wrap)
+	}
+
+	hash := hashKey(key)
+	s := b.getShard(hash)
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	// slabs are released by Close
+	if s.buf == nil {
+		return 0, fmt.Errorf("%s: %w", wrap, cacheErrors.ErrCacheClosed)
+	}
+
+	return s.set(key, hash, data, b.getExpiresAt()), nil
+}
+
+func (b *byteSlab) Delete(ctx context.Context, key string) error {
+	const wrap = "byteSlab/Delete"
+
+	if err := cache.ValidateInput(
+		cache.WithClosedValidation(&b.closed, wrap),
+		cache.WithCtxValidation(ctx, wrap),
+		cache.WithKeyValidation(key, wrap),
+	); err != nil {
+		return err
+	}
+
+	hash := hashKey(key)
+	s := b.getShard(hash)
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if offset, ok := s.index[hash]; ok && s.readKey(offset) == key {
+		delete(s.index, hash)
+	}
+
+	return nil
+}
+
+// Close stops the cache and releases all slabs. It never returns an error.
+func (b *byteSlab) Close(_ context.Context) error {
+	b.closeOnce.Do(func() {
+		b.closed.Store(true)
+		close(b.closeCh)
+
+		for _, s := range b.shards {
+			s.mtx.Lock()
+			s.buf = nil
+			s.index = nil
+			s.mtx.Unlock()
+		}
+	})
+
+	return nil
+}
+
+func (b *byteSlab) GetLength() (int64, error) {
+	const wrap = "byteSlab/GetLength"
+
+	if err := cache.ValidateInput(
+		cache.WithClosedValidation(&b.closed, wrap),
+	); err != nil {
+		return 0, err
+	}
+
+	var i int64
+
+	for _, s := range b.shards {
+		s.mtx.RLock()
+		i += int64(len(s.index))
+		s.mtx.RUnlock()
+	}
+
+	return i, nil
+}
+
+func (b *byteSlab) GetKeys(ctx context.Context) ([]string, error) {
+	const wrap = "byteSlab/GetKeys"
+
+	if err := cache.ValidateInput(
+		cache.WithClosedValidation(&b.closed, wrap),
+		cache.WithCtxValidation(ctx, wrap),
+	); err != nil {
+		return nil, err
+	}
+
+	ln, err := b.GetLength()
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, ln)
+	now := getTime().UnixNano()
+
+	for _, s := range b.shards {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("%s: %w", wrap, ctx.Err())
+		}
+
+		s.mtx.RLock()
+		for _, offset := range s.index {
+			if s.isExpired(offset, now) {
+				continue
+			}
+			keys = append(keys, s.readKey(offset))
+		}
+		s.mtx.RUnlock()
+	}
+
+	return keys, nil
+}
+
+func (b *byteSlab) getShard(hash uint64) *shard {
+	return b.shards[hash%uint64(b.shardNumber)]
+}
+
+func (b *byteSlab) getExpiresAt() int64 {
+	if b.ttl <= 0 {
+		return 0
+	}
+
+	return getTime().Add(b.ttl).UnixNano()
+}
+
+// cleanUp periodically removes expired entries from the index.
+// Their space is reclaimed once the ring buffer reaches them.
+func (b *byteSlab) cleanUp() {
+	for {
+		select {
+		case <-b.ticker.C:
+			now := getTime().UnixNano()
+			for _, s := range b.shards {
+				s.mtx.Lock()
+				for hash, offset := range s.index {
+					if s.isExpired(offset, now) {
+						delete(s.index, hash)
+					}
+				}
+				s.mtx.Unlock()
+			}
+		case <-b.closeCh:
+			b.ticker.Stop()
+			return
+		}
+	}
+}
+
+// get returns a view of the serialized value. It must be called under the read lock.
+func (s *shard) get(key string, hash uint64, now int64) (data []byte, ok, expired bool) {
+	offset, ok := s.index[hash]
+	if !ok || s.readKey(offset) != key {
+		return nil, false, false
+	}
+
+	if s.isExpired(offset, now) {
+		return nil, false, true
+	}
+
+	return s.readValue(offset), true, false
+}
+
+// set stores the serialized value and returns http-like code just like other cache.CacheInterface implementations.
+// It must be called under the write lock.
+func (s *shard) set(key string, hash uint64, data []byte, expiresAt int64) int {
+	code := 201
+
+	if offset, ok := s.index[hash]; ok && s.readKey(offset) == key && !s.isExpired(offset, getTime().UnixNano()) {
+		if string(s.readValue(offset)) == string(data) {
+			// same value, only prolong ttl
+			binary.LittleEndian.PutUint64(s.buf[offset+expiresAtOffset:], uint64(expiresAt))
+			return 204
+		}
+		code = 200
+	}
+
+	offset := s.alloc(uint32(headerSize + len(key) + len(data)))
+
+	binary.LittleEndian.PutUint64(s.buf[offset+expiresAtOffset:], uint64(expiresAt))
+	binary.LittleEndian.PutUint64(s.buf[offset+hashOffset:], hash)
+	binary.LittleEndian.PutUint16(s.buf[offset+keyLenOffset:], uint16(len(key)))
+	binary.LittleEndian.PutUint32(s.buf[offset+valLenOffset:], uint32(len(data)))
+	copy(s.buf[offset+headerSize:], key)
+	copy(s.buf[offset+headerSize+uint32(len(key)):], data)
+
+	s.index[hash] = offset
+	s.entries++
+
+	return code
+}
+
+// alloc reserves size contiguous bytes in the ring buffer evicting the oldest entries if required.
+// Caller guarantees that size fits into the shard.
+func (s *shard) alloc(size uint32) uint32 {
+	capacity := uint32(len(s.buf))
+
+	for {
+		if s.entries == 0 {
+			s.head, s.tail, s.wrapAt = 0, 0, capacity
+		}
+
+		switch {
+		case s.entries == 0 || s.tail > s.head:
+			if size <= capacity-s.tail {
+				return s.advanceTail(size)
+			}
+			if size <= s.head {
+				s.wrapAt = s.tail
+				s.tail = 0
+				return s.advanceTail(size)
+			}
+		case s.tail < s.head:
+			if size <= s.head-s.tail {
+				return s.advanceTail(size)
+			}
+		}
+
+		s.evictOldest()
+	}
+}
+
+func (s *shard) advanceTail(size uint32) uint32 {
+	offset := s.tail
+	s.tail += size
+	return offset
+}
+
+// evictOldest drops the entry at head. The index is only updated when it still points to the entry.
+func (s *shard) evictOldest() {
+	offset := s.head
+	hash := binary.LittleEndian.Uint64(s.buf[offset+hashOffset:])
+
+	if idx, ok := s.index[hash]; ok && idx == offset {
+		delete(s.index, hash)
+	}
+
+	s.head += s.entrySize(offset)
+	s.entries--
+
+	if s.head == s.wrapAt && s.entries > 0 {
+		s.head = 0
+		s.wrapAt = uint32(len(s.buf))
+	}
+}
+
+// deleteExpired removes key from the index if it is still expired. It must be called under the write lock.
+func (s *shard) deleteExpired(key string, hash uint64, now int64) {
+	if offset, ok := s.index[hash]; ok && s.readKey(offset) == key && s.isExpired(offset, now) {
+		delete(s.index, hash)
+	}
+}
+
+func (s *shard) isExpired(offset uint32, now int64) bool {
+	expiresAt := int64(binary.LittleEndian.Uint64(s.buf[offset+expiresAtOffset:]))
+	return expiresAt != 0 && now > expiresAt
+}
+
+func (s *shard) entrySize(offset uint32) uint32 {
+	keyLen := uint32(binary.LittleEndian.Uint16(s.buf[offset+keyLenOffset:]))
+	valLen := binary.LittleEndian.Uint32(s.buf[offset+valLenOffset:])
+	return headerSize + keyLen + valLen
+}
+
+func (s *shard) readKey(offset uint32) string {
+	keyLen := uint32(binary.LittleEndian.Uint16(s.buf[offset+keyLenOffset:]))
+	start := offset + headerSize
+	return string(s.buf[start : start+keyLen])
+}
+
+func (s *shard) readValue(offset uint32) []byte {
+	keyLen := uint32(binary.LittleEndian.Uint16(s.buf[offset+keyLenOffset:]))
+	valLen := binary.LittleEndian.Uint32(s.buf[offset+valLenOffset:])
+	start := offset + headerSize + keyLen
+	return s.buf[start : start+valLen]
+}
+
+// hashKey is an allocation-free FNV-1a
+func hashKey(key string) uint64 {
+	const (
+		offset64 uint64 = 14695981039346656037
+		prime64  uint64 = 1099511628211
+	)
+
+	hash := offset64
+	for i := 0; i < len(key); i++ {
+		hash ^= uint64(key[i])
+		hash *= prime64
+	}
+
+	return hash
+}
+
+func getTime() time.Time {
+	return time.Now()
+}
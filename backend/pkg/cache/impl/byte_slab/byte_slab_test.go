@@ -0,0 +1,271 @@
+package byte_slab
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KennyMacCormik/otel/backend/pkg/cache"
+	byteSlabErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/byte_slab"
+	cache2 "github.com/KennyMacCormik/otel/backend/pkg/models/errors/cache"
+)
+
+const (
+	testShardNumber   int64 = 4
+	testShardSize     int64 = 4096
+	testCleanInterval       = 50 * time.Millisecond
+)
+
+func initByteSlab(ttl time.Duration) cache.CacheInterface {
+	return NewByteSlabCache(WithOverrideDefaults(testShardNumber, testShardSize, ttl, testCleanInterval))
+}
+
+func typeCastCache(t *testing.T, c cache.CacheInterface) *byteSlab {
+	b, ok := c.(*byteSlab)
+	require.True(t, ok, "type cast shall succeed")
+	return b
+}
+
+func TestByteSlab_New(t *testing.T) {
+	t.Run("init", func(t *testing.T) {
+		c := NewByteSlabCache()
+		require.NotNil(t, c, "cache shall be created")
+		assert.Implements(t, (*cache.CacheInterface)(nil), c, "cache shall implement cache interface")
+
+		impl := typeCastCache(t, c)
+		assert.Equal(t, defaultShardNumber, impl.shardNumber, "cache shall have shardNumber = defaultShardNumber")
+		assert.Equal(t, defaultShardSize, impl.shardSize, "cache shall have shardSize = defaultShardSize")
+		assert.Equal(t, defaultTTL, impl.ttl, "cache shall have ttl = defaultTTL")
+		assert.Equal(t, defaultCleanInterval, impl.cleanInterval, "cache shall have cleanInterval = defaultCleanInterval")
+		assert.Len(t, impl.shards, int(defaultShardNumber), "cache shall allocate all shards")
+		assert.Len(t, impl.shards[0].buf, int(defaultShardSize), "shard slab shall be pre-allocated")
+	})
+
+	t.Run("with override defaults", func(t *testing.T) {
+		impl := typeCastCache(t, initByteSlab(time.Minute))
+		defer func() { _ = impl.Close(context.Background()) }()
+
+		assert.Equal(t, testShardNumber, impl.shardNumber, "cache shall have shardNumber = testShardNumber")
+		assert.Equal(t, testShardSize, impl.shardSize, "cache shall have shardSize = testShardSize")
+		assert.Equal(t, time.Minute, impl.ttl, "cache shall have ttl = time.Minute")
+		assert.Equal(t, testCleanInterval, impl.cleanInterval, "cache shall have cleanInterval = testCleanInterval")
+	})
+
+	t.Run("with incorrect settings", func(t *testing.T) {
+		impl := typeCastCache(t, NewByteSlabCache(WithOverrideDefaults(-1, -1, -1, -1)))
+
+		assert.Equal(t, defaultShardNumber, impl.shardNumber, "cache shall have shardNumber = defaultShardNumber")
+		assert.Equal(t, defaultShardSize, impl.shardSize, "cache shall have shardSize = defaultShardSize")
+		assert.Equal(t, defaultTTL, impl.ttl, "cache shall have ttl = defaultTTL")
+		assert.Equal(t, defaultCleanInterval, impl.cleanInterval, "cache shall have cleanInterval = defaultCleanInterval")
+	})
+}
+
+func TestByteSlab_Set(t *testing.T) {
+	t.Run("positive", func(t *testing.T) {
+		c := initByteSlab(0)
+
+		code, err := c.Set(context.Background(), "key1", "value1")
+		require.NoError(t, err, "Shall return no error for valid input")
+		assert.Equal(t, 201, code, "Shall return code 201")
+
+		code, err = c.Set(context.Background(), "key1", "value1")
+		require.NoError(t, err, "Shall return no error for valid input")
+		assert.Equal(t, 204, code, "Shall return code 204")
+
+		code, err = c.Set(context.Background(), "key1", "value2")
+		require.NoError(t, err, "Shall return no error for valid input")
+		assert.Equal(t, 200, code, "Shall return code 200")
+
+		ln, err := c.GetLength()
+		require.NoError(t, err, "Shall return no error for GetLength()")
+		assert.Equal(t, int64(1), ln, "Same length shall be stored")
+	})
+
+	t.Run("unsupported type", func(t *testing.T) {
+		c := initByteSlab(0)
+
+		_, err := c.Set(context.Background(), "key1", 42)
+		require.Error(t, err, "Shall return error for unsupported type")
+		assert.ErrorIs(t, err, cache2.ErrUnsupportedType, "Shall return cache.ErrUnsupportedType")
+	})
+
+	t.Run("entry too large", func(t *testing.T) {
+		c := initByteSlab(0)
+
+		_, err := c.Set(context.Background(), "key1", string(make([]byte, testShardSize)))
+		require.Error(t, err, "Shall return error for entry larger than shard")
+		assert.ErrorIs(t, err, byteSlabErrors.ErrEntryTooLarge, "Shall return byte_slab.ErrEntryTooLarge")
+	})
+
+	t.Run("cache closed", func(t *testing.T) {
+		c := initByteSlab(0)
+		require.NoError(t, c.Close(context.Background()), "Shall return no error for Close()")
+
+		_, err := c.Set(context.Background(), "key1", "value1")
+		require.Error(t, err, "Shall return error for closed cache")
+		assert.ErrorIs(t, err, cache2.ErrCacheClosed, "Shall return cache.ErrCacheClosed")
+	})
+
+	t.Run("nil ctx", func(t *testing.T) {
+		c := initByteSlab(0)
+
+		_, err := c.Set(nil, "key1", "value1")
+		require.Error(t, err, "Shall return error for nil ctx")
+		assert.ErrorIs(t, err, cache2.NewErrNilOrErrCtx("", nil), "Shall return cache.ErrCtx")
+	})
+}
+
+func TestByteSlab_Get(t *testing.T) {
+	t.Run("positive", func(t *testing.T) {
+		c := initByteSlab(0)
+
+		_, err := c.Set(context.Background(), "key1", "value1")
+		require.NoError(t, err, "Shall return no error for valid input")
+		_, err = c.Set(context.Background(), "key2", []byte("value2"))
+		require.NoError(t, err, "Shall return no error for valid input")
+
+		val, err := c.Get(context.Background(), "key1")
+		require.NoError(t, err, "Shall return no error for existing key")
+		assert.Equal(t, "value1", val, "Shall return stored string")
+
+		val, err = c.Get(context.Background(), "key2")
+		require.NoError(t, err, "Shall return no error for existing key")
+		assert.Equal(t, []byte("value2"), val, "Shall return stored []byte")
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		c := initByteSlab(0)
+
+		_, err := c.Get(context.Background(), "key1")
+		require.Error(t, err, "Shall return error for missing key")
+		assert.ErrorIs(t, err, cache2.ErrNotFound, "Shall return cache.ErrNotFound")
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		c := initByteSlab(10 * time.Millisecond)
+		defer func() { _ = c.Close(context.Background()) }()
+
+		_, err := c.Set(context.Background(), "key1", "value1")
+		require.NoError(t, err, "Shall return no error for valid input")
+
+		time.Sleep(20 * time.Millisecond)
+
+		_, err = c.Get(context.Background(), "key1")
+		require.Error(t, err, "Shall return error for expired key")
+		assert.ErrorIs(t, err, cache2.ErrNotFound, "Shall return cache.ErrNotFound")
+
+		code, err := c.Set(context.Background(), "key1", "value1")
+		require.NoError(t, err, "Shall return no error for valid input")
+		assert.Equal(t, 201, code, "Shall return code 201 for expired key")
+	})
+
+	t.Run("cache closed", func(t *testing.T) {
+		c := initByteSlab(0)
+		require.NoError(t, c.Close(context.Background()), "Shall return no error for Close()")
+
+		_, err := c.Get(context.Background(), "key1")
+		require.Error(t, err, "Shall return error for closed cache")
+		assert.ErrorIs(t, err, cache2.ErrCacheClosed, "Shall return cache.ErrCacheClosed")
+	})
+}
+
+func TestByteSlab_Delete(t *testing.T) {
+	c := initByteSlab(0)
+
+	_, err := c.Set(context.Background(), "key1", "value1")
+	require.NoError(t, err, "Shall return no error for valid input")
+
+	require.NoError(t, c.Delete(context.Background(), "key1"), "Shall return no error for Delete()")
+	require.NoError(t, c.Delete(context.Background(), "key1"), "Shall return no error for missing key")
+
+	_, err = c.Get(context.Background(), "key1")
+	assert.ErrorIs(t, err, cache2.ErrNotFound, "Shall return cache.ErrNotFound for deleted key")
+}
+
+func TestByteSlab_Eviction(t *testing.T) {
+	c := NewByteSlabCache(WithOverrideDefaults(1, 256, 0, 0))
+	impl := typeCastCache(t, c)
+
+	for i := 0; i < 100; i++ {
+		key := "key" + strconv.Itoa(i)
+		_, err := c.Set(context.Background(), key, "value"+strconv.Itoa(i))
+		require.NoError(t, err, "Shall return no error when shard is full")
+
+		val, err := c.Get(context.Background(), key)
+		require.NoError(t, err, "Latest value shall always be available")
+		assert.Equal(t, "value"+strconv.Itoa(i), val, "Shall return latest value")
+	}
+
+	_, err := c.Get(context.Background(), "key0")
+	assert.ErrorIs(t, err, cache2.ErrNotFound, "Oldest entries shall be evicted")
+
+	ln, err := c.GetLength()
+	require.NoError(t, err, "Shall return no error for GetLength()")
+	assert.Equal(t, impl.shards[0].entries, ln, "Index shall match live entries")
+	assert.Less(t, ln, int64(100), "Shard shall not hold all entries")
+}
+
+func TestByteSlab_CleanUp(t *testing.T) {
+	c := initByteSlab(10 * time.Millisecond)
+	defer func() { _ = c.Close(context.Background()) }()
+
+	_, err := c.Set(context.Background(), "key1", "value1")
+	require.NoError(t, err, "Shall return no error for valid input")
+
+	assert.Eventually(t, func() bool {
+		ln, _ := c.GetLength()
+		return ln == 0
+	}, time.Second, testCleanInterval, "Expired entries shall be removed in background")
+}
+
+func TestByteSlab_GetKeys(t *testing.T) {
+	c := initByteSlab(0)
+
+	for i := 0; i < 10; i++ {
+		_, err := c.Set(context.Background(), "key"+strconv.Itoa(i), "value")
+		require.NoError(t, err, "Shall return no error for valid input")
+	}
+
+	keys, err := c.GetKeys(context.Background())
+	require.NoError(t, err, "Shall return no error for GetKeys()")
+	assert.Len(t, keys, 10, "Shall return all keys")
+	assert.Contains(t, keys, "key5", "Shall return stored keys")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = c.GetKeys(ctx)
+	require.Error(t, err, "Shall return error for canceled ctx")
+}
+
+func TestByteSlab_Concurrent(t *testing.T) {
+	const numConcurrent = 100
+	c := initByteSlab(time.Minute)
+	defer func() { _ = c.Close(context.Background()) }()
+
+	t.Run("set", func(t *testing.T) {
+		for i := 0; i < numConcurrent; i++ {
+			t.Run(fmt.Sprintf("concurrent set %d", i), func(t *testing.T) {
+				t.Parallel()
+				_, err := c.Set(context.Background(), "key"+strconv.Itoa(i), "value"+strconv.Itoa(i))
+				require.NoError(t, err, "Set should not return an error")
+			})
+		}
+	})
+
+	t.Run("get", func(t *testing.T) {
+		for i := 0; i < numConcurrent; i++ {
+			t.Run(fmt.Sprintf("concurrent get %d", i), func(t *testing.T) {
+				t.Parallel()
+				res, err := c.Get(context.Background(), "key"+strconv.Itoa(i))
+				require.NoError(t, err, "Get should not return an error")
+				assert.Equal(t, "value"+strconv.Itoa(i), res, "Get should return the same value")
+			})
+		}
+	})
+}
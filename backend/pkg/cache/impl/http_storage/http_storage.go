@@ -0,0 +1,260 @@
+package http_storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+
+	"github.com/KennyMacCormik/otel/backend/pkg/cache"
+	cacheErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/cache"
+	httpStorageErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/http_storage"
+	httpModels "github.com/KennyMacCormik/otel/backend/pkg/models/http"
+	otelHelpers "github.com/KennyMacCormik/otel/backend/pkg/otel/helpers"
+)
+
+const defaultTimeout = time.Second
+
+// httpStorage is a cache.CacheInterface client of any service exposing /storage endpoints.
+// The protocol transfers values as strings, so only string and []byte values are accepted by Set,
+// and Get always returns string. The protocol can't list keys, so GetKeys and GetLength return ErrNotSupported.
+type httpStorage struct {
+	endpoint string
+	timeout  time.Duration
+	client   *http.Client
+
+	closedOnce sync.Once
+	closed     atomic.Bool
+}
+
+type InitOptions func(h *httpStorage)
+
+// WithOverrideDefaults sets request timeout. Non-positive timeout falls back to the default one.
+func WithOverrideDefaults(timeout time.Duration) InitOptions {
+	return func(h *httpStorage) {
+		if timeout <= 0 {
+			timeout = defaultTimeout
+		}
+
+		h.timeout = timeout
+	}
+}
+
+// WithHttpClient replaces the default http.Client. Client timeout is kept unless it is zero.
+func WithHttpClient(client *http.Client) InitOptions {
+	return func(h *httpStorage) {
+		if client != nil {
+			h.client = client
+		}
+	}
+}
+
+// NewHttpStorage returns cache.CacheInterface for the storage endpoint, e.g. http://backend:8080/storage
+func NewHttpStorage(endpoint string, opts ...InitOptions) (cache.CacheInterface, error) {
+	const wrap = "NewHttpStorage"
+
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, cacheErrors.NewErrInvalidValue(endpoint, httpStorageErrors.ErrInvalidEndpoint, wrap)
+	}
+
+	h := &httpStorage{endpoint: endpoint, timeout: defaultTimeout, client: &http.Client{}}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	if h.client.Timeout == 0 {
+		h.client.Timeout = h.timeout
+	}
+
+	return h, nil
+}
+
+func (h *httpStorage) Get(ctx context.Context, key string) (any, error) {
+	const wrap = "httpStorage/Get"
+	if err := cache.ValidateInput(
+		cache.WithClosedValidation(&h.closed, wrap),
+		cache.WithCtxValidation(ctx, wrap),
+		cache.WithKeyValidation(key, wrap),
+	); err != nil {
+		return nil, err
+	}
+
+	b, code, err := h.invoke(ctx, wrap, http.MethodGet, h.keyPath(key), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	switch code {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, cacheErrors.NewErrKeyNotFound(key)
+	default:
+		return nil, fmt.Errorf("%s: key [%s]: %w: %d", wrap, key, httpStorageErrors.ErrUnexpectedStatus, code)
+	}
+
+	var body httpModels.Body
+	if err = json.Unmarshal(b, &body); err != nil {
+		return nil, fmt.Errorf("%s: key [%s]: %w: %w", wrap, key, cacheErrors.ErrMalformedData, err)
+	}
+
+	return body.Val, nil
+}
+
+func (h *httpStorage) Set(ctx context.Context, key string, value any) (int, error) {
+	const wrap = "httpStorage/Set"
+	if err := cache.ValidateInput(
+		cache.WithClosedValidation(&h.closed, wrap),
+		cache.WithCtxValidation(ctx, wrap),
+		cache.WithKeyValidation(key, wrap),
+		cache.WithValueValidation(value, wrap),
+	); err != nil {
+		return 0, err
+	}
+
+	var val string
+	switch v := value.(type) {
+	case string:
+		val = v
+	case []byte:
+		val = string(v)
+	default:
+		return 0, cacheErrors.NewErrInvalidValue(value, cacheErrors.ErrUnsupportedType, wrap)
+	}
+
+	b, err := json.Marshal(httpModels.Body{Key: key, Val: val})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", wrap, err)
+	}
+
+	_, code, err := h.invoke(ctx, wrap, http.MethodPut, h.endpoint, b)
+	if err != nil {
+		return 0, err
+	}
+
+	switch code {
+	case http.StatusOK, http.StatusCreated, http.StatusNoContent:
+		return code, nil
+	default:
+		return 0, fmt.Errorf("%s: key [%s]: %w: %d", wrap, key, httpStorageErrors.ErrUnexpectedStatus, code)
+	}
+}
+
+func (h *httpStorage) Delete(ctx context.Context, key string) error {
+	const wrap = "httpStorage/Delete"
+	if err := cache.ValidateInput(
+		cache.WithClosedValidation(&h.closed, wrap),
+		cache.WithCtxValidation(ctx, wrap),
+		cache.WithKeyValidation(key, wrap),
+	); err != nil {
+		return err
+	}
+
+	_, code, err := h.invoke(ctx, wrap, http.MethodDelete, h.keyPath(key), nil)
+	if err != nil {
+		return err
+	}
+
+	switch code {
+	case http.StatusOK, http.StatusNoContent:
+		return nil
+	case http.StatusNotFound:
+		return cacheErrors.NewErrKeyNotFound(key)
+	default:
+		return fmt.Errorf("%s: key [%s]: %w: %d", wrap, key, httpStorageErrors.ErrUnexpectedStatus, code)
+	}
+}
+
+// Close marks cache as closed and releases idle connections. Remote storage is left intact.
+func (h *httpStorage) Close(_ context.Context) error {
+	h.closedOnce.Do(func() {
+		h.closed.Store(true)
+		h.client.CloseIdleConnections()
+	})
+
+	return nil
+}
+
+func (h *httpStorage) GetKeys(ctx context.Context) ([]string, error) {
+	const wrap = "httpStorage/GetKeys"
+	if err := cache.ValidateInput(
+		cache.WithClosedValidation(&h.closed, wrap),
+		cache.WithCtxValidation(ctx, wrap),
+	); err != nil {
+		return nil, err
+	}
+
+	return nil, fmt.Errorf("%s: %w", wrap, httpStorageErrors.ErrNotSupported)
+}
+
+func (h *httpStorage) GetLength() (int64, error) {
+	const wrap = "httpStorage/GetLength"
+	if err := cache.ValidateInput(
+		cache.WithClosedValidation(&h.closed, wrap),
+	); err != nil {
+		return 0, err
+	}
+
+	return 0, fmt.Errorf("%s: %w", wrap, httpStorageErrors.ErrNotSupported)
+}
+
+// keyPath returns url of the key. Key is escaped as a single path segment, as storage handlers require.
+func (h *httpStorage) keyPath(key string) string {
+	return strings.TrimSuffix(h.endpoint, "/") + "/" + url.PathEscape(key)
+}
+
+// invoke sends request with trace context and returns response body and status code
+func (h *httpStorage) invoke(ctx context.Context, spanName, method, path string, body []byte) ([]byte, int, error) {
+	ctx, span := otelHelpers.StartSpanWithCtx(ctx, spanName, spanName)
+	defer span.End()
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	r, err := http.NewRequestWithContext(ctx, method, path, reader)
+	if err != nil {
+		err = fmt.Errorf("%s: %w", spanName, err)
+		otelHelpers.SetSpanExceptionWithErr(span, err)
+		return nil, 0, err
+	}
+
+	span.SetAttributes(attribute.String("http.method", method), attribute.String("http.url", r.URL.String()))
+
+	if body != nil {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(r.Header))
+
+	resp, err := h.client.Do(r)
+	if err != nil {
+		err = fmt.Errorf("%s %s: %s: %w", method, r.URL, spanName, err)
+		otelHelpers.SetSpanExceptionWithErr(span, err)
+		return nil, 0, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	span.SetAttributes(attribute.Int("http.status_code", resp.StatusCode))
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		err = fmt.Errorf("%s %s: %s: %w", method, r.URL, spanName, err)
+		otelHelpers.SetSpanExceptionWithErr(span, err)
+		return nil, 0, err
+	}
+
+	return b, resp.StatusCode, nil
+}
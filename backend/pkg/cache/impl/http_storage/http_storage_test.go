@@ -0,0 +1,147 @@
+package http_storage
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	storageHandler "github.com/KennyMacCormik/otel/backend/internal/http/handlers/storage"
+	"github.com/KennyMacCormik/otel/backend/pkg/cache"
+	"github.com/KennyMacCormik/otel/backend/pkg/cache/impl/sync_map"
+	"github.com/KennyMacCormik/otel/backend/pkg/gin/gin_request_id"
+	cache2 "github.com/KennyMacCormik/otel/backend/pkg/models/errors/cache"
+	httpStorageErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/http_storage"
+)
+
+func getServer(t *testing.T) (*httptest.Server, cache.CacheInterface) {
+	gin.SetMode(gin.TestMode)
+
+	st := sync_map.NewSyncMapCache()
+	router := gin.New()
+	router.Use(gin_request_id.RequestIDMiddleware())
+	storageHandler.NewStorageHandler(st).GetGinHandler()(router)
+
+	srv := httptest.NewServer(router)
+	t.Cleanup(srv.Close)
+
+	return srv, st
+}
+
+func getHttpStorage(t *testing.T, endpoint string, opts ...InitOptions) cache.CacheInterface {
+	h, err := NewHttpStorage(endpoint, opts...)
+	require.NoError(t, err, "expect no error with valid endpoint")
+	require.NotNil(t, h, "expect result not nil with valid endpoint")
+	return h
+}
+
+func TestHttpStorage_New(t *testing.T) {
+	t.Run("default", func(t *testing.T) {
+		h := getHttpStorage(t, "http://localhost:8080/storage")
+		assert.Equal(t, defaultTimeout, h.(*httpStorage).client.Timeout, "expect default timeout")
+	})
+
+	t.Run("override defaults", func(t *testing.T) {
+		h := getHttpStorage(t, "http://localhost:8080/storage", WithOverrideDefaults(-1))
+		assert.Equal(t, defaultTimeout, h.(*httpStorage).client.Timeout, "expect default timeout with invalid value")
+
+		h = getHttpStorage(t, "http://localhost:8080/storage", WithOverrideDefaults(time.Minute), WithHttpClient(&http.Client{}))
+		assert.Equal(t, time.Minute, h.(*httpStorage).client.Timeout, "expect timeout to be applied to the custom client")
+	})
+
+	t.Run("invalid endpoint", func(t *testing.T) {
+		for _, endpoint := range []string{"", "storage", "://storage"} {
+			h, err := NewHttpStorage(endpoint)
+			require.Error(t, err, "expect an error with invalid endpoint")
+			assert.ErrorIs(t, err, httpStorageErrors.ErrInvalidEndpoint, "expect ErrInvalidEndpoint")
+			assert.Nil(t, h, "result should be nil with invalid endpoint")
+		}
+	})
+}
+
+func TestHttpStorage_RoundTrip(t *testing.T) {
+	srv, st := getServer(t)
+	h := getHttpStorage(t, srv.URL+"/storage")
+
+	const key = "some key?with=special&chars"
+
+	code, err := h.Set(context.Background(), key, "value")
+	require.NoError(t, err, "expect no error on Set")
+	assert.Equal(t, 201, code, "expect 201 for the first Set")
+
+	code, err = h.Set(context.Background(), key, []byte("value"))
+	require.NoError(t, err, "expect no error on Set")
+	assert.Equal(t, 204, code, "expect 204 for the same value")
+
+	val, err := h.Get(context.Background(), key)
+	require.NoError(t, err, "expect no error on Get")
+	assert.Equal(t, "value", val, "expect stored value")
+
+	val, err = st.Get(context.Background(), key)
+	require.NoError(t, err, "expect value in remote storage")
+	assert.Equal(t, "value", val, "expect stored value")
+
+	require.NoError(t, h.Delete(context.Background(), key), "expect no error on Delete")
+
+	_, err = h.Get(context.Background(), key)
+	require.Error(t, err, "expect an error for deleted key")
+	assert.ErrorIs(t, err, cache2.NewErrKeyNotFound(""), "expect ErrKeyNotFound")
+}
+
+func TestHttpStorage_Errors(t *testing.T) {
+	t.Run("unsupported type", func(t *testing.T) {
+		_, err := getHttpStorage(t, "http://localhost:8080/storage").Set(context.Background(), "key", 1)
+		require.Error(t, err, "expect an error with unsupported type")
+		assert.ErrorIs(t, err, cache2.ErrUnsupportedType, "expect ErrUnsupportedType")
+	})
+
+	t.Run("unexpected status", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		t.Cleanup(srv.Close)
+		h := getHttpStorage(t, srv.URL+"/storage")
+
+		_, err := h.Get(context.Background(), "key")
+		assert.ErrorIs(t, err, httpStorageErrors.ErrUnexpectedStatus, "expect ErrUnexpectedStatus on Get")
+
+		_, err = h.Set(context.Background(), "key", "value")
+		assert.ErrorIs(t, err, httpStorageErrors.ErrUnexpectedStatus, "expect ErrUnexpectedStatus on Set")
+
+		err = h.Delete(context.Background(), "key")
+		assert.ErrorIs(t, err, httpStorageErrors.ErrUnexpectedStatus, "expect ErrUnexpectedStatus on Delete")
+	})
+
+	t.Run("malformed body", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("not json"))
+		}))
+		t.Cleanup(srv.Close)
+
+		_, err := getHttpStorage(t, srv.URL+"/storage").Get(context.Background(), "key")
+		assert.ErrorIs(t, err, cache2.ErrMalformedData, "expect ErrMalformedData")
+	})
+
+	t.Run("not supported", func(t *testing.T) {
+		h := getHttpStorage(t, "http://localhost:8080/storage")
+
+		_, err := h.GetKeys(context.Background())
+		assert.ErrorIs(t, err, httpStorageErrors.ErrNotSupported, "expect ErrNotSupported")
+
+		_, err = h.GetLength()
+		assert.ErrorIs(t, err, httpStorageErrors.ErrNotSupported, "expect ErrNotSupported")
+	})
+
+	t.Run("closed", func(t *testing.T) {
+		h := getHttpStorage(t, "http://localhost:8080/storage")
+		require.NoError(t, h.Close(context.Background()), "expect no error on Close")
+
+		_, err := h.Get(context.Background(), "key")
+		assert.ErrorIs(t, err, cache2.ErrCacheClosed, "expect error to be cache.ErrCacheClosed")
+	})
+}
@@ -10,3 +10,11 @@ type CacheInterface interface {
 	GetKeys(ctx context.Context) ([]string, error)
 	GetLength() (int64, error)
 }
+
+// Codec converts cache values to and from their serialized form.
+// It is used by implementations and wrappers that operate on raw bytes.
+// Unmarshal must not retain data after it returns.
+type Codec interface {
+	Marshal(value any) ([]byte, error)
+	Unmarshal(data []byte) (any, error)
+}
@@ -0,0 +1,25 @@
+package cache
+
+// Unwrapper is implemented by wrappers exposing the cache they wrap
+type Unwrapper interface {
+	Unwrap() CacheInterface
+}
+
+// As returns the outermost layer of c implementing T. Layers are unwrapped while they implement Unwrapper,
+// so optional interfaces of inner layers stay reachable from outer ones.
+func As[T any](c CacheInterface) (T, bool) {
+	for c != nil {
+		if t, ok := c.(T); ok {
+			return t, true
+		}
+
+		u, ok := c.(Unwrapper)
+		if !ok {
+			break
+		}
+		c = u.Unwrap()
+	}
+
+	var zero T
+	return zero, false
+}
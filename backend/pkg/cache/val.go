@@ -88,3 +88,15 @@ func IsNotNil(value any, callerInfo string) error {
 
 	return nil
 }
+
+// ValueSize returns size of string and []byte values in bytes, other types are unsupported
+func ValueSize(value any, callerInfo string) (int64, error) {
+	switch v := value.(type) {
+	case string:
+		return int64(len(v)), nil
+	case []byte:
+		return int64(len(v)), nil
+	default:
+		return 0, cache2.NewErrInvalidValue(value, cache2.ErrUnsupportedType, callerInfo)
+	}
+}
@@ -0,0 +1,43 @@
+package compressed_cache
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	compressedWritesDesc = prometheus.NewDesc("storage_compression_compressed_writes",
+		"Number of values compressed on write", nil, nil)
+	uncompressedWritesDesc = prometheus.NewDesc("storage_compression_uncompressed_writes",
+		"Number of values stored as is as they are below the threshold", nil, nil)
+	originalBytesDesc = prometheus.NewDesc("storage_compression_original_bytes",
+		"Number of bytes of compressed values before compression", nil, nil)
+	compressedBytesDesc = prometheus.NewDesc("storage_compression_compressed_bytes",
+		"Number of bytes of compressed values after compression", nil, nil)
+	ratioDesc = prometheus.NewDesc("storage_compression_ratio",
+		"Compressed to original bytes ratio of compressed values, 1 if nothing was compressed yet", nil, nil)
+)
+
+type collector struct {
+	stats StatsGetter
+}
+
+// NewCollector returns prometheus.Collector exporting compression stats
+func NewCollector(stats StatsGetter) prometheus.Collector {
+	return &collector{stats: stats}
+}
+
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- compressedWritesDesc
+	ch <- uncompressedWritesDesc
+	ch <- originalBytesDesc
+	ch <- compressedBytesDesc
+	ch <- ratioDesc
+}
+
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	s := c.stats.GetStats()
+
+	ch <- prometheus.MustNewConstMetric(compressedWritesDesc, prometheus.CounterValue, float64(s.CompressedWrites))
+	ch <- prometheus.MustNewConstMetric(uncompressedWritesDesc, prometheus.CounterValue, float64(s.UncompressedWrites))
+	ch <- prometheus.MustNewConstMetric(originalBytesDesc, prometheus.CounterValue, float64(s.OriginalBytes))
+	ch <- prometheus.MustNewConstMetric(compressedBytesDesc, prometheus.CounterValue, float64(s.CompressedBytes))
+	ch <- prometheus.MustNewConstMetric(ratioDesc, prometheus.GaugeValue, s.Ratio())
+}
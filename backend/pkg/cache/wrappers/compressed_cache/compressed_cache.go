@@ -0,0 +1,268 @@
+package compressed_cache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/KennyMacCormik/otel/backend/pkg/cache"
+	compressedCacheModels "github.com/KennyMacCormik/otel/backend/pkg/models/compressed_cache"
+	cacheErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/cache"
+	compressedCacheErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/compressed_cache"
+)
+
+const (
+	defaultAlgorithm       = AlgorithmGzip
+	defaultThreshold int64 = 1024
+)
+
+// StatsGetter is implemented by cache.CacheInterface returned from NewCompressedCache
+type StatsGetter interface {
+	GetStats() compressedCacheModels.Stats
+}
+
+// compressedCache serializes values with cache.Codec and compresses ones exceeding the threshold.
+// Values are stored in the underlying cache as strings prefixed with the algorithm id,
+// so entries written with a different algorithm or threshold remain readable.
+type compressedCache struct {
+	impl  cache.CacheInterface
+	codec cache.Codec
+
+	algorithm   Algorithm
+	threshold   int64
+	compressors map[byte]compressor
+	zstd        *zstdCompressor
+
+	compressedWrites, uncompressedWrites, originalBytes, compressedBytes atomic.Int64
+
+	closedOnce sync.Once
+	closed     atomic.Bool
+}
+
+type InitOptions func(c *compressedCache)
+
+// WithOverrideDefaults sets compression algorithm and minimal serialized value size in bytes to be compressed
+func WithOverrideDefaults(algorithm Algorithm, threshold int64) InitOptions {
+	return func(c *compressedCache) {
+		if algorithm == "" {
+			algorithm = defaultAlgorithm
+		}
+
+		if threshold < 0 {
+			threshold = defaultThreshold
+		}
+
+		c.algorithm = algorithm
+		c.threshold = threshold
+	}
+}
+
+// WithCodec replaces cache.NewDefaultCodec used to serialize values
+func WithCodec(codec cache.Codec) InitOptions {
+	return func(c *compressedCache) {
+		if codec != nil {
+			c.codec = codec
+		}
+	}
+}
+
+func NewCompressedCache(impl cache.CacheInterface, opts ...InitOptions) (cache.CacheInterface, error) {
+	const wrap = "NewCompressedCache"
+
+	err := cache.WithValueValidation(impl, wrap)()
+	if err != nil {
+		return nil, err
+	}
+
+	c := &compressedCache{
+		impl:      impl,
+		codec:     cache.NewDefaultCodec(),
+		algorithm: defaultAlgorithm,
+		threshold: defaultThreshold,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if _, ok := algorithmIDs[c.algorithm]; !ok {
+		return nil, cacheErrors.NewErrInvalidValue(c.algorithm, compressedCacheErrors.ErrUnknownAlgorithm, wrap)
+	}
+
+	c.zstd, err = newZstdCompressor()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", wrap, err)
+	}
+
+	c.compressors = map[byte]compressor{
+		idGzip:   newGzipCompressor(),
+		idZstd:   c.zstd,
+		idSnappy: snappyCompressor{},
+	}
+
+	return c, nil
+}
+
+var algorithmIDs = map[Algorithm]byte{
+	AlgorithmNone:   idNone,
+	AlgorithmGzip:   idGzip,
+	AlgorithmZstd:   idZstd,
+	AlgorithmSnappy: idSnappy,
+}
+
+func (c *compressedCache) Get(ctx context.Context, key string) (any, error) {
+	const wrap = "compressedCache/Get"
+	if err := cache.ValidateInput(
+		cache.WithClosedValidation(&c.closed, wrap),
+		cache.WithCtxValidation(ctx, wrap),
+		cache.WithKeyValidation(key, wrap),
+	); err != nil {
+		return nil, err
+	}
+
+	val, err := c.impl.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	stored, ok := val.(string)
+	if !ok {
+		return nil, cacheErrors.NewErrTypeCastFailed(key, val, wrap)
+	}
+
+	data, err := c.decompress(stored, wrap)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.codec.Unmarshal(data)
+}
+
+func (c *compressedCache) Set(ctx context.Context, key string, value any) (int, error) {
+	const wrap = "compressedCache/Set"
+	if err := cache.ValidateInput(
+		cache.WithClosedValidation(&c.closed, wrap),
+		cache.WithCtxValidation(ctx, wrap),
+		cache.WithKeyValidation(key, wrap),
+		cache.WithValueValidation(value, wrap),
+	); err != nil {
+		return 0, err
+	}
+
+	data, err := c.codec.Marshal(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", wrap, err)
+	}
+
+	stored, err := c.compress(data)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", wrap, err)
+	}
+
+	return c.impl.Set(ctx, key, stored)
+}
+
+func (c *compressedCache) Delete(ctx context.Context, key string) error {
+	const wrap = "compressedCache/Delete"
+	if err := cache.ValidateInput(
+		cache.WithClosedValidation(&c.closed, wrap),
+		cache.WithCtxValidation(ctx, wrap),
+		cache.WithKeyValidation(key, wrap),
+	); err != nil {
+		return err
+	}
+
+	return c.impl.Delete(ctx, key)
+}
+
+func (c *compressedCache) Close(ctx context.Context) error {
+	var err error
+	c.closedOnce.Do(func() {
+		c.closed.Store(true)
+		c.zstd.close()
+		err = c.impl.Close(ctx)
+	})
+
+	return err
+}
+
+func (c *compressedCache) GetKeys(ctx context.Context) ([]string, error) {
+	const wrap = "compressedCache/GetKeys"
+	if err := cache.ValidateInput(
+		cache.WithClosedValidation(&c.closed, wrap),
+		cache.WithCtxValidation(ctx, wrap),
+	); err != nil {
+		return nil, err
+	}
+
+	return c.impl.GetKeys(ctx)
+}
+
+func (c *compressedCache) GetLength() (int64, error) {
+	const wrap = "compressedCache/GetLength"
+	if err := cache.ValidateInput(
+		cache.WithClosedValidation(&c.closed, wrap),
+	); err != nil {
+		return 0, err
+	}
+
+	return c.impl.GetLength()
+}
+
+func (c *compressedCache) GetStats() compressedCacheModels.Stats {
+	return compressedCacheModels.Stats{
+		CompressedWrites:   c.compressedWrites.Load(),
+		UncompressedWrites: c.uncompressedWrites.Load(),
+		OriginalBytes:      c.originalBytes.Load(),
+		CompressedBytes:    c.compressedBytes.Load(),
+	}
+}
+
+// compress returns data prefixed with the algorithm id.
+// Data is stored as is if it is below the threshold or compression doesn't make it smaller.
+func (c *compressedCache) compress(data []byte) (string, error) {
+	id := algorithmIDs[c.algorithm]
+
+	if id != idNone && int64(len(data)) >= c.threshold {
+		compressed, err := c.compressors[id].compress(data)
+		if err != nil {
+			return "", err
+		}
+
+		if len(compressed) < len(data) {
+			c.compressedWrites.Add(1)
+			c.originalBytes.Add(int64(len(data)))
+			c.compressedBytes.Add(int64(len(compressed)))
+
+			return string(id) + string(compressed), nil
+		}
+	}
+
+	c.uncompressedWrites.Add(1)
+
+	return string(idNone) + string(data), nil
+}
+
+func (c *compressedCache) decompress(stored, wrap string) ([]byte, error) {
+	if len(stored) == 0 {
+		return nil, cacheErrors.NewErrInvalidValue(stored, cacheErrors.ErrMalformedData, wrap)
+	}
+
+	id, payload := stored[0], []byte(stored[1:])
+	if id == idNone {
+		return payload, nil
+	}
+
+	comp, ok := c.compressors[id]
+	if !ok {
+		return nil, cacheErrors.NewErrInvalidValue(stored, cacheErrors.ErrMalformedData, wrap)
+	}
+
+	data, err := comp.decompress(payload)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: %w", wrap, cacheErrors.ErrMalformedData, err)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,252 @@
+package compressed_cache
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	mock "github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KennyMacCormik/otel/backend/pkg/cache"
+	"github.com/KennyMacCormik/otel/backend/pkg/cache/impl/sync_map"
+	cache2 "github.com/KennyMacCormik/otel/backend/pkg/models/errors/cache"
+	compressedCacheErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/compressed_cache"
+
+	mockCache "github.com/KennyMacCormik/otel/backend/pkg/cache/mocks"
+)
+
+const testThreshold int64 = 64
+
+var testLargeValue = strings.Repeat(`{"key":"value"},`, 64)
+
+func getCompressedCacheMock(t *testing.T, opts ...InitOptions) (*mockCache.MockCacheInterface, cache.CacheInterface) {
+	c := mockCache.NewMockCacheInterface(t)
+
+	cc, err := NewCompressedCache(c, opts...)
+	require.NoError(t, err, "expect no error with default configuration")
+	require.NotNil(t, cc, "expect result not nil with default configuration")
+
+	return c, cc
+}
+
+func typeAssertion(t *testing.T, c cache.CacheInterface) *compressedCache {
+	cacheImpl, ok := c.(*compressedCache)
+	require.True(t, ok, "expect result to be of type *compressedCache")
+	require.NotNil(t, cacheImpl, "expect result to be not nil")
+	return cacheImpl
+}
+
+func TestCompressedCache_New(t *testing.T) {
+	c := mockCache.NewMockCacheInterface(t)
+
+	t.Run("default", func(t *testing.T) {
+		cc, err := NewCompressedCache(c)
+		require.NoError(t, err, "expect no error with default configuration")
+		assert.Implements(t, (*cache.CacheInterface)(nil), cc, "result should implement cache.Interface")
+		assert.Implements(t, (*StatsGetter)(nil), cc, "result should implement StatsGetter")
+
+		cacheImpl := typeAssertion(t, cc)
+		assert.Equal(t, defaultAlgorithm, cacheImpl.algorithm, "expect defaultAlgorithm")
+		assert.Equal(t, defaultThreshold, cacheImpl.threshold, "expect defaultThreshold")
+	})
+
+	t.Run("with override default", func(t *testing.T) {
+		cc, err := NewCompressedCache(c, WithOverrideDefaults(AlgorithmZstd, testThreshold))
+		require.NoError(t, err, "expect no error with valid configuration")
+
+		cacheImpl := typeAssertion(t, cc)
+		assert.Equal(t, AlgorithmZstd, cacheImpl.algorithm, "expect AlgorithmZstd")
+		assert.Equal(t, testThreshold, cacheImpl.threshold, "expect testThreshold")
+	})
+
+	t.Run("with incorrect override default", func(t *testing.T) {
+		cc, err := NewCompressedCache(c, WithOverrideDefaults("", -1))
+		require.NoError(t, err, "expect no error with incorrect configuration")
+
+		cacheImpl := typeAssertion(t, cc)
+		assert.Equal(t, defaultAlgorithm, cacheImpl.algorithm, "expect defaultAlgorithm")
+		assert.Equal(t, defaultThreshold, cacheImpl.threshold, "expect defaultThreshold")
+	})
+
+	t.Run("with unknown algorithm", func(t *testing.T) {
+		cc, err := NewCompressedCache(c, WithOverrideDefaults("lz4", testThreshold))
+		require.Error(t, err, "expect an error with unknown algorithm")
+		assert.ErrorIs(t, err, compressedCacheErrors.ErrUnknownAlgorithm, "expect ErrUnknownAlgorithm")
+		assert.Nil(t, cc, "result should be nil with unknown algorithm")
+	})
+
+	t.Run("with nil cache", func(t *testing.T) {
+		cc, err := NewCompressedCache(nil)
+		require.Error(t, err, "expect an error with with nil cache")
+		assert.ErrorIs(t, err, cache2.NewErrInvalidValue("", cache2.ErrNil, ""), "expect err be ErrInvalidValue")
+		assert.Nil(t, cc, "result should be nil with nil cache")
+	})
+}
+
+func TestCompressedCache_RoundTrip(t *testing.T) {
+	for _, algorithm := range []Algorithm{AlgorithmNone, AlgorithmGzip, AlgorithmZstd, AlgorithmSnappy} {
+		t.Run(string(algorithm), func(t *testing.T) {
+			cc, err := NewCompressedCache(sync_map.NewSyncMapCache(), WithOverrideDefaults(algorithm, testThreshold))
+			require.NoError(t, err, "expect no error with valid configuration")
+			defer func() { _ = cc.Close(context.Background()) }()
+
+			for key, value := range map[string]any{"small": "value", "large": testLargeValue, "bytes": []byte(testLargeValue)} {
+				code, err := cc.Set(context.Background(), key, value)
+				require.NoError(t, err, "expect no error on Set")
+				assert.Equal(t, 201, code, "expect 201 for the first Set")
+
+				code, err = cc.Set(context.Background(), key, value)
+				require.NoError(t, err, "expect no error on Set")
+				assert.Equal(t, 204, code, "expect 204 for the same value")
+
+				val, err := cc.Get(context.Background(), key)
+				require.NoError(t, err, "expect no error on Get")
+				assert.Equal(t, value, val, "expect original value")
+			}
+		})
+	}
+}
+
+func TestCompressedCache_Stats(t *testing.T) {
+	c, cc := getCompressedCacheMock(t, WithOverrideDefaults(AlgorithmGzip, testThreshold))
+	c.EXPECT().Set(mock.Anything, mock.Anything, mock.Anything).Return(201, nil)
+
+	_, err := cc.Set(context.Background(), "small", "value")
+	require.NoError(t, err, "expect no error on Set")
+	_, err = cc.Set(context.Background(), "large", testLargeValue)
+	require.NoError(t, err, "expect no error on Set")
+
+	stats := cc.(StatsGetter).GetStats()
+	assert.Equal(t, int64(1), stats.CompressedWrites, "expect one compressed write")
+	assert.Equal(t, int64(1), stats.UncompressedWrites, "expect one uncompressed write")
+	assert.Equal(t, int64(len(testLargeValue)+1), stats.OriginalBytes, "expect serialized size of the large value")
+	assert.Less(t, stats.Ratio(), 0.5, "expect repetitive value to be compressed")
+}
+
+func TestCollector(t *testing.T) {
+	c, cc := getCompressedCacheMock(t, WithOverrideDefaults(AlgorithmGzip, testThreshold))
+	c.EXPECT().Set(mock.Anything, mock.Anything, mock.Anything).Return(201, nil)
+
+	reg := prometheus.NewRegistry()
+	require.NoError(t, reg.Register(NewCollector(cc.(StatsGetter))), "expect collector to register")
+
+	expected := `
+# HELP storage_compression_ratio Compressed to original bytes ratio of compressed values, 1 if nothing was compressed yet
+# TYPE storage_compression_ratio gauge
+storage_compression_ratio 1
+`
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expected), "storage_compression_ratio"), "expect ratio 1 before compression")
+
+	_, err := cc.Set(context.Background(), "small", "value")
+	require.NoError(t, err, "expect no error on Set")
+	_, err = cc.Set(context.Background(), "large", testLargeValue)
+	require.NoError(t, err, "expect no error on Set")
+
+	expected = `
+# HELP storage_compression_compressed_writes Number of values compressed on write
+# TYPE storage_compression_compressed_writes counter
+storage_compression_compressed_writes 1
+# HELP storage_compression_uncompressed_writes Number of values stored as is as they are below the threshold
+# TYPE storage_compression_uncompressed_writes counter
+storage_compression_uncompressed_writes 1
+`
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expected),
+		"storage_compression_compressed_writes", "storage_compression_uncompressed_writes"), "Unexpected metrics")
+
+	ratio := cc.(StatsGetter).GetStats().Ratio()
+	assert.Less(t, ratio, 0.5, "expect repetitive value to be compressed")
+	families, err := reg.Gather()
+	require.NoError(t, err, "expect metrics to be gathered")
+	for _, f := range families {
+		if f.GetName() == "storage_compression_ratio" {
+			assert.Equal(t, ratio, f.GetMetric()[0].GetGauge().GetValue(), "expect exported ratio to match stats")
+		}
+	}
+}
+
+func TestCompressedCache_Get(t *testing.T) {
+	t.Run("negative", func(t *testing.T) {
+		c, cc := getCompressedCacheMock(t)
+		c.EXPECT().Get(mock.Anything, "key1").Return(nil, assert.AnError)
+
+		val, err := cc.Get(context.Background(), "key1")
+		require.Error(t, err, "expect error")
+		assert.Nil(t, val, "expect nil result with error")
+		assert.ErrorIs(t, err, assert.AnError, "expect error to be assert.AnError")
+	})
+
+	t.Run("typecast failure", func(t *testing.T) {
+		c, cc := getCompressedCacheMock(t)
+		c.EXPECT().Get(mock.Anything, "key1").Return(42, nil)
+
+		val, err := cc.Get(context.Background(), "key1")
+		require.Error(t, err, "expect error")
+		assert.Nil(t, val, "expect nil result with error")
+		assert.ErrorIs(t, err, cache2.NewErrTypeCastFailed("", "", ""), "expect error to be cache.ErrTypeCastFailed")
+	})
+
+	t.Run("malformed data", func(t *testing.T) {
+		c, cc := getCompressedCacheMock(t)
+		c.EXPECT().Get(mock.Anything, "key1").Return(string(idGzip)+"value1", nil)
+
+		val, err := cc.Get(context.Background(), "key1")
+		require.Error(t, err, "expect error")
+		assert.Nil(t, val, "expect nil result with error")
+		assert.ErrorIs(t, err, cache2.ErrMalformedData, "expect error to be cache.ErrMalformedData")
+	})
+
+	t.Run("closed", func(t *testing.T) {
+		c, cc := getCompressedCacheMock(t)
+		c.EXPECT().Close(mock.Anything).Return(nil)
+		require.NoError(t, cc.Close(context.Background()), "expect no error with cache closed")
+
+		val, err := cc.Get(context.Background(), "key1")
+		require.Error(t, err, "expect error")
+		assert.Nil(t, val, "expect nil result with error")
+		assert.ErrorIs(t, err, cache2.ErrCacheClosed, "expect error to be cache.ErrCacheClosed")
+	})
+}
+
+func TestCompressedCache_Set(t *testing.T) {
+	t.Run("negative", func(t *testing.T) {
+		c, cc := getCompressedCacheMock(t)
+		c.EXPECT().Set(mock.Anything, "key1", mock.Anything).Return(0, assert.AnError)
+
+		code, err := cc.Set(context.Background(), "key1", "value1")
+		require.Error(t, err, "expect an error")
+		assert.ErrorIs(t, err, assert.AnError, "expect error to be assert.AnError")
+		assert.Equal(t, 0, code, "expect 0 code for an error")
+	})
+
+	t.Run("unsupported type", func(t *testing.T) {
+		_, cc := getCompressedCacheMock(t)
+
+		_, err := cc.Set(context.Background(), "key1", 42)
+		require.Error(t, err, "expect an error")
+		assert.ErrorIs(t, err, cache2.ErrUnsupportedType, "expect error to be cache.ErrUnsupportedType")
+	})
+
+	t.Run("nil value", func(t *testing.T) {
+		_, cc := getCompressedCacheMock(t)
+
+		_, err := cc.Set(context.Background(), "key1", nil)
+		require.Error(t, err, "expect an error")
+		assert.ErrorIs(t, err, cache2.NewErrInvalidValue("", cache2.ErrNil, ""), "expect error to be cache.ErrInvalidValue")
+	})
+}
+
+func TestCompressedCache_Close(t *testing.T) {
+	c, cc := getCompressedCacheMock(t)
+	c.EXPECT().Close(mock.Anything).Return(assert.AnError).Once()
+
+	err := cc.Close(context.Background())
+	require.Error(t, err, "expect error with first close")
+	assert.ErrorIs(t, err, assert.AnError, "expect error to be assert.AnError")
+
+	err = cc.Close(context.Background())
+	require.NoError(t, err, "expect no error with second close")
+}
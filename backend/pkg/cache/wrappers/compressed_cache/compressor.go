@@ -0,0 +1,112 @@
+package compressed_cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+type Algorithm string
+
+const (
+	AlgorithmNone   Algorithm = "none"
+	AlgorithmGzip   Algorithm = "gzip"
+	AlgorithmZstd   Algorithm = "zstd"
+	AlgorithmSnappy Algorithm = "snappy"
+)
+
+// algorithm ids are written as the first byte of every stored value
+const (
+	idNone byte = iota
+	idGzip
+	idZstd
+	idSnappy
+)
+
+type compressor interface {
+	compress(src []byte) ([]byte, error)
+	decompress(src []byte) ([]byte, error)
+}
+
+type gzipCompressor struct {
+	writers sync.Pool
+}
+
+func newGzipCompressor() *gzipCompressor {
+	return &gzipCompressor{writers: sync.Pool{New: func() any { return gzip.NewWriter(nil) }}}
+}
+
+func (g *gzipCompressor) compress(src []byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	w := g.writers.Get().(*gzip.Writer)
+	defer g.writers.Put(w)
+	w.Reset(&buf)
+
+	if _, err := w.Write(src); err != nil {
+		return nil, err
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (g *gzipCompressor) decompress(src []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(src))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = r.Close() }()
+
+	return io.ReadAll(r)
+}
+
+// zstdCompressor relies on EncodeAll and DecodeAll which are safe for concurrent use
+type zstdCompressor struct {
+	encoder *zstd.Encoder
+	decoder *zstd.Decoder
+}
+
+func newZstdCompressor() (*zstdCompressor, error) {
+	encoder, err := zstd.NewWriter(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder, err := zstd.NewReader(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &zstdCompressor{encoder: encoder, decoder: decoder}, nil
+}
+
+func (z *zstdCompressor) compress(src []byte) ([]byte, error) {
+	return z.encoder.EncodeAll(src, nil), nil
+}
+
+func (z *zstdCompressor) decompress(src []byte) ([]byte, error) {
+	return z.decoder.DecodeAll(src, nil)
+}
+
+func (z *zstdCompressor) close() {
+	_ = z.encoder.Close()
+	z.decoder.Close()
+}
+
+type snappyCompressor struct{}
+
+func (snappyCompressor) compress(src []byte) ([]byte, error) {
+	return snappy.Encode(nil, src), nil
+}
+
+func (snappyCompressor) decompress(src []byte) ([]byte, error) {
+	return snappy.Decode(nil, src)
+}
@@ -0,0 +1,339 @@
+package encrypted_cache
+
+import (
+	"bytes"
+	"context"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+	"hash/maphash"
+	"sync"
+	"sync/atomic"
+
+	"github.com/KennyMacCormik/common/log"
+
+	"github.com/KennyMacCormik/otel/backend/pkg/cache"
+	cacheErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/cache"
+	encryptedCacheErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/encrypted_cache"
+)
+
+const (
+	formatVersion byte = 1
+	dataKeySize        = 32
+	keyLocks           = 64
+)
+
+// encryptedCache applies AES-GCM envelope encryption to values.
+// Every entry is encrypted with its own random data key, which is in turn encrypted with the keyring primary key.
+// Stored entry layout: version (1) | key id length (1) | key id | data key nonce | encrypted data key | nonce | ciphertext.
+// Cache key is used as additional authenticated data, so ciphertext can't be moved to another key.
+type encryptedCache struct {
+	impl    cache.CacheInterface
+	codec   cache.Codec
+	keyring *Keyring
+
+	// keyMtx serializes writes of a key, so re-encryption never overwrites a concurrent Set or Delete
+	keyMtx [keyLocks]sync.Mutex
+	seed   maphash.Seed
+
+	closedOnce sync.Once
+	closed     atomic.Bool
+}
+
+type InitOptions func(e *encryptedCache)
+
+// WithCodec replaces cache.NewDefaultCodec used to serialize values
+func WithCodec(codec cache.Codec) InitOptions {
+	return func(e *encryptedCache) {
+		if codec != nil {
+			e.codec = codec
+		}
+	}
+}
+
+func NewEncryptedCache(impl cache.CacheInterface, keyring *Keyring, opts ...InitOptions) (cache.CacheInterface, error) {
+	const wrap = "NewEncryptedCache"
+
+	if err := cache.ValidateInput(
+		cache.WithValueValidation(impl, wrap),
+		cache.WithValueValidation(keyring, wrap),
+	); err != nil {
+		return nil, err
+	}
+
+	e := &encryptedCache{impl: impl, codec: cache.NewDefaultCodec(), keyring: keyring, seed: maphash.MakeSeed()}
+
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	return e, nil
+}
+
+// Get returns decrypted value. Entries encrypted with a non-primary key are re-encrypted with the primary one.
+func (e *encryptedCache) Get(ctx context.Context, key string) (any, error) {
+	const wrap = "encryptedCache/Get"
+	if err := cache.ValidateInput(
+		cache.WithClosedValidation(&e.closed, wrap),
+		cache.WithCtxValidation(ctx, wrap),
+		cache.WithKeyValidation(key, wrap),
+	); err != nil {
+		return nil, err
+	}
+
+	stored, err := e.getStored(ctx, key, wrap)
+	if err != nil {
+		return nil, err
+	}
+
+	data, keyID, err := e.open(key, stored, wrap)
+	if err != nil {
+		return nil, err
+	}
+
+	if keyID != e.keyring.PrimaryID() {
+		e.reEncrypt(ctx, key, stored, data)
+	}
+
+	return e.codec.Unmarshal(data)
+}
+
+func (e *encryptedCache) Set(ctx context.Context, key string, value any) (int, error) {
+	const wrap = "encryptedCache/Set"
+	if err := cache.ValidateInput(
+		cache.WithClosedValidation(&e.closed, wrap),
+		cache.WithCtxValidation(ctx, wrap),
+		cache.WithKeyValidation(key, wrap),
+		cache.WithValueValidation(value, wrap),
+	); err != nil {
+		return 0, err
+	}
+
+	data, err := e.codec.Marshal(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", wrap, err)
+	}
+
+	m := e.keyLock(key)
+	m.Lock()
+	defer m.Unlock()
+
+	// ciphertext differs on every write, so plaintexts are compared to keep 204 semantics
+	existing, keyID, err := e.getPlaintext(ctx, key, wrap)
+	if err == nil && keyID == e.keyring.PrimaryID() && bytes.Equal(existing, data) {
+		return 204, nil
+	}
+
+	stored, err := e.seal(key, data)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", wrap, err)
+	}
+
+	code, err := e.impl.Set(ctx, key, stored)
+	if err != nil {
+		return 0, err
+	}
+
+	if existing != nil && bytes.Equal(existing, data) {
+		return 204, nil
+	}
+
+	return code, nil
+}
+
+func (e *encryptedCache) Delete(ctx context.Context, key string) error {
+	const wrap = "encryptedCache/Delete"
+	if err := cache.ValidateInput(
+		cache.WithClosedValidation(&e.closed, wrap),
+		cache.WithCtxValidation(ctx, wrap),
+		cache.WithKeyValidation(key, wrap),
+	); err != nil {
+		return err
+	}
+
+	m := e.keyLock(key)
+	m.Lock()
+	defer m.Unlock()
+
+	return e.impl.Delete(ctx, key)
+}
+
+func (e *encryptedCache) Close(ctx context.Context) error {
+	var err error
+	e.closedOnce.Do(func() {
+		e.closed.Store(true)
+		err = e.impl.Close(ctx)
+	})
+
+	return err
+}
+
+func (e *encryptedCache) GetKeys(ctx context.Context) ([]string, error) {
+	const wrap = "encryptedCache/GetKeys"
+	if err := cache.ValidateInput(
+		cache.WithClosedValidation(&e.closed, wrap),
+		cache.WithCtxValidation(ctx, wrap),
+	); err != nil {
+		return nil, err
+	}
+
+	return e.impl.GetKeys(ctx)
+}
+
+func (e *encryptedCache) GetLength() (int64, error) {
+	const wrap = "encryptedCache/GetLength"
+	if err := cache.ValidateInput(
+		cache.WithClosedValidation(&e.closed, wrap),
+	); err != nil {
+		return 0, err
+	}
+
+	return e.impl.GetLength()
+}
+
+// getPlaintext returns decrypted serialized value and id of the key it was encrypted with
+func (e *encryptedCache) getPlaintext(ctx context.Context, key, wrap string) ([]byte, string, error) {
+	stored, err := e.getStored(ctx, key, wrap)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return e.open(key, stored, wrap)
+}
+
+// getStored returns encrypted entry as it is kept in impl
+func (e *encryptedCache) getStored(ctx context.Context, key, wrap string) (string, error) {
+	val, err := e.impl.Get(ctx, key)
+	if err != nil {
+		return "", err
+	}
+
+	stored, ok := val.(string)
+	if !ok {
+		return "", cacheErrors.NewErrTypeCastFailed(key, val, wrap)
+	}
+
+	return stored, nil
+}
+
+// reEncrypt replaces the entry read as prev with data encrypted by the primary key.
+// Entry is left as is if it was changed or deleted since it was read.
+func (e *encryptedCache) reEncrypt(ctx context.Context, key, prev string, data []byte) {
+	const wrap = "encryptedCache/reEncrypt"
+
+	m := e.keyLock(key)
+	m.Lock()
+	defer m.Unlock()
+
+	current, err := e.getStored(ctx, key, wrap)
+	if err != nil || current != prev {
+		return
+	}
+
+	stored, err := e.seal(key, data)
+	if err == nil {
+		_, err = e.impl.Set(ctx, key, stored)
+	}
+
+	if err != nil {
+		log.Warn(fmt.Sprintf("%s: failed to re-encrypt entry", wrap), "key", key, "err", err)
+	}
+}
+
+func (e *encryptedCache) keyLock(key string) *sync.Mutex {
+	return &e.keyMtx[maphash.String(e.seed, key)%keyLocks]
+}
+
+func (e *encryptedCache) seal(key string, data []byte) (string, error) {
+	keyID := e.keyring.PrimaryID()
+	kek, _ := e.keyring.get(keyID)
+
+	dataKey := make([]byte, dataKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", err
+	}
+
+	dek, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+
+	buf := make([]byte, 0, 2+len(keyID)+2*kek.NonceSize()+dataKeySize+2*kek.Overhead()+len(data))
+	buf = append(buf, formatVersion, byte(len(keyID)))
+	buf = append(buf, keyID...)
+
+	buf, err = appendSealed(buf, kek, dataKey, []byte(keyID))
+	if err != nil {
+		return "", err
+	}
+
+	buf, err = appendSealed(buf, dek, data, []byte(key))
+	if err != nil {
+		return "", err
+	}
+
+	return string(buf), nil
+}
+
+// appendSealed appends random nonce followed by the ciphertext to dst
+func appendSealed(dst []byte, aead cipher.AEAD, plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	dst = append(dst, nonce...)
+
+	return aead.Seal(dst, nonce, plaintext, additionalData), nil
+}
+
+func (e *encryptedCache) open(key, stored, wrap string) ([]byte, string, error) {
+	malformed := func() ([]byte, string, error) {
+		return nil, "", cacheErrors.NewErrInvalidValue(key, cacheErrors.ErrMalformedData, wrap)
+	}
+
+	b := []byte(stored)
+	if len(b) < 2 || b[0] != formatVersion {
+		return malformed()
+	}
+
+	idLen := int(b[1])
+	if len(b) < 2+idLen {
+		return malformed()
+	}
+
+	keyID := string(b[2 : 2+idLen])
+	b = b[2+idLen:]
+
+	kek, ok := e.keyring.get(keyID)
+	if !ok {
+		return nil, "", cacheErrors.NewErrInvalidValue(keyID, encryptedCacheErrors.ErrUnknownKeyID, wrap)
+	}
+
+	wrappedLen := kek.NonceSize() + dataKeySize + kek.Overhead()
+	if len(b) < wrappedLen {
+		return malformed()
+	}
+
+	dataKey, err := kek.Open(nil, b[:kek.NonceSize()], b[kek.NonceSize():wrappedLen], []byte(keyID))
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: key [%s]: %w", wrap, key, encryptedCacheErrors.ErrDecryptionFailed)
+	}
+	b = b[wrappedLen:]
+
+	dek, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: key [%s]: %w: %w", wrap, key, encryptedCacheErrors.ErrDecryptionFailed, err)
+	}
+
+	if len(b) < dek.NonceSize()+dek.Overhead() {
+		return malformed()
+	}
+
+	data, err := dek.Open(nil, b[:dek.NonceSize()], b[dek.NonceSize():], []byte(key))
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: key [%s]: %w", wrap, key, encryptedCacheErrors.ErrDecryptionFailed)
+	}
+
+	return data, keyID, nil
+}
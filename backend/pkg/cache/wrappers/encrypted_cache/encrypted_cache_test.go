@@ -0,0 +1,260 @@
+package encrypted_cache
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	mock "github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KennyMacCormik/otel/backend/pkg/cache"
+	"github.com/KennyMacCormik/otel/backend/pkg/cache/impl/sync_map"
+	cache2 "github.com/KennyMacCormik/otel/backend/pkg/models/errors/cache"
+	encryptedCacheErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/encrypted_cache"
+
+	mockCache "github.com/KennyMacCormik/otel/backend/pkg/cache/mocks"
+)
+
+var (
+	testKey1 = bytes.Repeat([]byte{1}, 32)
+	testKey2 = bytes.Repeat([]byte{2}, 16)
+)
+
+func encodedEntry(id string, key []byte) string {
+	return id + ":" + base64.StdEncoding.EncodeToString(key)
+}
+
+func getKeyring(t *testing.T, primary string) *Keyring {
+	k, err := NewKeyring(primary, map[string][]byte{"key1": testKey1, "key2": testKey2})
+	require.NoError(t, err, "expect no error with valid keys")
+	return k
+}
+
+func getEncryptedCache(t *testing.T, impl cache.CacheInterface, k *Keyring) cache.CacheInterface {
+	e, err := NewEncryptedCache(impl, k)
+	require.NoError(t, err, "expect no error with valid configuration")
+	require.NotNil(t, e, "expect result not nil with valid configuration")
+	return e
+}
+
+func TestKeyring_New(t *testing.T) {
+	t.Run("positive", func(t *testing.T) {
+		k := getKeyring(t, "key1")
+		assert.Equal(t, "key1", k.PrimaryID(), "expect primary key id")
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		_, err := NewKeyring("key1", nil)
+		require.Error(t, err, "expect an error with no keys")
+		assert.ErrorIs(t, err, encryptedCacheErrors.ErrEmptyKeyring, "expect ErrEmptyKeyring")
+	})
+
+	t.Run("unknown primary", func(t *testing.T) {
+		_, err := NewKeyring("key3", map[string][]byte{"key1": testKey1})
+		require.Error(t, err, "expect an error with unknown primary")
+		assert.ErrorIs(t, err, encryptedCacheErrors.ErrUnknownKeyID, "expect ErrUnknownKeyID")
+	})
+
+	t.Run("invalid key size", func(t *testing.T) {
+		_, err := NewKeyring("key1", map[string][]byte{"key1": []byte("short")})
+		require.Error(t, err, "expect an error with invalid key size")
+		assert.ErrorIs(t, err, encryptedCacheErrors.ErrInvalidKey, "expect ErrInvalidKey")
+	})
+}
+
+func TestKeyring_Parse(t *testing.T) {
+	t.Run("positive", func(t *testing.T) {
+		k, err := ParseKeyring(encodedEntry("key2", testKey2) + ", " + encodedEntry("key1", testKey1))
+		require.NoError(t, err, "expect no error with valid keyring")
+		assert.Equal(t, "key2", k.PrimaryID(), "expect the first key to be primary")
+		assert.Len(t, k.aeads, 2, "expect all keys to be loaded")
+	})
+
+	t.Run("file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "keys")
+		content := strings.Join([]string{"# rotated monthly", encodedEntry("key1", testKey1), "", encodedEntry("key2", testKey2)}, "\n")
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600), "expect test file to be written")
+
+		k, err := LoadKeyringFromFile(path)
+		require.NoError(t, err, "expect no error with valid keyring file")
+		assert.Equal(t, "key1", k.PrimaryID(), "expect the first key to be primary")
+		assert.Len(t, k.aeads, 2, "expect all keys to be loaded")
+	})
+
+	t.Run("env", func(t *testing.T) {
+		t.Setenv("TEST_ENCRYPTION_KEYS", encodedEntry("key1", testKey1))
+
+		k, err := LoadKeyringFromEnv("TEST_ENCRYPTION_KEYS")
+		require.NoError(t, err, "expect no error with valid keyring env")
+		assert.Equal(t, "key1", k.PrimaryID(), "expect the first key to be primary")
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		_, err := ParseKeyring("key1")
+		require.Error(t, err, "expect an error without separator")
+		assert.ErrorIs(t, err, encryptedCacheErrors.ErrInvalidKey, "expect ErrInvalidKey")
+
+		_, err = ParseKeyring("key1:not base64")
+		require.Error(t, err, "expect an error with invalid base64")
+		assert.ErrorIs(t, err, encryptedCacheErrors.ErrInvalidKey, "expect ErrInvalidKey")
+
+		_, err = ParseKeyring("")
+		require.Error(t, err, "expect an error with empty keyring")
+		assert.ErrorIs(t, err, encryptedCacheErrors.ErrEmptyKeyring, "expect ErrEmptyKeyring")
+	})
+}
+
+func TestEncryptedCache_New(t *testing.T) {
+	t.Run("nil cache", func(t *testing.T) {
+		e, err := NewEncryptedCache(nil, getKeyring(t, "key1"))
+		require.Error(t, err, "expect an error with nil cache")
+		assert.ErrorIs(t, err, cache2.NewErrInvalidValue("", cache2.ErrNil, ""), "expect err be ErrInvalidValue")
+		assert.Nil(t, e, "result should be nil with nil cache")
+	})
+
+	t.Run("nil keyring", func(t *testing.T) {
+		e, err := NewEncryptedCache(mockCache.NewMockCacheInterface(t), nil)
+		require.Error(t, err, "expect an error with nil keyring")
+		assert.ErrorIs(t, err, cache2.NewErrInvalidValue("", cache2.ErrNilPointerOrNilInterface, ""), "expect err be ErrInvalidValue")
+		assert.Nil(t, e, "result should be nil with nil keyring")
+	})
+}
+
+func TestEncryptedCache_RoundTrip(t *testing.T) {
+	sm := sync_map.NewSyncMapCache()
+	e := getEncryptedCache(t, sm, getKeyring(t, "key1"))
+
+	code, err := e.Set(context.Background(), "key", "secret value")
+	require.NoError(t, err, "expect no error on Set")
+	assert.Equal(t, 201, code, "expect 201 for the first Set")
+
+	code, err = e.Set(context.Background(), "key", "secret value")
+	require.NoError(t, err, "expect no error on Set")
+	assert.Equal(t, 204, code, "expect 204 for the same value")
+
+	code, err = e.Set(context.Background(), "key", "another secret")
+	require.NoError(t, err, "expect no error on Set")
+	assert.Equal(t, 200, code, "expect 200 for a new value")
+
+	val, err := e.Get(context.Background(), "key")
+	require.NoError(t, err, "expect no error on Get")
+	assert.Equal(t, "another secret", val, "expect original value")
+
+	raw, err := sm.Get(context.Background(), "key")
+	require.NoError(t, err, "expect value in the underlying cache")
+	assert.NotContains(t, raw, "another secret", "expect value to be encrypted in the underlying cache")
+}
+
+func TestEncryptedCache_Rotation(t *testing.T) {
+	sm := sync_map.NewSyncMapCache()
+
+	old := getEncryptedCache(t, sm, getKeyring(t, "key2"))
+	_, err := old.Set(context.Background(), "key", "value")
+	require.NoError(t, err, "expect no error on Set")
+
+	rotated := getEncryptedCache(t, sm, getKeyring(t, "key1"))
+	val, err := rotated.Get(context.Background(), "key")
+	require.NoError(t, err, "expect old entries to be readable after rotation")
+	assert.Equal(t, "value", val, "expect original value")
+
+	k, err := NewKeyring("key1", map[string][]byte{"key1": testKey1})
+	require.NoError(t, err, "expect no error with valid keys")
+	retired := getEncryptedCache(t, sm, k)
+
+	val, err = retired.Get(context.Background(), "key")
+	require.NoError(t, err, "expect entry to be re-encrypted with the primary key on read")
+	assert.Equal(t, "value", val, "expect original value")
+}
+
+func TestEncryptedCache_ReEncrypt(t *testing.T) {
+	sm := sync_map.NewSyncMapCache()
+
+	_, err := getEncryptedCache(t, sm, getKeyring(t, "key2")).Set(context.Background(), "key", "old")
+	require.NoError(t, err, "expect no error on Set")
+	prev, err := sm.Get(context.Background(), "key")
+	require.NoError(t, err, "expect value in the underlying cache")
+
+	e := getEncryptedCache(t, sm, getKeyring(t, "key1"))
+	data, keyID, err := e.(*encryptedCache).open("key", prev.(string), "test")
+	require.NoError(t, err, "expect no error on open")
+	require.Equal(t, "key2", keyID, "expect entry encrypted with the old key")
+
+	_, err = e.Set(context.Background(), "key", "new")
+	require.NoError(t, err, "expect no error on Set")
+
+	e.(*encryptedCache).reEncrypt(context.Background(), "key", prev.(string), data)
+	val, err := e.Get(context.Background(), "key")
+	require.NoError(t, err, "expect no error on Get")
+	assert.Equal(t, "new", val, "expect re-encryption not to overwrite concurrent Set")
+
+	require.NoError(t, e.Delete(context.Background(), "key"), "expect no error on Delete")
+	e.(*encryptedCache).reEncrypt(context.Background(), "key", prev.(string), data)
+	_, err = e.Get(context.Background(), "key")
+	assert.ErrorIs(t, err, cache2.ErrNotFound, "expect re-encryption not to restore deleted entry")
+}
+
+func TestEncryptedCache_Get(t *testing.T) {
+	t.Run("unknown key id", func(t *testing.T) {
+		sm := sync_map.NewSyncMapCache()
+		_, err := getEncryptedCache(t, sm, getKeyring(t, "key2")).Set(context.Background(), "key", "value")
+		require.NoError(t, err, "expect no error on Set")
+
+		k, err := NewKeyring("key1", map[string][]byte{"key1": testKey1})
+		require.NoError(t, err, "expect no error with valid keys")
+
+		_, err = getEncryptedCache(t, sm, k).Get(context.Background(), "key")
+		require.Error(t, err, "expect an error with unknown key id")
+		assert.ErrorIs(t, err, encryptedCacheErrors.ErrUnknownKeyID, "expect ErrUnknownKeyID")
+	})
+
+	t.Run("moved ciphertext", func(t *testing.T) {
+		sm := sync_map.NewSyncMapCache()
+		e := getEncryptedCache(t, sm, getKeyring(t, "key1"))
+		_, err := e.Set(context.Background(), "key1", "value")
+		require.NoError(t, err, "expect no error on Set")
+
+		raw, err := sm.Get(context.Background(), "key1")
+		require.NoError(t, err, "expect value in the underlying cache")
+		_, err = sm.Set(context.Background(), "key2", raw)
+		require.NoError(t, err, "expect no error on Set")
+
+		_, err = e.Get(context.Background(), "key2")
+		require.Error(t, err, "expect an error for ciphertext stored under another key")
+		assert.ErrorIs(t, err, encryptedCacheErrors.ErrDecryptionFailed, "expect ErrDecryptionFailed")
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		c := mockCache.NewMockCacheInterface(t)
+		c.EXPECT().Get(mock.Anything, "key").Return("value", nil)
+
+		_, err := getEncryptedCache(t, c, getKeyring(t, "key1")).Get(context.Background(), "key")
+		require.Error(t, err, "expect an error for malformed entry")
+		assert.ErrorIs(t, err, cache2.ErrMalformedData, "expect ErrMalformedData")
+	})
+
+	t.Run("negative", func(t *testing.T) {
+		c := mockCache.NewMockCacheInterface(t)
+		c.EXPECT().Get(mock.Anything, "key").Return(nil, assert.AnError)
+
+		_, err := getEncryptedCache(t, c, getKeyring(t, "key1")).Get(context.Background(), "key")
+		require.Error(t, err, "expect an error")
+		assert.ErrorIs(t, err, assert.AnError, "expect error to be assert.AnError")
+	})
+
+	t.Run("closed", func(t *testing.T) {
+		c := mockCache.NewMockCacheInterface(t)
+		c.EXPECT().Close(mock.Anything).Return(nil)
+		e := getEncryptedCache(t, c, getKeyring(t, "key1"))
+		require.NoError(t, e.Close(context.Background()), "expect no error with cache closed")
+
+		_, err := e.Get(context.Background(), "key")
+		require.Error(t, err, "expect error")
+		assert.ErrorIs(t, err, cache2.ErrCacheClosed, "expect error to be cache.ErrCacheClosed")
+	})
+}
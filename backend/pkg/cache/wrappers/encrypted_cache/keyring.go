@@ -0,0 +1,124 @@
+package encrypted_cache
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+
+	cacheErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/cache"
+	encryptedCacheErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/encrypted_cache"
+)
+
+const maxKeyIDLength = 255
+
+// Keyring holds key encryption keys by their id. New entries are always encrypted with the primary key,
+// other keys are only used to decrypt entries written before rotation.
+type Keyring struct {
+	primary string
+	aeads   map[string]cipher.AEAD
+}
+
+// NewKeyring validates keys and returns Keyring. Keys must be 16, 24 or 32 bytes long to select AES-128, AES-192 or AES-256.
+func NewKeyring(primary string, keys map[string][]byte) (*Keyring, error) {
+	const wrap = "NewKeyring"
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%s: %w", wrap, encryptedCacheErrors.ErrEmptyKeyring)
+	}
+
+	if _, ok := keys[primary]; !ok {
+		return nil, cacheErrors.NewErrInvalidValue(primary, encryptedCacheErrors.ErrUnknownKeyID, wrap)
+	}
+
+	k := &Keyring{primary: primary, aeads: make(map[string]cipher.AEAD, len(keys))}
+
+	for id, key := range keys {
+		if id == "" || len(id) > maxKeyIDLength {
+			return nil, cacheErrors.NewErrInvalidValue(id, encryptedCacheErrors.ErrInvalidKey, wrap)
+		}
+
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, fmt.Errorf("%s: key [%s]: %w: %w", wrap, id, encryptedCacheErrors.ErrInvalidKey, err)
+		}
+
+		k.aeads[id] = aead
+	}
+
+	return k, nil
+}
+
+// ParseKeyring parses comma or newline separated list of id:base64key pairs.
+// The first key in the list is the primary one. Empty lines and lines starting with # are ignored.
+//
+// Example:
+//
+// key-2024-02:q8Xz...=,key-2024-01:Yt1w...=
+func ParseKeyring(s string) (*Keyring, error) {
+	const wrap = "ParseKeyring"
+
+	var primary string
+	keys := make(map[string][]byte)
+
+	entries := strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == '\n' })
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+
+		id, encoded, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, cacheErrors.NewErrInvalidValue(id, encryptedCacheErrors.ErrInvalidKey, wrap)
+		}
+
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, fmt.Errorf("%s: key [%s]: %w: %w", wrap, id, encryptedCacheErrors.ErrInvalidKey, err)
+		}
+
+		id = strings.TrimSpace(id)
+		if primary == "" {
+			primary = id
+		}
+		keys[id] = key
+	}
+
+	return NewKeyring(primary, keys)
+}
+
+// LoadKeyringFromFile reads keyring from file in ParseKeyring format
+func LoadKeyringFromFile(path string) (*Keyring, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("LoadKeyringFromFile: %w", err)
+	}
+
+	return ParseKeyring(string(b))
+}
+
+// LoadKeyringFromEnv reads keyring from environment variable in ParseKeyring format
+func LoadKeyringFromEnv(name string) (*Keyring, error) {
+	return ParseKeyring(os.Getenv(name))
+}
+
+func (k *Keyring) PrimaryID() string {
+	return k.primary
+}
+
+func (k *Keyring) get(id string) (cipher.AEAD, bool) {
+	aead, ok := k.aeads[id]
+	return aead, ok
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,396 @@
+package meta_cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/maphash"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/KennyMacCormik/common/log"
+
+	"github.com/KennyMacCormik/otel/backend/pkg/cache"
+	cacheErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/cache"
+)
+
+const (
+	keyLocks             = 256
+	defaultSweepInterval = time.Second
+)
+
+// Meta is kept along with the value of a key
+type Meta struct {
+	// Deadline of the key, zero means it never expires
+	Deadline time.Time
+	// Flags are opaque to the storage, memcached clients use them
+	Flags uint32
+}
+
+// Item is a value with its metadata
+type Item struct {
+	Value any
+	Meta
+}
+
+// Action tells Update what to do with the key
+type Action int
+
+const (
+	// Keep leaves the key as it is
+	Keep Action = iota
+	// Store replaces the key with the returned item
+	Store
+	// Remove deletes the key
+	Remove
+)
+
+// UpdateFunc gets current item of the key and whether it exists, expired keys don't
+type UpdateFunc func(item Item, exists bool) (Item, Action, error)
+
+// MetaCache is implemented by cache.CacheInterface returned from NewMetaCache
+type MetaCache interface {
+	cache.CacheInterface
+	// GetItem returns value of the key with its metadata
+	GetItem(ctx context.Context, key string) (Item, error)
+	// Update applies fn to the key holding the key lock, so no other write of the key interleaves
+	Update(ctx context.Context, key string, fn UpdateFunc) error
+}
+
+// metaCache keeps metadata of keys written with Update. Set and Delete drop metadata of the key, so a key
+// written over any transport loses expiration and flags set by another one, as Redis SET does.
+// Expired keys are not found and are removed in background through the wrapped cache.
+// Metadata is only kept for keys with expiration or flags.
+type metaCache struct {
+	impl cache.CacheInterface
+
+	keyMtx [keyLocks]sync.Mutex
+	seed   maphash.Seed
+
+	mtx   sync.Mutex
+	metas map[string]Meta
+	now   func() time.Time
+
+	sweepInterval time.Duration
+	ticker        *time.Ticker
+	closedOnce    sync.Once
+	closed        atomic.Bool
+	closeCh       chan struct{}
+	doneCh        chan struct{}
+}
+
+type InitOptions func(m *metaCache)
+
+// WithSweepInterval sets how often expired keys are removed
+func WithSweepInterval(interval time.Duration) InitOptions {
+	return func(m *metaCache) {
+		if interval > 0 {
+			m.sweepInterval = interval
+		}
+	}
+}
+
+// NewMetaCache returns MetaCache over impl
+func NewMetaCache(impl cache.CacheInterface, opts ...InitOptions) (MetaCache, error) {
+	const wrap = "NewMetaCache"
+
+	err := cache.WithValueValidation(impl, wrap)()
+	if err != nil {
+		return nil, err
+	}
+
+	m := &metaCache{
+		impl:          impl,
+		seed:          maphash.MakeSeed(),
+		metas:         make(map[string]Meta),
+		now:           time.Now,
+		sweepInterval: defaultSweepInterval,
+		closeCh:       make(chan struct{}),
+		doneCh:        make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	m.ticker = time.NewTicker(m.sweepInterval)
+	go m.sweep()
+
+	return m, nil
+}
+
+// Unwrap implements cache.Unwrapper
+func (m *metaCache) Unwrap() cache.CacheInterface {
+	return m.impl
+}
+
+func (m *metaCache) Get(ctx context.Context, key string) (any, error) {
+	const wrap = "metaCache/Get"
+
+	item, err := m.getItem(ctx, key, wrap)
+	if err != nil {
+		return nil, err
+	}
+
+	return item.Value, nil
+}
+
+func (m *metaCache) GetItem(ctx context.Context, key string) (Item, error) {
+	return m.getItem(ctx, key, "metaCache/GetItem")
+}
+
+func (m *metaCache) Set(ctx context.Context, key string, value any) (int, error) {
+	const wrap = "metaCache/Set"
+	if err := cache.ValidateInput(
+		cache.WithClosedValidation(&m.closed, wrap),
+		cache.WithCtxValidation(ctx, wrap),
+		cache.WithKeyValidation(key, wrap),
+		cache.WithValueValidation(value, wrap),
+	); err != nil {
+		return 0, err
+	}
+
+	unlock := m.lock(key)
+	defer unlock()
+
+	code, err := m.impl.Set(ctx, key, value)
+	if err != nil {
+		return 0, err
+	}
+	m.setMeta(key, Meta{})
+
+	return code, nil
+}
+
+func (m *metaCache) Delete(ctx context.Context, key string) error {
+	const wrap = "metaCache/Delete"
+	if err := cache.ValidateInput(
+		cache.WithClosedValidation(&m.closed, wrap),
+		cache.WithCtxValidation(ctx, wrap),
+		cache.WithKeyValidation(key, wrap),
+	); err != nil {
+		return err
+	}
+
+	unlock := m.lock(key)
+	defer unlock()
+
+	if m.expired(key) {
+		if err := m.remove(ctx, key); err != nil {
+			return err
+		}
+		return fmt.Errorf("%s: %w", wrap, cacheErrors.NewErrKeyNotFound(key))
+	}
+
+	err := m.impl.Delete(ctx, key)
+	if err == nil || errors.Is(err, cacheErrors.ErrNotFound) {
+		m.setMeta(key, Meta{})
+	}
+
+	return err
+}
+
+func (m *metaCache) Update(ctx context.Context, key string, fn UpdateFunc) error {
+	const wrap = "metaCache/Update"
+	if err := cache.ValidateInput(
+		cache.WithClosedValidation(&m.closed, wrap),
+		cache.WithCtxValidation(ctx, wrap),
+		cache.WithKeyValidation(key, wrap),
+		cache.WithValueValidation(fn, wrap),
+	); err != nil {
+		return err
+	}
+
+	unlock := m.lock(key)
+	defer unlock()
+
+	item, err := m.load(ctx, key)
+	exists := err == nil
+	if err != nil && !errors.Is(err, cacheErrors.ErrNotFound) {
+		return err
+	}
+
+	next, action, err := fn(item, exists)
+	if err != nil {
+		return err
+	}
+
+	switch action {
+	case Store:
+		if !next.Deadline.IsZero() && !m.now().Before(next.Deadline) {
+			return m.remove(ctx, key)
+		}
+		if _, err = m.impl.Set(ctx, key, next.Value); err != nil {
+			return err
+		}
+		m.setMeta(key, next.Meta)
+	case Remove:
+		if exists {
+			return m.remove(ctx, key)
+		}
+	}
+
+	return nil
+}
+
+func (m *metaCache) Close(ctx context.Context) error {
+	var err error
+	m.closedOnce.Do(func() {
+		m.closed.Store(true)
+		close(m.closeCh)
+		<-m.doneCh
+		err = m.impl.Close(ctx)
+	})
+
+	return err
+}
+
+// GetKeys skips expired keys
+func (m *metaCache) GetKeys(ctx context.Context) ([]string, error) {
+	const wrap = "metaCache/GetKeys"
+	if err := cache.ValidateInput(
+		cache.WithClosedValidation(&m.closed, wrap),
+		cache.WithCtxValidation(ctx, wrap),
+	); err != nil {
+		return nil, err
+	}
+
+	keys, err := m.impl.GetKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	live := keys[:0]
+	for _, key := range keys {
+		if !m.expired(key) {
+			live = append(live, key)
+		}
+	}
+
+	return live, nil
+}
+
+// GetLength counts expired keys until they are removed
+func (m *metaCache) GetLength() (int64, error) {
+	const wrap = "metaCache/GetLength"
+	if err := cache.ValidateInput(
+		cache.WithClosedValidation(&m.closed, wrap),
+	); err != nil {
+		return 0, err
+	}
+
+	return m.impl.GetLength()
+}
+
+func (m *metaCache) getItem(ctx context.Context, key, wrap string) (Item, error) {
+	if err := cache.ValidateInput(
+		cache.WithClosedValidation(&m.closed, wrap),
+		cache.WithCtxValidation(ctx, wrap),
+		cache.WithKeyValidation(key, wrap),
+	); err != nil {
+		return Item{}, err
+	}
+
+	item, err := m.load(ctx, key)
+	if err != nil {
+		return Item{}, fmt.Errorf("%s: %w", wrap, err)
+	}
+
+	return item, nil
+}
+
+// load returns item of the key, expired key is reported as not found
+func (m *metaCache) load(ctx context.Context, key string) (Item, error) {
+	meta, _ := m.getMeta(key)
+	if !meta.Deadline.IsZero() && !m.now().Before(meta.Deadline) {
+		return Item{}, cacheErrors.NewErrKeyNotFound(key)
+	}
+
+	value, err := m.impl.Get(ctx, key)
+	if err != nil {
+		return Item{}, err
+	}
+
+	return Item{Value: value, Meta: meta}, nil
+}
+
+// remove deletes key with its metadata. Key must be locked.
+func (m *metaCache) remove(ctx context.Context, key string) error {
+	if err := m.impl.Delete(ctx, key); err != nil && !errors.Is(err, cacheErrors.ErrNotFound) {
+		return err
+	}
+	m.setMeta(key, Meta{})
+
+	return nil
+}
+
+func (m *metaCache) sweep() {
+	defer close(m.doneCh)
+
+	for {
+		select {
+		case <-m.ticker.C:
+			m.sweepOnce()
+		case <-m.closeCh:
+			m.ticker.Stop()
+			return
+		}
+	}
+}
+
+func (m *metaCache) sweepOnce() {
+	now := m.now()
+
+	var expired []string
+	m.mtx.Lock()
+	for key, meta := range m.metas {
+		if !meta.Deadline.IsZero() && !now.Before(meta.Deadline) {
+			expired = append(expired, key)
+		}
+	}
+	m.mtx.Unlock()
+
+	for _, key := range expired {
+		unlock := m.lock(key)
+		// the key might have been rewritten since it was collected
+		if m.expired(key) {
+			if err := m.remove(context.Background(), key); err != nil {
+				log.Warn("metaCache/sweep: failed to remove expired key", "key", key, "err", err)
+			}
+		}
+		unlock()
+	}
+}
+
+func (m *metaCache) lock(key string) func() {
+	mtx := &m.keyMtx[maphash.String(m.seed, key)%keyLocks]
+	mtx.Lock()
+
+	return mtx.Unlock
+}
+
+func (m *metaCache) expired(key string) bool {
+	meta, ok := m.getMeta(key)
+	return ok && !meta.Deadline.IsZero() && !m.now().Before(meta.Deadline)
+}
+
+func (m *metaCache) getMeta(key string) (Meta, bool) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	meta, ok := m.metas[key]
+	return meta, ok
+}
+
+// setMeta forgets zero metadata, so keys without expiration and flags take no memory
+func (m *metaCache) setMeta(key string, meta Meta) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	if meta == (Meta{}) {
+		delete(m.metas, key)
+		return
+	}
+	m.metas[key] = meta
+}
@@ -0,0 +1,197 @@
+package meta_cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KennyMacCormik/otel/backend/pkg/cache"
+	"github.com/KennyMacCormik/otel/backend/pkg/cache/impl/sync_map"
+	"github.com/KennyMacCormik/otel/backend/pkg/cache/wrappers/watch_cache"
+	cacheErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/cache"
+)
+
+func typeAssertion(t *testing.T, c cache.CacheInterface) *metaCache {
+	cacheImpl, ok := c.(*metaCache)
+	require.True(t, ok, "expect result to be of type *metaCache")
+	require.NotNil(t, cacheImpl, "expect result to be not nil")
+	return cacheImpl
+}
+
+func newTestCache(t *testing.T, impl cache.CacheInterface) (*metaCache, *time.Time) {
+	mc, err := NewMetaCache(impl, WithSweepInterval(time.Hour))
+	require.NoError(t, err, "expect no error with valid configuration")
+	t.Cleanup(func() { _ = mc.Close(context.Background()) })
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	cacheImpl := typeAssertion(t, mc)
+	cacheImpl.now = func() time.Time { return now }
+
+	return cacheImpl, &now
+}
+
+func store(item Item) UpdateFunc {
+	return func(Item, bool) (Item, Action, error) {
+		return item, Store, nil
+	}
+}
+
+func TestMetaCache_New(t *testing.T) {
+	_, err := NewMetaCache(nil)
+	assert.ErrorIs(t, err, cacheErrors.ErrNil, "expect error with nil impl")
+
+	mc, _ := newTestCache(t, sync_map.NewSyncMapCache())
+	assert.Implements(t, (*MetaCache)(nil), mc, "result should implement MetaCache")
+}
+
+func TestMetaCache_Expiration(t *testing.T) {
+	mc, now := newTestCache(t, sync_map.NewSyncMapCache())
+	ctx := context.Background()
+
+	deadline := now.Add(time.Second)
+	require.NoError(t, mc.Update(ctx, "k", store(Item{Value: "v", Meta: Meta{Deadline: deadline, Flags: 7}})), "expect no error on update")
+
+	item, err := mc.GetItem(ctx, "k")
+	require.NoError(t, err, "expect key before deadline")
+	assert.Equal(t, Item{Value: "v", Meta: Meta{Deadline: deadline, Flags: 7}}, item, "Unexpected item")
+
+	*now = deadline
+	_, err = mc.Get(ctx, "k")
+	assert.ErrorIs(t, err, cacheErrors.ErrNotFound, "expect expired key not to be found")
+
+	keys, err := mc.GetKeys(ctx)
+	require.NoError(t, err, "expect no error on keys")
+	assert.Empty(t, keys, "expect expired key to be skipped")
+
+	mc.sweepOnce()
+	n, err := mc.impl.GetLength()
+	require.NoError(t, err, "expect no error on length")
+	assert.Zero(t, n, "expect expired key to be removed from impl")
+	assert.Empty(t, mc.metas, "expect metadata to be removed")
+}
+
+func TestMetaCache_ForeignWrite(t *testing.T) {
+	mc, now := newTestCache(t, sync_map.NewSyncMapCache())
+	ctx := context.Background()
+
+	require.NoError(t, mc.Update(ctx, "k", store(Item{Value: "v", Meta: Meta{Deadline: now.Add(time.Second), Flags: 1}})), "expect no error on update")
+
+	_, err := mc.Set(ctx, "k", "other")
+	require.NoError(t, err, "expect no error on set")
+	assert.Empty(t, mc.metas, "expect set to drop metadata")
+
+	*now = now.Add(time.Hour)
+	mc.sweepOnce()
+
+	item, err := mc.GetItem(ctx, "k")
+	require.NoError(t, err, "expect key written by set to outlive the old deadline")
+	assert.Equal(t, Item{Value: "other"}, item, "Unexpected item")
+}
+
+func TestMetaCache_Update(t *testing.T) {
+	mc, now := newTestCache(t, sync_map.NewSyncMapCache())
+	ctx := context.Background()
+
+	var seen bool
+	err := mc.Update(ctx, "k", func(item Item, exists bool) (Item, Action, error) {
+		seen = exists
+		return item, Keep, nil
+	})
+	require.NoError(t, err, "expect no error on update")
+	assert.False(t, seen, "expect missing key")
+
+	require.NoError(t, mc.Update(ctx, "k", store(Item{Value: "v"})), "expect no error on update")
+	assert.Empty(t, mc.metas, "expect no metadata for key without expiration and flags")
+
+	errUpdate := errors.New("update failed")
+	err = mc.Update(ctx, "k", func(item Item, exists bool) (Item, Action, error) {
+		return Item{Value: "x"}, Store, errUpdate
+	})
+	assert.ErrorIs(t, err, errUpdate, "expect error of fn")
+
+	val, err := mc.Get(ctx, "k")
+	require.NoError(t, err, "expect no error on get")
+	assert.Equal(t, "v", val, "expect failed update not to change the key")
+
+	require.NoError(t, mc.Update(ctx, "k", store(Item{Value: "v", Meta: Meta{Deadline: *now}})), "expect no error on update")
+	_, err = mc.Get(ctx, "k")
+	assert.ErrorIs(t, err, cacheErrors.ErrNotFound, "expect key stored with past deadline to be removed")
+
+	require.NoError(t, mc.Update(ctx, "k", store(Item{Value: "v"})), "expect no error on update")
+	require.NoError(t, mc.Update(ctx, "k", func(item Item, exists bool) (Item, Action, error) {
+		return item, Remove, nil
+	}), "expect no error on update")
+	_, err = mc.Get(ctx, "k")
+	assert.ErrorIs(t, err, cacheErrors.ErrNotFound, "expect removed key not to be found")
+}
+
+func TestMetaCache_Delete(t *testing.T) {
+	mc, now := newTestCache(t, sync_map.NewSyncMapCache())
+	ctx := context.Background()
+
+	require.NoError(t, mc.Update(ctx, "k", store(Item{Value: "v", Meta: Meta{Deadline: now.Add(time.Second)}})), "expect no error on update")
+	*now = now.Add(time.Second)
+
+	assert.ErrorIs(t, mc.Delete(ctx, "k"), cacheErrors.ErrNotFound, "expect expired key not to be found")
+	assert.Empty(t, mc.metas, "expect metadata to be removed")
+}
+
+func TestMetaCache_Atomic(t *testing.T) {
+	mc, _ := newTestCache(t, sync_map.NewSyncMapCache())
+	ctx := context.Background()
+
+	incr := func(item Item, exists bool) (Item, Action, error) {
+		n := 0
+		if exists {
+			n = item.Value.(int)
+		}
+		return Item{Value: n + 1}, Store, nil
+	}
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				assert.NoError(t, mc.Update(ctx, "k", incr), "expect no error on update")
+			}
+		}()
+	}
+	wg.Wait()
+
+	val, err := mc.Get(ctx, "k")
+	require.NoError(t, err, "expect no error on get")
+	assert.Equal(t, 800, val, "expect no lost updates")
+}
+
+func TestMetaCache_Unwrap(t *testing.T) {
+	wc, err := watch_cache.NewWatchCache(sync_map.NewSyncMapCache())
+	require.NoError(t, err, "expect no error with valid configuration")
+
+	mc, now := newTestCache(t, wc)
+	ctx := context.Background()
+
+	watcher, ok := cache.As[watch_cache.Watcher](mc)
+	require.True(t, ok, "expect watcher to be found under meta cache")
+
+	sub, err := watcher.Watch(ctx, "")
+	require.NoError(t, err, "expect no error on watch")
+
+	require.NoError(t, mc.Update(ctx, "k", store(Item{Value: "v", Meta: Meta{Deadline: now.Add(time.Second)}})), "expect no error on update")
+	*now = now.Add(time.Second)
+	mc.sweepOnce()
+
+	for range 2 {
+		select {
+		case <-sub.Events():
+		case <-time.After(time.Second):
+			require.Fail(t, "expect write and expiration to reach watchers")
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package quota_cache
+
+import "github.com/prometheus/client_golang/prometheus"
+
+const tenantLabel = "tenant"
+
+var (
+	keysDesc = prometheus.NewDesc("storage_tenant_keys",
+		"Number of keys stored by the tenant", []string{tenantLabel}, nil)
+	bytesDesc = prometheus.NewDesc("storage_tenant_bytes",
+		"Number of bytes of keys and values stored by the tenant", []string{tenantLabel}, nil)
+	rejectedDesc = prometheus.NewDesc("storage_tenant_rejected_writes",
+		"Number of writes rejected as they would exceed the tenant quota", []string{tenantLabel}, nil)
+	maxKeysDesc = prometheus.NewDesc("storage_tenant_quota_keys",
+		"Maximum number of keys the tenant may store, zero means no limit", []string{tenantLabel}, nil)
+	maxBytesDesc = prometheus.NewDesc("storage_tenant_quota_bytes",
+		"Maximum number of bytes the tenant may store, zero means no limit", []string{tenantLabel}, nil)
+)
+
+type collector struct {
+	usage UsageGetter
+}
+
+// NewCollector returns prometheus.Collector exporting usage and quota of every tenant
+func NewCollector(usage UsageGetter) prometheus.Collector {
+	return &collector{usage: usage}
+}
+
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- keysDesc
+	ch <- bytesDesc
+	ch <- rejectedDesc
+	ch <- maxKeysDesc
+	ch <- maxBytesDesc
+}
+
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	for t, u := range c.usage.GetUsage() {
+		ch <- prometheus.MustNewConstMetric(keysDesc, prometheus.GaugeValue, float64(u.Keys), t)
+		ch <- prometheus.MustNewConstMetric(bytesDesc, prometheus.GaugeValue, float64(u.Bytes), t)
+		ch <- prometheus.MustNewConstMetric(rejectedDesc, prometheus.CounterValue, float64(u.Rejected), t)
+		ch <- prometheus.MustNewConstMetric(maxKeysDesc, prometheus.GaugeValue, float64(u.MaxKeys), t)
+		ch <- prometheus.MustNewConstMetric(maxBytesDesc, prometheus.GaugeValue, float64(u.MaxBytes), t)
+	}
+}
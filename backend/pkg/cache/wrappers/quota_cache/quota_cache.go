@@ -0,0 +1,351 @@
+package quota_cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/maphash"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/KennyMacCormik/otel/backend/pkg/cache"
+	cacheErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/cache"
+	"github.com/KennyMacCormik/otel/backend/pkg/models/tenant"
+)
+
+const keyLocks = 64
+
+// Quota limits storage usage of a tenant, zero means no limit
+type Quota struct {
+	MaxKeys  int64 `json:"max_keys"`
+	MaxBytes int64 `json:"max_bytes"`
+}
+
+// Usage of storage by a tenant. Bytes include keys and values as they were written.
+type Usage struct {
+	Keys     int64 `json:"keys"`
+	Bytes    int64 `json:"bytes"`
+	Rejected int64 `json:"rejected_writes"`
+	Quota
+}
+
+// UsageGetter reports usage of every tenant that has a quota or has written anything
+type UsageGetter interface {
+	GetUsage() map[string]Usage
+}
+
+// quotaCache accounts keys and bytes stored by every tenant, see tenant.FromContext,
+// and rejects Set exceeding the tenant quota. Usage is updated incrementally on Set and Delete,
+// so every write must go through the wrapper. A key belongs to the tenant that wrote it last.
+type quotaCache struct {
+	impl cache.CacheInterface
+
+	defaultQuota Quota
+	quotas       map[string]Quota
+
+	// keyMtx serializes writes of a key, so usage follows the order writes reach impl
+	keyMtx [keyLocks]sync.Mutex
+	seed   maphash.Seed
+
+	mtx    sync.Mutex
+	owners map[string]owner
+	usage  map[string]*Usage
+
+	closedOnce sync.Once
+	closed     atomic.Bool
+}
+
+type owner struct {
+	tenant string
+	size   int64
+}
+
+type InitOptions func(q *quotaCache)
+
+// WithQuotas sets quotas per tenant, tenants without one get defaultQuota
+func WithQuotas(defaultQuota Quota, quotas map[string]Quota) InitOptions {
+	return func(q *quotaCache) {
+		q.defaultQuota = defaultQuota
+		for t, quota := range quotas {
+			q.quotas[t] = quota
+		}
+	}
+}
+
+// NewQuotaCache returns cache.CacheInterface failing Set with cacheErrors.ErrQuotaExceeded
+// once the tenant stores too many keys or bytes. Only string and []byte values are supported.
+// Without options no quota is enforced, usage is tracked anyway.
+func NewQuotaCache(impl cache.CacheInterface, opts ...InitOptions) (cache.CacheInterface, error) {
+	const wrap = "NewQuotaCache"
+
+	err := cache.WithValueValidation(impl, wrap)()
+	if err != nil {
+		return nil, err
+	}
+
+	q := &quotaCache{
+		impl:   impl,
+		quotas: make(map[string]Quota),
+		seed:   maphash.MakeSeed(),
+		owners: make(map[string]owner),
+		usage:  make(map[string]*Usage),
+	}
+
+	for _, opt := range opts {
+		opt(q)
+	}
+
+	return q, nil
+}
+
+func (q *quotaCache) Get(ctx context.Context, key string) (any, error) {
+	const wrap = "quotaCache/Get"
+	if err := cache.ValidateInput(
+		cache.WithClosedValidation(&q.closed, wrap),
+		cache.WithCtxValidation(ctx, wrap),
+		cache.WithKeyValidation(key, wrap),
+	); err != nil {
+		return nil, err
+	}
+
+	return q.impl.Get(ctx, key)
+}
+
+func (q *quotaCache) Set(ctx context.Context, key string, value any) (int, error) {
+	const wrap = "quotaCache/Set"
+	if err := cache.ValidateInput(
+		cache.WithClosedValidation(&q.closed, wrap),
+		cache.WithCtxValidation(ctx, wrap),
+		cache.WithKeyValidation(key, wrap),
+		cache.WithValueValidation(value, wrap),
+	); err != nil {
+		return 0, err
+	}
+
+	size, err := cache.ValueSize(value, wrap)
+	if err != nil {
+		return 0, err
+	}
+	size += int64(len(key))
+
+	t := tenant.FromContext(ctx)
+
+	m := q.keyLock(key)
+	m.Lock()
+	defer m.Unlock()
+
+	prev, existed, err := q.reserve(t, key, size)
+	if err != nil {
+		return 0, fmt.Errorf("%s: tenant %s: key %s: %w", wrap, t, key, err)
+	}
+
+	code, err := q.impl.Set(ctx, key, value)
+	if err != nil {
+		q.mtx.Lock()
+		q.release(key)
+		if existed {
+			q.acquire(key, prev)
+		}
+		q.mtx.Unlock()
+	}
+
+	return code, err
+}
+
+func (q *quotaCache) Delete(ctx context.Context, key string) error {
+	const wrap = "quotaCache/Delete"
+	if err := cache.ValidateInput(
+		cache.WithClosedValidation(&q.closed, wrap),
+		cache.WithCtxValidation(ctx, wrap),
+		cache.WithKeyValidation(key, wrap),
+	); err != nil {
+		return err
+	}
+
+	m := q.keyLock(key)
+	m.Lock()
+	defer m.Unlock()
+
+	err := q.impl.Delete(ctx, key)
+	if err == nil || errors.Is(err, cacheErrors.ErrNotFound) {
+		q.mtx.Lock()
+		q.release(key)
+		q.mtx.Unlock()
+	}
+
+	return err
+}
+
+func (q *quotaCache) Close(ctx context.Context) error {
+	var err error
+	q.closedOnce.Do(func() {
+		q.closed.Store(true)
+		err = q.impl.Close(ctx)
+	})
+
+	return err
+}
+
+func (q *quotaCache) GetKeys(ctx context.Context) ([]string, error) {
+	const wrap = "quotaCache/GetKeys"
+	if err := cache.ValidateInput(
+		cache.WithClosedValidation(&q.closed, wrap),
+		cache.WithCtxValidation(ctx, wrap),
+	); err != nil {
+		return nil, err
+	}
+
+	return q.impl.GetKeys(ctx)
+}
+
+func (q *quotaCache) GetLength() (int64, error) {
+	const wrap = "quotaCache/GetLength"
+	if err := cache.ValidateInput(
+		cache.WithClosedValidation(&q.closed, wrap),
+	); err != nil {
+		return 0, err
+	}
+
+	return q.impl.GetLength()
+}
+
+// GetUsage returns usage of tenant.Default, tenants with a quota and tenants that have written anything
+func (q *quotaCache) GetUsage() map[string]Usage {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	res := make(map[string]Usage, len(q.usage)+len(q.quotas)+1)
+	res[tenant.Default] = Usage{Quota: q.quota(tenant.Default)}
+	for t, quota := range q.quotas {
+		res[t] = Usage{Quota: quota}
+	}
+	for t, u := range q.usage {
+		res[t] = *u
+	}
+
+	return res
+}
+
+func (q *quotaCache) keyLock(key string) *sync.Mutex {
+	return &q.keyMtx[maphash.String(q.seed, key)%keyLocks]
+}
+
+func (q *quotaCache) quota(t string) Quota {
+	if quota, ok := q.quotas[t]; ok {
+		return quota
+	}
+
+	return q.defaultQuota
+}
+
+// tenantUsage must be called with mtx held
+func (q *quotaCache) tenantUsage(t string) *Usage {
+	u, ok := q.usage[t]
+	if !ok {
+		u = &Usage{Quota: q.quota(t)}
+		q.usage[t] = u
+	}
+
+	return u
+}
+
+// reserve accounts key of size to tenant t if it fits the quota and returns the previous owner of the key.
+// Overwriting a key of another tenant moves it to t, so it counts toward the quota of t in full.
+func (q *quotaCache) reserve(t, key string, size int64) (owner, bool, error) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	u := q.tenantUsage(t)
+	prev, existed := q.owners[key]
+
+	dKeys, dBytes := int64(1), size
+	if existed && prev.tenant == t {
+		dKeys, dBytes = 0, size-prev.size
+	}
+
+	if dKeys > 0 && u.MaxKeys > 0 && u.Keys+dKeys > u.MaxKeys {
+		u.Rejected++
+		return owner{}, false, fmt.Errorf("%w: %d keys", cacheErrors.ErrQuotaExceeded, u.MaxKeys)
+	}
+
+	if dBytes > 0 && u.MaxBytes > 0 && u.Bytes+dBytes > u.MaxBytes {
+		u.Rejected++
+		return owner{}, false, fmt.Errorf("%w: %d bytes", cacheErrors.ErrQuotaExceeded, u.MaxBytes)
+	}
+
+	q.release(key)
+	q.acquire(key, owner{tenant: t, size: size})
+
+	return prev, existed, nil
+}
+
+// acquire must be called with mtx held
+func (q *quotaCache) acquire(key string, o owner) {
+	u := q.tenantUsage(o.tenant)
+	u.Keys++
+	u.Bytes += o.size
+	q.owners[key] = o
+}
+
+// release must be called with mtx held
+func (q *quotaCache) release(key string) {
+	o, ok := q.owners[key]
+	if !ok {
+		return
+	}
+
+	u := q.tenantUsage(o.tenant)
+	u.Keys--
+	u.Bytes -= o.size
+	delete(q.owners, key)
+}
+
+// ParseQuotas parses comma separated list of tenant=max_keys:max_bytes pairs, zero means no limit.
+//
+// Example:
+//
+// search=100000:1073741824,billing=5000:0
+func ParseQuotas(s string) (map[string]Quota, error) {
+	quotas := make(map[string]Quota)
+
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		t, value, ok := strings.Cut(entry, "=")
+		t, value = strings.TrimSpace(t), strings.TrimSpace(value)
+		if !ok || t == "" {
+			return nil, fmt.Errorf("ParseQuotas: invalid entry [%s]", entry)
+		}
+
+		maxKeys, maxBytes, ok := strings.Cut(value, ":")
+		if !ok {
+			return nil, fmt.Errorf("ParseQuotas: invalid quota for tenant [%s]: %s", t, value)
+		}
+
+		var quota Quota
+		var err error
+
+		quota.MaxKeys, err = strconv.ParseInt(maxKeys, 10, 64)
+		if err != nil || quota.MaxKeys < 0 {
+			return nil, fmt.Errorf("ParseQuotas: invalid max keys for tenant [%s]: %s", t, value)
+		}
+
+		quota.MaxBytes, err = strconv.ParseInt(maxBytes, 10, 64)
+		if err != nil || quota.MaxBytes < 0 {
+			return nil, fmt.Errorf("ParseQuotas: invalid max bytes for tenant [%s]: %s", t, value)
+		}
+
+		if _, ok = quotas[t]; ok {
+			return nil, fmt.Errorf("ParseQuotas: duplicate tenant [%s]", t)
+		}
+
+		quotas[t] = quota
+	}
+
+	return quotas, nil
+}
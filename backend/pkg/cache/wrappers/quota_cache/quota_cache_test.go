@@ -0,0 +1,218 @@
+package quota_cache
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KennyMacCormik/otel/backend/pkg/cache"
+	"github.com/KennyMacCormik/otel/backend/pkg/cache/impl/sync_map"
+	cacheErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/cache"
+	"github.com/KennyMacCormik/otel/backend/pkg/models/tenant"
+
+	mockCache "github.com/KennyMacCormik/otel/backend/pkg/cache/mocks"
+)
+
+func typeAssertion(t *testing.T, c cache.CacheInterface) *quotaCache {
+	cacheImpl, ok := c.(*quotaCache)
+	require.True(t, ok, "expect result to be of type *quotaCache")
+	require.NotNil(t, cacheImpl, "expect result to be not nil")
+	return cacheImpl
+}
+
+func newTestCache(t *testing.T, opts ...InitOptions) *quotaCache {
+	qc, err := NewQuotaCache(sync_map.NewSyncMapCache(), opts...)
+	require.NoError(t, err, "expect no error with valid configuration")
+	t.Cleanup(func() { _ = qc.Close(context.Background()) })
+
+	return typeAssertion(t, qc)
+}
+
+func TestQuotaCache_New(t *testing.T) {
+	c := mockCache.NewMockCacheInterface(t)
+	c.EXPECT().Close(context.Background()).Return(nil)
+
+	qc, err := NewQuotaCache(c, WithQuotas(Quota{MaxKeys: 10}, map[string]Quota{"a": {MaxBytes: 100}}))
+	require.NoError(t, err, "expect no error with valid configuration")
+	assert.Implements(t, (*cache.CacheInterface)(nil), qc, "result should implement cache.Interface")
+
+	usage := typeAssertion(t, qc).GetUsage()
+	assert.Equal(t, map[string]Usage{
+		tenant.Default: {Quota: Quota{MaxKeys: 10}},
+		"a":            {Quota: Quota{MaxBytes: 100}},
+	}, usage, "expect default and configured tenants with zero usage")
+	require.NoError(t, qc.Close(context.Background()), "expect no error on close")
+
+	_, err = NewQuotaCache(nil)
+	assert.ErrorIs(t, err, cacheErrors.ErrNil, "expect error with nil impl")
+}
+
+func TestQuotaCache_Keys(t *testing.T) {
+	qc := newTestCache(t, WithQuotas(Quota{}, map[string]Quota{"a": {MaxKeys: 2}}))
+	ctx := tenant.NewContext(context.Background(), "a")
+
+	for _, key := range []string{"k1", "k2"} {
+		_, err := qc.Set(ctx, key, "v")
+		require.NoError(t, err, "expect writes within quota to succeed")
+	}
+
+	_, err := qc.Set(ctx, "k1", "value")
+	require.NoError(t, err, "expect overwrite of own key to succeed")
+
+	_, err = qc.Set(ctx, "k3", "v")
+	assert.ErrorIs(t, err, cacheErrors.ErrQuotaExceeded, "expect new key above quota to be rejected")
+
+	_, err = qc.Get(ctx, "k3")
+	assert.ErrorIs(t, err, cacheErrors.ErrNotFound, "expect rejected key not to be stored")
+
+	require.NoError(t, qc.Delete(ctx, "k2"), "expect no error on delete")
+	_, err = qc.Set(ctx, "k3", "v")
+	require.NoError(t, err, "expect delete to free quota")
+
+	_, err = qc.Set(context.Background(), "k4", "v")
+	require.NoError(t, err, "expect default tenant not to be limited")
+
+	usage := qc.GetUsage()
+	assert.Equal(t, Usage{Keys: 2, Bytes: int64(len("k1value") + len("k3v")), Rejected: 1, Quota: Quota{MaxKeys: 2}}, usage["a"], "Unexpected usage of a")
+	assert.Equal(t, Usage{Keys: 1, Bytes: int64(len("k4v"))}, usage[tenant.Default], "Unexpected usage of default tenant")
+}
+
+func TestQuotaCache_Bytes(t *testing.T) {
+	qc := newTestCache(t, WithQuotas(Quota{MaxBytes: 10}, nil))
+	ctx := context.Background()
+
+	_, err := qc.Set(ctx, "k", strings.Repeat("v", 9))
+	require.NoError(t, err, "expect write within quota to succeed")
+
+	_, err = qc.Set(ctx, "k", strings.Repeat("v", 10))
+	assert.ErrorIs(t, err, cacheErrors.ErrQuotaExceeded, "expect growing value above quota to be rejected")
+
+	_, err = qc.Set(ctx, "k", []byte("v"))
+	require.NoError(t, err, "expect shrinking value to succeed")
+
+	_, err = qc.Set(ctx, "other", "v")
+	require.NoError(t, err, "expect freed bytes to be reused")
+
+	_, err = qc.Set(ctx, "k", 1)
+	assert.ErrorIs(t, err, cacheErrors.ErrUnsupportedType, "expect unsupported value to be rejected")
+
+	assert.Equal(t, int64(len("kv")+len("otherv")), qc.GetUsage()[tenant.Default].Bytes, "Unexpected bytes")
+}
+
+func TestQuotaCache_Ownership(t *testing.T) {
+	qc := newTestCache(t, WithQuotas(Quota{}, map[string]Quota{"b": {MaxKeys: 1}}))
+	a := tenant.NewContext(context.Background(), "a")
+	b := tenant.NewContext(context.Background(), "b")
+
+	_, err := qc.Set(a, "k1", "v")
+	require.NoError(t, err, "expect no error on set")
+	_, err = qc.Set(a, "k2", "v")
+	require.NoError(t, err, "expect no error on set")
+
+	_, err = qc.Set(b, "k1", "value")
+	require.NoError(t, err, "expect overwrite within quota to succeed")
+
+	_, err = qc.Set(b, "k2", "value")
+	assert.ErrorIs(t, err, cacheErrors.ErrQuotaExceeded, "expect overwrite of another tenant key to count toward quota")
+
+	usage := qc.GetUsage()
+	assert.Equal(t, int64(1), usage["a"].Keys, "expect overwritten key to move away from a")
+	assert.Equal(t, int64(len("k2v")), usage["a"].Bytes, "Unexpected bytes of a")
+	assert.Equal(t, int64(1), usage["b"].Keys, "expect overwritten key to move to b")
+	assert.Equal(t, int64(len("k1value")), usage["b"].Bytes, "Unexpected bytes of b")
+
+	require.NoError(t, qc.Delete(b, "k2"), "expect any tenant to delete the key")
+	assert.Zero(t, qc.GetUsage()["a"].Keys, "expect delete to release the key of the owner")
+}
+
+func TestQuotaCache_Rollback(t *testing.T) {
+	c := mockCache.NewMockCacheInterface(t)
+	errSet := errors.New("set failed")
+	ctx := context.Background()
+
+	c.EXPECT().Set(ctx, "k", "v").Return(201, nil).Once()
+	c.EXPECT().Set(ctx, "k", "value").Return(0, errSet).Once()
+	c.EXPECT().Delete(ctx, "missing").Return(cacheErrors.ErrNotFound).Once()
+	c.EXPECT().Delete(ctx, "k").Return(errSet).Once()
+
+	qc, err := NewQuotaCache(c)
+	require.NoError(t, err, "expect no error with valid configuration")
+	cacheImpl := typeAssertion(t, qc)
+
+	_, err = qc.Set(ctx, "k", "v")
+	require.NoError(t, err, "expect no error on set")
+
+	_, err = qc.Set(ctx, "k", "value")
+	assert.ErrorIs(t, err, errSet, "expect error of impl")
+	assert.Equal(t, Usage{Keys: 1, Bytes: int64(len("kv"))}, cacheImpl.GetUsage()[tenant.Default], "expect failed set to be rolled back")
+
+	assert.ErrorIs(t, qc.Delete(ctx, "missing"), cacheErrors.ErrNotFound, "expect error of impl")
+	assert.ErrorIs(t, qc.Delete(ctx, "k"), errSet, "expect error of impl")
+	assert.Equal(t, int64(1), cacheImpl.GetUsage()[tenant.Default].Keys, "expect failed delete to keep the key")
+}
+
+func TestQuotaCache_Concurrent(t *testing.T) {
+	qc := newTestCache(t, WithQuotas(Quota{MaxKeys: 50}, nil))
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 100 {
+				key := string(rune('a'+i)) + string(rune('a'+j%26))
+				_, _ = qc.Set(ctx, key, "v")
+				if j%3 == 0 {
+					_ = qc.Delete(ctx, key)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	n, err := qc.GetLength()
+	require.NoError(t, err, "expect no error on length")
+
+	usage := qc.GetUsage()[tenant.Default]
+	assert.Equal(t, n, usage.Keys, "expect usage to match stored keys")
+	assert.Equal(t, n*3, usage.Bytes, "expect usage to match stored bytes")
+	assert.LessOrEqual(t, usage.Keys, int64(50), "expect quota to hold")
+}
+
+func TestCollector(t *testing.T) {
+	qc := newTestCache(t, WithQuotas(Quota{MaxKeys: 10, MaxBytes: 100}, nil))
+	_, err := qc.Set(context.Background(), "k", "v")
+	require.NoError(t, err, "expect no error on set")
+
+	reg := prometheus.NewRegistry()
+	require.NoError(t, reg.Register(NewCollector(qc)), "expect collector to register")
+
+	expected := `
+# HELP storage_tenant_keys Number of keys stored by the tenant
+# TYPE storage_tenant_keys gauge
+storage_tenant_keys{tenant="default"} 1
+# HELP storage_tenant_quota_bytes Maximum number of bytes the tenant may store, zero means no limit
+# TYPE storage_tenant_quota_bytes gauge
+storage_tenant_quota_bytes{tenant="default"} 100
+`
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expected), "storage_tenant_keys", "storage_tenant_quota_bytes"), "Unexpected metrics")
+}
+
+func TestParseQuotas(t *testing.T) {
+	quotas, err := ParseQuotas(" a=10:0, b=0:1024,")
+	require.NoError(t, err, "expect valid list to be parsed")
+	assert.Equal(t, map[string]Quota{"a": {MaxKeys: 10}, "b": {MaxBytes: 1024}}, quotas, "Unexpected quotas")
+
+	for _, s := range []string{"a", "a=10", "=1:1", "a=x:1", "a=1:-1", "a=1:1,a=2:2"} {
+		_, err = ParseQuotas(s)
+		assert.Error(t, err, "expect error for [%s]", s)
+	}
+}
@@ -0,0 +1,258 @@
+package tiered_cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/KennyMacCormik/common/conv"
+	"github.com/KennyMacCormik/common/log"
+
+	"github.com/KennyMacCormik/otel/backend/pkg/cache"
+	cacheErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/cache"
+	tieredCacheErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/tiered_cache"
+	ttlCacheErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/ttl_cache"
+)
+
+type WritePolicy string
+
+const (
+	// WriteThrough writes value to L2 and then to L1
+	WriteThrough WritePolicy = "write-through"
+	// WriteAround writes value to L2 and invalidates L1, value gets to L1 on the next L2 hit
+	WriteAround WritePolicy = "write-around"
+)
+
+const (
+	defaultWritePolicy = WriteThrough
+	stripeNumber       = 256
+)
+
+// stripe serializes writes of a subset of keys to both tiers.
+// epoch is incremented on every write or delete, so Get doesn't promote a value read from L2 before the write.
+type stripe struct {
+	mtx   sync.Mutex
+	epoch uint64
+}
+
+// tieredCache composes small and fast L1 with larger L2.
+// L2 is the source of truth: it returns Set codes, keys and length, while L1 only holds a subset of L2 entries.
+// L1 failures are logged and never fail the request, since L2 still has the value.
+type tieredCache struct {
+	l1, l2 cache.CacheInterface
+
+	policy  WritePolicy
+	stripes [stripeNumber]stripe
+
+	closedOnce sync.Once
+	closed     atomic.Bool
+}
+
+type InitOptions func(t *tieredCache)
+
+// WithOverrideDefaults sets the write policy. Empty policy falls back to WriteThrough.
+func WithOverrideDefaults(policy WritePolicy) InitOptions {
+	return func(t *tieredCache) {
+		if policy == "" {
+			policy = defaultWritePolicy
+		}
+
+		t.policy = policy
+	}
+}
+
+func NewTieredCache(l1, l2 cache.CacheInterface, opts ...InitOptions) (cache.CacheInterface, error) {
+	const wrap = "NewTieredCache"
+
+	if err := cache.ValidateInput(
+		cache.WithValueValidation(l1, wrap),
+		cache.WithValueValidation(l2, wrap),
+	); err != nil {
+		return nil, err
+	}
+
+	t := &tieredCache{l1: l1, l2: l2, policy: defaultWritePolicy}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	if t.policy != WriteThrough && t.policy != WriteAround {
+		return nil, cacheErrors.NewErrInvalidValue(t.policy, tieredCacheErrors.ErrUnknownWritePolicy, wrap)
+	}
+
+	return t, nil
+}
+
+// Get returns value from L1. On L1 miss value is read from L2 and promoted to L1.
+// If L1 entry is expired and L2 fails too, both errors are returned.
+func (t *tieredCache) Get(ctx context.Context, key string) (any, error) {
+	const wrap = "tieredCache/Get"
+	if err := cache.ValidateInput(
+		cache.WithClosedValidation(&t.closed, wrap),
+		cache.WithCtxValidation(ctx, wrap),
+		cache.WithKeyValidation(key, wrap),
+	); err != nil {
+		return nil, err
+	}
+
+	val, l1Err := t.l1.Get(ctx, key)
+	if l1Err == nil {
+		return val, nil
+	}
+
+	if !errors.Is(l1Err, cacheErrors.ErrNotFound) && !errors.Is(l1Err, ttlCacheErrors.ErrExpired) {
+		log.Warn(fmt.Sprintf("%s: L1 failed", wrap), "key", key, "err", l1Err)
+	}
+
+	s := t.getStripe(key)
+	epoch := s.loadEpoch()
+
+	val, err := t.l2.Get(ctx, key)
+	if err != nil {
+		// L1 error is kept in the chain, so callers can still reach the stale value of an expired L1 entry
+		if errors.Is(l1Err, ttlCacheErrors.ErrExpired) {
+			return nil, errors.Join(err, l1Err)
+		}
+		return nil, err
+	}
+
+	t.promote(ctx, s, epoch, key, val)
+
+	return val, nil
+}
+
+func (t *tieredCache) Set(ctx context.Context, key string, value any) (int, error) {
+	const wrap = "tieredCache/Set"
+	if err := cache.ValidateInput(
+		cache.WithClosedValidation(&t.closed, wrap),
+		cache.WithCtxValidation(ctx, wrap),
+		cache.WithKeyValidation(key, wrap),
+		cache.WithValueValidation(value, wrap),
+	); err != nil {
+		return 0, err
+	}
+
+	// stripe is held across both writes, so concurrent writes of a key reach L1 and L2 in the same order
+	s := t.getStripe(key)
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.epoch++
+
+	code, err := t.l2.Set(ctx, key, value)
+	if err != nil {
+		// L2 state is unknown after a failed write, so L1 can't keep the previous value either
+		t.deleteL1(ctx, key)
+		return 0, err
+	}
+
+	if t.policy == WriteAround {
+		t.deleteL1(ctx, key)
+		return code, nil
+	}
+
+	if _, err = t.l1.Set(ctx, key, value); err != nil {
+		log.Warn(fmt.Sprintf("%s: L1 failed", wrap), "key", key, "err", err)
+		t.deleteL1(ctx, key)
+	}
+
+	return code, nil
+}
+
+// Delete removes key from L2 and then from L1. L1 is invalidated even if L2 returns an error.
+func (t *tieredCache) Delete(ctx context.Context, key string) error {
+	const wrap = "tieredCache/Delete"
+	if err := cache.ValidateInput(
+		cache.WithClosedValidation(&t.closed, wrap),
+		cache.WithCtxValidation(ctx, wrap),
+		cache.WithKeyValidation(key, wrap),
+	); err != nil {
+		return err
+	}
+
+	s := t.getStripe(key)
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.epoch++
+
+	err := t.l2.Delete(ctx, key)
+	t.deleteL1(ctx, key)
+
+	return err
+}
+
+func (t *tieredCache) Close(ctx context.Context) error {
+	var err error
+	t.closedOnce.Do(func() {
+		t.closed.Store(true)
+		err = errors.Join(t.l1.Close(ctx), t.l2.Close(ctx))
+	})
+
+	return err
+}
+
+func (t *tieredCache) GetKeys(ctx context.Context) ([]string, error) {
+	const wrap = "tieredCache/GetKeys"
+	if err := cache.ValidateInput(
+		cache.WithClosedValidation(&t.closed, wrap),
+		cache.WithCtxValidation(ctx, wrap),
+	); err != nil {
+		return nil, err
+	}
+
+	return t.l2.GetKeys(ctx)
+}
+
+func (t *tieredCache) GetLength() (int64, error) {
+	const wrap = "tieredCache/GetLength"
+	if err := cache.ValidateInput(
+		cache.WithClosedValidation(&t.closed, wrap),
+	); err != nil {
+		return 0, err
+	}
+
+	return t.l2.GetLength()
+}
+
+// promote writes value to L1 unless the key was written or deleted after epoch was loaded
+func (t *tieredCache) promote(ctx context.Context, s *stripe, epoch uint64, key string, val any) {
+	const wrap = "tieredCache/promote"
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.epoch != epoch {
+		return
+	}
+
+	if _, err := t.l1.Set(ctx, key, val); err != nil {
+		log.Warn(fmt.Sprintf("%s: L1 failed", wrap), "key", key, "err", err)
+	}
+}
+
+func (t *tieredCache) deleteL1(ctx context.Context, key string) {
+	const wrap = "tieredCache/deleteL1"
+
+	if err := t.l1.Delete(ctx, key); err != nil && !errors.Is(err, cacheErrors.ErrNotFound) {
+		log.Warn(fmt.Sprintf("%s: L1 failed", wrap), "key", key, "err", err)
+	}
+}
+
+func (t *tieredCache) getStripe(key string) *stripe {
+	hasher := fnv.New32a()
+	_, _ = hasher.Write(conv.StrToBytes(key))
+
+	return &t.stripes[hasher.Sum32()%stripeNumber]
+}
+
+func (s *stripe) loadEpoch() uint64 {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return s.epoch
+}
@@ -0,0 +1,264 @@
+package tiered_cache
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KennyMacCormik/otel/backend/pkg/cache"
+	"github.com/KennyMacCormik/otel/backend/pkg/cache/impl/sync_map"
+	mockCache "github.com/KennyMacCormik/otel/backend/pkg/cache/mocks"
+	"github.com/KennyMacCormik/otel/backend/pkg/cache/wrappers/ttl_cache"
+	cache2 "github.com/KennyMacCormik/otel/backend/pkg/models/errors/cache"
+	tieredCacheErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/tiered_cache"
+	ttlCacheModels "github.com/KennyMacCormik/otel/backend/pkg/models/ttl_cache"
+)
+
+func getTieredCache(t *testing.T, l1, l2 cache.CacheInterface, opts ...InitOptions) cache.CacheInterface {
+	c, err := NewTieredCache(l1, l2, opts...)
+	require.NoError(t, err, "expect no error with valid configuration")
+	require.NotNil(t, c, "expect result not nil with valid configuration")
+	return c
+}
+
+func TestTieredCache_New(t *testing.T) {
+	t.Run("default", func(t *testing.T) {
+		c := getTieredCache(t, sync_map.NewSyncMapCache(), sync_map.NewSyncMapCache())
+		assert.Equal(t, defaultWritePolicy, c.(*tieredCache).policy, "expect default write policy")
+	})
+
+	t.Run("empty policy", func(t *testing.T) {
+		c := getTieredCache(t, sync_map.NewSyncMapCache(), sync_map.NewSyncMapCache(), WithOverrideDefaults(""))
+		assert.Equal(t, defaultWritePolicy, c.(*tieredCache).policy, "expect default write policy")
+	})
+
+	t.Run("unknown policy", func(t *testing.T) {
+		c, err := NewTieredCache(sync_map.NewSyncMapCache(), sync_map.NewSyncMapCache(), WithOverrideDefaults("write-back"))
+		require.Error(t, err, "expect an error with unknown policy")
+		assert.ErrorIs(t, err, tieredCacheErrors.ErrUnknownWritePolicy, "expect ErrUnknownWritePolicy")
+		assert.Nil(t, c, "result should be nil with unknown policy")
+	})
+
+	t.Run("nil tier", func(t *testing.T) {
+		c, err := NewTieredCache(sync_map.NewSyncMapCache(), nil)
+		require.Error(t, err, "expect an error with nil L2")
+		assert.ErrorIs(t, err, cache2.NewErrInvalidValue("", cache2.ErrNil, ""), "expect err be ErrInvalidValue")
+		assert.Nil(t, c, "result should be nil with nil L2")
+	})
+}
+
+func TestTieredCache_Get(t *testing.T) {
+	t.Run("L1 hit", func(t *testing.T) {
+		l1, l2 := mockCache.NewMockCacheInterface(t), mockCache.NewMockCacheInterface(t)
+		l1.EXPECT().Get(mock.Anything, "key").Return("value", nil)
+
+		val, err := getTieredCache(t, l1, l2).Get(context.Background(), "key")
+		require.NoError(t, err, "expect no error on L1 hit")
+		assert.Equal(t, "value", val, "expect value from L1")
+	})
+
+	t.Run("promotion", func(t *testing.T) {
+		l1, l2 := sync_map.NewSyncMapCache(), sync_map.NewSyncMapCache()
+		_, err := l2.Set(context.Background(), "key", "value")
+		require.NoError(t, err, "expect no error on Set")
+
+		val, err := getTieredCache(t, l1, l2).Get(context.Background(), "key")
+		require.NoError(t, err, "expect no error on L2 hit")
+		assert.Equal(t, "value", val, "expect value from L2")
+
+		val, err = l1.Get(context.Background(), "key")
+		require.NoError(t, err, "expect value to be promoted to L1")
+		assert.Equal(t, "value", val, "expect promoted value")
+	})
+
+	t.Run("L1 error", func(t *testing.T) {
+		l1, l2 := mockCache.NewMockCacheInterface(t), mockCache.NewMockCacheInterface(t)
+		l1.EXPECT().Get(mock.Anything, "key").Return(nil, assert.AnError)
+		l2.EXPECT().Get(mock.Anything, "key").Return("value", nil)
+		l1.EXPECT().Set(mock.Anything, "key", "value").Return(0, assert.AnError)
+
+		val, err := getTieredCache(t, l1, l2).Get(context.Background(), "key")
+		require.NoError(t, err, "expect L1 errors to be ignored")
+		assert.Equal(t, "value", val, "expect value from L2")
+	})
+
+	t.Run("miss", func(t *testing.T) {
+		l1, l2 := sync_map.NewSyncMapCache(), sync_map.NewSyncMapCache()
+
+		_, err := getTieredCache(t, l1, l2).Get(context.Background(), "key")
+		require.Error(t, err, "expect an error on miss")
+		assert.ErrorIs(t, err, cache2.NewErrKeyNotFound(""), "expect ErrKeyNotFound")
+	})
+
+	t.Run("expired L1 entry", func(t *testing.T) {
+		l1 := getExpiredTtlCache(t, "key", "stale")
+		l2 := mockCache.NewMockCacheInterface(t)
+		l2.EXPECT().Get(mock.Anything, "key").Return(nil, assert.AnError)
+
+		_, err := getTieredCache(t, l1, l2).Get(context.Background(), "key")
+		require.Error(t, err, "expect an error")
+		assert.ErrorIs(t, err, assert.AnError, "expect L2 error")
+
+		var errTimeout *ttl_cache.ErrTimeout
+		require.ErrorAs(t, err, &errTimeout, "expect expired L1 entry in the error chain")
+		assert.Equal(t, "stale", errTimeout.GetStaleValue(), "expect stale value to be reachable")
+	})
+
+	t.Run("stale promotion", func(t *testing.T) {
+		l1, l2 := sync_map.NewSyncMapCache(), mockCache.NewMockCacheInterface(t)
+		c := getTieredCache(t, l1, l2)
+
+		// key is deleted while the old value is being read from L2
+		l2.EXPECT().Get(mock.Anything, "key").RunAndReturn(func(ctx context.Context, key string) (any, error) {
+			require.NoError(t, c.Delete(ctx, key), "expect no error on Delete")
+			return "old", nil
+		})
+		l2.EXPECT().Delete(mock.Anything, "key").Return(nil)
+
+		val, err := c.Get(context.Background(), "key")
+		require.NoError(t, err, "expect no error on L2 hit")
+		assert.Equal(t, "old", val, "expect value from L2")
+
+		_, err = l1.Get(context.Background(), "key")
+		assert.ErrorIs(t, err, cache2.NewErrKeyNotFound(""), "expect value read before Delete not to be promoted")
+	})
+}
+
+func getExpiredTtlCache(t *testing.T, key string, val any) cache.CacheInterface {
+	sm := sync_map.NewSyncMapCache()
+	c, err := ttl_cache.NewTtlCache(sm)
+	require.NoError(t, err, "expect no error with valid configuration")
+
+	_, err = sm.Set(context.Background(), key, &ttlCacheModels.TtlCacheEntry{Value: val, ExpiresAt: time.Now().Add(-time.Second)})
+	require.NoError(t, err, "expect no error on Set")
+
+	return c
+}
+
+func TestTieredCache_Set(t *testing.T) {
+	t.Run("write-through", func(t *testing.T) {
+		l1, l2 := sync_map.NewSyncMapCache(), sync_map.NewSyncMapCache()
+		c := getTieredCache(t, l1, l2, WithOverrideDefaults(WriteThrough))
+
+		code, err := c.Set(context.Background(), "key", "value")
+		require.NoError(t, err, "expect no error on Set")
+		assert.Equal(t, 201, code, "expect code from L2")
+
+		for _, tier := range []cache.CacheInterface{l1, l2} {
+			val, err := tier.Get(context.Background(), "key")
+			require.NoError(t, err, "expect value in both tiers")
+			assert.Equal(t, "value", val, "expect value in both tiers")
+		}
+	})
+
+	t.Run("write-around", func(t *testing.T) {
+		l1, l2 := sync_map.NewSyncMapCache(), sync_map.NewSyncMapCache()
+		_, err := l1.Set(context.Background(), "key", "old")
+		require.NoError(t, err, "expect no error on Set")
+
+		c := getTieredCache(t, l1, l2, WithOverrideDefaults(WriteAround))
+
+		_, err = c.Set(context.Background(), "key", "value")
+		require.NoError(t, err, "expect no error on Set")
+
+		_, err = l1.Get(context.Background(), "key")
+		assert.ErrorIs(t, err, cache2.NewErrKeyNotFound(""), "expect L1 to be invalidated")
+
+		val, err := c.Get(context.Background(), "key")
+		require.NoError(t, err, "expect no error on Get")
+		assert.Equal(t, "value", val, "expect new value")
+	})
+
+	t.Run("L2 error", func(t *testing.T) {
+		l1, l2 := sync_map.NewSyncMapCache(), mockCache.NewMockCacheInterface(t)
+		_, err := l1.Set(context.Background(), "key", "old")
+		require.NoError(t, err, "expect no error on Set")
+		l2.EXPECT().Set(mock.Anything, "key", "value").Return(0, assert.AnError)
+
+		_, err = getTieredCache(t, l1, l2).Set(context.Background(), "key", "value")
+		require.Error(t, err, "expect an error")
+		assert.ErrorIs(t, err, assert.AnError, "expect error to be assert.AnError")
+
+		_, err = l1.Get(context.Background(), "key")
+		assert.ErrorIs(t, err, cache2.NewErrKeyNotFound(""), "expect L1 to be invalidated")
+	})
+}
+
+func TestTieredCache_ConcurrentWrites(t *testing.T) {
+	l1, l2 := sync_map.NewSyncMapCache(), sync_map.NewSyncMapCache()
+	c := getTieredCache(t, l1, l2)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if i%5 == 0 {
+				_ = c.Delete(context.Background(), "key")
+				return
+			}
+			_, _ = c.Set(context.Background(), "key", strconv.Itoa(i))
+		}()
+	}
+	wg.Wait()
+
+	v2, err := l2.Get(context.Background(), "key")
+	if err != nil {
+		_, err = l1.Get(context.Background(), "key")
+		assert.ErrorIs(t, err, cache2.NewErrKeyNotFound(""), "expect key missing from L2 to be missing from L1")
+		return
+	}
+
+	v1, err := l1.Get(context.Background(), "key")
+	require.NoError(t, err, "expect value in L1 with write-through")
+	assert.Equal(t, v2, v1, "expect tiers not to diverge")
+}
+
+func TestTieredCache_Delete(t *testing.T) {
+	t.Run("positive", func(t *testing.T) {
+		l1, l2 := sync_map.NewSyncMapCache(), sync_map.NewSyncMapCache()
+		c := getTieredCache(t, l1, l2)
+
+		_, err := c.Set(context.Background(), "key", "value")
+		require.NoError(t, err, "expect no error on Set")
+		require.NoError(t, c.Delete(context.Background(), "key"), "expect no error on Delete")
+
+		for _, tier := range []cache.CacheInterface{l1, l2} {
+			_, err = tier.Get(context.Background(), "key")
+			assert.ErrorIs(t, err, cache2.NewErrKeyNotFound(""), "expect key to be deleted from both tiers")
+		}
+	})
+
+	t.Run("L2 error", func(t *testing.T) {
+		l1, l2 := sync_map.NewSyncMapCache(), mockCache.NewMockCacheInterface(t)
+		_, err := l1.Set(context.Background(), "key", "value")
+		require.NoError(t, err, "expect no error on Set")
+		l2.EXPECT().Delete(mock.Anything, "key").Return(assert.AnError)
+
+		err = getTieredCache(t, l1, l2).Delete(context.Background(), "key")
+		assert.ErrorIs(t, err, assert.AnError, "expect error to be assert.AnError")
+
+		_, err = l1.Get(context.Background(), "key")
+		assert.ErrorIs(t, err, cache2.NewErrKeyNotFound(""), "expect L1 to be invalidated")
+	})
+}
+
+func TestTieredCache_Close(t *testing.T) {
+	l1, l2 := mockCache.NewMockCacheInterface(t), mockCache.NewMockCacheInterface(t)
+	l1.EXPECT().Close(mock.Anything).Return(nil).Once()
+	l2.EXPECT().Close(mock.Anything).Return(assert.AnError).Once()
+	c := getTieredCache(t, l1, l2)
+
+	err := c.Close(context.Background())
+	assert.ErrorIs(t, err, assert.AnError, "expect L2 error")
+	assert.NoError(t, c.Close(context.Background()), "expect no error on second Close")
+
+	_, err = c.Get(context.Background(), "key")
+	assert.ErrorIs(t, err, cache2.ErrCacheClosed, "expect error to be cache.ErrCacheClosed")
+}
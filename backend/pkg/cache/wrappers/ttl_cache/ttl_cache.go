@@ -119,7 +119,10 @@ func (t *ttlCache) Get(ctx context.Context, key string) (any, error) {
 	}
 
 	if ttlExpired(castedValue.ExpiresAt) {
-		return nil, NewErrTimeout(key, wrap, castedValue.ExpiresAt)
+		errTimeout := NewErrTimeout(key, wrap, castedValue.ExpiresAt)
+		errTimeout.staleValue = castedValue.Value
+
+		return nil, errTimeout
 	}
 
 	return castedValue.Value, nil
@@ -266,6 +269,7 @@ type ErrTimeout struct {
 	expirationTime time.Time
 	callerInfo     string
 	signalErr      error
+	staleValue     any
 }
 
 func NewErrTimeout(key, callerInfo string, expirationTime time.Time) *ErrTimeout {
@@ -284,6 +288,11 @@ func (e *ErrTimeout) GetTtl() time.Time {
 	return e.expirationTime
 }
 
+// GetStaleValue returns expired value if it is known. It lets callers serve stale data when the source is unavailable.
+func (e *ErrTimeout) GetStaleValue() any {
+	return e.staleValue
+}
+
 func (e *ErrTimeout) Error() string {
 	return fmt.Errorf("%s: key [%s] ttl [%s]: %w", e.callerInfo, e.key, e.expirationTime, e.signalErr).Error()
 }
@@ -237,6 +237,10 @@ func TestTtlCache_Get(t *testing.T) {
 		require.Error(t, err, "expect err with expired record")
 		assert.Nil(t, val, "expect nil result with error")
 		assert.ErrorIs(t, err, NewErrTimeout("", "", time.Now()), "expect error to be cache.ErrTimeout")
+
+		var errTimeout *ErrTimeout
+		require.ErrorAs(t, err, &errTimeout, "expect error to be cache.ErrTimeout")
+		assert.Equal(t, value1.Value, errTimeout.GetStaleValue(), "expect stale value in error")
 	})
 
 	t.Run("closed", func(t *testing.T) {
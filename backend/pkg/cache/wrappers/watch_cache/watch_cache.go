@@ -0,0 +1,262 @@
+package watch_cache
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/KennyMacCormik/otel/backend/pkg/cache"
+	cacheErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/cache"
+	watchCacheErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/watch_cache"
+	watchCacheModels "github.com/KennyMacCormik/otel/backend/pkg/models/watch_cache"
+)
+
+const defaultBufferSize = 128
+
+// Watcher is implemented by cache.CacheInterface returned from NewWatchCache
+type Watcher interface {
+	Watch(ctx context.Context, prefix string) (*Subscription, error)
+}
+
+// Subscription delivers events of keys starting with its prefix
+type Subscription struct {
+	prefix string
+	events chan watchCacheModels.Event
+	done   chan struct{}
+	err    error
+}
+
+// Events returns channel of events. It is closed when the subscription ends, see Err.
+func (s *Subscription) Events() <-chan watchCacheModels.Event {
+	return s.events
+}
+
+// Err returns the reason subscription ended: context error, cacheErrors.ErrCacheClosed
+// or watchCacheErrors.ErrWatcherFellBehind. It must only be called after Events is closed.
+func (s *Subscription) Err() error {
+	return s.err
+}
+
+// watchCache notifies subscribers about successful Set and Delete calls.
+// Set returning 204 is not reported, as the value didn't change. Expiration in the underlying cache is not reported either.
+// Every subscriber has a buffer of events. Subscriber that doesn't drain its buffer in time is unsubscribed
+// with watchCacheErrors.ErrWatcherFellBehind, so slow watchers never block writes.
+// Events of concurrent writes to the same key may be delivered in a different order than they were applied.
+type watchCache struct {
+	impl       cache.CacheInterface
+	bufferSize int
+
+	mtx  sync.RWMutex
+	subs map[*Subscription]struct{}
+
+	closedOnce sync.Once
+	closed     atomic.Bool
+}
+
+type InitOptions func(w *watchCache)
+
+// WithOverrideDefaults sets number of events buffered per subscriber. Non-positive size falls back to the default.
+func WithOverrideDefaults(bufferSize int) InitOptions {
+	return func(w *watchCache) {
+		if bufferSize <= 0 {
+			bufferSize = defaultBufferSize
+		}
+
+		w.bufferSize = bufferSize
+	}
+}
+
+func NewWatchCache(impl cache.CacheInterface, opts ...InitOptions) (cache.CacheInterface, error) {
+	const wrap = "NewWatchCache"
+
+	if err := cache.WithValueValidation(impl, wrap)(); err != nil {
+		return nil, err
+	}
+
+	w := &watchCache{
+		impl:       impl,
+		bufferSize: defaultBufferSize,
+		subs:       make(map[*Subscription]struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	return w, nil
+}
+
+// Watch subscribes to changes of keys starting with prefix. Subscription ends when ctx is done.
+func (w *watchCache) Watch(ctx context.Context, prefix string) (*Subscription, error) {
+	const wrap = "watchCache/Watch"
+	if err := cache.ValidateInput(
+		cache.WithClosedValidation(&w.closed, wrap),
+		cache.WithCtxValidation(ctx, wrap),
+	); err != nil {
+		return nil, err
+	}
+
+	s := &Subscription{
+		prefix: prefix,
+		events: make(chan watchCacheModels.Event, w.bufferSize),
+		done:   make(chan struct{}),
+	}
+
+	w.mtx.Lock()
+	// Close might have run since validation
+	if w.closed.Load() {
+		w.mtx.Unlock()
+		return nil, fmt.Errorf("%s: %w", wrap, cacheErrors.ErrCacheClosed)
+	}
+	w.subs[s] = struct{}{}
+	w.mtx.Unlock()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			w.unsubscribe(s, ctx.Err())
+		case <-s.done:
+		}
+	}()
+
+	return s, nil
+}
+
+func (w *watchCache) Get(ctx context.Context, key string) (any, error) {
+	const wrap = "watchCache/Get"
+	if err := cache.ValidateInput(
+		cache.WithClosedValidation(&w.closed, wrap),
+		cache.WithCtxValidation(ctx, wrap),
+		cache.WithKeyValidation(key, wrap),
+	); err != nil {
+		return nil, err
+	}
+
+	return w.impl.Get(ctx, key)
+}
+
+func (w *watchCache) Set(ctx context.Context, key string, value any) (int, error) {
+	const wrap = "watchCache/Set"
+	if err := cache.ValidateInput(
+		cache.WithClosedValidation(&w.closed, wrap),
+		cache.WithCtxValidation(ctx, wrap),
+		cache.WithKeyValidation(key, wrap),
+		cache.WithValueValidation(value, wrap),
+	); err != nil {
+		return 0, err
+	}
+
+	code, err := w.impl.Set(ctx, key, value)
+	if err != nil {
+		return 0, err
+	}
+
+	if code != 204 {
+		w.publish(watchCacheModels.Event{Type: watchCacheModels.EventSet, Key: key, Value: value})
+	}
+
+	return code, nil
+}
+
+func (w *watchCache) Delete(ctx context.Context, key string) error {
+	const wrap = "watchCache/Delete"
+	if err := cache.ValidateInput(
+		cache.WithClosedValidation(&w.closed, wrap),
+		cache.WithCtxValidation(ctx, wrap),
+		cache.WithKeyValidation(key, wrap),
+	); err != nil {
+		return err
+	}
+
+	if err := w.impl.Delete(ctx, key); err != nil {
+		return err
+	}
+
+	w.publish(watchCacheModels.Event{Type: watchCacheModels.EventDelete, Key: key})
+
+	return nil
+}
+
+// Close ends all subscriptions with cacheErrors.ErrCacheClosed
+func (w *watchCache) Close(ctx context.Context) error {
+	var err error
+	w.closedOnce.Do(func() {
+		w.closed.Store(true)
+
+		w.mtx.Lock()
+		for s := range w.subs {
+			w.remove(s, cacheErrors.ErrCacheClosed)
+		}
+		w.mtx.Unlock()
+
+		err = w.impl.Close(ctx)
+	})
+
+	return err
+}
+
+func (w *watchCache) GetKeys(ctx context.Context) ([]string, error) {
+	const wrap = "watchCache/GetKeys"
+	if err := cache.ValidateInput(
+		cache.WithClosedValidation(&w.closed, wrap),
+		cache.WithCtxValidation(ctx, wrap),
+	); err != nil {
+		return nil, err
+	}
+
+	return w.impl.GetKeys(ctx)
+}
+
+func (w *watchCache) GetLength() (int64, error) {
+	const wrap = "watchCache/GetLength"
+	if err := cache.ValidateInput(
+		cache.WithClosedValidation(&w.closed, wrap),
+	); err != nil {
+		return 0, err
+	}
+
+	return w.impl.GetLength()
+}
+
+func (w *watchCache) publish(e watchCacheModels.Event) {
+	var behind []*Subscription
+
+	w.mtx.RLock()
+	for s := range w.subs {
+		if !strings.HasPrefix(e.Key, s.prefix) {
+			continue
+		}
+
+		select {
+		case s.events <- e:
+		default:
+			behind = append(behind, s)
+		}
+	}
+	w.mtx.RUnlock()
+
+	for _, s := range behind {
+		w.unsubscribe(s, watchCacheErrors.ErrWatcherFellBehind)
+	}
+}
+
+func (w *watchCache) unsubscribe(s *Subscription, err error) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	w.remove(s, err)
+}
+
+// remove must be called with mtx locked
+func (w *watchCache) remove(s *Subscription, err error) {
+	if _, ok := w.subs[s]; !ok {
+		return
+	}
+
+	delete(w.subs, s)
+	s.err = err
+	close(s.events)
+	close(s.done)
+}
@@ -0,0 +1,155 @@
+package watch_cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KennyMacCormik/otel/backend/pkg/cache"
+	"github.com/KennyMacCormik/otel/backend/pkg/cache/impl/sync_map"
+	mockCache "github.com/KennyMacCormik/otel/backend/pkg/cache/mocks"
+	cache2 "github.com/KennyMacCormik/otel/backend/pkg/models/errors/cache"
+	watchCacheErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/watch_cache"
+	watchCacheModels "github.com/KennyMacCormik/otel/backend/pkg/models/watch_cache"
+)
+
+func getWatchCache(t *testing.T, impl cache.CacheInterface, opts ...InitOptions) cache.CacheInterface {
+	c, err := NewWatchCache(impl, opts...)
+	require.NoError(t, err, "expect no error with valid configuration")
+	require.NotNil(t, c, "expect result not nil with valid configuration")
+	return c
+}
+
+func watch(t *testing.T, c cache.CacheInterface, ctx context.Context, prefix string) *Subscription {
+	s, err := c.(Watcher).Watch(ctx, prefix)
+	require.NoError(t, err, "expect no error on Watch")
+	return s
+}
+
+func receive(t *testing.T, s *Subscription) watchCacheModels.Event {
+	select {
+	case e, ok := <-s.Events():
+		require.True(t, ok, "expect subscription to be active")
+		return e
+	case <-time.After(time.Second):
+		require.Fail(t, "expect event to be delivered")
+		return watchCacheModels.Event{}
+	}
+}
+
+func waitClosed(t *testing.T, s *Subscription) {
+	select {
+	case _, ok := <-s.Events():
+		require.False(t, ok, "expect no more events")
+	case <-time.After(time.Second):
+		require.Fail(t, "expect subscription to end")
+	}
+}
+
+func TestWatchCache_New(t *testing.T) {
+	t.Run("default", func(t *testing.T) {
+		c := getWatchCache(t, sync_map.NewSyncMapCache(), WithOverrideDefaults(-1))
+		assert.Equal(t, defaultBufferSize, c.(*watchCache).bufferSize, "expect default buffer size")
+	})
+
+	t.Run("nil impl", func(t *testing.T) {
+		c, err := NewWatchCache(nil)
+		require.Error(t, err, "expect an error with nil impl")
+		assert.ErrorIs(t, err, cache2.NewErrInvalidValue("", cache2.ErrNil, ""), "expect err be ErrInvalidValue")
+		assert.Nil(t, c, "result should be nil with nil impl")
+	})
+}
+
+func TestWatchCache_Events(t *testing.T) {
+	ctx := context.Background()
+	c := getWatchCache(t, sync_map.NewSyncMapCache())
+	all := watch(t, c, ctx, "")
+	users := watch(t, c, ctx, "user:")
+
+	code, err := c.Set(ctx, "user:1", "alice")
+	require.NoError(t, err, "expect no error on Set")
+	assert.Equal(t, 201, code, "expect 201 on new key")
+
+	_, err = c.Set(ctx, "order:1", "book")
+	require.NoError(t, err, "expect no error on Set")
+
+	code, err = c.Set(ctx, "user:1", "alice")
+	require.NoError(t, err, "expect no error on Set")
+	assert.Equal(t, 204, code, "expect 204 on unchanged value")
+
+	require.NoError(t, c.Delete(ctx, "user:1"), "expect no error on Delete")
+
+	set := watchCacheModels.Event{Type: watchCacheModels.EventSet, Key: "user:1", Value: "alice"}
+	del := watchCacheModels.Event{Type: watchCacheModels.EventDelete, Key: "user:1"}
+	order := watchCacheModels.Event{Type: watchCacheModels.EventSet, Key: "order:1", Value: "book"}
+
+	assert.Equal(t, set, receive(t, all), "expect set event")
+	assert.Equal(t, order, receive(t, all), "expect set event of another prefix")
+	assert.Equal(t, del, receive(t, all), "expect delete event, unchanged value is not reported")
+
+	assert.Equal(t, set, receive(t, users), "expect set event")
+	assert.Equal(t, del, receive(t, users), "expect delete event, other prefixes are filtered out")
+}
+
+func TestWatchCache_FailedWriteNotReported(t *testing.T) {
+	ctx := context.Background()
+	impl := mockCache.NewMockCacheInterface(t)
+	impl.EXPECT().Set(mock.Anything, "key", "value").Return(0, errors.New("boom"))
+	impl.EXPECT().Delete(mock.Anything, "key").Return(errors.New("boom"))
+
+	c := getWatchCache(t, impl)
+	s := watch(t, c, ctx, "")
+
+	_, err := c.Set(ctx, "key", "value")
+	require.Error(t, err, "expect impl error on Set")
+	require.Error(t, c.Delete(ctx, "key"), "expect impl error on Delete")
+
+	assert.Empty(t, s.Events(), "expect no events of failed writes")
+}
+
+func TestWatchCache_Unsubscribe(t *testing.T) {
+	t.Run("context done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		c := getWatchCache(t, sync_map.NewSyncMapCache())
+		s := watch(t, c, ctx, "")
+
+		cancel()
+
+		waitClosed(t, s)
+		assert.ErrorIs(t, s.Err(), context.Canceled, "expect context error")
+	})
+
+	t.Run("fell behind", func(t *testing.T) {
+		ctx := context.Background()
+		c := getWatchCache(t, sync_map.NewSyncMapCache(), WithOverrideDefaults(1))
+		s := watch(t, c, ctx, "")
+
+		_, err := c.Set(ctx, "key", "1")
+		require.NoError(t, err, "expect no error on Set")
+		_, err = c.Set(ctx, "key", "2")
+		require.NoError(t, err, "expect slow watcher not to fail Set")
+
+		assert.Equal(t, "1", receive(t, s).Value, "expect buffered event")
+		waitClosed(t, s)
+		assert.ErrorIs(t, s.Err(), watchCacheErrors.ErrWatcherFellBehind, "expect ErrWatcherFellBehind")
+	})
+
+	t.Run("cache closed", func(t *testing.T) {
+		ctx := context.Background()
+		c := getWatchCache(t, sync_map.NewSyncMapCache())
+		s := watch(t, c, ctx, "")
+
+		require.NoError(t, c.Close(ctx), "expect no error on Close")
+
+		waitClosed(t, s)
+		assert.ErrorIs(t, s.Err(), cache2.ErrCacheClosed, "expect ErrCacheClosed")
+
+		_, err := c.(Watcher).Watch(ctx, "")
+		assert.ErrorIs(t, err, cache2.ErrCacheClosed, "expect ErrCacheClosed on closed cache")
+	})
+}
@@ -0,0 +1,260 @@
+package write_limit_cache
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/KennyMacCormik/otel/backend/pkg/cache"
+	cacheErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/cache"
+)
+
+const (
+	defaultLimit               = 10
+	defaultWindow              = time.Second
+	defaultMaxValueSize  int64 = 0
+	defaultSweepInterval       = 10 * time.Second
+)
+
+// writeLimitCache limits the rate of Set calls per key with a token bucket and rejects values above the size limit.
+// Buckets refilled to full are swept, so memory is bounded by keys written recently.
+type writeLimitCache struct {
+	impl cache.CacheInterface
+
+	// rate is in tokens per nanosecond
+	rate, burst   float64
+	maxValueSize  int64
+	sweepInterval time.Duration
+
+	mtx     sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+
+	ticker     *time.Ticker
+	closedOnce sync.Once
+	closed     atomic.Bool
+	closeCh    chan struct{}
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+type InitOptions func(w *writeLimitCache)
+
+// WithOverrideDefaults allows limit writes of a key per window with bursts of up to burst writes,
+// zero limit disables rate limiting, zero burst equals to limit.
+// Values larger than maxValueSize bytes are rejected, zero means no limit.
+func WithOverrideDefaults(limit int64, window time.Duration, burst, maxValueSize int64) InitOptions {
+	return func(w *writeLimitCache) {
+		if limit < 0 {
+			limit = defaultLimit
+		}
+
+		if window <= 0 {
+			window = defaultWindow
+		}
+
+		if burst < 1 {
+			burst = max(1, limit)
+		}
+
+		if maxValueSize < 0 {
+			maxValueSize = defaultMaxValueSize
+		}
+
+		w.rate = float64(limit) / float64(window)
+		w.burst = float64(burst)
+		w.maxValueSize = maxValueSize
+	}
+}
+
+// WithSweepInterval sets how often idle keys are forgotten
+func WithSweepInterval(interval time.Duration) InitOptions {
+	return func(w *writeLimitCache) {
+		if interval > 0 {
+			w.sweepInterval = interval
+		}
+	}
+}
+
+// NewWriteLimitCache returns cache.CacheInterface failing Set with *cacheErrors.ErrWriteLimited
+// once a key is written too often and with cacheErrors.ErrValueTooLarge if the value exceeds the size limit.
+// Only string and []byte values are supported if the size limit is set.
+func NewWriteLimitCache(impl cache.CacheInterface, opts ...InitOptions) (cache.CacheInterface, error) {
+	const wrap = "NewWriteLimitCache"
+
+	err := cache.WithValueValidation(impl, wrap)()
+	if err != nil {
+		return nil, err
+	}
+
+	w := &writeLimitCache{
+		impl:          impl,
+		maxValueSize:  defaultMaxValueSize,
+		sweepInterval: defaultSweepInterval,
+		buckets:       make(map[string]*bucket),
+		now:           time.Now,
+		closeCh:       make(chan struct{}),
+	}
+	WithOverrideDefaults(defaultLimit, defaultWindow, 0, defaultMaxValueSize)(w)
+
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	w.ticker = time.NewTicker(w.sweepInterval)
+	go w.sweep()
+
+	return w, nil
+}
+
+func (w *writeLimitCache) Get(ctx context.Context, key string) (any, error) {
+	const wrap = "writeLimitCache/Get"
+	if err := cache.ValidateInput(
+		cache.WithClosedValidation(&w.closed, wrap),
+		cache.WithCtxValidation(ctx, wrap),
+		cache.WithKeyValidation(key, wrap),
+	); err != nil {
+		return nil, err
+	}
+
+	return w.impl.Get(ctx, key)
+}
+
+func (w *writeLimitCache) Set(ctx context.Context, key string, value any) (int, error) {
+	const wrap = "writeLimitCache/Set"
+	if err := cache.ValidateInput(
+		cache.WithClosedValidation(&w.closed, wrap),
+		cache.WithCtxValidation(ctx, wrap),
+		cache.WithKeyValidation(key, wrap),
+		cache.WithValueValidation(value, wrap),
+	); err != nil {
+		return 0, err
+	}
+
+	if w.maxValueSize > 0 {
+		size, err := cache.ValueSize(value, wrap)
+		if err != nil {
+			return 0, err
+		}
+
+		if size > w.maxValueSize {
+			return 0, fmt.Errorf("%s: key %s: %w: %d bytes exceed %d", wrap, key, cacheErrors.ErrValueTooLarge, size, w.maxValueSize)
+		}
+	}
+
+	if retryAfter, ok := w.allow(key); !ok {
+		return 0, fmt.Errorf("%s: %w", wrap, cacheErrors.NewErrWriteLimited(key, retryAfter))
+	}
+
+	return w.impl.Set(ctx, key, value)
+}
+
+func (w *writeLimitCache) Delete(ctx context.Context, key string) error {
+	const wrap = "writeLimitCache/Delete"
+	if err := cache.ValidateInput(
+		cache.WithClosedValidation(&w.closed, wrap),
+		cache.WithCtxValidation(ctx, wrap),
+		cache.WithKeyValidation(key, wrap),
+	); err != nil {
+		return err
+	}
+
+	return w.impl.Delete(ctx, key)
+}
+
+func (w *writeLimitCache) Close(ctx context.Context) error {
+	var err error
+	w.closedOnce.Do(func() {
+		w.closed.Store(true)
+		close(w.closeCh)
+		err = w.impl.Close(ctx)
+	})
+
+	return err
+}
+
+func (w *writeLimitCache) GetKeys(ctx context.Context) ([]string, error) {
+	const wrap = "writeLimitCache/GetKeys"
+	if err := cache.ValidateInput(
+		cache.WithClosedValidation(&w.closed, wrap),
+		cache.WithCtxValidation(ctx, wrap),
+	); err != nil {
+		return nil, err
+	}
+
+	return w.impl.GetKeys(ctx)
+}
+
+func (w *writeLimitCache) GetLength() (int64, error) {
+	const wrap = "writeLimitCache/GetLength"
+	if err := cache.ValidateInput(
+		cache.WithClosedValidation(&w.closed, wrap),
+	); err != nil {
+		return 0, err
+	}
+
+	return w.impl.GetLength()
+}
+
+// allow takes a token of the key, otherwise it returns time until the next token
+func (w *writeLimitCache) allow(key string) (time.Duration, bool) {
+	if w.rate == 0 {
+		return 0, true
+	}
+
+	now := w.now()
+
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	b, ok := w.buckets[key]
+	if !ok {
+		b = &bucket{tokens: w.burst, last: now}
+		w.buckets[key] = b
+	}
+
+	w.refill(b, now)
+
+	if b.tokens < 1 {
+		return time.Duration(math.Ceil((1 - b.tokens) / w.rate)), false
+	}
+
+	b.tokens--
+
+	return 0, true
+}
+
+func (w *writeLimitCache) refill(b *bucket, now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = min(w.burst, b.tokens+float64(elapsed)*w.rate)
+		b.last = now
+	}
+}
+
+// sweep forgets full buckets, as they are equal to new ones
+func (w *writeLimitCache) sweep() {
+	for {
+		select {
+		case <-w.ticker.C:
+			now := w.now()
+
+			w.mtx.Lock()
+			for key, b := range w.buckets {
+				w.refill(b, now)
+				if b.tokens >= w.burst {
+					delete(w.buckets, key)
+				}
+			}
+			w.mtx.Unlock()
+		case <-w.closeCh:
+			w.ticker.Stop()
+			return
+		}
+	}
+}
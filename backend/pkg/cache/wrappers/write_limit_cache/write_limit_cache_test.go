@@ -0,0 +1,123 @@
+package write_limit_cache
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KennyMacCormik/otel/backend/pkg/cache"
+	"github.com/KennyMacCormik/otel/backend/pkg/cache/impl/sync_map"
+	cacheErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/cache"
+
+	mockCache "github.com/KennyMacCormik/otel/backend/pkg/cache/mocks"
+)
+
+func typeAssertion(t *testing.T, c cache.CacheInterface) *writeLimitCache {
+	cacheImpl, ok := c.(*writeLimitCache)
+	require.True(t, ok, "expect result to be of type *writeLimitCache")
+	require.NotNil(t, cacheImpl, "expect result to be not nil")
+	return cacheImpl
+}
+
+func newTestCache(t *testing.T, opts ...InitOptions) (*writeLimitCache, *time.Time) {
+	wc, err := NewWriteLimitCache(sync_map.NewSyncMapCache(), opts...)
+	require.NoError(t, err, "expect no error with valid configuration")
+	t.Cleanup(func() { _ = wc.Close(context.Background()) })
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	cacheImpl := typeAssertion(t, wc)
+	cacheImpl.now = func() time.Time { return now }
+
+	return cacheImpl, &now
+}
+
+func TestWriteLimitCache_New(t *testing.T) {
+	c := mockCache.NewMockCacheInterface(t)
+	c.EXPECT().Close(context.Background()).Return(nil)
+
+	wc, err := NewWriteLimitCache(c)
+	require.NoError(t, err, "expect no error with default configuration")
+	assert.Implements(t, (*cache.CacheInterface)(nil), wc, "result should implement cache.Interface")
+
+	cacheImpl := typeAssertion(t, wc)
+	assert.Equal(t, float64(defaultLimit), cacheImpl.burst, "expect burst to equal defaultLimit")
+	assert.Equal(t, defaultMaxValueSize, cacheImpl.maxValueSize, "expect defaultMaxValueSize")
+	require.NoError(t, wc.Close(context.Background()), "expect no error on close")
+
+	_, err = NewWriteLimitCache(nil)
+	assert.ErrorIs(t, err, cacheErrors.ErrNil, "expect error with nil impl")
+}
+
+func TestWriteLimitCache_Set(t *testing.T) {
+	wc, now := newTestCache(t, WithOverrideDefaults(2, time.Second, 3, 0))
+	ctx := context.Background()
+
+	for range 3 {
+		_, err := wc.Set(ctx, "hot", "value")
+		require.NoError(t, err, "expect writes within burst")
+	}
+
+	_, err := wc.Set(ctx, "hot", "value")
+	var errLimited *cacheErrors.ErrWriteLimited
+	require.ErrorAs(t, err, &errLimited, "expect typed error once burst is exhausted")
+	assert.ErrorIs(t, err, cacheErrors.ErrWriteRateLimited, "expect rate limited error")
+	assert.Equal(t, "hot", errLimited.GetKey(), "expect limited key")
+	assert.Equal(t, 500*time.Millisecond, errLimited.GetRetryAfter(), "expect retry after a token refill")
+
+	_, err = wc.Set(ctx, "cold", "value")
+	assert.NoError(t, err, "expect other keys to be limited separately")
+
+	*now = now.Add(500 * time.Millisecond)
+	_, err = wc.Set(ctx, "hot", "value")
+	assert.NoError(t, err, "expect write after refill")
+
+	_, err = wc.Get(ctx, "hot")
+	assert.NoError(t, err, "expect reads not to be limited")
+}
+
+func TestWriteLimitCache_MaxValueSize(t *testing.T) {
+	wc, _ := newTestCache(t, WithOverrideDefaults(0, time.Second, 0, 8))
+	ctx := context.Background()
+
+	for range 2 * defaultLimit {
+		_, err := wc.Set(ctx, "key", "12345678")
+		require.NoError(t, err, "expect value within limit to be written without rate limit")
+	}
+
+	_, err := wc.Set(ctx, "key", "")
+	assert.NoError(t, err, "expect empty value")
+
+	_, err = wc.Set(ctx, "key", []byte(strings.Repeat("a", 9)))
+	assert.ErrorIs(t, err, cacheErrors.ErrValueTooLarge, "expect large value to be rejected")
+
+	_, err = wc.Set(ctx, "key", 42)
+	assert.ErrorIs(t, err, cacheErrors.ErrUnsupportedType, "expect size of unsupported type to be rejected")
+}
+
+func TestWriteLimitCache_Sweep(t *testing.T) {
+	wc, err := NewWriteLimitCache(sync_map.NewSyncMapCache(), WithOverrideDefaults(1, time.Millisecond, 1, 0), WithSweepInterval(time.Millisecond))
+	require.NoError(t, err, "expect no error with valid configuration")
+	defer func() { _ = wc.Close(context.Background()) }()
+
+	_, err = wc.Set(context.Background(), "key", "value")
+	require.NoError(t, err, "expect write")
+
+	cacheImpl := typeAssertion(t, wc)
+	assert.Eventually(t, func() bool {
+		cacheImpl.mtx.Lock()
+		defer cacheImpl.mtx.Unlock()
+		return len(cacheImpl.buckets) == 0
+	}, time.Second, time.Millisecond, "expect idle key to be forgotten")
+}
+
+func TestWriteLimitCache_Closed(t *testing.T) {
+	wc, _ := newTestCache(t)
+	require.NoError(t, wc.Close(context.Background()), "expect no error on close")
+
+	_, err := wc.Set(context.Background(), "key", "value")
+	assert.ErrorIs(t, err, cacheErrors.ErrCacheClosed, "expect closed error")
+}
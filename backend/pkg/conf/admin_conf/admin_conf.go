@@ -0,0 +1,43 @@
+package admin_conf
+
+import (
+	"github.com/KennyMacCormik/common/log"
+	"github.com/KennyMacCormik/common/val"
+	"github.com/spf13/viper"
+
+	"github.com/KennyMacCormik/otel/backend/pkg/conf"
+)
+
+type adminConf struct {
+	AdminToken string `mapstructure:"admin_token" validate:"omitempty,min=16"`
+}
+
+func NewAdminConf() conf.AdminConf {
+	c := &adminConf{}
+
+	viper.SetDefault("admin_token", "")
+	err := viper.BindEnv("admin_token")
+	if err != nil {
+		log.Error("Failed to bind admin_token")
+	}
+
+	err = viper.Unmarshal(c)
+	if err != nil {
+		log.Error("Failed to unmarshal adminConf")
+	}
+
+	err = val.ValidateStruct(c)
+	if err != nil {
+		log.Error("Failed to validate adminConf", "err", err)
+	}
+
+	if err != nil {
+		return nil
+	}
+
+	return c
+}
+
+func (a *adminConf) Token() string {
+	return a.AdminToken
+}
@@ -0,0 +1,54 @@
+package compression_conf
+
+import (
+	"github.com/KennyMacCormik/common/log"
+	"github.com/KennyMacCormik/common/val"
+	"github.com/spf13/viper"
+
+	"github.com/KennyMacCormik/otel/backend/pkg/conf"
+)
+
+type compressionConf struct {
+	CompressionAlgorithm string `mapstructure:"compression_algorithm" validate:"oneof=none gzip zstd snappy"`
+	CompressionThreshold int64  `mapstructure:"compression_threshold" validate:"min=0,max=1048576"`
+}
+
+func NewCompressionConf() conf.CompressionConf {
+	c := &compressionConf{}
+
+	viper.SetDefault("compression_algorithm", "none")
+	err := viper.BindEnv("compression_algorithm")
+	if err != nil {
+		log.Error("Failed to bind compression_algorithm")
+	}
+
+	viper.SetDefault("compression_threshold", "1024")
+	err = viper.BindEnv("compression_threshold")
+	if err != nil {
+		log.Error("Failed to bind compression_threshold")
+	}
+
+	err = viper.Unmarshal(c)
+	if err != nil {
+		log.Error("Failed to unmarshal compressionConf")
+	}
+
+	err = val.ValidateStruct(c)
+	if err != nil {
+		log.Error("Failed to validate compressionConf", "err", err)
+	}
+
+	if err != nil {
+		return nil
+	}
+
+	return c
+}
+
+func (c *compressionConf) Algorithm() string {
+	return c.CompressionAlgorithm
+}
+
+func (c *compressionConf) Threshold() int64 {
+	return c.CompressionThreshold
+}
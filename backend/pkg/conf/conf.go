@@ -13,12 +13,40 @@ type HttpConf interface {
 	WriteTimeout() time.Duration
 	IdleTimeout() time.Duration
 	ShutdownTimeout() time.Duration
+	H2CEnabled() bool
 }
 
 type RateLimiterConf interface {
 	MaxRunning() int64
 	MaxWaiting() int64
 	RetryAfter() int64
+	Mode() string
+	Limit() int64
+	Window() time.Duration
+	Burst() int64
+	KeySource() string
+	KeyHeaderName() string
+	KeyClaimName() string
+	KeyLimitsList() string
+	MaxKeys() int
+	UnknownKeysLimit() int64
+	Store() string
+	StoreEndpointAddr() string
+	StoreKeyPrefix() string
+	SyncInterval() time.Duration
+	StoreRequestTimeout() time.Duration
+	Adaptive() string
+	AdaptiveMinLimit() int64
+	AdaptiveMaxLimit() int64
+	AdaptiveLatencyThreshold() time.Duration
+	Scheduling() string
+	Classes() string
+	ClassRoutes() string
+	ClassHeaderName() string
+	MaxQueueWait() time.Duration
+	ShedTarget() time.Duration
+	ShedInterval() time.Duration
+	Name() string
 }
 
 type OTelConfig interface {
@@ -29,3 +57,54 @@ type OTelConfig interface {
 type GinConfig interface {
 	Mode() string
 }
+
+type AdminConf interface {
+	Token() string
+}
+
+type CompressionConf interface {
+	Algorithm() string
+	Threshold() int64
+}
+
+type WriteLimitConf interface {
+	Limit() int64
+	Window() time.Duration
+	Burst() int64
+	MaxValueSize() int64
+}
+
+type QuotaConf interface {
+	Enabled() bool
+	TenantHeader() string
+	Tenants() string
+	Limits() string
+	MaxKeys() int64
+	MaxBytes() int64
+}
+
+type EncryptionConf interface {
+	Keys() string
+	KeysFile() string
+}
+
+type GrpcConf interface {
+	Enabled() bool
+	Endpoint() string
+	ShutdownTimeout() time.Duration
+	WatchBufferSize() int
+}
+
+type RespConf interface {
+	Enabled() bool
+	Endpoint() string
+	ShutdownTimeout() time.Duration
+	IdleTimeout() time.Duration
+}
+
+type MemcacheConf interface {
+	Enabled() bool
+	Endpoint() string
+	ShutdownTimeout() time.Duration
+	IdleTimeout() time.Duration
+}
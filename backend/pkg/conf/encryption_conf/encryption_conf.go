@@ -0,0 +1,52 @@
+package encryption_conf
+
+import (
+	"github.com/KennyMacCormik/common/log"
+	"github.com/KennyMacCormik/common/val"
+	"github.com/spf13/viper"
+
+	"github.com/KennyMacCormik/otel/backend/pkg/conf"
+)
+
+type encryptionConf struct {
+	EncryptionKeys     string `mapstructure:"encryption_keys" validate:"excluded_with=EncryptionKeysFile"`
+	EncryptionKeysFile string `mapstructure:"encryption_keys_file" validate:"omitempty,file"`
+}
+
+func NewEncryptionConf() conf.EncryptionConf {
+	c := &encryptionConf{}
+
+	err := viper.BindEnv("encryption_keys")
+	if err != nil {
+		log.Error("Failed to bind encryption_keys")
+	}
+
+	err = viper.BindEnv("encryption_keys_file")
+	if err != nil {
+		log.Error("Failed to bind encryption_keys_file")
+	}
+
+	err = viper.Unmarshal(c)
+	if err != nil {
+		log.Error("Failed to unmarshal encryptionConf")
+	}
+
+	err = val.ValidateStruct(c)
+	if err != nil {
+		log.Error("Failed to validate encryptionConf", "err", err)
+	}
+
+	if err != nil {
+		return nil
+	}
+
+	return c
+}
+
+func (e *encryptionConf) Keys() string {
+	return e.EncryptionKeys
+}
+
+func (e *encryptionConf) KeysFile() string {
+	return e.EncryptionKeysFile
+}
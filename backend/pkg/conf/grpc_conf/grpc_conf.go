@@ -0,0 +1,87 @@
+package grpc_conf
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KennyMacCormik/common/log"
+	"github.com/KennyMacCormik/common/val"
+	"github.com/spf13/viper"
+
+	"github.com/KennyMacCormik/otel/backend/pkg/conf"
+)
+
+type grpcConf struct {
+	GrpcEnabled         bool          `mapstructure:"grpc_enabled"`
+	GrpcHost            string        `mapstructure:"grpc_host" validate:"ip4_addr|fqdn,required"`
+	GrpcPort            int           `mapstructure:"grpc_port" validate:"numeric,gt=1024,lt=65536,required"`
+	GrpcShutdownTimeout time.Duration `mapstructure:"grpc_shutdown_timeout" validate:"min=100ms,max=30s"`
+	GrpcWatchBufferSize int           `mapstructure:"grpc_watch_buffer_size" validate:"min=1,max=100000"`
+}
+
+func NewGrpcConf() conf.GrpcConf {
+	c := &grpcConf{}
+
+	viper.SetDefault("grpc_enabled", false)
+	err := viper.BindEnv("grpc_enabled")
+	if err != nil {
+		log.Error("Failed to bind grpc_enabled")
+	}
+
+	viper.SetDefault("grpc_host", "0.0.0.0")
+	err = viper.BindEnv("grpc_host")
+	if err != nil {
+		log.Error("Failed to bind grpc_host")
+	}
+
+	viper.SetDefault("grpc_port", 9090)
+	err = viper.BindEnv("grpc_port")
+	if err != nil {
+		log.Error("Failed to bind grpc_port")
+	}
+
+	viper.SetDefault("grpc_shutdown_timeout", "10s")
+	err = viper.BindEnv("grpc_shutdown_timeout")
+	if err != nil {
+		log.Error("Failed to bind grpc_shutdown_timeout")
+	}
+
+	viper.SetDefault("grpc_watch_buffer_size", 128)
+	err = viper.BindEnv("grpc_watch_buffer_size")
+	if err != nil {
+		log.Error("Failed to bind grpc_watch_buffer_size")
+	}
+
+	err = viper.Unmarshal(c)
+	if err != nil {
+		log.Error("Failed to unmarshal grpcConf")
+	}
+
+	err = val.ValidateStruct(c)
+	if err != nil {
+		log.Error("Failed to validate grpcConf", "err", err)
+	}
+
+	if err != nil {
+		return nil
+	}
+
+	return c
+}
+
+func (g grpcConf) Enabled() bool {
+	return g.GrpcEnabled
+}
+
+func (g grpcConf) Endpoint() string {
+	return strings.Join([]string{g.GrpcHost, strconv.Itoa(g.GrpcPort)}, ":")
+}
+
+func (g grpcConf) ShutdownTimeout() time.Duration {
+	return g.GrpcShutdownTimeout
+}
+
+func (g grpcConf) WatchBufferSize() int {
+	return g.GrpcWatchBufferSize
+}
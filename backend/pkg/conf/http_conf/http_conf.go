@@ -19,6 +19,7 @@ type httpConf struct {
 	HttpWriteTimeout    time.Duration `mapstructure:"http_write_timeout" validate:"min=100ms,max=1s"`
 	HttpIdleTimeout     time.Duration `mapstructure:"http_idle_timeout" validate:"min=100ms,max=1s"`
 	HttpShutdownTimeout time.Duration `mapstructure:"http_shutdown_timeout" validate:"min=100ms,max=30s"`
+	HttpH2CEnabled      bool          `mapstructure:"http_h2c_enabled"`
 }
 
 func NewHTTPConf() conf.HttpConf {
@@ -58,6 +59,12 @@ func NewHTTPConf() conf.HttpConf {
 		log.Error("Failed to bind http_shutdown_timeout")
 	}
 
+	viper.SetDefault("http_h2c_enabled", false)
+	err = viper.BindEnv("http_h2c_enabled")
+	if err != nil {
+		log.Error("Failed to bind http_h2c_enabled")
+	}
+
 	err = viper.Unmarshal(c)
 	if err != nil {
 		log.Error("Failed to unmarshal httpConf")
@@ -94,3 +101,7 @@ func (h httpConf) IdleTimeout() time.Duration {
 func (h httpConf) ShutdownTimeout() time.Duration {
 	return h.HttpShutdownTimeout
 }
+
+func (h httpConf) H2CEnabled() bool {
+	return h.HttpH2CEnabled
+}
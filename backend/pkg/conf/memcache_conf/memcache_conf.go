@@ -0,0 +1,87 @@
+package memcache_conf
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KennyMacCormik/common/log"
+	"github.com/KennyMacCormik/common/val"
+	"github.com/spf13/viper"
+
+	"github.com/KennyMacCormik/otel/backend/pkg/conf"
+)
+
+type memcacheConf struct {
+	MemcacheEnabled         bool          `mapstructure:"memcache_enabled"`
+	MemcacheHost            string        `mapstructure:"memcache_host" validate:"ip4_addr|fqdn,required"`
+	MemcachePort            int           `mapstructure:"memcache_port" validate:"numeric,gt=1024,lt=65536,required"`
+	MemcacheShutdownTimeout time.Duration `mapstructure:"memcache_shutdown_timeout" validate:"min=100ms,max=30s"`
+	MemcacheIdleTimeout     time.Duration `mapstructure:"memcache_idle_timeout" validate:"min=1s,max=24h"`
+}
+
+func NewMemcacheConf() conf.MemcacheConf {
+	c := &memcacheConf{}
+
+	viper.SetDefault("memcache_enabled", false)
+	err := viper.BindEnv("memcache_enabled")
+	if err != nil {
+		log.Error("Failed to bind memcache_enabled")
+	}
+
+	viper.SetDefault("memcache_host", "0.0.0.0")
+	err = viper.BindEnv("memcache_host")
+	if err != nil {
+		log.Error("Failed to bind memcache_host")
+	}
+
+	viper.SetDefault("memcache_port", 11211)
+	err = viper.BindEnv("memcache_port")
+	if err != nil {
+		log.Error("Failed to bind memcache_port")
+	}
+
+	viper.SetDefault("memcache_shutdown_timeout", "10s")
+	err = viper.BindEnv("memcache_shutdown_timeout")
+	if err != nil {
+		log.Error("Failed to bind memcache_shutdown_timeout")
+	}
+
+	viper.SetDefault("memcache_idle_timeout", "5m")
+	err = viper.BindEnv("memcache_idle_timeout")
+	if err != nil {
+		log.Error("Failed to bind memcache_idle_timeout")
+	}
+
+	err = viper.Unmarshal(c)
+	if err != nil {
+		log.Error("Failed to unmarshal memcacheConf")
+	}
+
+	err = val.ValidateStruct(c)
+	if err != nil {
+		log.Error("Failed to validate memcacheConf", "err", err)
+	}
+
+	if err != nil {
+		return nil
+	}
+
+	return c
+}
+
+func (m memcacheConf) Enabled() bool {
+	return m.MemcacheEnabled
+}
+
+func (m memcacheConf) Endpoint() string {
+	return strings.Join([]string{m.MemcacheHost, strconv.Itoa(m.MemcachePort)}, ":")
+}
+
+func (m memcacheConf) ShutdownTimeout() time.Duration {
+	return m.MemcacheShutdownTimeout
+}
+
+func (m memcacheConf) IdleTimeout() time.Duration {
+	return m.MemcacheIdleTimeout
+}
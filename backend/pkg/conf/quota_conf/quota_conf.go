@@ -0,0 +1,98 @@
+package quota_conf
+
+import (
+	"github.com/KennyMacCormik/common/log"
+	"github.com/KennyMacCormik/common/val"
+	"github.com/spf13/viper"
+
+	"github.com/KennyMacCormik/otel/backend/pkg/conf"
+)
+
+type quotaConf struct {
+	QuotaEnabled     bool   `mapstructure:"quota_enabled"`
+	QuotaTenantHdr   string `mapstructure:"quota_tenant_header" validate:"required"`
+	QuotaTenantsList string `mapstructure:"quota_tenants"`
+	QuotaLimitsList  string `mapstructure:"quota_limits"`
+	QuotaMaxKeys     int64  `mapstructure:"quota_max_keys" validate:"min=0"`
+	QuotaMaxBytes    int64  `mapstructure:"quota_max_bytes" validate:"min=0"`
+}
+
+func NewQuotaConf() conf.QuotaConf {
+	c := &quotaConf{}
+
+	viper.SetDefault("quota_enabled", "false")
+	err := viper.BindEnv("quota_enabled")
+	if err != nil {
+		log.Error("Failed to bind quota_enabled")
+	}
+
+	viper.SetDefault("quota_tenant_header", "X-API-Key")
+	err = viper.BindEnv("quota_tenant_header")
+	if err != nil {
+		log.Error("Failed to bind quota_tenant_header")
+	}
+
+	viper.SetDefault("quota_tenants", "")
+	err = viper.BindEnv("quota_tenants")
+	if err != nil {
+		log.Error("Failed to bind quota_tenants")
+	}
+
+	viper.SetDefault("quota_limits", "")
+	err = viper.BindEnv("quota_limits")
+	if err != nil {
+		log.Error("Failed to bind quota_limits")
+	}
+
+	viper.SetDefault("quota_max_keys", "0")
+	err = viper.BindEnv("quota_max_keys")
+	if err != nil {
+		log.Error("Failed to bind quota_max_keys")
+	}
+
+	viper.SetDefault("quota_max_bytes", "0")
+	err = viper.BindEnv("quota_max_bytes")
+	if err != nil {
+		log.Error("Failed to bind quota_max_bytes")
+	}
+
+	err = viper.Unmarshal(c)
+	if err != nil {
+		log.Error("Failed to unmarshal quotaConf")
+	}
+
+	err = val.ValidateStruct(c)
+	if err != nil {
+		log.Error("Failed to validate quotaConf", "err", err)
+	}
+
+	if err != nil {
+		return nil
+	}
+
+	return c
+}
+
+func (q *quotaConf) Enabled() bool {
+	return q.QuotaEnabled
+}
+
+func (q *quotaConf) TenantHeader() string {
+	return q.QuotaTenantHdr
+}
+
+func (q *quotaConf) Tenants() string {
+	return q.QuotaTenantsList
+}
+
+func (q *quotaConf) Limits() string {
+	return q.QuotaLimitsList
+}
+
+func (q *quotaConf) MaxKeys() int64 {
+	return q.QuotaMaxKeys
+}
+
+func (q *quotaConf) MaxBytes() int64 {
+	return q.QuotaMaxBytes
+}
@@ -1,6 +1,10 @@
 package rate_limiter_conf
 
 import (
+	"errors"
+	"fmt"
+	"time"
+
 	"github.com/KennyMacCormik/common/log"
 	"github.com/KennyMacCormik/common/val"
 	"github.com/spf13/viper"
@@ -8,10 +12,54 @@ import (
 	"github.com/KennyMacCormik/otel/backend/pkg/conf"
 )
 
+// errGlobalStorageStore is returned for storage store with global limits. Storage store updates counters
+// by read-modify-write, so replicas contending for the single global counter lose most of their updates.
+var errGlobalStorageStore = errors.New("rate_limiter_store storage requires rate_limiter_key, use resp store for global limits")
+
+// errConcurrencyKey is returned for keyed limits in concurrency mode, which has no rate limit to apply per key
+var errConcurrencyKey = errors.New("rate_limiter_key requires rate_limiter_mode other than concurrency")
+
+// errConcurrencyStore is returned for a store in concurrency mode, only rate limits are shared between instances
+var errConcurrencyStore = errors.New("rate_limiter_store requires rate_limiter_mode other than concurrency")
+
 type rateLimiterConfig struct {
 	MaxRun  int64 `mapstructure:"rate_limiter_max_conn" validate:"min=1,max=100000"`
 	MaxWait int64 `mapstructure:"rate_limiter_max_wait" validate:"min=1,max=100000"`
 	Retry   int64 `mapstructure:"rate_limiter_retry_after" validate:"min=1,max=60"`
+
+	LimiterMode string        `mapstructure:"rate_limiter_mode" validate:"oneof=concurrency token_bucket sliding_window_log sliding_window_counter"`
+	RateLimit   int64         `mapstructure:"rate_limiter_limit" validate:"min=1,max=1000000"`
+	RateWindow  time.Duration `mapstructure:"rate_limiter_window" validate:"min=1ms,max=1h"`
+	RateBurst   int64         `mapstructure:"rate_limiter_burst" validate:"min=0,max=1000000"`
+
+	Key        string `mapstructure:"rate_limiter_key" validate:"oneof=none ip header jwt_claim route"`
+	KeyHeader  string `mapstructure:"rate_limiter_key_header" validate:"required"`
+	KeyClaim   string `mapstructure:"rate_limiter_key_claim" validate:"required"`
+	KeyLimits  string `mapstructure:"rate_limiter_key_limits"`
+	KeysMaxNum int    `mapstructure:"rate_limiter_max_keys" validate:"min=1,max=10000000"`
+	UnknownMax int64  `mapstructure:"rate_limiter_unknown_keys_limit" validate:"min=0,max=1000000"`
+
+	StoreKind         string        `mapstructure:"rate_limiter_store" validate:"oneof=none local resp storage"`
+	StoreEndpoint     string        `mapstructure:"rate_limiter_store_endpoint" validate:"required_if=StoreKind resp,required_if=StoreKind storage"`
+	StorePrefix       string        `mapstructure:"rate_limiter_store_prefix"`
+	StoreSyncInterval time.Duration `mapstructure:"rate_limiter_store_sync_interval" validate:"min=10ms,max=10s"`
+	StoreTimeout      time.Duration `mapstructure:"rate_limiter_store_timeout" validate:"min=10ms,max=30s"`
+
+	AdaptiveAlgorithm string        `mapstructure:"rate_limiter_adaptive" validate:"oneof=none aimd gradient"`
+	AdaptiveMin       int64         `mapstructure:"rate_limiter_adaptive_min" validate:"min=1,max=100000"`
+	AdaptiveMax       int64         `mapstructure:"rate_limiter_adaptive_max" validate:"min=1,max=100000,gtefield=AdaptiveMin"`
+	AdaptiveLatency   time.Duration `mapstructure:"rate_limiter_adaptive_latency" validate:"min=0,max=60s"`
+
+	SchedulingPolicy string `mapstructure:"rate_limiter_scheduling" validate:"oneof=fifo priority wfq"`
+	ClassList        string `mapstructure:"rate_limiter_classes"`
+	ClassRouteList   string `mapstructure:"rate_limiter_class_routes"`
+	ClassHeader      string `mapstructure:"rate_limiter_class_header"`
+
+	QueueWait     time.Duration `mapstructure:"rate_limiter_queue_timeout" validate:"min=0,max=60s"`
+	CodelTarget   time.Duration `mapstructure:"rate_limiter_codel_target" validate:"min=0,max=10s"`
+	CodelInterval time.Duration `mapstructure:"rate_limiter_codel_interval" validate:"min=1ms,max=60s"`
+
+	LimiterName string `mapstructure:"rate_limiter_name"`
 }
 
 func NewRateLimiterConfig() conf.RateLimiterConf {
@@ -35,12 +83,182 @@ func NewRateLimiterConfig() conf.RateLimiterConf {
 		log.Error("Failed to bind rate_limiter_retry_after")
 	}
 
+	viper.SetDefault("rate_limiter_mode", "concurrency")
+	err = viper.BindEnv("rate_limiter_mode")
+	if err != nil {
+		log.Error("Failed to bind rate_limiter_mode")
+	}
+
+	viper.SetDefault("rate_limiter_limit", "100")
+	err = viper.BindEnv("rate_limiter_limit")
+	if err != nil {
+		log.Error("Failed to bind rate_limiter_limit")
+	}
+
+	viper.SetDefault("rate_limiter_window", "1s")
+	err = viper.BindEnv("rate_limiter_window")
+	if err != nil {
+		log.Error("Failed to bind rate_limiter_window")
+	}
+
+	viper.SetDefault("rate_limiter_burst", "0")
+	err = viper.BindEnv("rate_limiter_burst")
+	if err != nil {
+		log.Error("Failed to bind rate_limiter_burst")
+	}
+
+	viper.SetDefault("rate_limiter_key", "none")
+	err = viper.BindEnv("rate_limiter_key")
+	if err != nil {
+		log.Error("Failed to bind rate_limiter_key")
+	}
+
+	viper.SetDefault("rate_limiter_key_header", "X-API-Key")
+	err = viper.BindEnv("rate_limiter_key_header")
+	if err != nil {
+		log.Error("Failed to bind rate_limiter_key_header")
+	}
+
+	viper.SetDefault("rate_limiter_key_claim", "sub")
+	err = viper.BindEnv("rate_limiter_key_claim")
+	if err != nil {
+		log.Error("Failed to bind rate_limiter_key_claim")
+	}
+
+	viper.SetDefault("rate_limiter_key_limits", "")
+	err = viper.BindEnv("rate_limiter_key_limits")
+	if err != nil {
+		log.Error("Failed to bind rate_limiter_key_limits")
+	}
+
+	viper.SetDefault("rate_limiter_max_keys", "10000")
+	err = viper.BindEnv("rate_limiter_max_keys")
+	if err != nil {
+		log.Error("Failed to bind rate_limiter_max_keys")
+	}
+
+	viper.SetDefault("rate_limiter_unknown_keys_limit", "0")
+	err = viper.BindEnv("rate_limiter_unknown_keys_limit")
+	if err != nil {
+		log.Error("Failed to bind rate_limiter_unknown_keys_limit")
+	}
+
+	viper.SetDefault("rate_limiter_store", "none")
+	err = viper.BindEnv("rate_limiter_store")
+	if err != nil {
+		log.Error("Failed to bind rate_limiter_store")
+	}
+
+	err = viper.BindEnv("rate_limiter_store_endpoint")
+	if err != nil {
+		log.Error("Failed to bind rate_limiter_store_endpoint")
+	}
+
+	viper.SetDefault("rate_limiter_store_prefix", "ratelimit:")
+	err = viper.BindEnv("rate_limiter_store_prefix")
+	if err != nil {
+		log.Error("Failed to bind rate_limiter_store_prefix")
+	}
+
+	viper.SetDefault("rate_limiter_store_sync_interval", "100ms")
+	err = viper.BindEnv("rate_limiter_store_sync_interval")
+	if err != nil {
+		log.Error("Failed to bind rate_limiter_store_sync_interval")
+	}
+
+	viper.SetDefault("rate_limiter_store_timeout", "500ms")
+	err = viper.BindEnv("rate_limiter_store_timeout")
+	if err != nil {
+		log.Error("Failed to bind rate_limiter_store_timeout")
+	}
+
+	viper.SetDefault("rate_limiter_adaptive", "none")
+	err = viper.BindEnv("rate_limiter_adaptive")
+	if err != nil {
+		log.Error("Failed to bind rate_limiter_adaptive")
+	}
+
+	viper.SetDefault("rate_limiter_adaptive_min", "1")
+	err = viper.BindEnv("rate_limiter_adaptive_min")
+	if err != nil {
+		log.Error("Failed to bind rate_limiter_adaptive_min")
+	}
+
+	viper.SetDefault("rate_limiter_adaptive_max", "1000")
+	err = viper.BindEnv("rate_limiter_adaptive_max")
+	if err != nil {
+		log.Error("Failed to bind rate_limiter_adaptive_max")
+	}
+
+	viper.SetDefault("rate_limiter_adaptive_latency", "0s")
+	err = viper.BindEnv("rate_limiter_adaptive_latency")
+	if err != nil {
+		log.Error("Failed to bind rate_limiter_adaptive_latency")
+	}
+
+	viper.SetDefault("rate_limiter_scheduling", "fifo")
+	err = viper.BindEnv("rate_limiter_scheduling")
+	if err != nil {
+		log.Error("Failed to bind rate_limiter_scheduling")
+	}
+
+	viper.SetDefault("rate_limiter_classes", "")
+	err = viper.BindEnv("rate_limiter_classes")
+	if err != nil {
+		log.Error("Failed to bind rate_limiter_classes")
+	}
+
+	viper.SetDefault("rate_limiter_class_routes", "")
+	err = viper.BindEnv("rate_limiter_class_routes")
+	if err != nil {
+		log.Error("Failed to bind rate_limiter_class_routes")
+	}
+
+	viper.SetDefault("rate_limiter_class_header", "")
+	err = viper.BindEnv("rate_limiter_class_header")
+	if err != nil {
+		log.Error("Failed to bind rate_limiter_class_header")
+	}
+
+	viper.SetDefault("rate_limiter_queue_timeout", "0s")
+	err = viper.BindEnv("rate_limiter_queue_timeout")
+	if err != nil {
+		log.Error("Failed to bind rate_limiter_queue_timeout")
+	}
+
+	viper.SetDefault("rate_limiter_codel_target", "0s")
+	err = viper.BindEnv("rate_limiter_codel_target")
+	if err != nil {
+		log.Error("Failed to bind rate_limiter_codel_target")
+	}
+
+	viper.SetDefault("rate_limiter_codel_interval", "100ms")
+	err = viper.BindEnv("rate_limiter_codel_interval")
+	if err != nil {
+		log.Error("Failed to bind rate_limiter_codel_interval")
+	}
+
+	viper.SetDefault("rate_limiter_name", "")
+	err = viper.BindEnv("rate_limiter_name")
+	if err != nil {
+		log.Error("Failed to bind rate_limiter_name")
+	}
+
 	err = viper.Unmarshal(c)
 	if err != nil {
 		log.Error("Failed to unmarshal rateLimiterConfig")
 	}
 
 	err = val.ValidateStruct(c)
+	if err == nil && c.LimiterMode == "concurrency" && c.Key != "none" {
+		err = errConcurrencyKey
+	}
+	if err == nil && c.LimiterMode == "concurrency" && c.StoreKind != "none" {
+		err = errConcurrencyStore
+	}
+	if err == nil && c.StoreKind == "storage" && c.Key == "none" {
+		err = errGlobalStorageStore
+	}
 	if err != nil {
 		log.Error("Failed to validate rateLimiterConfig", "err", err)
 	}
@@ -63,3 +281,116 @@ func (r *rateLimiterConfig) MaxWaiting() int64 {
 func (r *rateLimiterConfig) RetryAfter() int64 {
 	return r.Retry
 }
+
+func (r *rateLimiterConfig) Mode() string {
+	return r.LimiterMode
+}
+
+func (r *rateLimiterConfig) Limit() int64 {
+	return r.RateLimit
+}
+
+func (r *rateLimiterConfig) Window() time.Duration {
+	return r.RateWindow
+}
+
+func (r *rateLimiterConfig) Burst() int64 {
+	return r.RateBurst
+}
+
+func (r *rateLimiterConfig) KeySource() string {
+	return r.Key
+}
+
+func (r *rateLimiterConfig) KeyHeaderName() string {
+	return r.KeyHeader
+}
+
+func (r *rateLimiterConfig) KeyClaimName() string {
+	return r.KeyClaim
+}
+
+func (r *rateLimiterConfig) KeyLimitsList() string {
+	return r.KeyLimits
+}
+
+func (r *rateLimiterConfig) MaxKeys() int {
+	return r.KeysMaxNum
+}
+
+func (r *rateLimiterConfig) UnknownKeysLimit() int64 {
+	return r.UnknownMax
+}
+
+func (r *rateLimiterConfig) Store() string {
+	return r.StoreKind
+}
+
+func (r *rateLimiterConfig) StoreEndpointAddr() string {
+	return r.StoreEndpoint
+}
+
+func (r *rateLimiterConfig) StoreKeyPrefix() string {
+	return r.StorePrefix
+}
+
+func (r *rateLimiterConfig) SyncInterval() time.Duration {
+	return r.StoreSyncInterval
+}
+
+func (r *rateLimiterConfig) StoreRequestTimeout() time.Duration {
+	return r.StoreTimeout
+}
+
+func (r *rateLimiterConfig) Adaptive() string {
+	return r.AdaptiveAlgorithm
+}
+
+func (r *rateLimiterConfig) AdaptiveMinLimit() int64 {
+	return r.AdaptiveMin
+}
+
+func (r *rateLimiterConfig) AdaptiveMaxLimit() int64 {
+	return r.AdaptiveMax
+}
+
+func (r *rateLimiterConfig) AdaptiveLatencyThreshold() time.Duration {
+	return r.AdaptiveLatency
+}
+
+func (r *rateLimiterConfig) Scheduling() string {
+	return r.SchedulingPolicy
+}
+
+func (r *rateLimiterConfig) Classes() string {
+	return r.ClassList
+}
+
+func (r *rateLimiterConfig) ClassRoutes() string {
+	return r.ClassRouteList
+}
+
+func (r *rateLimiterConfig) ClassHeaderName() string {
+	return r.ClassHeader
+}
+
+func (r *rateLimiterConfig) MaxQueueWait() time.Duration {
+	return r.QueueWait
+}
+
+func (r *rateLimiterConfig) ShedTarget() time.Duration {
+	return r.CodelTarget
+}
+
+func (r *rateLimiterConfig) ShedInterval() time.Duration {
+	return r.CodelInterval
+}
+
+func (r *rateLimiterConfig) Name() string {
+	return r.LimiterName
+}
+
+// String prints the settings rather than the pointer in the logged config
+func (r *rateLimiterConfig) String() string {
+	return fmt.Sprintf("%+v", *r)
+}
@@ -0,0 +1,87 @@
+package resp_conf
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KennyMacCormik/common/log"
+	"github.com/KennyMacCormik/common/val"
+	"github.com/spf13/viper"
+
+	"github.com/KennyMacCormik/otel/backend/pkg/conf"
+)
+
+type respConf struct {
+	RespEnabled         bool          `mapstructure:"resp_enabled"`
+	RespHost            string        `mapstructure:"resp_host" validate:"ip4_addr|fqdn,required"`
+	RespPort            int           `mapstructure:"resp_port" validate:"numeric,gt=1024,lt=65536,required"`
+	RespShutdownTimeout time.Duration `mapstructure:"resp_shutdown_timeout" validate:"min=100ms,max=30s"`
+	RespIdleTimeout     time.Duration `mapstructure:"resp_idle_timeout" validate:"min=1s,max=24h"`
+}
+
+func NewRespConf() conf.RespConf {
+	c := &respConf{}
+
+	viper.SetDefault("resp_enabled", false)
+	err := viper.BindEnv("resp_enabled")
+	if err != nil {
+		log.Error("Failed to bind resp_enabled")
+	}
+
+	viper.SetDefault("resp_host", "0.0.0.0")
+	err = viper.BindEnv("resp_host")
+	if err != nil {
+		log.Error("Failed to bind resp_host")
+	}
+
+	viper.SetDefault("resp_port", 6379)
+	err = viper.BindEnv("resp_port")
+	if err != nil {
+		log.Error("Failed to bind resp_port")
+	}
+
+	viper.SetDefault("resp_shutdown_timeout", "10s")
+	err = viper.BindEnv("resp_shutdown_timeout")
+	if err != nil {
+		log.Error("Failed to bind resp_shutdown_timeout")
+	}
+
+	viper.SetDefault("resp_idle_timeout", "5m")
+	err = viper.BindEnv("resp_idle_timeout")
+	if err != nil {
+		log.Error("Failed to bind resp_idle_timeout")
+	}
+
+	err = viper.Unmarshal(c)
+	if err != nil {
+		log.Error("Failed to unmarshal respConf")
+	}
+
+	err = val.ValidateStruct(c)
+	if err != nil {
+		log.Error("Failed to validate respConf", "err", err)
+	}
+
+	if err != nil {
+		return nil
+	}
+
+	return c
+}
+
+func (r respConf) Enabled() bool {
+	return r.RespEnabled
+}
+
+func (r respConf) Endpoint() string {
+	return strings.Join([]string{r.RespHost, strconv.Itoa(r.RespPort)}, ":")
+}
+
+func (r respConf) ShutdownTimeout() time.Duration {
+	return r.RespShutdownTimeout
+}
+
+func (r respConf) IdleTimeout() time.Duration {
+	return r.RespIdleTimeout
+}
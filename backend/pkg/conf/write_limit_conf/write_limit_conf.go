@@ -0,0 +1,78 @@
+package write_limit_conf
+
+import (
+	"time"
+
+	"github.com/KennyMacCormik/common/log"
+	"github.com/KennyMacCormik/common/val"
+	"github.com/spf13/viper"
+
+	"github.com/KennyMacCormik/otel/backend/pkg/conf"
+)
+
+type writeLimitConf struct {
+	WriteLimit        int64         `mapstructure:"write_limit" validate:"min=0,max=1000000"`
+	WriteLimitWindow  time.Duration `mapstructure:"write_limit_window" validate:"min=1ms,max=1h"`
+	WriteLimitBurst   int64         `mapstructure:"write_limit_burst" validate:"min=0,max=1000000"`
+	WriteMaxValueSize int64         `mapstructure:"write_limit_max_value_size" validate:"min=0,max=1073741824"`
+}
+
+func NewWriteLimitConf() conf.WriteLimitConf {
+	c := &writeLimitConf{}
+
+	viper.SetDefault("write_limit", "0")
+	err := viper.BindEnv("write_limit")
+	if err != nil {
+		log.Error("Failed to bind write_limit")
+	}
+
+	viper.SetDefault("write_limit_window", "1s")
+	err = viper.BindEnv("write_limit_window")
+	if err != nil {
+		log.Error("Failed to bind write_limit_window")
+	}
+
+	viper.SetDefault("write_limit_burst", "0")
+	err = viper.BindEnv("write_limit_burst")
+	if err != nil {
+		log.Error("Failed to bind write_limit_burst")
+	}
+
+	viper.SetDefault("write_limit_max_value_size", "0")
+	err = viper.BindEnv("write_limit_max_value_size")
+	if err != nil {
+		log.Error("Failed to bind write_limit_max_value_size")
+	}
+
+	err = viper.Unmarshal(c)
+	if err != nil {
+		log.Error("Failed to unmarshal writeLimitConf")
+	}
+
+	err = val.ValidateStruct(c)
+	if err != nil {
+		log.Error("Failed to validate writeLimitConf", "err", err)
+	}
+
+	if err != nil {
+		return nil
+	}
+
+	return c
+}
+
+func (w *writeLimitConf) Limit() int64 {
+	return w.WriteLimit
+}
+
+func (w *writeLimitConf) Window() time.Duration {
+	return w.WriteLimitWindow
+}
+
+func (w *writeLimitConf) Burst() int64 {
+	return w.WriteLimitBurst
+}
+
+func (w *writeLimitConf) MaxValueSize() int64 {
+	return w.WriteMaxValueSize
+}
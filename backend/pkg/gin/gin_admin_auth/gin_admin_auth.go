@@ -0,0 +1,39 @@
+package gin_admin_auth
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/KennyMacCormik/common/log"
+	"github.com/gin-gonic/gin"
+
+	"github.com/KennyMacCormik/otel/backend/pkg/gin/gin_request_id"
+	httpErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/http"
+)
+
+const bearerPrefix = "Bearer "
+
+var unauthorizedBody = httpErrors.NewErrStatus(http.StatusUnauthorized, httpErrors.CodeUnauthorized, "").GetBody()
+
+// AdminAuth rejects requests without "Authorization: Bearer <token>" header with 401.
+// Empty token rejects every request, so admin endpoints can't be left open by mistake.
+func AdminAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		got, ok := strings.CutPrefix(header, bearerPrefix)
+
+		if !ok || token == "" || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			requestID, _ := gin_request_id.GetRequestIDFromCtx(c)
+			log.Warn("admin request rejected: invalid token",
+				"requestID", requestID,
+				"path", c.FullPath(),
+			)
+
+			c.AbortWithStatusJSON(http.StatusUnauthorized, unauthorizedBody)
+			return
+		}
+
+		c.Next()
+	}
+}
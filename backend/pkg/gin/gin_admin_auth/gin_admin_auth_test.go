@@ -0,0 +1,35 @@
+package gin_admin_auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func serve(token, header string) int {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/", AdminAuth(token), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if header != "" {
+		req.Header.Set("Authorization", header)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	return w.Code
+}
+
+func TestAdminAuth(t *testing.T) {
+	const token = "0123456789abcdef"
+
+	assert.Equal(t, http.StatusOK, serve(token, "Bearer "+token), "expect valid token to pass")
+	assert.Equal(t, http.StatusUnauthorized, serve(token, ""), "expect missing token to be rejected")
+	assert.Equal(t, http.StatusUnauthorized, serve(token, "Bearer wrong"), "expect wrong token to be rejected")
+	assert.Equal(t, http.StatusUnauthorized, serve(token, token), "expect token without scheme to be rejected")
+	assert.Equal(t, http.StatusUnauthorized, serve("", "Bearer "), "expect empty token to reject every request")
+}
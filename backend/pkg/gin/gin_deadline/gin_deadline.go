@@ -0,0 +1,73 @@
+package gin_deadline
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/KennyMacCormik/common/log"
+	"github.com/gin-gonic/gin"
+
+	httpErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/http"
+)
+
+// TimeoutHeader carries the remaining budget of the caller in milliseconds.
+// Relative timeout is used instead of absolute deadline, so it doesn't depend on clock skew between hosts.
+const TimeoutHeader = "X-Request-Timeout"
+
+var errInvalidTimeout = errors.New("invalid timeout")
+
+var timeoutBody = httpErrors.NewErrStatus(http.StatusGatewayTimeout, httpErrors.CodeTimeout, "deadline exceeded").GetBody()
+
+// FormatTimeout returns TimeoutHeader value of d rounded up to milliseconds
+func FormatTimeout(d time.Duration) string {
+	if d <= 0 {
+		return "0"
+	}
+
+	return strconv.FormatInt(int64((d+time.Millisecond-1)/time.Millisecond), 10)
+}
+
+// ParseTimeout parses TimeoutHeader value
+func ParseTimeout(v string) (time.Duration, error) {
+	ms, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || ms < 0 || ms > int64(time.Duration(1<<63-1)/time.Millisecond) {
+		return 0, errInvalidTimeout
+	}
+
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+// DeadlineMiddleware derives request context deadline from TimeoutHeader, so handlers stop working
+// on requests abandoned by the caller. Requests with exhausted budget are rejected with 504.
+// Requests without the header or with malformed one keep their context.
+func DeadlineMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		v := c.GetHeader(TimeoutHeader)
+		if v == "" {
+			c.Next()
+			return
+		}
+
+		timeout, err := ParseTimeout(v)
+		if err != nil {
+			log.Warn("ignoring malformed request timeout", "header", TimeoutHeader, "value", v)
+			c.Next()
+			return
+		}
+
+		if timeout == 0 {
+			c.AbortWithStatusJSON(http.StatusGatewayTimeout, timeoutBody)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+	}
+}
@@ -0,0 +1,80 @@
+package gin_deadline
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatAndParseTimeout(t *testing.T) {
+	assert.Equal(t, "0", FormatTimeout(-time.Second), "expect exhausted budget to be zero")
+	assert.Equal(t, "1", FormatTimeout(time.Microsecond), "expect timeout to be rounded up")
+	assert.Equal(t, "1500", FormatTimeout(1500*time.Millisecond), "expect timeout in milliseconds")
+
+	d, err := ParseTimeout("250")
+	require.NoError(t, err, "expect valid timeout")
+	assert.Equal(t, 250*time.Millisecond, d, "expect timeout in milliseconds")
+
+	for _, v := range []string{"-1", "1.5", "abc", "99999999999999999"} {
+		_, err = ParseTimeout(v)
+		assert.Error(t, err, "expect invalid timeout %s to be rejected", v)
+	}
+}
+
+func newRouter(remaining *time.Duration, hasDeadline *bool) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(DeadlineMiddleware())
+	r.GET("/", func(c *gin.Context) {
+		var deadline time.Time
+		deadline, *hasDeadline = c.Request.Context().Deadline()
+		*remaining = time.Until(deadline)
+		c.Status(http.StatusOK)
+	})
+
+	return r
+}
+
+func TestDeadlineMiddleware(t *testing.T) {
+	var remaining time.Duration
+	var hasDeadline bool
+	r := newRouter(&remaining, &hasDeadline)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(TimeoutHeader, "500")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code, "expect request to be served")
+	assert.True(t, hasDeadline, "expect context deadline to be set")
+	assert.InDelta(t, 500*time.Millisecond, remaining, float64(100*time.Millisecond), "expect deadline derived from header")
+
+	for _, v := range []string{"", "abc"} {
+		req = httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(TimeoutHeader, v)
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code, "expect request to be served")
+		assert.False(t, hasDeadline, "expect no deadline for header %q", v)
+	}
+}
+
+func TestDeadlineMiddleware_Exhausted(t *testing.T) {
+	var remaining time.Duration
+	var hasDeadline bool
+	r := newRouter(&remaining, &hasDeadline)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(TimeoutHeader, "0")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code, "expect request with exhausted budget to be rejected")
+	assert.JSONEq(t, `{"code":"timeout","message":"deadline exceeded"}`, w.Body.String(), "expect typed error body")
+}
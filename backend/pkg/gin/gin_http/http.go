@@ -6,15 +6,26 @@ import (
 	"time"
 
 	"github.com/KennyMacCormik/common/gin_factory"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 )
 
 type GinServer struct {
 	svr http.Server
 }
 
+type InitOptions func(s *GinServer)
+
+// WithH2C serves HTTP/2 without TLS to clients with prior knowledge or sending h2c upgrade, alongside HTTP/1.1
+func WithH2C() InitOptions {
+	return func(s *GinServer) {
+		s.svr.Handler = h2c.NewHandler(s.svr.Handler, &http2.Server{IdleTimeout: s.svr.IdleTimeout})
+	}
+}
+
 func NewHttpServer(endpoint string, r *gin_factory.GinFactory, rTimeout time.Duration,
-	wTimeout time.Duration, iTimeout time.Duration) *GinServer {
-	return &GinServer{
+	wTimeout time.Duration, iTimeout time.Duration, opts ...InitOptions) *GinServer {
+	s := &GinServer{
 		svr: http.Server{
 			Addr:         endpoint,
 			Handler:      r.CreateRouter(),
@@ -23,6 +34,12 @@ func NewHttpServer(endpoint string, r *gin_factory.GinFactory, rTimeout time.Dur
 			IdleTimeout:  iTimeout,
 		},
 	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
 }
 
 func (s *GinServer) Start() error {
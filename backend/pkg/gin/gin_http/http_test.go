@@ -1,6 +1,9 @@
 package gin_http
 
 import (
+	"context"
+	"crypto/tls"
+	"net"
 	"net/http"
 	"testing"
 	"time"
@@ -8,6 +11,8 @@ import (
 	"github.com/KennyMacCormik/common/gin_factory"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
 )
 
 func TestNewHttpServer(t *testing.T) {
@@ -114,3 +119,49 @@ func TestHttpServer_CloseTimeout(t *testing.T) {
 	// Assert that an error occurs due to timeout
 	assert.Error(t, err, "Server should return an error when shutdown times out")
 }
+
+func TestHttpServer_H2C(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	gf := gin_factory.NewGinFactory()
+	gf.AddHandlers(func(r *gin.Engine) {
+		r.GET("/proto", func(c *gin.Context) {
+			c.String(http.StatusOK, c.Request.Proto)
+		})
+	})
+
+	server := NewHttpServer(
+		"127.0.0.1:8082",
+		gf,
+		10*time.Second,
+		10*time.Second,
+		10*time.Second,
+		WithH2C(),
+	)
+
+	go func() { _ = server.Start() }()
+	defer func() { _ = server.Close(5 * time.Second) }()
+
+	// HTTP/2 with prior knowledge
+	client := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		},
+	}}
+
+	var resp *http.Response
+	require.Eventually(t, func() bool {
+		var err error
+		resp, err = client.Get("http://127.0.0.1:8082/proto")
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond, "expect h2c request to succeed")
+	defer func() { _ = resp.Body.Close() }()
+
+	assert.Equal(t, 2, resp.ProtoMajor, "expect response over HTTP/2")
+
+	// HTTP/1.1 is still served
+	resp1, err := http.Get("http://127.0.0.1:8082/proto")
+	require.NoError(t, err, "expect HTTP/1.1 request to succeed")
+	defer func() { _ = resp1.Body.Close() }()
+	assert.Equal(t, 1, resp1.ProtoMajor, "expect response over HTTP/1.1")
+}
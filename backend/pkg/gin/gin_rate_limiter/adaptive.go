@@ -0,0 +1,145 @@
+package gin_rate_limiter
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Adaptive selects algorithm adjusting concurrency limit
+type Adaptive string
+
+const (
+	// AdaptiveNone keeps the limit static
+	AdaptiveNone Adaptive = "none"
+	// AdaptiveAIMD increases the limit by one while requests succeed and decreases it by backoff ratio
+	// on errors or latency above the threshold, at most once per round trip
+	AdaptiveAIMD Adaptive = "aimd"
+	// AdaptiveGradient adjusts the limit by the ratio of long-term to short-term latency, see Netflix Gradient2
+	AdaptiveGradient Adaptive = "gradient"
+)
+
+const (
+	defaultMinLimit = 1
+	defaultMaxLimit = 1000
+
+	aimdBackoff = 0.9
+
+	gradientTolerance = 1.5
+	gradientSmoothing = 0.2
+	gradientLongRTT   = 600 // samples in the long-term latency average
+	gradientShortRTT  = 10  // samples in the short-term latency average
+)
+
+// sample is an observation of a completed request
+type sample struct {
+	start    time.Time
+	rtt      time.Duration
+	inflight int64
+	failed   bool
+}
+
+// limitAlgorithm estimates concurrency limit from samples
+type limitAlgorithm interface {
+	// update returns new limit after the sample
+	update(s sample) int64
+}
+
+func newLimitAlgorithm(adaptive Adaptive, initial, minLimit, maxLimit int64, latency time.Duration) limitAlgorithm {
+	switch adaptive {
+	case AdaptiveAIMD:
+		return &aimd{limit: initial, min: minLimit, max: maxLimit, latency: latency}
+	case AdaptiveGradient:
+		return &gradient{limit: float64(initial), min: minLimit, max: maxLimit}
+	default:
+		return nil
+	}
+}
+
+type aimd struct {
+	mtx sync.Mutex
+
+	limit, min, max int64
+	// latency above which sample is treated as failed, zero disables latency signal
+	latency time.Duration
+	// backoffAt is the completion time of the sample causing the last decrease
+	backoffAt time.Time
+}
+
+func (a *aimd) update(s sample) int64 {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	switch {
+	case s.failed || (a.latency > 0 && s.rtt > a.latency):
+		// requests started before the last decrease ran under the previous limit and report the same congestion,
+		// backing off for every one of them would collapse the limit
+		if !s.start.After(a.backoffAt) {
+			break
+		}
+		a.limit = int64(float64(a.limit) * aimdBackoff)
+		a.backoffAt = s.start.Add(s.rtt)
+	case s.inflight*2 >= a.limit:
+		// grow only when the limit is actually used
+		a.limit++
+	}
+
+	a.limit = min(a.max, max(a.min, a.limit))
+
+	return a.limit
+}
+
+type gradient struct {
+	mtx sync.Mutex
+
+	limit    float64
+	min, max int64
+
+	longRTT, shortRTT float64
+	samples           int64
+}
+
+func (g *gradient) update(s sample) int64 {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+
+	rtt := float64(s.rtt)
+	g.samples++
+
+	if g.samples == 1 {
+		g.longRTT, g.shortRTT = rtt, rtt
+	} else {
+		g.longRTT = ewma(g.longRTT, rtt, gradientLongRTT)
+		g.shortRTT = ewma(g.shortRTT, rtt, gradientShortRTT)
+	}
+
+	// long-term latency drifts up under sustained load, make it recover faster
+	if g.longRTT/g.shortRTT > 2 {
+		g.longRTT *= 0.95
+	}
+
+	// don't grow the limit while it is not used
+	if float64(s.inflight) < g.limit/2 {
+		return g.current()
+	}
+
+	grad := math.Max(0.5, math.Min(1, gradientTolerance*g.longRTT/g.shortRTT))
+	if s.failed {
+		grad = 0.5
+	}
+
+	next := g.limit*grad + math.Sqrt(g.limit)
+	g.limit = g.limit*(1-gradientSmoothing) + next*gradientSmoothing
+	g.limit = math.Min(float64(g.max), math.Max(float64(g.min), g.limit))
+
+	return g.current()
+}
+
+func (g *gradient) current() int64 {
+	return int64(g.limit)
+}
+
+func ewma(avg, v float64, n int) float64 {
+	f := 2 / float64(n+1)
+	return avg*(1-f) + v*f
+}
@@ -0,0 +1,89 @@
+package gin_rate_limiter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAIMD(t *testing.T) {
+	a := newLimitAlgorithm(AdaptiveAIMD, 10, 5, 12, 100*time.Millisecond)
+	now := time.Now()
+	at := func(offset time.Duration) time.Time { return now.Add(offset) }
+
+	assert.Equal(t, int64(10), a.update(sample{start: at(0), rtt: time.Millisecond, inflight: 1}), "expect unused limit not to grow")
+	assert.Equal(t, int64(11), a.update(sample{start: at(0), rtt: time.Millisecond, inflight: 10}), "expect additive increase")
+	assert.Equal(t, int64(12), a.update(sample{start: at(0), rtt: time.Millisecond, inflight: 11}), "expect additive increase")
+	assert.Equal(t, int64(12), a.update(sample{start: at(0), rtt: time.Millisecond, inflight: 12}), "expect limit to be capped by max")
+
+	assert.Equal(t, int64(10), a.update(sample{start: at(0), rtt: time.Millisecond, failed: true}), "expect multiplicative decrease on error")
+	assert.Equal(t, int64(10), a.update(sample{start: at(0), rtt: 2 * time.Millisecond, failed: true}), "expect no decrease for request started before the last one")
+	assert.Equal(t, int64(10), a.update(sample{start: at(time.Millisecond), rtt: time.Second}), "expect no decrease for request started before the last one")
+	assert.Equal(t, int64(9), a.update(sample{start: at(2 * time.Millisecond), rtt: time.Second}), "expect multiplicative decrease on latency")
+
+	for i := 0; i < 20; i++ {
+		a.update(sample{start: at(time.Duration(i+3) * time.Second), failed: true})
+	}
+	assert.Equal(t, int64(5), a.update(sample{start: at(time.Minute), failed: true}), "expect limit to be capped by min")
+}
+
+func TestAIMD_BackoffOncePerRoundTrip(t *testing.T) {
+	a := newLimitAlgorithm(AdaptiveAIMD, 100, 1, 100, 0)
+	start := time.Now()
+
+	var limit int64
+	for i := 0; i < 50; i++ {
+		limit = a.update(sample{start: start, rtt: time.Duration(i) * time.Millisecond, failed: true})
+	}
+	assert.Equal(t, int64(90), limit, "expect burst of failures from one round trip to back off once")
+}
+
+func TestGradient(t *testing.T) {
+	g := newLimitAlgorithm(AdaptiveGradient, 20, 5, 100, 0)
+
+	var limit int64
+	for i := 0; i < 50; i++ {
+		limit = g.update(sample{rtt: 10 * time.Millisecond, inflight: 100})
+	}
+	assert.Greater(t, limit, int64(20), "expect stable latency to grow the limit")
+	assert.LessOrEqual(t, limit, int64(100), "expect limit to be capped by max")
+
+	grown := limit
+	for i := 0; i < 20; i++ {
+		limit = g.update(sample{rtt: 200 * time.Millisecond, inflight: 100})
+	}
+	assert.Less(t, limit, grown, "expect latency growth to shrink the limit")
+	assert.GreaterOrEqual(t, limit, int64(5), "expect limit to be capped by min")
+
+	assert.Equal(t, limit, g.update(sample{rtt: time.Millisecond, inflight: 1}), "expect unused limit not to change")
+}
+
+func TestAdaptiveLimit_Failures(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rm := NewRateLimiter(10, 1, 1, WithRegisterer(prometheus.NewRegistry()), WithAdaptiveLimit(AdaptiveAIMD, 1, 100, 0))
+
+	r := gin.New()
+	r.Use(rm.GetRateLimiter())
+	r.GET("/deadline", func(c *gin.Context) {
+		<-c.Request.Context().Done()
+		c.Status(http.StatusGatewayTimeout)
+	})
+	r.GET("/error", func(c *gin.Context) {
+		c.Status(http.StatusInternalServerError)
+	})
+
+	// caller budget is what X-Request-Timeout sets on the request context
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/deadline", nil).WithContext(ctx))
+	assert.Equal(t, int64(10), rm.ConcurrencyLimit(), "expect caller deadline not to be a failure")
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/error", nil))
+	assert.Equal(t, int64(9), rm.ConcurrencyLimit(), "expect server error to decrease the limit")
+}
@@ -0,0 +1,131 @@
+package gin_rate_limiter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultClass is assigned to requests not matching any class
+const DefaultClass = "default"
+
+// Scheduling selects the order requests waiting for a concurrency slot are granted
+type Scheduling string
+
+const (
+	// SchedulingFIFO grants waiters in arrival order regardless of class
+	SchedulingFIFO Scheduling = "fifo"
+	// SchedulingPriority grants waiters of the highest priority class first, in arrival order within a class
+	SchedulingPriority Scheduling = "priority"
+	// SchedulingWFQ shares slots between classes waiting at the same time proportionally to their weights
+	SchedulingWFQ Scheduling = "wfq"
+)
+
+// Class is a group of requests sharing queue and scheduling parameters.
+// Weight is the share of slots under SchedulingWFQ, values below 1 are replaced with 1.
+// MaxQueue limits waiting requests of the class, zero means it is only limited by the limiter queue.
+type Class struct {
+	Name     string
+	Priority int
+	Weight   int64
+	MaxQueue int64
+}
+
+// ClassifyFunc returns class of the request, unknown classes are treated as DefaultClass
+type ClassifyFunc func(c *gin.Context) string
+
+// NewClassifier classifies requests by route first, then by header value.
+// Routes are keyed either by "METHOD /pattern" or by "/pattern" matching any method, e.g. "GET /storage/:key".
+// Header allows callers to choose their class, so it should only be trusted behind a gateway. Empty header disables it.
+func NewClassifier(routes map[string]string, header string) ClassifyFunc {
+	return func(c *gin.Context) string {
+		if class, ok := routes[c.Request.Method+" "+c.FullPath()]; ok {
+			return class
+		}
+
+		if class, ok := routes[c.FullPath()]; ok {
+			return class
+		}
+
+		if header != "" {
+			if class := c.GetHeader(header); class != "" {
+				return class
+			}
+		}
+
+		return DefaultClass
+	}
+}
+
+// ParseClasses parses comma separated list of name:priority:weight:max_queue entries.
+// Weight defaults to 1 and max queue to 0, so name:priority is a valid entry as well.
+//
+// Example:
+//
+// critical:10:8:50,batch:0:1:100
+func ParseClasses(s string) ([]Class, error) {
+	var classes []Class
+
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.Split(entry, ":")
+		if len(parts) < 2 || len(parts) > 4 || parts[0] == "" {
+			return nil, fmt.Errorf("ParseClasses: invalid entry [%s]", entry)
+		}
+
+		class := Class{Name: parts[0], Weight: 1}
+
+		var err error
+		if class.Priority, err = strconv.Atoi(parts[1]); err != nil {
+			return nil, fmt.Errorf("ParseClasses: invalid priority of class [%s]: %s", class.Name, parts[1])
+		}
+
+		if len(parts) > 2 {
+			if class.Weight, err = strconv.ParseInt(parts[2], 10, 64); err != nil || class.Weight < 1 {
+				return nil, fmt.Errorf("ParseClasses: invalid weight of class [%s]: %s", class.Name, parts[2])
+			}
+		}
+
+		if len(parts) > 3 {
+			if class.MaxQueue, err = strconv.ParseInt(parts[3], 10, 64); err != nil || class.MaxQueue < 0 {
+				return nil, fmt.Errorf("ParseClasses: invalid max queue of class [%s]: %s", class.Name, parts[3])
+			}
+		}
+
+		classes = append(classes, class)
+	}
+
+	return classes, nil
+}
+
+// ParseClassRoutes parses comma separated list of route=class pairs, see NewClassifier for route format.
+//
+// Example:
+//
+// GET /storage/:key=critical,PUT /storage=batch,/health=critical
+func ParseClassRoutes(s string) (map[string]string, error) {
+	routes := make(map[string]string)
+
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		route, class, ok := strings.Cut(entry, "=")
+		route, class = strings.TrimSpace(route), strings.TrimSpace(class)
+		if !ok || route == "" || class == "" {
+			return nil, fmt.Errorf("ParseClassRoutes: invalid entry [%s]", entry)
+		}
+
+		routes[route] = class
+	}
+
+	return routes, nil
+}
@@ -0,0 +1,67 @@
+package gin_rate_limiter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseClasses(t *testing.T) {
+	classes, err := ParseClasses(" critical:10:8:50, batch:-1 ,")
+	require.NoError(t, err, "expect valid classes")
+	assert.Equal(t, []Class{
+		{Name: "critical", Priority: 10, Weight: 8, MaxQueue: 50},
+		{Name: "batch", Priority: -1, Weight: 1},
+	}, classes, "expect parsed classes")
+
+	for _, s := range []string{"critical", ":1", "a:x", "a:1:0", "a:1:1:-1", "a:1:1:1:1"} {
+		_, err = ParseClasses(s)
+		assert.Error(t, err, "expect invalid entry %s to be rejected", s)
+	}
+}
+
+func TestParseClassRoutes(t *testing.T) {
+	routes, err := ParseClassRoutes("GET /storage/:key=critical, /health = critical")
+	require.NoError(t, err, "expect valid routes")
+	assert.Equal(t, map[string]string{"GET /storage/:key": "critical", "/health": "critical"}, routes, "expect parsed routes")
+
+	for _, s := range []string{"/health", "=critical", "/health="} {
+		_, err = ParseClassRoutes(s)
+		assert.Error(t, err, "expect invalid entry %s to be rejected", s)
+	}
+}
+
+func TestNewClassifier(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	classify := NewClassifier(map[string]string{"GET /storage/:key": "critical", "/health": "probe"}, "X-Request-Class")
+
+	var class string
+	r := gin.New()
+	handler := func(c *gin.Context) { class = classify(c) }
+	r.GET("/storage/:key", handler)
+	r.PUT("/storage/:key", handler)
+	r.POST("/health", handler)
+
+	cases := []struct {
+		method, path, header, expected string
+	}{
+		{http.MethodGet, "/storage/a", "batch", "critical"},
+		{http.MethodPost, "/health", "", "probe"},
+		{http.MethodPut, "/storage/a", "batch", "batch"},
+		{http.MethodPut, "/storage/a", "", DefaultClass},
+	}
+
+	for _, tc := range cases {
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		if tc.header != "" {
+			req.Header.Set("X-Request-Class", tc.header)
+		}
+		r.ServeHTTP(httptest.NewRecorder(), req)
+		assert.Equal(t, tc.expected, class, "expect class of %s %s", tc.method, tc.path)
+	}
+}
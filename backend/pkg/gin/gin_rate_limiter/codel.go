@@ -0,0 +1,82 @@
+package gin_rate_limiter
+
+import (
+	"math"
+	"time"
+)
+
+const defaultCoDelInterval = 100 * time.Millisecond
+
+// codel decides whether a waiter should be shed based on its queue sojourn time, see RFC 8289.
+// Shedding starts once sojourn time stays above target for an interval, and gets more frequent
+// while it stays there: the n-th waiter is shed interval/sqrt(n) after the previous one.
+type codel struct {
+	target, interval time.Duration
+
+	firstAbove time.Time
+	dropNext   time.Time
+	dropping   bool
+	count      int
+	lastCount  int
+}
+
+func newCoDel(target, interval time.Duration) *codel {
+	return &codel{target: target, interval: interval}
+}
+
+// shouldShed is called for every dequeued waiter
+func (c *codel) shouldShed(sojourn time.Duration, now time.Time) bool {
+	ok := c.okToShed(sojourn, now)
+
+	if c.dropping {
+		if !ok {
+			c.dropping = false
+			return false
+		}
+
+		if !now.Before(c.dropNext) {
+			c.count++
+			c.dropNext = c.controlLaw(c.dropNext)
+			return true
+		}
+
+		return false
+	}
+
+	if !ok {
+		return false
+	}
+
+	c.dropping = true
+
+	// resume the previous shedding rate if it ended recently
+	delta := c.count - c.lastCount
+	if delta > 1 && now.Sub(c.dropNext) < 16*c.interval {
+		c.count = delta
+	} else {
+		c.count = 1
+	}
+	c.lastCount = c.count
+	c.dropNext = c.controlLaw(now)
+
+	return true
+}
+
+// okToShed reports whether sojourn time has been above target for at least an interval
+func (c *codel) okToShed(sojourn time.Duration, now time.Time) bool {
+	if sojourn < c.target {
+		c.firstAbove = time.Time{}
+		return false
+	}
+
+	if c.firstAbove.IsZero() {
+		c.firstAbove = now.Add(c.interval)
+		return false
+	}
+
+	return !now.Before(c.firstAbove)
+}
+
+func (c *codel) controlLaw(t time.Time) time.Time {
+	return t.Add(time.Duration(float64(c.interval) / math.Sqrt(float64(c.count))))
+}
@@ -0,0 +1,30 @@
+package gin_rate_limiter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCoDel(t *testing.T) {
+	c := newCoDel(10*time.Millisecond, 100*time.Millisecond)
+	now := time.Now()
+
+	assert.False(t, c.shouldShed(5*time.Millisecond, now), "expect sojourn below target to pass")
+	assert.False(t, c.shouldShed(20*time.Millisecond, now), "expect first sojourn above target to pass")
+	assert.False(t, c.shouldShed(20*time.Millisecond, now.Add(50*time.Millisecond)), "expect sojourn above target within interval to pass")
+
+	now = now.Add(100 * time.Millisecond)
+	assert.True(t, c.shouldShed(20*time.Millisecond, now), "expect sojourn above target for interval to be shed")
+	assert.False(t, c.shouldShed(20*time.Millisecond, now.Add(time.Millisecond)), "expect next shed to be delayed")
+
+	now = now.Add(100 * time.Millisecond)
+	assert.True(t, c.shouldShed(20*time.Millisecond, now), "expect shed after interval/sqrt(1)")
+	assert.False(t, c.shouldShed(20*time.Millisecond, now.Add(70*time.Millisecond)), "expect shed interval to shrink to interval/sqrt(2)")
+	now = now.Add(71 * time.Millisecond)
+	assert.True(t, c.shouldShed(20*time.Millisecond, now), "expect shed after interval/sqrt(2)")
+
+	assert.False(t, c.shouldShed(5*time.Millisecond, now), "expect sojourn below target to stop shedding")
+	assert.False(t, c.shouldShed(20*time.Millisecond, now.Add(time.Millisecond)), "expect shedding to restart after interval")
+}
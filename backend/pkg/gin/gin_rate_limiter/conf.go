@@ -0,0 +1,47 @@
+package gin_rate_limiter
+
+import (
+	"fmt"
+
+	"github.com/KennyMacCormik/otel/backend/pkg/conf"
+)
+
+// NewRateLimiterFromConf returns RateLimiter configured by c, opts are applied on top of it.
+// The limiter must be closed to flush the shared state store.
+func NewRateLimiterFromConf(c conf.RateLimiterConf, opts ...InitOptions) (*RateLimiter, error) {
+	const wrap = "NewRateLimiterFromConf"
+
+	keyLimits, err := ParseLimits(c.KeyLimitsList())
+	if err != nil {
+		return nil, fmt.Errorf("%s: key limits: %w", wrap, err)
+	}
+
+	classes, err := ParseClasses(c.Classes())
+	if err != nil {
+		return nil, fmt.Errorf("%s: classes: %w", wrap, err)
+	}
+
+	classRoutes, err := ParseClassRoutes(c.ClassRoutes())
+	if err != nil {
+		return nil, fmt.Errorf("%s: class routes: %w", wrap, err)
+	}
+
+	store, err := NewStore(StoreKind(c.Store()), c.StoreEndpointAddr(), c.StoreKeyPrefix(), c.StoreRequestTimeout())
+	if err != nil {
+		return nil, err
+	}
+
+	confOpts := []InitOptions{
+		WithMode(Mode(c.Mode()), c.Limit(), c.Window(), c.Burst()),
+		WithKeyedLimits(NewKeyFunc(KeySource(c.KeySource()), c.KeyHeaderName(), c.KeyClaimName()), keyLimits, c.MaxKeys()),
+		WithUnknownKeysLimit(Limit{Limit: c.UnknownKeysLimit()}),
+		WithAdaptiveLimit(Adaptive(c.Adaptive()), c.AdaptiveMinLimit(), c.AdaptiveMaxLimit(), c.AdaptiveLatencyThreshold()),
+		WithClasses(Scheduling(c.Scheduling()), classes, NewClassifier(classRoutes, c.ClassHeaderName())),
+		WithQueueTimeout(c.MaxQueueWait()),
+		WithCoDel(c.ShedTarget(), c.ShedInterval()),
+		WithName(c.Name(), nil),
+		WithStore(store, c.SyncInterval(), c.StoreRequestTimeout()),
+	}
+
+	return NewRateLimiter(c.MaxRunning(), c.MaxWaiting(), c.RetryAfter(), append(confOpts, opts...)...), nil
+}
@@ -0,0 +1,76 @@
+package gin_rate_limiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testConf struct {
+	mode        string
+	keyLimits   string
+	classes     string
+	classRoutes string
+}
+
+func (t testConf) MaxRunning() int64                       { return 1 }
+func (t testConf) MaxWaiting() int64                       { return 1 }
+func (t testConf) RetryAfter() int64                       { return 1 }
+func (t testConf) Mode() string                            { return t.mode }
+func (t testConf) Limit() int64                            { return 10 }
+func (t testConf) Window() time.Duration                   { return time.Second }
+func (t testConf) Burst() int64                            { return 0 }
+func (t testConf) KeySource() string                       { return string(KeyNone) }
+func (t testConf) KeyHeaderName() string                   { return "X-API-Key" }
+func (t testConf) KeyClaimName() string                    { return "sub" }
+func (t testConf) KeyLimitsList() string                   { return t.keyLimits }
+func (t testConf) MaxKeys() int                            { return 100 }
+func (t testConf) UnknownKeysLimit() int64                 { return 0 }
+func (t testConf) Store() string                           { return string(StoreNone) }
+func (t testConf) StoreEndpointAddr() string               { return "" }
+func (t testConf) StoreKeyPrefix() string                  { return "" }
+func (t testConf) SyncInterval() time.Duration             { return time.Second }
+func (t testConf) StoreRequestTimeout() time.Duration      { return time.Second }
+func (t testConf) Adaptive() string                        { return string(AdaptiveNone) }
+func (t testConf) AdaptiveMinLimit() int64                 { return 1 }
+func (t testConf) AdaptiveMaxLimit() int64                 { return 1 }
+func (t testConf) AdaptiveLatencyThreshold() time.Duration { return 0 }
+func (t testConf) Scheduling() string                      { return string(SchedulingFIFO) }
+func (t testConf) Classes() string                         { return t.classes }
+func (t testConf) ClassRoutes() string                     { return t.classRoutes }
+func (t testConf) ClassHeaderName() string                 { return "" }
+func (t testConf) MaxQueueWait() time.Duration             { return 0 }
+func (t testConf) ShedTarget() time.Duration               { return 0 }
+func (t testConf) ShedInterval() time.Duration             { return time.Second }
+func (t testConf) Name() string                            { return "" }
+
+func TestNewRateLimiterFromConf(t *testing.T) {
+	testCases := []struct {
+		name    string
+		conf    testConf
+		wantErr bool
+	}{
+		{name: "valid", conf: testConf{mode: string(ModeTokenBucket), keyLimits: "a=5:2"}},
+		{name: "invalid key limits", conf: testConf{mode: string(ModeTokenBucket), keyLimits: "a=x"}, wantErr: true},
+		{name: "invalid classes", conf: testConf{mode: string(ModeConcurrency), classes: "x"}, wantErr: true},
+		{name: "invalid class routes", conf: testConf{mode: string(ModeConcurrency), classRoutes: "x"}, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rm, err := NewRateLimiterFromConf(tc.conf, WithRegisterer(prometheus.NewRegistry()))
+			if tc.wantErr {
+				assert.Error(t, err, "expect parse error")
+				return
+			}
+
+			require.NoError(t, err, "expect valid conf")
+			assert.Equal(t, Mode(tc.conf.mode), rm.Mode(), "expect mode from conf")
+			assert.NoError(t, rm.Close(context.Background()), "expect close")
+		})
+	}
+}
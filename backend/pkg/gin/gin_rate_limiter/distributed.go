@@ -0,0 +1,264 @@
+package gin_rate_limiter
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	defaultSyncInterval = 100 * time.Millisecond
+	defaultStoreTimeout = 500 * time.Millisecond
+	// maxSyncBatch bounds the number of counters sent per sync, the rest wait for the next one
+	maxSyncBatch = 1000
+)
+
+// syncedCounter is a sliding window counter sharing its counts through Store.
+// Requests are admitted against the global counts known as of the last sync plus local counts not sent yet,
+// local counts are sent in batches by storeSync. Limits are approximate: every instance may overshoot
+// by the number of requests it admits within a sync interval.
+type syncedCounter struct {
+	mtx sync.Mutex
+
+	key    string
+	limit  int64
+	window time.Duration
+	sync   *storeSync
+
+	index      int64
+	prev, curr int64
+	// pending counts are not sent yet, inflight ones are being sent
+	pending, inflight int64
+	// lagging counts belong to the previous window and weren't sent before it ended
+	lagging int64
+	synced  time.Time
+	queued  bool
+}
+
+func newSyncedCounter(key string, limit int64, window time.Duration, sync *storeSync) *syncedCounter {
+	return &syncedCounter{key: key, limit: limit, window: window, sync: sync}
+}
+
+func (s *syncedCounter) allow(now time.Time) decision {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.advance(now)
+
+	local := s.curr + s.pending + s.inflight
+	d := counterDecision(s.limit, s.prev, local, now.Sub(s.windowStart()), s.window)
+	if d.allowed {
+		s.pending++
+	}
+
+	if !s.queued && (s.pending > 0 || now.Sub(s.synced) >= s.sync.interval) {
+		s.queued = true
+		s.sync.enqueue(s)
+	}
+
+	return d
+}
+
+func (s *syncedCounter) windowStart() time.Time {
+	return time.Unix(0, s.index*int64(s.window))
+}
+
+// advance moves the counter to the window containing now, counts of the ended window become the previous ones
+func (s *syncedCounter) advance(now time.Time) {
+	index := now.UnixNano() / int64(s.window)
+	switch {
+	case index == s.index:
+		return
+	case index == s.index+1:
+		s.prev = s.curr + s.pending + s.inflight
+		s.lagging += s.pending
+	default:
+		s.prev = 0
+		s.lagging = 0
+	}
+
+	s.index = index
+	s.curr, s.pending = 0, 0
+}
+
+// counterFlush holds local counts of syncedCounter being sent to the store
+type counterFlush struct {
+	c                    *syncedCounter
+	index, sent, lagging int64
+}
+
+// begin takes local counts to be sent, they stay inflight until complete or restore
+func (s *syncedCounter) begin() counterFlush {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	f := counterFlush{c: s, index: s.index, sent: s.pending, lagging: s.lagging}
+	s.inflight += s.pending
+	s.pending, s.lagging, s.queued = 0, 0, false
+
+	return f
+}
+
+// increments returns store updates of the flush, the update of the current window is the last one
+func (f counterFlush) increments() []Increment {
+	ttl := 2 * f.c.window
+
+	incs := make([]Increment, 0, 2)
+	if f.lagging > 0 {
+		incs = append(incs, Increment{Key: f.c.key, Window: f.index - 1, Delta: f.lagging, TTL: ttl})
+	}
+
+	return append(incs, Increment{Key: f.c.key, Window: f.index, Delta: f.sent, TTL: ttl})
+}
+
+// complete refreshes global counts with the reply to the current window update
+func (s *syncedCounter) complete(f counterFlush, counts Counts) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.inflight -= f.sent
+	s.synced = time.Now()
+
+	switch s.index {
+	case f.index:
+		s.curr, s.prev = counts.Curr, counts.Prev
+	case f.index + 1:
+		s.prev = max(s.prev, counts.Curr)
+	}
+}
+
+func (s *syncedCounter) restore(index, sent, lagging int64) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.inflight -= sent
+
+	switch s.index {
+	case index:
+		s.pending += sent
+		s.lagging += lagging
+	case index + 1:
+		s.lagging += sent
+	}
+
+	if !s.queued {
+		s.queued = true
+		s.sync.enqueue(s)
+	}
+}
+
+// storeSync periodically flushes counters used since the previous sync
+type storeSync struct {
+	store    Store
+	interval time.Duration
+	timeout  time.Duration
+	onError  func(err error)
+
+	mtx   sync.Mutex
+	queue []*syncedCounter
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func newStoreSync(store Store, interval, timeout time.Duration, onError func(err error)) *storeSync {
+	s := &storeSync{
+		store:    store,
+		interval: interval,
+		timeout:  timeout,
+		onError:  onError,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	go s.run()
+
+	return s
+}
+
+func (s *storeSync) enqueue(c *syncedCounter) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.queue = append(s.queue, c)
+}
+
+func (s *storeSync) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			// counters restored by a failed flush are queued behind and not retried
+			s.mtx.Lock()
+			n := len(s.queue)
+			s.mtx.Unlock()
+
+			for ; n > 0; n -= maxSyncBatch {
+				s.flush(min(n, maxSyncBatch))
+			}
+			return
+		case <-ticker.C:
+			s.flush(maxSyncBatch)
+		}
+	}
+}
+
+// flush sends counts of up to limit queued counters in a single batch and refreshes their global counts.
+// Counts which weren't applied are kept to be sent later.
+func (s *storeSync) flush(limit int) {
+	s.mtx.Lock()
+	n := min(limit, len(s.queue))
+	batch := s.queue[:n:n]
+	s.queue = append([]*syncedCounter(nil), s.queue[n:]...)
+	s.mtx.Unlock()
+
+	if n == 0 {
+		return
+	}
+
+	flushes := make([]counterFlush, 0, n)
+	incs := make([]Increment, 0, n)
+	for _, c := range batch {
+		f := c.begin()
+		flushes = append(flushes, f)
+		incs = append(incs, f.increments()...)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	counts, err := addBatch(ctx, s.store, incs)
+	cancel()
+
+	i := 0
+	for _, f := range flushes {
+		next := i + 1
+		if f.lagging > 0 {
+			next++
+		}
+		switch {
+		case next <= len(counts):
+			f.c.complete(f, counts[next-1])
+		case f.lagging > 0 && i < len(counts):
+			// lagging counts were applied
+			f.c.restore(f.index, f.sent, 0)
+		default:
+			f.c.restore(f.index, f.sent, f.lagging)
+		}
+		i = next
+	}
+
+	if err != nil {
+		s.onError(err)
+	}
+}
+
+// close flushes pending counts and closes the store
+func (s *storeSync) close(ctx context.Context) error {
+	close(s.stop)
+	<-s.done
+
+	return s.store.Close(ctx)
+}
@@ -1,10 +1,15 @@
 package gin_rate_limiter
 
 import (
+	"context"
+	"errors"
 	"log/slog"
+	"math"
 	"net/http"
 	"strconv"
+	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/KennyMacCormik/common/log"
 	"github.com/gin-gonic/gin"
@@ -13,71 +18,570 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	"github.com/KennyMacCormik/otel/backend/pkg/gin/gin_request_id"
+	httpErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/http"
+	rateLimiterErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/rate_limiter"
 )
 
 const (
 	defaultMaxRunning = 100
 	defaultMaxWait    = 100
 	defaultRetryAfter = 1 // in seconds
+	defaultLimit      = 100
+	defaultWindow     = time.Second
+	// globalKey is the store key of the limit shared by all requests
+	globalKey = "global"
+	// unknownKey is the store key of the limit shared by keys without override
+	unknownKey = "unknown"
+	// limiterLabel is the constant metric label set by WithName
+	limiterLabel = "limiter"
 )
 
-// RateLimiter struct represents rate-limiting gin-specific middleware
+// Rate limit headers as defined by the IETF draft "RateLimit header fields for HTTP"
+const (
+	HeaderRateLimitLimit     = "RateLimit-Limit"
+	HeaderRateLimitRemaining = "RateLimit-Remaining"
+	HeaderRateLimitReset     = "RateLimit-Reset"
+	HeaderRateLimitPolicy    = "RateLimit-Policy"
+	HeaderRetryAfter         = "Retry-After"
+)
+
+var rateLimitedBody = httpErrors.NewErrStatus(http.StatusTooManyRequests, httpErrors.CodeRateLimited, "").GetBody()
+
+// RateLimiter struct represents rate-limiting gin-specific middleware.
+// By default it limits concurrency, WithMode additionally limits request rate in front of it.
 type RateLimiter struct {
-	running chan struct{}
+	running *semaphore
+
+	adaptive           Adaptive
+	minLimit, maxLimit int64
+	latency            time.Duration
+	algorithm          limitAlgorithm
+
+	scheduling Scheduling
+	classes    []Class
+	classNames map[string]struct{}
+	classify   ClassifyFunc
+
+	maxQueueWait               time.Duration
+	codelTarget, codelInterval time.Duration
 
-	maxRunning, maxWaiting, retryAfter                                       int64
+	mode  Mode
+	limit Limit
+	rate  *limitEntry
+
+	keyFunc      KeyFunc
+	overrides    map[string]Limit
+	maxKeys      int
+	keys         *keyedLimits
+	pinned       map[string]*limitEntry
+	unknownLimit Limit
+	unknown      *limitEntry
+
+	store        Store
+	syncInterval time.Duration
+	storeTimeout time.Duration
+	sync         *storeSync
+
+	registerer prometheus.Registerer
+	gatherer   prometheus.Gatherer
+	labels     prometheus.Labels
+
+	// maxRunning, maxWaiting and retryAfter are changed at runtime by UpdateSettings
+	settingsMtx                                                              sync.Mutex
+	maxRunning, maxWaiting, retryAfter                                       atomic.Int64
 	runningRequests, totalRequests, timedOutWaiting, rejectedTooManyRequests atomic.Int64
+	// exempt routes, such as the admin endpoint, are not limited
+	exempt map[string]struct{}
 
 	metricRunningPlusWaitingRequests prometheus.Gauge
 	metricRunningRequests            prometheus.Gauge
+	metricConcurrencyLimit           prometheus.Gauge
 	metricRejected                   prometheus.Counter
 	metricTimeouts                   prometheus.Counter
 	metricTotalRequests              prometheus.Counter
+	metricTrackedKeys                prometheus.Gauge
+	metricEvictedKeys                prometheus.Counter
+	metricStoreErrors                prometheus.Counter
+	metricClassWaiting               *prometheus.GaugeVec
+	metricClassRunning               *prometheus.GaugeVec
+	metricClassRejected              *prometheus.CounterVec
+	metricShed                       *prometheus.CounterVec
+	metricQueueTimeouts              *prometheus.CounterVec
+	metricQueueWait                  *prometheus.HistogramVec
+}
+
+type InitOptions func(rm *RateLimiter)
+
+// WithMode selects limiting algorithm. Rate modes allow limit requests per window,
+// ModeTokenBucket additionally allows bursts of up to burst requests, zero burst equals to limit.
+// Requests admitted by the rate limit are still limited by concurrency settings.
+func WithMode(mode Mode, limit int64, window time.Duration, burst int64) InitOptions {
+	return func(rm *RateLimiter) {
+		switch mode {
+		case ModeTokenBucket, ModeSlidingWindowLog, ModeSlidingWindowCounter:
+		case "", ModeConcurrency:
+			rm.mode = ModeConcurrency
+			return
+		default:
+			log.Warn("unknown rate limiter mode: replacing with concurrency", "mode", mode)
+			rm.mode = ModeConcurrency
+			return
+		}
+
+		rm.mode = mode
+		rm.limit = normalizeLimit(Limit{Limit: limit, Window: window, Burst: burst}, defaultWindow)
+	}
+}
+
+// WithKeyedLimits limits every key returned by keyFunc separately in rate modes, it is ignored in ModeConcurrency.
+// Keys found in overrides get their own limits, others get the default one set by WithMode.
+// Override keys are always tracked, at most maxKeys recently used other keys are tracked.
+func WithKeyedLimits(keyFunc KeyFunc, overrides map[string]Limit, maxKeys int) InitOptions {
+	return func(rm *RateLimiter) {
+		if keyFunc == nil {
+			return
+		}
+
+		if maxKeys < 1 {
+			log.Warn("maxKeys should be > 1: replacing with defaultMaxKeys",
+				"maxKeys", maxKeys, "defaultMaxKeys", defaultMaxKeys)
+			maxKeys = defaultMaxKeys
+		}
+
+		rm.keyFunc = keyFunc
+		rm.overrides = overrides
+		rm.maxKeys = maxKeys
+	}
+}
+
+// WithUnknownKeysLimit limits requests of all keys without override together, on top of their own limits.
+// Keys taken from unverified headers or tokens cost nothing to rotate, the shared limit keeps such callers
+// within a single budget. Zero Window inherits the default one, zero Limit disables the shared limit.
+func WithUnknownKeysLimit(l Limit) InitOptions {
+	return func(rm *RateLimiter) {
+		rm.unknownLimit = l
+	}
+}
+
+// WithStore shares rate limit state with other instances through the store in sliding window counter mode,
+// other rate modes are replaced with it. Concurrency limits are never shared, the store is unused in ModeConcurrency.
+// Local counts of up to maxSyncBatch keys are sent every syncInterval, each sync takes up to timeout.
+// Limits stay enforced with the last known global state if the store is unavailable.
+// Store is closed by RateLimiter.Close.
+func WithStore(store Store, syncInterval, timeout time.Duration) InitOptions {
+	return func(rm *RateLimiter) {
+		if store == nil {
+			return
+		}
+
+		if syncInterval <= 0 {
+			syncInterval = defaultSyncInterval
+		}
+
+		if timeout <= 0 {
+			timeout = defaultStoreTimeout
+		}
+
+		rm.store = store
+		rm.syncInterval = syncInterval
+		rm.storeTimeout = timeout
+	}
+}
+
+// WithAdaptiveLimit adjusts concurrency limit within [minLimit, maxLimit] from latency and errors of completed requests,
+// starting from maxRunning. Responses with 5xx status and handlers exceeding their deadline are errors.
+// Latency is the threshold of AdaptiveAIMD, zero means only errors decrease the limit.
+func WithAdaptiveLimit(adaptive Adaptive, minLimit, maxLimit int64, latency time.Duration) InitOptions {
+	return func(rm *RateLimiter) {
+		switch adaptive {
+		case AdaptiveAIMD, AdaptiveGradient:
+		case "", AdaptiveNone:
+			rm.adaptive = AdaptiveNone
+			return
+		default:
+			log.Warn("unknown adaptive algorithm: replacing with none", "adaptive", adaptive)
+			rm.adaptive = AdaptiveNone
+			return
+		}
+
+		if minLimit < 1 {
+			log.Warn("minLimit should be > 1: replacing with defaultMinLimit",
+				"minLimit", minLimit, "defaultMinLimit", defaultMinLimit)
+			minLimit = defaultMinLimit
+		}
+
+		if maxLimit < minLimit {
+			log.Warn("maxLimit should be >= minLimit: replacing with defaultMaxLimit",
+				"maxLimit", maxLimit, "minLimit", minLimit, "defaultMaxLimit", defaultMaxLimit)
+			maxLimit = max(minLimit, defaultMaxLimit)
+		}
+
+		rm.adaptive = adaptive
+		rm.minLimit, rm.maxLimit = minLimit, maxLimit
+		rm.latency = max(0, latency)
+	}
+}
+
+// WithClasses queues waiting requests per class and grants them according to scheduling.
+// Requests are classified by classify, DefaultClass is used if it is nil or returns unknown class.
+func WithClasses(scheduling Scheduling, classes []Class, classify ClassifyFunc) InitOptions {
+	return func(rm *RateLimiter) {
+		switch scheduling {
+		case SchedulingFIFO, SchedulingPriority, SchedulingWFQ:
+		default:
+			log.Warn("unknown scheduling: replacing with fifo", "scheduling", scheduling)
+			scheduling = SchedulingFIFO
+		}
+
+		rm.scheduling = scheduling
+		rm.classes = classes
+		rm.classify = classify
+	}
+}
+
+// WithQueueTimeout rejects requests waiting for a slot longer than maxWait, zero means no limit.
+func WithQueueTimeout(maxWait time.Duration) InitOptions {
+	return func(rm *RateLimiter) {
+		rm.maxQueueWait = max(0, maxWait)
+	}
+}
+
+// WithCoDel sheds waiting requests once their queue sojourn time
+// stays above target for at least interval, zero target disables shedding.
+func WithCoDel(target, interval time.Duration) InitOptions {
+	return func(rm *RateLimiter) {
+		if target <= 0 {
+			return
+		}
+
+		if interval <= 0 {
+			interval = defaultCoDelInterval
+		}
+
+		rm.codelTarget, rm.codelInterval = target, interval
+	}
+}
+
+// WithRegisterer registers metrics with reg instead of prometheus.DefaultRegisterer.
+// GetRateLimiterMetricsEndpoint serves reg if it is a prometheus.Gatherer.
+func WithRegisterer(reg prometheus.Registerer) InitOptions {
+	return func(rm *RateLimiter) {
+		if reg == nil {
+			return
+		}
+
+		rm.registerer = reg
+		if g, ok := reg.(prometheus.Gatherer); ok {
+			rm.gatherer = g
+		} else {
+			rm.gatherer = nil
+		}
+	}
+}
+
+// WithName adds limiter=name and labels as constant labels to every metric,
+// so several limiters may share one registerer. Empty name adds labels only.
+func WithName(name string, labels prometheus.Labels) InitOptions {
+	return func(rm *RateLimiter) {
+		rm.labels = make(prometheus.Labels, len(labels)+1)
+		for k, v := range labels {
+			rm.labels[k] = v
+		}
+
+		if name != "" {
+			rm.labels[limiterLabel] = name
+		}
+	}
 }
 
-// NewRateLimiter returns initialized RateLimiter
-func NewRateLimiter(maxRunning, maxWait, retryAfter int64) *RateLimiter {
+// NewRateLimiter returns initialized RateLimiter.
+// Limiters without distinct names registered with the same registerer share their metrics.
+func NewRateLimiter(maxRunning, maxWait, retryAfter int64, opts ...InitOptions) *RateLimiter {
 	maxRunning, maxWait, retryAfter = normalizeParams(maxRunning, maxWait, retryAfter)
 
-	rm := &RateLimiter{running: make(chan struct{}, maxRunning),
-		maxRunning: maxRunning, maxWaiting: maxWait, retryAfter: retryAfter}
+	rm := &RateLimiter{mode: ModeConcurrency, adaptive: AdaptiveNone, scheduling: SchedulingFIFO,
+		registerer: prometheus.DefaultRegisterer, gatherer: prometheus.DefaultGatherer, exempt: map[string]struct{}{}}
+	rm.maxRunning.Store(maxRunning)
+	rm.maxWaiting.Store(maxWait)
+	rm.retryAfter.Store(retryAfter)
 
-	rm.metricRunningPlusWaitingRequests = promauto.NewGauge(prometheus.GaugeOpts{
-		Name: "rate_limiter_running_plus_waiting_requests",
-		Help: "Total number of queued + running requests",
-	})
-	rm.metricRunningRequests = promauto.NewGauge(prometheus.GaugeOpts{
-		Name: "rate_limiter_running_requests",
-		Help: "Number of currently running requests",
-	})
-	rm.metricRejected = promauto.NewCounter(prometheus.CounterOpts{
-		Name: "rate_limiter_rejected_requests",
-		Help: "Total number of requests rejected due to rate limits",
-	})
-	rm.metricTimeouts = promauto.NewCounter(prometheus.CounterOpts{
-		Name: "rate_limiter_timeout_requests",
-		Help: "Total number of requests that timed out waiting",
-	})
-	rm.metricTotalRequests = promauto.NewCounter(prometheus.CounterOpts{
-		Name: "rate_limiter_total_requests_static",
-		Help: "Total number of requests",
-	})
+	for _, opt := range opts {
+		opt(rm)
+	}
+
+	reg := rm.registerer
+	f := promauto.With(nil)
+
+	rm.metricRunningPlusWaitingRequests = registerMetric(reg, f.NewGauge(prometheus.GaugeOpts{
+		Name:        "rate_limiter_running_plus_waiting_requests",
+		Help:        "Total number of queued + running requests",
+		ConstLabels: rm.labels,
+	}))
+	rm.metricRunningRequests = registerMetric(reg, f.NewGauge(prometheus.GaugeOpts{
+		Name:        "rate_limiter_running_requests",
+		Help:        "Number of currently running requests",
+		ConstLabels: rm.labels,
+	}))
+	rm.metricConcurrencyLimit = registerMetric(reg, f.NewGauge(prometheus.GaugeOpts{
+		Name:        "rate_limiter_concurrency_limit",
+		Help:        "Current limit of running requests",
+		ConstLabels: rm.labels,
+	}))
+	rm.metricRejected = registerMetric(reg, f.NewCounter(prometheus.CounterOpts{
+		Name:        "rate_limiter_rejected_requests",
+		Help:        "Total number of requests rejected due to rate limits",
+		ConstLabels: rm.labels,
+	}))
+	rm.metricTimeouts = registerMetric(reg, f.NewCounter(prometheus.CounterOpts{
+		Name:        "rate_limiter_timeout_requests",
+		Help:        "Total number of requests that timed out waiting",
+		ConstLabels: rm.labels,
+	}))
+	rm.metricTotalRequests = registerMetric(reg, f.NewCounter(prometheus.CounterOpts{
+		Name:        "rate_limiter_total_requests_static",
+		Help:        "Total number of requests",
+		ConstLabels: rm.labels,
+	}))
+	rm.metricTrackedKeys = registerMetric(reg, f.NewGauge(prometheus.GaugeOpts{
+		Name:        "rate_limiter_tracked_keys",
+		Help:        "Number of keys with tracked rate limit state",
+		ConstLabels: rm.labels,
+	}))
+	rm.metricEvictedKeys = registerMetric(reg, f.NewCounter(prometheus.CounterOpts{
+		Name:        "rate_limiter_evicted_keys",
+		Help:        "Total number of rate limit keys evicted as least recently used",
+		ConstLabels: rm.labels,
+	}))
+	rm.metricClassWaiting = registerMetric(reg, f.NewGaugeVec(prometheus.GaugeOpts{
+		Name:        "rate_limiter_class_waiting_requests",
+		Help:        "Number of requests waiting for a slot by class",
+		ConstLabels: rm.labels,
+	}, []string{"class"}))
+	rm.metricClassRunning = registerMetric(reg, f.NewGaugeVec(prometheus.GaugeOpts{
+		Name:        "rate_limiter_class_running_requests",
+		Help:        "Number of currently running requests by class",
+		ConstLabels: rm.labels,
+	}, []string{"class"}))
+	rm.metricClassRejected = registerMetric(reg, f.NewCounterVec(prometheus.CounterOpts{
+		Name:        "rate_limiter_class_rejected_requests",
+		Help:        "Total number of requests rejected due to full class queue",
+		ConstLabels: rm.labels,
+	}, []string{"class"}))
+	rm.metricShed = registerMetric(reg, f.NewCounterVec(prometheus.CounterOpts{
+		Name:        "rate_limiter_shed_requests",
+		Help:        "Total number of waiting requests shed due to queue sojourn time by class",
+		ConstLabels: rm.labels,
+	}, []string{"class"}))
+	rm.metricQueueTimeouts = registerMetric(reg, f.NewCounterVec(prometheus.CounterOpts{
+		Name:        "rate_limiter_queue_timeout_requests",
+		Help:        "Total number of requests rejected after waiting for max queue wait by class",
+		ConstLabels: rm.labels,
+	}, []string{"class"}))
+	rm.metricQueueWait = registerMetric(reg, f.NewHistogramVec(prometheus.HistogramOpts{
+		Name:        "rate_limiter_queue_wait_seconds",
+		Help:        "Time requests spent waiting for a slot by class",
+		ConstLabels: rm.labels,
+		Buckets:     prometheus.ExponentialBuckets(0.001, 4, 8),
+	}, []string{"class"}))
+	rm.metricStoreErrors = registerMetric(reg, f.NewCounter(prometheus.CounterOpts{
+		Name:        "rate_limiter_store_errors",
+		Help:        "Total number of failed rate limit store syncs",
+		ConstLabels: rm.labels,
+	}))
+
+	rm.initConcurrency()
+	rm.initRate()
 
 	return rm
 }
 
+// registerMetric registers c with reg. If an equal metric is already registered, it is returned instead.
+func registerMetric[T prometheus.Collector](reg prometheus.Registerer, c T) T {
+	err := reg.Register(c)
+	if err == nil {
+		return c
+	}
+
+	var are prometheus.AlreadyRegisteredError
+	if errors.As(err, &are) {
+		if existing, ok := are.ExistingCollector.(T); ok {
+			return existing
+		}
+	}
+
+	log.Error("failed to register rate limiter metric", "err", err)
+
+	return c
+}
+
+// initConcurrency builds concurrency limit state once all options are applied
+func (rm *RateLimiter) initConcurrency() {
+	limit := rm.maxRunning.Load()
+	if rm.adaptive != AdaptiveNone {
+		limit = min(rm.maxLimit, max(rm.minLimit, limit))
+		rm.algorithm = newLimitAlgorithm(rm.adaptive, limit, rm.minLimit, rm.maxLimit, rm.latency)
+	}
+
+	var shedder *codel
+	if rm.codelTarget > 0 {
+		shedder = newCoDel(rm.codelTarget, rm.codelInterval)
+	}
+
+	rm.running = newSemaphore(limit, rm.scheduling, rm.classes, shedder)
+	rm.metricConcurrencyLimit.Set(float64(limit))
+
+	rm.classNames = map[string]struct{}{DefaultClass: {}}
+	for _, class := range rm.classes {
+		rm.classNames[class.Name] = struct{}{}
+	}
+}
+
+// classOf returns known class of the request, so metric labels are bounded
+func (rm *RateLimiter) classOf(c *gin.Context) string {
+	if rm.classify == nil {
+		return DefaultClass
+	}
+
+	class := rm.classify(c)
+	if _, ok := rm.classNames[class]; !ok {
+		return DefaultClass
+	}
+
+	return class
+}
+
+// initRate builds rate limit state once all options are applied
+func (rm *RateLimiter) initRate() {
+	if rm.mode == ModeConcurrency {
+		if rm.keyFunc != nil {
+			log.Warn("keyed limits require a rate mode: ignoring them", "mode", rm.mode)
+		}
+		if rm.store != nil {
+			log.Warn("rate limiter store requires a rate mode: ignoring it", "mode", rm.mode)
+		}
+		return
+	}
+
+	if rm.store != nil {
+		if rm.mode != ModeSlidingWindowCounter {
+			log.Warn("rate limiter store requires sliding window counter: replacing mode",
+				"mode", rm.mode, "newMode", ModeSlidingWindowCounter)
+			rm.mode = ModeSlidingWindowCounter
+		}
+
+		rm.sync = newStoreSync(rm.store, rm.syncInterval, rm.storeTimeout, func(err error) {
+			rm.metricStoreErrors.Inc()
+			log.Warn("failed to sync rate limiter store", "err", err)
+		})
+	}
+
+	if rm.keyFunc == nil {
+		rm.rate = rm.newEntry(globalKey, rm.limit)
+		return
+	}
+
+	// overrides are kept out of the LRU, so flooding it with new keys can't reset their state
+	rm.pinned = make(map[string]*limitEntry, len(rm.overrides))
+	for key, l := range rm.overrides {
+		rm.pinned[key] = rm.newEntry(key, normalizeLimit(l, rm.limit.Window))
+	}
+
+	if rm.unknownLimit.Limit > 0 {
+		rm.unknown = rm.newEntry(unknownKey, normalizeLimit(rm.unknownLimit, rm.limit.Window))
+	}
+
+	rm.keys = newKeyedLimits(rm.maxKeys, func(key string) *limitEntry {
+		return rm.newEntry(key, rm.limit)
+	}, func(size int, evicted bool) {
+		rm.metricTrackedKeys.Set(float64(size))
+		if evicted {
+			rm.metricEvictedKeys.Inc()
+		}
+	})
+}
+
+func (rm *RateLimiter) newEntry(key string, l Limit) *limitEntry {
+	if rm.sync == nil {
+		return newLimitEntry(rm.mode, l)
+	}
+
+	return &limitEntry{rateAlgorithm: newSyncedCounter(key, l.Limit, l.Window, rm.sync), policy: limitPolicy(rm.mode, l)}
+}
+
+// Close sends pending counts to the store and closes it, it is a no-op without store
+func (rm *RateLimiter) Close(ctx context.Context) error {
+	if rm.sync == nil {
+		if rm.store != nil {
+			return rm.store.Close(ctx)
+		}
+		return nil
+	}
+
+	return rm.sync.close(ctx)
+}
+
+// entry returns rate limit state the request is accounted to along with the shared state
+// of keys without override, the latter is nil if the request is not limited by it
+func (rm *RateLimiter) entry(c *gin.Context) (*limitEntry, *limitEntry) {
+	if rm.keys == nil {
+		return rm.rate, nil
+	}
+
+	key := rm.keyFunc(c)
+	if key == "" {
+		key = c.ClientIP()
+	}
+
+	if e, ok := rm.pinned[key]; ok {
+		return e, nil
+	}
+
+	return rm.keys.get(key), rm.unknown
+}
+
+// GetRateLimiterMetricsEndpoint serves metrics of the registerer set by WithRegisterer at /metrics,
+// prometheus.DefaultGatherer is served by default
 func (rm *RateLimiter) GetRateLimiterMetricsEndpoint() func(*gin.Engine) {
 	return func(router *gin.Engine) {
-		router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+		if rm.gatherer == nil || rm.gatherer == prometheus.DefaultGatherer {
+			router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+			return
+		}
+
+		router.GET("/metrics", gin.WrapH(promhttp.HandlerFor(rm.gatherer, promhttp.HandlerOpts{})))
 	}
 }
 
-// GetRateLimiter returns gin-compatible rate-limiting middleware
+// Mode returns limiting algorithm in use
+func (rm *RateLimiter) Mode() Mode {
+	return rm.mode
+}
+
+// Exempt excludes routes from limiting, e.g. health checks which must be answered during overload.
+// Routes are patterns as registered in the router. It must be called before the router starts serving.
+func (rm *RateLimiter) Exempt(routes ...string) {
+	for _, route := range routes {
+		rm.exempt[route] = struct{}{}
+	}
+}
+
+// GetRateLimiter returns gin-compatible rate-limiting middleware.
+// In rate modes requests exceeding the rate limit are rejected before they wait for a concurrency slot.
 func (rm *RateLimiter) GetRateLimiter() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if _, ok := rm.exempt[c.FullPath()]; ok {
+			c.Next()
+			return
+		}
+
+		rm.metricTotalRequests.Inc()
+
+		if rm.mode != ModeConcurrency && !rm.allowRate(c) {
+			return
+		}
+
 		rm.totalRequests.Add(1)
 		rm.metricRunningPlusWaitingRequests.Inc()
-		rm.metricTotalRequests.Inc()
 		defer func() {
 			rm.totalRequests.Add(-1)
 			rm.metricRunningPlusWaitingRequests.Dec()
@@ -89,60 +593,174 @@ func (rm *RateLimiter) GetRateLimiter() gin.HandlerFunc {
 		}
 
 		lg := log.CopyLogger().With("requestID", requestID)
+		retryAfter := rm.retryAfter.Load()
 
 		if rm.rejectIfTooManyRequests(c, lg) {
 			return
 		}
 
+		class := rm.classOf(c)
+
 		// wait or run
-		select {
-		case rm.running <- struct{}{}:
-			rm.runRequest(c)
-		case <-c.Request.Context().Done():
+		ctx, cancel := c.Request.Context(), context.CancelFunc(func() {})
+		if rm.maxQueueWait > 0 {
+			ctx, cancel = context.WithTimeout(ctx, rm.maxQueueWait)
+		}
+
+		start := time.Now()
+		rm.metricClassWaiting.WithLabelValues(class).Inc()
+		err = rm.running.acquire(ctx, class)
+		rm.metricClassWaiting.WithLabelValues(class).Dec()
+		cancel()
+
+		switch {
+		case err == nil:
+			rm.metricQueueWait.WithLabelValues(class).Observe(time.Since(start).Seconds())
+			rm.runRequest(c, class)
+		case errors.Is(err, rateLimiterErrors.ErrQueueFull):
+			rm.rejectedTooManyRequests.Add(1)
+			rm.metricRejected.Inc()
+			rm.metricClassRejected.WithLabelValues(class).Inc()
+
+			lg.Warn("request rejected: class queue is full",
+				"class", class,
+				"Retry-After", retryAfter,
+			)
+
+			c.Header(HeaderRetryAfter, strconv.Itoa(int(retryAfter)))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, rateLimitedBody)
+		case errors.Is(err, rateLimiterErrors.ErrShed):
+			rm.metricShed.WithLabelValues(class).Inc()
+
+			lg.Warn("request rejected: shed after waiting too long",
+				"class", class,
+				"waited", time.Since(start),
+				"Retry-After", retryAfter,
+			)
+
+			c.Header(HeaderRetryAfter, strconv.Itoa(int(retryAfter)))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, rateLimitedBody)
+		case c.Request.Context().Err() == nil:
+			// max queue wait exceeded
+			rm.metricQueueTimeouts.WithLabelValues(class).Inc()
+
+			lg.Warn("request rejected: max queue wait exceeded",
+				"class", class,
+				"maxQueueWait", rm.maxQueueWait,
+				"Retry-After", retryAfter,
+			)
+
+			c.Header(HeaderRetryAfter, strconv.Itoa(int(retryAfter)))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, rateLimitedBody)
+		default:
 			// reject with timeout
 			rm.timedOutWaiting.Add(1)
 			rm.metricTimeouts.Inc()
 
 			lg.Warn("request rejected: context canceled",
-				"Retry-After", rm.retryAfter,
+				"Retry-After", retryAfter,
 			)
 
-			c.Header("Retry-After", strconv.Itoa(int(rm.retryAfter)))
-			c.AbortWithStatus(http.StatusTooManyRequests)
+			c.Header(HeaderRetryAfter, strconv.Itoa(int(retryAfter)))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, rateLimitedBody)
 		}
 	}
 }
 
-// runRequest executes a request
-func (rm *RateLimiter) runRequest(c *gin.Context) {
-	rm.runningRequests.Add(1)
+// allowRate reports whether request is admitted by the rate algorithm, rejected request is aborted.
+// Every response carries RateLimit headers, rejected ones also carry Retry-After.
+func (rm *RateLimiter) allowRate(c *gin.Context) bool {
+	now := time.Now()
+	e, shared := rm.entry(c)
+	d := e.allow(now)
+	// shared limit is checked last, requests of a single exhausted key must not drain it
+	if d.allowed && shared != nil {
+		if sd := shared.allow(now); !sd.allowed {
+			e, d = shared, sd
+		}
+	}
+
+	c.Header(HeaderRateLimitPolicy, e.policy)
+	c.Header(HeaderRateLimitLimit, strconv.FormatInt(d.limit, 10))
+	c.Header(HeaderRateLimitRemaining, strconv.FormatInt(d.remaining, 10))
+	c.Header(HeaderRateLimitReset, strconv.FormatInt(ceilSeconds(d.reset), 10))
+
+	if d.allowed {
+		return true
+	}
+
+	rm.rejectedTooManyRequests.Add(1)
+	rm.metricRejected.Inc()
+
+	retryAfter := max(1, ceilSeconds(d.retryAfter))
+
+	requestID, _ := gin_request_id.GetRequestIDFromCtx(c)
+	log.Warn("request rejected: rate limit exceeded",
+		"requestID", requestID,
+		"mode", rm.mode,
+		"limit", d.limit,
+		"Retry-After", retryAfter,
+	)
+
+	c.Header(HeaderRetryAfter, strconv.FormatInt(retryAfter, 10))
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, rateLimitedBody)
+
+	return false
+}
+
+// runRequest executes a request, its latency and outcome adjust adaptive limit
+func (rm *RateLimiter) runRequest(c *gin.Context, class string) {
+	inflight := rm.runningRequests.Add(1)
 	rm.metricRunningRequests.Inc()
+	rm.metricClassRunning.WithLabelValues(class).Inc()
 	defer func() {
 		rm.runningRequests.Add(-1)
 		rm.metricRunningRequests.Dec()
+		rm.metricClassRunning.WithLabelValues(class).Dec()
 	}()
 
-	defer func() { <-rm.running }()
+	defer rm.running.release()
 
+	start := time.Now()
 	c.Next()
+
+	if rm.algorithm != nil {
+		// request context deadline is set by the caller budget, so requests running out of it say nothing
+		// about the server on their own, their latency still reaches the algorithm
+		limit := rm.algorithm.update(sample{
+			start:    start,
+			rtt:      time.Since(start),
+			inflight: inflight,
+			failed:   c.Writer.Status() >= http.StatusInternalServerError && !errors.Is(c.Request.Context().Err(), context.DeadlineExceeded),
+		})
+		rm.running.setLimit(limit)
+		rm.metricConcurrencyLimit.Set(float64(limit))
+	}
 }
 
-// rejectIfTooManyRequests if totalRequests exceeds maxWaiting + maxRunning request will be rejected
+// ConcurrencyLimit returns current limit of running requests
+func (rm *RateLimiter) ConcurrencyLimit() int64 {
+	return rm.running.getLimit()
+}
+
+// rejectIfTooManyRequests if totalRequests exceeds maxWaiting + concurrency limit request will be rejected
 // as both queues are full
 func (rm *RateLimiter) rejectIfTooManyRequests(c *gin.Context, lg *slog.Logger) bool {
 	t := rm.totalRequests.Load()
-	if t >= rm.maxWaiting+rm.maxRunning {
+	limit := rm.running.getLimit()
+	maxWaiting := rm.maxWaiting.Load()
+	if t >= maxWaiting+limit {
 		rm.rejectedTooManyRequests.Add(1)
 		rm.metricRejected.Inc()
 
 		lg.Warn("request rejected: too many requests",
 			"totalRequests", t,
-			"maxRunning", rm.maxRunning,
-			"maxWaiting", rm.maxWaiting,
+			"maxRunning", limit,
+			"maxWaiting", maxWaiting,
 		)
 
-		c.Header("Retry-After", strconv.Itoa(int(rm.retryAfter)))
-		c.AbortWithStatus(http.StatusTooManyRequests)
+		c.Header(HeaderRetryAfter, strconv.Itoa(int(rm.retryAfter.Load())))
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, rateLimitedBody)
 
 		return true
 	}
@@ -173,3 +791,32 @@ func normalizeParams(maxRunning, maxWaiting, retryAfter int64) (int64, int64, in
 
 	return maxRunning, maxWaiting, retryAfter
 }
+
+// normalizeLimit validates limit, window, burst
+// and replaces them with default values if validation fails
+func normalizeLimit(l Limit, window time.Duration) Limit {
+	if l.Limit < 1 {
+		log.Warn("limit should be > 1: replacing with defaultLimit",
+			"limit", l.Limit, "defaultLimit", defaultLimit)
+		l.Limit = defaultLimit
+	}
+
+	if l.Window <= 0 {
+		l.Window = window
+	}
+
+	if l.Burst < 1 {
+		l.Burst = l.Limit
+	}
+
+	return l
+}
+
+// ceilSeconds rounds duration up to whole seconds as required by Retry-After and RateLimit headers
+func ceilSeconds(d time.Duration) int64 {
+	if d <= 0 {
+		return 0
+	}
+
+	return int64(math.Ceil(d.Seconds()))
+}
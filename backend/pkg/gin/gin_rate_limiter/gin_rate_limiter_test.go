@@ -0,0 +1,91 @@
+package gin_rate_limiter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KennyMacCormik/otel/backend/pkg/gin/gin_request_id"
+)
+
+func TestRateLimiterMetrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	reg := prometheus.NewRegistry()
+
+	reads := NewRateLimiter(1, 1, 1, WithRegisterer(reg), WithName("reads", prometheus.Labels{"service": "test"}))
+	writes := NewRateLimiter(1, 1, 1, WithRegisterer(reg), WithName("writes", prometheus.Labels{"service": "test"}))
+
+	r := gin.New()
+	reads.GetRateLimiterMetricsEndpoint()(r)
+	r.GET("/", reads.GetRateLimiter(), func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.PUT("/", writes.GetRateLimiter(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for range 2 {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPut, "/", nil))
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(reads.metricTotalRequests), "expect requests of the first limiter")
+	assert.Equal(t, 1.0, testutil.ToFloat64(writes.metricTotalRequests), "expect requests of the second limiter")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, w.Code, "expect metrics endpoint")
+	assert.Contains(t, w.Body.String(), `rate_limiter_total_requests_static{limiter="reads",service="test"} 2`, "expect registry metrics")
+	assert.Contains(t, w.Body.String(), `rate_limiter_total_requests_static{limiter="writes",service="test"} 1`, "expect registry metrics")
+
+	shared := prometheus.NewRegistry()
+	first := NewRateLimiter(1, 1, 1, WithRegisterer(shared))
+	second := NewRateLimiter(1, 1, 1, WithRegisterer(shared))
+	assert.Same(t, first.metricTotalRequests, second.metricTotalRequests, "expect unnamed limiters to share metrics")
+}
+
+func TestRateLimiterExempt(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testCases := []struct {
+		name string
+		opts []InitOptions
+	}{
+		{"concurrency", []InitOptions{WithQueueTimeout(10 * time.Millisecond)}},
+		{"rate", []InitOptions{WithMode(ModeTokenBucket, 1, time.Minute, 1)}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rm := NewRateLimiter(1, 1, 1, append(tc.opts, WithRegisterer(prometheus.NewRegistry()))...)
+			rm.Exempt("/health")
+
+			// the limited route holds the only slot until released
+			release := make(chan struct{})
+			r := gin.New()
+			r.Use(gin_request_id.RequestIDMiddleware(), rm.GetRateLimiter())
+			r.GET("/", func(c *gin.Context) { <-release; c.Status(http.StatusOK) })
+			r.GET("/health", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+			}()
+			require.Eventually(t, func() bool { return rm.runningRequests.Load() == 1 }, time.Second, time.Millisecond,
+				"expect limited request to run")
+
+			for range 3 {
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+				assert.Equal(t, http.StatusOK, w.Code, "expect exempt route not to be limited")
+			}
+
+			close(release)
+			<-done
+		})
+	}
+}
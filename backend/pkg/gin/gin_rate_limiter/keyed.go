@@ -0,0 +1,232 @@
+package gin_rate_limiter
+
+import (
+	"container/list"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const defaultMaxKeys = 10000
+
+// KeySource selects request attribute used as rate limit key
+type KeySource string
+
+const (
+	KeyNone     KeySource = "none"
+	KeyClientIP KeySource = "ip"
+	KeyHeader   KeySource = "header"
+	KeyJWTClaim KeySource = "jwt_claim"
+	KeyRoute    KeySource = "route"
+)
+
+// NewKeyFunc returns KeyFunc for the source. Header is used by KeyHeader and claim by KeyJWTClaim.
+// It returns nil for KeyNone and unknown sources.
+func NewKeyFunc(source KeySource, header, claim string) KeyFunc {
+	switch source {
+	case KeyClientIP:
+		return KeyByClientIP()
+	case KeyHeader:
+		return KeyByHeader(header)
+	case KeyJWTClaim:
+		return KeyByJWTClaim(claim)
+	case KeyRoute:
+		return KeyByRoute()
+	default:
+		return nil
+	}
+}
+
+// KeyFunc derives rate limit key from request. Requests with empty key are limited by client IP.
+type KeyFunc func(c *gin.Context) string
+
+// KeyByClientIP limits every client IP separately, see gin.Context.ClientIP for proxy handling
+func KeyByClientIP() KeyFunc {
+	return func(c *gin.Context) string {
+		return c.ClientIP()
+	}
+}
+
+// KeyByHeader limits every value of the header separately, e.g. X-API-Key
+func KeyByHeader(name string) KeyFunc {
+	return func(c *gin.Context) string {
+		return c.GetHeader(name)
+	}
+}
+
+// KeyByJWTClaim limits every value of the bearer token claim separately.
+// The token signature is not verified, authentication is expected to happen before the limiter.
+func KeyByJWTClaim(claim string) KeyFunc {
+	return func(c *gin.Context) string {
+		token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok {
+			return ""
+		}
+
+		parts := strings.Split(strings.TrimSpace(token), ".")
+		if len(parts) != 3 {
+			return ""
+		}
+
+		payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+		if err != nil {
+			return ""
+		}
+
+		var claims map[string]any
+		if err = json.Unmarshal(payload, &claims); err != nil {
+			return ""
+		}
+
+		switch v := claims[claim].(type) {
+		case nil:
+			return ""
+		case string:
+			return v
+		default:
+			return fmt.Sprint(v)
+		}
+	}
+}
+
+// KeyByRoute limits every route separately, keys are route patterns like /storage/:key
+func KeyByRoute() KeyFunc {
+	return func(c *gin.Context) string {
+		return c.FullPath()
+	}
+}
+
+// Limit describes request rate limit. Zero Window inherits the default one, zero Burst equals to Limit.
+type Limit struct {
+	Limit  int64
+	Window time.Duration
+	Burst  int64
+}
+
+// ParseLimits parses comma separated list of key=limit or key=limit:burst pairs.
+// Route keys may contain colons, value is separated by the last equal sign.
+//
+// Example:
+//
+// tenant-a=1000,tenant-b=50:100,/storage/:key=200
+func ParseLimits(s string) (map[string]Limit, error) {
+	limits := make(map[string]Limit)
+
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		i := strings.LastIndex(entry, "=")
+		if i < 1 {
+			return nil, fmt.Errorf("ParseLimits: invalid entry [%s]", entry)
+		}
+
+		key, value := strings.TrimSpace(entry[:i]), strings.TrimSpace(entry[i+1:])
+		limit, burst, _ := strings.Cut(value, ":")
+
+		var l Limit
+		var err error
+
+		l.Limit, err = strconv.ParseInt(limit, 10, 64)
+		if err != nil || l.Limit < 1 {
+			return nil, fmt.Errorf("ParseLimits: invalid limit for key [%s]: %s", key, value)
+		}
+
+		if burst != "" {
+			l.Burst, err = strconv.ParseInt(burst, 10, 64)
+			if err != nil || l.Burst < 1 {
+				return nil, fmt.Errorf("ParseLimits: invalid burst for key [%s]: %s", key, value)
+			}
+		}
+
+		limits[key] = l
+	}
+
+	return limits, nil
+}
+
+// limitEntry is a rate limit state along with its RateLimit-Policy header value
+type limitEntry struct {
+	rateAlgorithm
+	policy string
+}
+
+func newLimitEntry(mode Mode, l Limit) *limitEntry {
+	return &limitEntry{rateAlgorithm: newRateAlgorithm(mode, l.Limit, l.Window, l.Burst), policy: limitPolicy(mode, l)}
+}
+
+func limitPolicy(mode Mode, l Limit) string {
+	policy := fmt.Sprintf("%d;w=%d", l.Limit, ceilSeconds(l.Window))
+	if mode == ModeTokenBucket {
+		policy += fmt.Sprintf(";burst=%d", l.Burst)
+	}
+
+	return policy
+}
+
+// keyedLimits is an LRU of per-key limit states bounded by maxKeys.
+// Evicted key starts with the full quota when it is seen again.
+type keyedLimits struct {
+	mtx sync.Mutex
+
+	maxKeys  int
+	order    *list.List
+	entries  map[string]*list.Element
+	newEntry func(key string) *limitEntry
+	onChange func(size int, evicted bool)
+}
+
+type keyedElement struct {
+	key   string
+	entry *limitEntry
+}
+
+func newKeyedLimits(maxKeys int, newEntry func(key string) *limitEntry, onChange func(size int, evicted bool)) *keyedLimits {
+	return &keyedLimits{
+		maxKeys:  maxKeys,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element, maxKeys),
+		newEntry: newEntry,
+		onChange: onChange,
+	}
+}
+
+// get returns state of the key creating it if needed
+func (k *keyedLimits) get(key string) *limitEntry {
+	k.mtx.Lock()
+	defer k.mtx.Unlock()
+
+	if el, ok := k.entries[key]; ok {
+		k.order.MoveToFront(el)
+		return el.Value.(*keyedElement).entry
+	}
+
+	evicted := false
+	if k.order.Len() >= k.maxKeys {
+		oldest := k.order.Back()
+		k.order.Remove(oldest)
+		delete(k.entries, oldest.Value.(*keyedElement).key)
+		evicted = true
+	}
+
+	e := k.newEntry(key)
+	k.entries[key] = k.order.PushFront(&keyedElement{key: key, entry: e})
+	k.onChange(k.order.Len(), evicted)
+
+	return e
+}
+
+func (k *keyedLimits) len() int {
+	k.mtx.Lock()
+	defer k.mtx.Unlock()
+
+	return k.order.Len()
+}
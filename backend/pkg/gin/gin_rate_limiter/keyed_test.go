@@ -0,0 +1,137 @@
+package gin_rate_limiter
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLimits(t *testing.T) {
+	limits, err := ParseLimits(" tenant-a=1000, tenant-b=50:100,/storage/:key=200,")
+	require.NoError(t, err, "expect valid limits")
+	assert.Equal(t, map[string]Limit{
+		"tenant-a":      {Limit: 1000},
+		"tenant-b":      {Limit: 50, Burst: 100},
+		"/storage/:key": {Limit: 200},
+	}, limits, "expect parsed limits")
+
+	limits, err = ParseLimits("")
+	require.NoError(t, err, "expect empty list to be valid")
+	assert.Empty(t, limits, "expect no limits")
+
+	for _, s := range []string{"tenant", "=10", "tenant=0", "tenant=abc", "tenant=1:0", "tenant=1:x"} {
+		_, err = ParseLimits(s)
+		assert.Error(t, err, "expect invalid entry %s to be rejected", s)
+	}
+}
+
+func TestKeyFuncs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	payload := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"user-1","tenant":42}`))
+	token := "e30." + payload + ".sig"
+
+	var got map[KeySource]string
+	r := gin.New()
+	r.GET("/storage/:key", func(c *gin.Context) {
+		got = map[KeySource]string{
+			KeyClientIP: NewKeyFunc(KeyClientIP, "", "")(c),
+			KeyHeader:   NewKeyFunc(KeyHeader, "X-API-Key", "")(c),
+			KeyJWTClaim: NewKeyFunc(KeyJWTClaim, "", "tenant")(c),
+			KeyRoute:    NewKeyFunc(KeyRoute, "", "")(c),
+			"sub":       KeyByJWTClaim("sub")(c),
+			"missing":   KeyByJWTClaim("missing")(c),
+		}
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/storage/foo", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set("X-API-Key", "secret")
+	req.Header.Set("Authorization", "Bearer "+token)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, "10.0.0.1", got[KeyClientIP], "expect client ip key")
+	assert.Equal(t, "secret", got[KeyHeader], "expect header key")
+	assert.Equal(t, "42", got[KeyJWTClaim], "expect non-string claim to be formatted")
+	assert.Equal(t, "user-1", got["sub"], "expect string claim")
+	assert.Equal(t, "", got["missing"], "expect missing claim to be empty")
+	assert.Equal(t, "/storage/:key", got[KeyRoute], "expect route pattern key")
+	assert.Nil(t, NewKeyFunc(KeyNone, "", ""), "expect no key func")
+}
+
+func TestKeyedLimits(t *testing.T) {
+	var size, evictions int
+	created := map[string]int{}
+
+	k := newKeyedLimits(2, func(key string) *limitEntry {
+		created[key]++
+		return newLimitEntry(ModeSlidingWindowLog, Limit{Limit: 1, Window: time.Minute, Burst: 1})
+	}, func(s int, evicted bool) {
+		size = s
+		if evicted {
+			evictions++
+		}
+	})
+
+	a := k.get("a")
+	assert.True(t, a.allow(epoch).allowed, "expect first request of a to be allowed")
+	assert.False(t, k.get("a").allow(epoch).allowed, "expect state of a to be kept")
+	assert.True(t, k.get("b").allow(epoch).allowed, "expect b to be limited separately")
+	assert.Equal(t, 2, size, "expect two tracked keys")
+
+	// a is the least recently used one after b is touched
+	k.get("b")
+	k.get("c")
+	assert.Equal(t, 2, k.len(), "expect size to be bounded")
+	assert.Equal(t, 1, evictions, "expect a single eviction")
+
+	assert.True(t, k.get("a").allow(epoch).allowed, "expect evicted key to start with full quota")
+	assert.Equal(t, 2, created["a"], "expect a to be recreated")
+	assert.Equal(t, 1, created["b"], "expect b to be kept")
+	assert.Equal(t, "1;w=60", a.policy, "expect policy of the entry")
+}
+
+func TestRateLimiter_UnknownKeys(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rm := NewRateLimiter(1, 1, 1,
+		WithMode(ModeTokenBucket, 1, time.Minute, 1),
+		WithKeyedLimits(KeyByHeader("X-API-Key"), map[string]Limit{"tenant": {Limit: 2}}, 2),
+		WithUnknownKeysLimit(Limit{Limit: 3}),
+		WithRegisterer(prometheus.NewRegistry()),
+	)
+
+	r := gin.New()
+	r.Use(rm.GetRateLimiter())
+	r.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	do := func(key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-API-Key", key)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, do("tenant").Code, "expect override key to be allowed")
+
+	assert.Equal(t, http.StatusOK, do("a").Code, "expect unknown key to be allowed")
+	assert.Equal(t, http.StatusTooManyRequests, do("a").Code, "expect unknown key to be limited by its own limit")
+	assert.Equal(t, http.StatusOK, do("b").Code, "expect rejected request not to count to the shared limit")
+	assert.Equal(t, http.StatusOK, do("c").Code, "expect unknown key to be allowed")
+
+	w := do("d")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code, "expect rotated keys to be limited together")
+	assert.Equal(t, "3;w=60;burst=3", w.Header().Get(HeaderRateLimitPolicy), "expect policy of the shared limit")
+	assert.Equal(t, "20", w.Header().Get(HeaderRetryAfter), "expect retry after shared token refill")
+
+	// unknown keys evicted all tracked ones, but override state is kept
+	assert.Equal(t, http.StatusOK, do("tenant").Code, "expect override key not to be limited by the shared limit")
+	assert.Equal(t, http.StatusTooManyRequests, do("tenant").Code, "expect override key state not to be evicted")
+}
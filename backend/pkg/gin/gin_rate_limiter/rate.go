@@ -0,0 +1,233 @@
+package gin_rate_limiter
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Mode selects the limiting algorithm
+type Mode string
+
+const (
+	// ModeConcurrency only limits the number of running and waiting requests, other modes limit request rate in front of it
+	ModeConcurrency Mode = "concurrency"
+	// ModeTokenBucket allows bursts up to the bucket size refilled at limit per window
+	ModeTokenBucket Mode = "token_bucket"
+	// ModeSlidingWindowLog allows limit requests in any window, it keeps timestamps of the allowed requests
+	ModeSlidingWindowLog Mode = "sliding_window_log"
+	// ModeSlidingWindowCounter approximates sliding window log with counters of the current and the previous windows
+	ModeSlidingWindowCounter Mode = "sliding_window_counter"
+)
+
+// decision describes the state of a rate limit after a request
+type decision struct {
+	allowed   bool
+	limit     int64
+	remaining int64
+	// reset is the time until the quota is fully restored
+	reset time.Duration
+	// retryAfter is the time until the next request is allowed, it is zero for allowed requests
+	retryAfter time.Duration
+}
+
+// rateAlgorithm holds the state of a single rate limit and is safe for concurrent use
+type rateAlgorithm interface {
+	// allow consumes quota of a single request if it is available
+	allow(now time.Time) decision
+}
+
+func newRateAlgorithm(mode Mode, limit int64, window time.Duration, burst int64) rateAlgorithm {
+	switch mode {
+	case ModeTokenBucket:
+		return newTokenBucket(limit, window, burst)
+	case ModeSlidingWindowLog:
+		return newSlidingWindowLog(limit, window)
+	case ModeSlidingWindowCounter:
+		return newSlidingWindowCounter(limit, window)
+	default:
+		return nil
+	}
+}
+
+type tokenBucket struct {
+	mtx sync.Mutex
+
+	// rate is in tokens per second
+	rate, burst float64
+	tokens      float64
+	last        time.Time
+}
+
+// newTokenBucket returns full bucket of burst tokens refilled with limit tokens per window
+func newTokenBucket(limit int64, window time.Duration, burst int64) *tokenBucket {
+	return &tokenBucket{
+		rate:   float64(limit) / window.Seconds(),
+		burst:  float64(burst),
+		tokens: float64(burst),
+	}
+}
+
+func (b *tokenBucket) allow(now time.Time) decision {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	if !b.last.IsZero() && now.After(b.last) {
+		b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	}
+	if now.After(b.last) {
+		b.last = now
+	}
+
+	d := decision{limit: int64(b.burst)}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		d.allowed = true
+	} else {
+		d.retryAfter = b.duration(1 - b.tokens)
+	}
+
+	d.remaining = int64(b.tokens)
+	d.reset = b.duration(b.burst - b.tokens)
+
+	return d
+}
+
+// duration returns time needed to refill n tokens
+func (b *tokenBucket) duration(n float64) time.Duration {
+	return time.Duration(math.Ceil(n / b.rate * float64(time.Second)))
+}
+
+type slidingWindowLog struct {
+	mtx sync.Mutex
+
+	window time.Duration
+	// log is a ring buffer of timestamps of allowed requests
+	log        []time.Time
+	head, size int
+}
+
+func newSlidingWindowLog(limit int64, window time.Duration) *slidingWindowLog {
+	return &slidingWindowLog{window: window, log: make([]time.Time, limit)}
+}
+
+func (l *slidingWindowLog) allow(now time.Time) decision {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	for l.size > 0 && !l.log[l.head].After(now.Add(-l.window)) {
+		l.head = (l.head + 1) % len(l.log)
+		l.size--
+	}
+
+	d := decision{limit: int64(len(l.log))}
+
+	if l.size < len(l.log) {
+		l.log[(l.head+l.size)%len(l.log)] = now
+		l.size++
+		d.allowed = true
+	} else {
+		d.retryAfter = l.log[l.head].Add(l.window).Sub(now)
+	}
+
+	d.remaining = int64(len(l.log) - l.size)
+	if l.size > 0 {
+		newest := l.log[(l.head+l.size-1)%len(l.log)]
+		d.reset = newest.Add(l.window).Sub(now)
+	}
+
+	return d
+}
+
+type slidingWindowCounter struct {
+	mtx sync.Mutex
+
+	limit      int64
+	window     time.Duration
+	start      time.Time
+	prev, curr int64
+}
+
+func newSlidingWindowCounter(limit int64, window time.Duration) *slidingWindowCounter {
+	return &slidingWindowCounter{limit: limit, window: window}
+}
+
+func (c *slidingWindowCounter) allow(now time.Time) decision {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.advance(now)
+
+	d := counterDecision(c.limit, c.prev, c.curr, now.Sub(c.start), c.window)
+	if d.allowed {
+		c.curr++
+	}
+
+	return d
+}
+
+// advance moves the current window to the one containing now
+func (c *slidingWindowCounter) advance(now time.Time) {
+	if c.start.IsZero() || now.Sub(c.start) >= 2*c.window {
+		c.prev, c.curr = 0, 0
+		c.start = now.Truncate(c.window)
+		return
+	}
+
+	if now.Sub(c.start) >= c.window {
+		c.prev, c.curr = c.curr, 0
+		c.start = c.start.Add(c.window)
+	}
+}
+
+// counterDecision admits a request against counts of the current and the previous windows.
+// Caller is responsible for counting the request if it is allowed.
+func counterDecision(limit, prev, curr int64, elapsed, window time.Duration) decision {
+	d := decision{limit: limit}
+
+	count := estimate(prev, curr, elapsed, window)
+	if count+1 <= float64(limit) {
+		curr++
+		count++
+		d.allowed = true
+	} else {
+		d.retryAfter = counterRetryAfter(limit, prev, curr, elapsed, window)
+	}
+
+	d.remaining = max(0, limit-int64(math.Ceil(count)))
+
+	switch {
+	case curr > 0:
+		d.reset = 2*window - elapsed
+	case prev > 0:
+		d.reset = window - elapsed
+	}
+
+	return d
+}
+
+// counterRetryAfter returns time until the estimate drops enough to allow a request.
+// The previous window weight decreases linearly within the current window, then the current window becomes the previous one.
+func counterRetryAfter(limit, prev, curr int64, elapsed, window time.Duration) time.Duration {
+	free := float64(limit - 1)
+	w := float64(window)
+
+	if prev > 0 && free >= float64(curr) {
+		if t := w*(1-(free-float64(curr))/float64(prev)) - float64(elapsed); t >= 0 {
+			return time.Duration(math.Ceil(t))
+		}
+	}
+
+	next := 0.0
+	if curr > 0 {
+		next = math.Max(0, w*(1-free/float64(curr)))
+	}
+
+	return window - elapsed + time.Duration(math.Ceil(next))
+}
+
+// estimate weights the previous window count by its share in the sliding window
+func estimate(prev, curr int64, elapsed, window time.Duration) float64 {
+	return float64(prev)*(1-float64(elapsed)/float64(window)) + float64(curr)
+}
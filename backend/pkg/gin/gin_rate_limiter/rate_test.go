@@ -0,0 +1,138 @@
+package gin_rate_limiter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KennyMacCormik/otel/backend/pkg/gin/gin_request_id"
+)
+
+var epoch = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestTokenBucket(t *testing.T) {
+	b := newTokenBucket(10, time.Second, 3)
+
+	for i := 0; i < 3; i++ {
+		d := b.allow(epoch)
+		require.True(t, d.allowed, "expect burst to be allowed")
+		assert.Equal(t, int64(2-i), d.remaining, "expect remaining to decrease")
+	}
+
+	d := b.allow(epoch)
+	assert.False(t, d.allowed, "expect empty bucket to reject")
+	assert.Equal(t, 100*time.Millisecond, d.retryAfter, "expect retry after a single token refill")
+	assert.Equal(t, 300*time.Millisecond, d.reset, "expect reset after full refill")
+
+	d = b.allow(epoch.Add(100 * time.Millisecond))
+	assert.True(t, d.allowed, "expect refilled token to be allowed")
+
+	d = b.allow(epoch.Add(time.Hour))
+	assert.True(t, d.allowed, "expect bucket to refill")
+	assert.Equal(t, int64(2), d.remaining, "expect bucket to be capped by burst")
+}
+
+func TestSlidingWindowLog(t *testing.T) {
+	l := newSlidingWindowLog(2, time.Second)
+
+	assert.True(t, l.allow(epoch).allowed, "expect request to be allowed")
+	assert.True(t, l.allow(epoch.Add(400*time.Millisecond)).allowed, "expect request to be allowed")
+
+	d := l.allow(epoch.Add(500 * time.Millisecond))
+	assert.False(t, d.allowed, "expect full window to reject")
+	assert.Equal(t, 500*time.Millisecond, d.retryAfter, "expect retry when the oldest entry expires")
+	assert.Equal(t, 900*time.Millisecond, d.reset, "expect reset when the newest entry expires")
+
+	d = l.allow(epoch.Add(time.Second))
+	assert.True(t, d.allowed, "expect expired entry to free quota")
+	assert.Equal(t, int64(0), d.remaining, "expect no quota left")
+}
+
+func TestSlidingWindowCounter(t *testing.T) {
+	c := newSlidingWindowCounter(4, time.Second)
+
+	for i := 0; i < 4; i++ {
+		require.True(t, c.allow(epoch).allowed, "expect request within limit to be allowed")
+	}
+
+	d := c.allow(epoch.Add(500 * time.Millisecond))
+	assert.False(t, d.allowed, "expect full window to reject")
+	assert.Equal(t, 750*time.Millisecond, d.retryAfter, "expect retry when previous window weight drops below limit")
+
+	d = c.allow(epoch.Add(1250 * time.Millisecond))
+	assert.True(t, d.allowed, "expect weighted previous window to leave quota")
+	assert.Equal(t, int64(0), d.remaining, "expect no quota left")
+
+	d = c.allow(epoch.Add(1250 * time.Millisecond))
+	assert.False(t, d.allowed, "expect limit to be reached")
+	assert.Equal(t, 250*time.Millisecond, d.retryAfter, "expect retry when previous window weight drops")
+
+	d = c.allow(epoch.Add(5 * time.Second))
+	assert.True(t, d.allowed, "expect stale windows to be dropped")
+	assert.Equal(t, int64(3), d.remaining, "expect full quota")
+}
+
+func TestRateLimiterHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rm := NewRateLimiter(1, 1, 1, WithMode(ModeTokenBucket, 1, time.Minute, 1), WithRegisterer(prometheus.NewRegistry()))
+	require.Equal(t, ModeTokenBucket, rm.Mode(), "expect token bucket mode")
+
+	r := gin.New()
+	r.Use(rm.GetRateLimiter())
+	r.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, w.Code, "expect request to be allowed")
+	assert.Equal(t, "1", w.Header().Get(HeaderRateLimitLimit), "expect limit header")
+	assert.Equal(t, "0", w.Header().Get(HeaderRateLimitRemaining), "expect remaining header")
+	assert.Equal(t, "60", w.Header().Get(HeaderRateLimitReset), "expect reset header")
+	assert.Equal(t, "1;w=60;burst=1", w.Header().Get(HeaderRateLimitPolicy), "expect policy header")
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusTooManyRequests, w.Code, "expect request to be rejected")
+	assert.Equal(t, "60", w.Header().Get(HeaderRetryAfter), "expect retry after token refill")
+}
+
+func TestRateLimiterRateAndConcurrency(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rm := NewRateLimiter(1, 2, 1, WithMode(ModeTokenBucket, 2, time.Minute, 2),
+		WithQueueTimeout(10*time.Millisecond), WithRegisterer(prometheus.NewRegistry()))
+
+	// the first request holds the only slot until released
+	release := make(chan struct{})
+	r := gin.New()
+	r.Use(gin_request_id.RequestIDMiddleware(), rm.GetRateLimiter())
+	r.GET("/", func(c *gin.Context) { <-release; c.Status(http.StatusOK) })
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}()
+	require.Eventually(t, func() bool { return rm.runningRequests.Load() == 1 }, time.Second, time.Millisecond,
+		"expect the first request to run")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusTooManyRequests, w.Code, "expect request within rate limit to wait for a slot")
+	assert.Equal(t, "0", w.Header().Get(HeaderRateLimitRemaining), "expect rate limit to be checked first")
+
+	close(release)
+	<-done
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusTooManyRequests, w.Code, "expect request over rate limit to be rejected")
+	assert.NotEmpty(t, w.Header().Get(HeaderRetryAfter), "expect retry after token refill")
+	assert.Equal(t, 1.0, testutil.ToFloat64(rm.metricQueueTimeouts.WithLabelValues(DefaultClass)),
+		"expect request over rate limit not to wait for a slot")
+}
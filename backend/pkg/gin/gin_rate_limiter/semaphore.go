@@ -0,0 +1,209 @@
+package gin_rate_limiter
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/KennyMacCormik/common/log"
+
+	rateLimiterErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/rate_limiter"
+)
+
+// semaphore limits the number of running requests. Unlike a buffered channel its limit may be changed at any time:
+// shrinking doesn't affect running requests, new ones wait until enough of them complete.
+// Waiters are queued per class and granted according to the scheduling, codel sheds waiters queued for too long.
+type semaphore struct {
+	mtx sync.Mutex
+
+	limit, running int64
+	scheduling     Scheduling
+	classes        map[string]*classQueue
+	codel          *codel
+	// seq orders waiters by arrival, vtime is the virtual time of weighted fair queuing
+	seq   int64
+	vtime float64
+}
+
+type classQueue struct {
+	Class
+	waiters list.List
+	// finish is the virtual finish tag of the last queued waiter
+	finish float64
+}
+
+type waiter struct {
+	ready    chan struct{}
+	enqueued time.Time
+	tag      float64
+	// granted and shed waiters are removed from the queue
+	granted, shed bool
+}
+
+// newSemaphore returns semaphore, nil codel disables shedding. Class weights below 1 are replaced with 1.
+func newSemaphore(limit int64, scheduling Scheduling, classes []Class, codel *codel) *semaphore {
+	s := &semaphore{limit: limit, scheduling: scheduling, classes: make(map[string]*classQueue, len(classes)+1), codel: codel}
+
+	s.classes[DefaultClass] = &classQueue{Class: Class{Name: DefaultClass, Weight: 1}}
+	for _, c := range classes {
+		// weighted fair queuing tags grow by 1/weight, zero weight would make them infinite and starve the class
+		if c.Weight < 1 {
+			log.Warn("class weight should be >= 1: replacing with 1", "class", c.Name, "weight", c.Weight)
+			c.Weight = 1
+		}
+
+		s.classes[c.Name] = &classQueue{Class: c}
+	}
+
+	return s
+}
+
+// acquire waits for a free slot until ctx is done.
+// It fails immediately with rateLimiterErrors.ErrQueueFull if the class queue is full,
+// and with rateLimiterErrors.ErrShed if the waiter is shed.
+func (s *semaphore) acquire(ctx context.Context, class string) error {
+	s.mtx.Lock()
+	q := s.class(class)
+
+	if s.running < s.limit && s.waiting() == 0 {
+		s.running++
+		s.mtx.Unlock()
+		return nil
+	}
+
+	if q.MaxQueue > 0 && int64(q.waiters.Len()) >= q.MaxQueue {
+		s.mtx.Unlock()
+		return fmt.Errorf("class [%s]: %w", q.Name, rateLimiterErrors.ErrQueueFull)
+	}
+
+	w := &waiter{ready: make(chan struct{}), enqueued: time.Now(), tag: s.tag(q)}
+	el := q.waiters.PushBack(w)
+	s.mtx.Unlock()
+
+	select {
+	case <-w.ready:
+		if w.shed {
+			return fmt.Errorf("class [%s]: %w", q.Name, rateLimiterErrors.ErrShed)
+		}
+		return nil
+	case <-ctx.Done():
+		s.mtx.Lock()
+		defer s.mtx.Unlock()
+
+		switch {
+		case w.granted:
+			// slot was granted concurrently with cancellation, pass it on
+			s.running--
+		case !w.shed:
+			q.waiters.Remove(el)
+		}
+		s.grant()
+
+		return ctx.Err()
+	}
+}
+
+func (s *semaphore) release() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.running--
+	s.grant()
+}
+
+func (s *semaphore) setLimit(limit int64) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.limit = limit
+	s.grant()
+}
+
+func (s *semaphore) getLimit() int64 {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return s.limit
+}
+
+// class returns queue of the class falling back to the default one, mtx must be held
+func (s *semaphore) class(name string) *classQueue {
+	if q, ok := s.classes[name]; ok {
+		return q
+	}
+
+	return s.classes[DefaultClass]
+}
+
+func (s *semaphore) waiting() int {
+	n := 0
+	for _, q := range s.classes {
+		n += q.waiters.Len()
+	}
+
+	return n
+}
+
+// tag returns ordering tag of a new waiter of the class, lower tags are granted first.
+// Weighted fair queuing tags are virtual finish times, so every class gets its weight share of slots.
+func (s *semaphore) tag(q *classQueue) float64 {
+	if s.scheduling != SchedulingWFQ {
+		s.seq++
+		return float64(s.seq)
+	}
+
+	q.finish = math.Max(s.vtime, q.finish) + 1/float64(q.Weight)
+
+	return q.finish
+}
+
+// grant hands free slots to waiters, mtx must be held
+func (s *semaphore) grant() {
+	now := time.Now()
+
+	for s.running < s.limit {
+		q := s.next()
+		if q == nil {
+			return
+		}
+
+		w := q.waiters.Remove(q.waiters.Front()).(*waiter)
+		if s.scheduling == SchedulingWFQ {
+			s.vtime = w.tag
+		}
+
+		if s.codel != nil && s.codel.shouldShed(now.Sub(w.enqueued), now) {
+			w.shed = true
+			close(w.ready)
+			continue
+		}
+
+		w.granted = true
+		s.running++
+		close(w.ready)
+	}
+}
+
+// next returns class of the waiter to be granted next, mtx must be held
+func (s *semaphore) next() *classQueue {
+	var best *classQueue
+	var bestTag float64
+
+	for _, q := range s.classes {
+		if q.waiters.Len() == 0 {
+			continue
+		}
+
+		tag := q.waiters.Front().Value.(*waiter).tag
+		if best == nil ||
+			(s.scheduling == SchedulingPriority && q.Priority > best.Priority) ||
+			((s.scheduling != SchedulingPriority || q.Priority == best.Priority) && tag < bestTag) {
+			best, bestTag = q, tag
+		}
+	}
+
+	return best
+}
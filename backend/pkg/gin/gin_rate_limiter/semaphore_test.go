@@ -0,0 +1,172 @@
+package gin_rate_limiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	rateLimiterErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/rate_limiter"
+)
+
+func acquireAsync(s *semaphore, ctx context.Context) chan error {
+	return acquireClassAsync(s, ctx, DefaultClass)
+}
+
+func acquireClassAsync(s *semaphore, ctx context.Context, class string) chan error {
+	ch := make(chan error, 1)
+	go func() { ch <- s.acquire(ctx, class) }()
+	return ch
+}
+
+func waitersLen(s *semaphore) int {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.waiting()
+}
+
+func TestSemaphore(t *testing.T) {
+	s := newSemaphore(1, SchedulingFIFO, nil, nil)
+	require.NoError(t, s.acquire(context.Background(), DefaultClass), "expect free slot")
+
+	first := acquireAsync(s, context.Background())
+	require.Eventually(t, func() bool { return waitersLen(s) == 1 }, time.Second, time.Millisecond, "expect waiter")
+	second := acquireAsync(s, context.Background())
+	require.Eventually(t, func() bool { return waitersLen(s) == 2 }, time.Second, time.Millisecond, "expect waiter")
+
+	s.release()
+	assert.NoError(t, <-first, "expect waiters to be granted in order")
+	assert.Empty(t, second, "expect second waiter to keep waiting")
+
+	s.setLimit(2)
+	assert.NoError(t, <-second, "expect grown limit to grant waiter")
+
+	s.setLimit(1)
+	s.release()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, s.acquire(ctx, DefaultClass), context.DeadlineExceeded, "expect shrunk limit to keep running requests")
+	assert.Equal(t, 0, waitersLen(s), "expect cancelled waiter to be removed")
+
+	s.release()
+	assert.NoError(t, s.acquire(context.Background(), DefaultClass), "expect slot after running requests complete")
+}
+
+func TestSemaphore_CancelledGrant(t *testing.T) {
+	s := newSemaphore(1, SchedulingFIFO, nil, nil)
+	require.NoError(t, s.acquire(context.Background(), DefaultClass), "expect free slot")
+
+	for i := 0; i < 100; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		ch := acquireAsync(s, ctx)
+		require.Eventually(t, func() bool { return waitersLen(s) == 1 }, time.Second, time.Millisecond, "expect waiter")
+
+		// grant and cancel race, the slot must not leak either way
+		go s.release()
+		cancel()
+
+		if err := <-ch; err == nil {
+			s.release()
+		}
+		require.NoError(t, s.acquire(context.Background(), DefaultClass), "expect slot not to leak")
+	}
+}
+
+// grantOrder queues waiters of the classes one by one and returns classes in the order they are granted
+func grantOrder(t *testing.T, s *semaphore, classes []string) []string {
+	require.NoError(t, s.acquire(context.Background(), DefaultClass), "expect free slot")
+
+	granted := make(chan string, len(classes))
+	for i, class := range classes {
+		go func() {
+			if s.acquire(context.Background(), class) == nil {
+				granted <- class
+			}
+		}()
+		require.Eventually(t, func() bool { return waitersLen(s) == i+1 }, time.Second, time.Millisecond, "expect waiter")
+	}
+
+	var order []string
+	for range classes {
+		s.release()
+		order = append(order, <-granted)
+	}
+
+	return order
+}
+
+func TestSemaphore_Priority(t *testing.T) {
+	s := newSemaphore(1, SchedulingPriority, []Class{{Name: "critical", Priority: 10, Weight: 1}, {Name: "batch", Priority: -1, Weight: 1}}, nil)
+
+	order := grantOrder(t, s, []string{"batch", DefaultClass, "critical", "batch", "critical"})
+	assert.Equal(t, []string{"critical", "critical", DefaultClass, "batch", "batch"}, order, "expect higher priority classes first")
+}
+
+func TestSemaphore_WFQ(t *testing.T) {
+	s := newSemaphore(1, SchedulingWFQ, []Class{{Name: "a", Weight: 3}, {Name: "b", Weight: 1}}, nil)
+
+	order := grantOrder(t, s, []string{"b", "b", "b", "b", "a", "a", "a", "a", "a", "a"})
+
+	a := 0
+	for _, class := range order[:4] {
+		if class == "a" {
+			a++
+		}
+	}
+	assert.Equal(t, 3, a, "expect weight share of slots among waiting classes")
+}
+
+func TestSemaphore_WFQZeroWeight(t *testing.T) {
+	s := newSemaphore(1, SchedulingWFQ, []Class{{Name: "a"}, {Name: "b", Weight: 1}}, nil)
+	assert.Equal(t, int64(1), s.classes["a"].Weight, "expect zero weight to be replaced")
+
+	order := grantOrder(t, s, []string{"b", "b", "a", "a"})
+	assert.Contains(t, order[:2], "a", "expect class without weight not to starve")
+}
+
+func TestSemaphore_FIFO(t *testing.T) {
+	s := newSemaphore(1, SchedulingFIFO, []Class{{Name: "critical", Priority: 10, Weight: 5}}, nil)
+
+	order := grantOrder(t, s, []string{DefaultClass, "critical", DefaultClass})
+	assert.Equal(t, []string{DefaultClass, "critical", DefaultClass}, order, "expect arrival order")
+}
+
+func TestSemaphore_QueueFull(t *testing.T) {
+	s := newSemaphore(1, SchedulingPriority, []Class{{Name: "batch", Weight: 1, MaxQueue: 1}}, nil)
+	require.NoError(t, s.acquire(context.Background(), "batch"), "expect free slot")
+
+	waiting := acquireClassAsync(s, context.Background(), "batch")
+	require.Eventually(t, func() bool { return waitersLen(s) == 1 }, time.Second, time.Millisecond, "expect waiter")
+
+	assert.ErrorIs(t, s.acquire(context.Background(), "batch"), rateLimiterErrors.ErrQueueFull, "expect full class queue to reject")
+
+	other := acquireAsync(s, context.Background())
+	require.Eventually(t, func() bool { return waitersLen(s) == 2 }, time.Second, time.Millisecond, "expect other classes to queue")
+
+	s.release()
+	assert.NoError(t, <-waiting, "expect waiter to be granted")
+	s.release()
+	assert.NoError(t, <-other, "expect waiter to be granted")
+}
+
+func TestSemaphore_Shed(t *testing.T) {
+	s := newSemaphore(1, SchedulingFIFO, nil, newCoDel(time.Millisecond, 5*time.Millisecond))
+	require.NoError(t, s.acquire(context.Background(), DefaultClass), "expect free slot")
+
+	first := acquireAsync(s, context.Background())
+	require.Eventually(t, func() bool { return waitersLen(s) == 1 }, time.Second, time.Millisecond, "expect waiter")
+	second := acquireAsync(s, context.Background())
+	require.Eventually(t, func() bool { return waitersLen(s) == 2 }, time.Second, time.Millisecond, "expect waiter")
+
+	time.Sleep(10 * time.Millisecond)
+	s.release()
+	assert.NoError(t, <-first, "expect waiter above target to be granted until interval passes")
+
+	time.Sleep(10 * time.Millisecond)
+	s.release()
+	assert.ErrorIs(t, <-second, rateLimiterErrors.ErrShed, "expect waiter above target for interval to be shed")
+
+	assert.NoError(t, s.acquire(context.Background(), DefaultClass), "expect shed waiter not to hold a slot")
+}
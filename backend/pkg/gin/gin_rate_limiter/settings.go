@@ -0,0 +1,130 @@
+package gin_rate_limiter
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/KennyMacCormik/common/log"
+	"github.com/gin-gonic/gin"
+
+	"github.com/KennyMacCormik/otel/backend/pkg/gin/gin_request_id"
+	httpErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/http"
+	rateLimiterErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/rate_limiter"
+)
+
+const (
+	// AdminPath is the route of the admin endpoint
+	AdminPath = "/admin/rate_limiter"
+
+	maxSettingsLimit = 100000
+	maxRetryAfter    = 60 // in seconds
+)
+
+// Settings are the concurrency limits that may be changed at runtime
+type Settings struct {
+	MaxRunning int64 `json:"max_conn"`
+	MaxWaiting int64 `json:"max_wait"`
+	RetryAfter int64 `json:"retry_after"`
+}
+
+// Settings returns current settings
+func (rm *RateLimiter) Settings() Settings {
+	return Settings{
+		MaxRunning: rm.maxRunning.Load(),
+		MaxWaiting: rm.maxWaiting.Load(),
+		RetryAfter: rm.retryAfter.Load(),
+	}
+}
+
+// UpdateSettings applies settings changed by update and returns the resulting ones.
+// Settings are left intact if update or validation fails, concurrent updates are serialized.
+// Growing MaxRunning grants waiting requests at once, shrinking it lets running requests complete
+// and holds new ones until the running set fits. MaxRunning is managed by the adaptive limit if one is set.
+func (rm *RateLimiter) UpdateSettings(update func(s *Settings) error) (Settings, error) {
+	rm.settingsMtx.Lock()
+	defer rm.settingsMtx.Unlock()
+
+	old := rm.Settings()
+	s := old
+
+	if err := update(&s); err != nil {
+		return old, err
+	}
+
+	if err := validateSettings(s); err != nil {
+		return old, err
+	}
+
+	if s.MaxRunning != old.MaxRunning {
+		if rm.algorithm != nil {
+			return old, fmt.Errorf("%w: max_conn is managed by adaptive limit", rateLimiterErrors.ErrInvalidSettings)
+		}
+
+		rm.running.setLimit(s.MaxRunning)
+		rm.metricConcurrencyLimit.Set(float64(s.MaxRunning))
+	}
+
+	rm.maxRunning.Store(s.MaxRunning)
+	rm.maxWaiting.Store(s.MaxWaiting)
+	rm.retryAfter.Store(s.RetryAfter)
+
+	log.Info("rate limiter settings updated",
+		"maxRunning", s.MaxRunning,
+		"maxWaiting", s.MaxWaiting,
+		"retryAfter", s.RetryAfter,
+	)
+
+	return s, nil
+}
+
+func validateSettings(s Settings) error {
+	switch {
+	case s.MaxRunning < 1 || s.MaxRunning > maxSettingsLimit:
+		return fmt.Errorf("%w: max_conn must be between 1 and %d", rateLimiterErrors.ErrInvalidSettings, maxSettingsLimit)
+	case s.MaxWaiting < 1 || s.MaxWaiting > maxSettingsLimit:
+		return fmt.Errorf("%w: max_wait must be between 1 and %d", rateLimiterErrors.ErrInvalidSettings, maxSettingsLimit)
+	case s.RetryAfter < 1 || s.RetryAfter > maxRetryAfter:
+		return fmt.Errorf("%w: retry_after must be between 1 and %d", rateLimiterErrors.ErrInvalidSettings, maxRetryAfter)
+	default:
+		return nil
+	}
+}
+
+// GetRateLimiterAdminEndpoint serves settings at AdminPath: GET returns them,
+// PATCH updates fields present in the JSON body. Requests must pass auth, the endpoint itself is not rate limited,
+// so it stays reachable during overload. It must be added before the router starts serving.
+func (rm *RateLimiter) GetRateLimiterAdminEndpoint(auth gin.HandlerFunc) func(*gin.Engine) {
+	return func(router *gin.Engine) {
+		rm.Exempt(AdminPath)
+
+		router.GET(AdminPath, auth, func(c *gin.Context) {
+			c.JSON(http.StatusOK, rm.Settings())
+		})
+		router.PATCH(AdminPath, auth, rm.ginUpdateSettings)
+	}
+}
+
+func (rm *RateLimiter) ginUpdateSettings(c *gin.Context) {
+	s, err := rm.UpdateSettings(func(s *Settings) error {
+		if err := c.ShouldBindJSON(s); err != nil {
+			return fmt.Errorf("%w: %w", httpErrors.ErrBadRequest, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		requestID, _ := gin_request_id.GetRequestIDFromCtx(c)
+		log.Warn("failed to update rate limiter settings", "requestID", requestID, "err", err)
+
+		if errors.Is(err, rateLimiterErrors.ErrInvalidSettings) {
+			err = fmt.Errorf("%w: %w", httpErrors.ErrBadRequest, err)
+		}
+
+		errStatus := httpErrors.FromError(err)
+		c.JSON(errStatus.GetStatus(), errStatus.GetBody())
+		return
+	}
+
+	c.JSON(http.StatusOK, s)
+}
@@ -0,0 +1,94 @@
+package gin_rate_limiter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	rateLimiterErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/rate_limiter"
+)
+
+func patchSettings(r *gin.Engine, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPatch, AdminPath, strings.NewReader(body)))
+	return w
+}
+
+func TestUpdateSettings(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rm := NewRateLimiter(1, 2, 1, WithRegisterer(prometheus.NewRegistry()))
+
+	release := make(chan struct{})
+	r := gin.New()
+	r.Use(rm.GetRateLimiter())
+	rm.GetRateLimiterAdminEndpoint(func(c *gin.Context) { c.Next() })(r)
+	r.GET("/", func(c *gin.Context) {
+		<-release
+		c.Status(http.StatusOK)
+	})
+
+	serve := func() chan int {
+		ch := make(chan int, 1)
+		go func() {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+			ch <- w.Code
+		}()
+		return ch
+	}
+
+	first := serve()
+	require.Eventually(t, func() bool { return rm.runningRequests.Load() == 1 }, time.Second, time.Millisecond, "expect running request")
+	second := serve()
+	require.Eventually(t, func() bool { return waitersLen(rm.running) == 1 }, time.Second, time.Millisecond, "expect waiting request")
+
+	w := patchSettings(r, `{"max_conn":2}`)
+	require.Equal(t, http.StatusOK, w.Code, "expect admin endpoint to bypass the full limiter")
+	assert.JSONEq(t, `{"max_conn":2,"max_wait":2,"retry_after":1}`, w.Body.String(), "expect partial update")
+	require.Eventually(t, func() bool { return rm.runningRequests.Load() == 2 }, time.Second, time.Millisecond, "expect grown limit to grant waiter")
+
+	w = patchSettings(r, `{"max_conn":1,"max_wait":3,"retry_after":5}`)
+	require.Equal(t, http.StatusOK, w.Code, "expect limit to shrink")
+	third := serve()
+	require.Eventually(t, func() bool { return waitersLen(rm.running) == 1 }, time.Second, time.Millisecond, "expect shrunk limit to queue requests")
+
+	close(release)
+	assert.Equal(t, http.StatusOK, <-first, "expect running request to complete")
+	assert.Equal(t, http.StatusOK, <-second, "expect running request to complete")
+	assert.Equal(t, http.StatusOK, <-third, "expect queued request to run")
+	assert.Equal(t, Settings{MaxRunning: 1, MaxWaiting: 3, RetryAfter: 5}, rm.Settings(), "expect updated settings")
+
+	w = patchSettings(r, `{"max_wait":0}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code, "expect invalid settings to be rejected")
+	w = patchSettings(r, `{"max_wait":`)
+	assert.Equal(t, http.StatusBadRequest, w.Code, "expect malformed body to be rejected")
+	assert.Equal(t, Settings{MaxRunning: 1, MaxWaiting: 3, RetryAfter: 5}, rm.Settings(), "expect settings intact after failed update")
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, AdminPath, nil))
+	assert.JSONEq(t, `{"max_conn":1,"max_wait":3,"retry_after":5}`, w.Body.String(), "expect current settings")
+}
+
+func TestUpdateSettings_Adaptive(t *testing.T) {
+	rm := NewRateLimiter(10, 1, 1, WithRegisterer(prometheus.NewRegistry()), WithAdaptiveLimit(AdaptiveAIMD, 1, 100, 0))
+
+	_, err := rm.UpdateSettings(func(s *Settings) error {
+		s.MaxRunning = 20
+		return nil
+	})
+	assert.ErrorIs(t, err, rateLimiterErrors.ErrInvalidSettings, "expect adaptive limit to own max_conn")
+
+	s, err := rm.UpdateSettings(func(s *Settings) error {
+		s.MaxWaiting = 50
+		return nil
+	})
+	require.NoError(t, err, "expect other settings to be updated")
+	assert.Equal(t, int64(50), s.MaxWaiting, "expect updated max_wait")
+}
@@ -0,0 +1,325 @@
+package gin_rate_limiter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KennyMacCormik/otel/backend/pkg/cache"
+	"github.com/KennyMacCormik/otel/backend/pkg/cache/impl/http_storage"
+	cacheErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/cache"
+	rateLimiterErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/rate_limiter"
+	"github.com/KennyMacCormik/otel/backend/pkg/resp"
+)
+
+// Store keeps sliding window counters shared by limiter instances, see WithStore
+type Store interface {
+	// Add adds delta to the counter of key in the window and returns counters of the window and the previous one.
+	// Window is the window index since the Unix epoch, counters are needed for ttl at least.
+	Add(ctx context.Context, key string, window, delta int64, ttl time.Duration) (curr, prev int64, err error)
+	Close(ctx context.Context) error
+}
+
+// Increment is a single counter update, see Store.Add
+type Increment struct {
+	Key           string
+	Window, Delta int64
+	TTL           time.Duration
+}
+
+// Counts are counters of the window and the previous one returned for Increment
+type Counts struct {
+	Curr, Prev int64
+}
+
+// BatchStore is Store applying several increments in a single round trip
+type BatchStore interface {
+	Store
+	// AddBatch applies increments and returns their counts in order. On error none of the counts are returned,
+	// though some increments may have been applied.
+	AddBatch(ctx context.Context, increments []Increment) ([]Counts, error)
+}
+
+// addBatch applies increments with a single round trip if store is BatchStore, one by one otherwise.
+// Counts of the increments applied before a failure are returned along with the error.
+func addBatch(ctx context.Context, store Store, increments []Increment) ([]Counts, error) {
+	if bs, ok := store.(BatchStore); ok {
+		return bs.AddBatch(ctx, increments)
+	}
+
+	counts := make([]Counts, 0, len(increments))
+	for _, inc := range increments {
+		curr, prev, err := store.Add(ctx, inc.Key, inc.Window, inc.Delta, inc.TTL)
+		if err != nil {
+			return counts, err
+		}
+		counts = append(counts, Counts{Curr: curr, Prev: prev})
+	}
+
+	return counts, nil
+}
+
+// StoreKind selects Store implementation
+type StoreKind string
+
+const (
+	// StoreNone keeps limits per instance without Store
+	StoreNone StoreKind = "none"
+	// StoreLocal shares limits between instances in the process, it is also a stand-in for remote stores in tests
+	StoreLocal StoreKind = "local"
+	// StoreResp shares limits through a RESP endpoint, either Redis or the backend RESP server
+	StoreResp StoreKind = "resp"
+	// StoreStorage shares limits through the backend storage HTTP API
+	StoreStorage StoreKind = "storage"
+)
+
+// NewStore returns Store of the kind, it returns nil Store for StoreNone.
+// Endpoint is host:port for StoreResp and base URL for StoreStorage, prefix is prepended to every counter key.
+func NewStore(kind StoreKind, endpoint, prefix string, timeout time.Duration) (Store, error) {
+	const wrap = "NewStore"
+
+	switch kind {
+	case "", StoreNone:
+		return nil, nil
+	case StoreLocal:
+		return NewLocalStore(), nil
+	case StoreResp:
+		return NewRespStore(resp.NewClient(endpoint, timeout), prefix), nil
+	case StoreStorage:
+		impl, err := http_storage.NewHttpStorage(endpoint, http_storage.WithOverrideDefaults(timeout))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", wrap, err)
+		}
+		return NewCacheStore(impl, prefix), nil
+	default:
+		return nil, cacheErrors.NewErrInvalidValue(kind, rateLimiterErrors.ErrUnknownStore, wrap)
+	}
+}
+
+// LocalStore keeps counters in memory
+type LocalStore struct {
+	mtx       sync.Mutex
+	counters  map[string]*localCounter
+	lastSweep time.Time
+}
+
+type localCounter struct {
+	window, curr, prev int64
+	expires            time.Time
+}
+
+func NewLocalStore() *LocalStore {
+	return &LocalStore{counters: make(map[string]*localCounter)}
+}
+
+func (l *LocalStore) Add(_ context.Context, key string, window, delta int64, ttl time.Duration) (int64, int64, error) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	now := time.Now()
+	l.sweep(now, ttl)
+
+	c, ok := l.counters[key]
+	if !ok {
+		c = &localCounter{window: window}
+		l.counters[key] = c
+	}
+
+	switch {
+	case window == c.window+1:
+		c.prev, c.curr = c.curr, 0
+		c.window = window
+	case window > c.window+1:
+		c.prev, c.curr = 0, 0
+		c.window = window
+	case window == c.window-1:
+		// late add of a lagging instance
+		c.prev += delta
+		return c.prev, 0, nil
+	case window < c.window:
+		return 0, 0, nil
+	}
+
+	c.curr += delta
+	c.expires = now.Add(ttl)
+
+	return c.curr, c.prev, nil
+}
+
+// sweep removes expired counters at most once per ttl
+func (l *LocalStore) sweep(now time.Time, ttl time.Duration) {
+	if now.Sub(l.lastSweep) < ttl {
+		return
+	}
+	l.lastSweep = now
+
+	for key, c := range l.counters {
+		if now.After(c.expires) {
+			delete(l.counters, key)
+		}
+	}
+}
+
+func (l *LocalStore) Close(_ context.Context) error {
+	return nil
+}
+
+// RespStore keeps counters as keys of a RESP server, one key per window expiring after ttl.
+// Counters are updated atomically with INCRBY, increments of a batch are pipelined in a single round trip.
+type RespStore struct {
+	client *resp.Client
+	prefix string
+}
+
+func NewRespStore(client *resp.Client, prefix string) *RespStore {
+	return &RespStore{client: client, prefix: prefix}
+}
+
+func (r *RespStore) Add(ctx context.Context, key string, window, delta int64, ttl time.Duration) (int64, int64, error) {
+	counts, err := r.AddBatch(ctx, []Increment{{Key: key, Window: window, Delta: delta, TTL: ttl}})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return counts[0].Curr, counts[0].Prev, nil
+}
+
+// respIncrementCmds is the number of commands sent per increment
+const respIncrementCmds = 3
+
+func (r *RespStore) AddBatch(ctx context.Context, increments []Increment) ([]Counts, error) {
+	const wrap = "RespStore/AddBatch"
+
+	cmds := make([][]string, 0, len(increments)*respIncrementCmds)
+	for _, inc := range increments {
+		currKey := r.windowKey(inc.Key, inc.Window)
+		cmds = append(cmds,
+			[]string{"INCRBY", currKey, strconv.FormatInt(inc.Delta, 10)},
+			[]string{"PEXPIRE", currKey, strconv.FormatInt(inc.TTL.Milliseconds(), 10)},
+			[]string{"GET", r.windowKey(inc.Key, inc.Window-1)},
+		)
+	}
+
+	replies, err := r.client.Do(ctx, cmds...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", wrap, err)
+	}
+
+	for _, reply := range replies {
+		if replyErr, ok := reply.(resp.ReplyError); ok {
+			return nil, fmt.Errorf("%s: %w: %w", wrap, rateLimiterErrors.ErrUnexpectedReply, replyErr)
+		}
+	}
+
+	counts := make([]Counts, 0, len(increments))
+	for i := 0; i < len(replies); i += respIncrementCmds {
+		curr, ok := replies[i].(int64)
+		if !ok {
+			return nil, cacheErrors.NewErrInvalidValue(replies[i], rateLimiterErrors.ErrUnexpectedReply, wrap)
+		}
+
+		var prev int64
+		if s, ok := replies[i+2].(string); ok {
+			if prev, err = strconv.ParseInt(s, 10, 64); err != nil {
+				return nil, cacheErrors.NewErrInvalidValue(s, rateLimiterErrors.ErrUnexpectedReply, wrap)
+			}
+		}
+
+		counts = append(counts, Counts{Curr: curr, Prev: prev})
+	}
+
+	return counts, nil
+}
+
+func (r *RespStore) windowKey(key string, window int64) string {
+	return r.prefix + key + ":" + strconv.FormatInt(window, 10)
+}
+
+func (r *RespStore) Close(_ context.Context) error {
+	return r.client.Close()
+}
+
+// CacheStore keeps counters in cache.CacheInterface, e.g. the backend storage API.
+// Storage has no atomic increments and expirations, so counters are updated by read-modify-write
+// and stored in three rotating slots per key as "window:count". Concurrent updates of a key by several
+// instances may be lost, which makes limits looser the more instances share a key. It suits keyed limits
+// with many keys, global limits should use RespStore.
+type CacheStore struct {
+	impl   cache.CacheInterface
+	prefix string
+}
+
+const cacheStoreSlots = 3
+
+func NewCacheStore(impl cache.CacheInterface, prefix string) *CacheStore {
+	return &CacheStore{impl: impl, prefix: prefix}
+}
+
+func (s *CacheStore) Add(ctx context.Context, key string, window, delta int64, _ time.Duration) (int64, int64, error) {
+	const wrap = "CacheStore/Add"
+
+	curr, err := s.get(ctx, key, window)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%s: %w", wrap, err)
+	}
+
+	if delta != 0 {
+		curr += delta
+		value := strconv.FormatInt(window, 10) + ":" + strconv.FormatInt(curr, 10)
+		if _, err = s.impl.Set(ctx, s.slotKey(key, window), value); err != nil {
+			return 0, 0, fmt.Errorf("%s: %w", wrap, err)
+		}
+	}
+
+	prev, err := s.get(ctx, key, window-1)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%s: %w", wrap, err)
+	}
+
+	return curr, prev, nil
+}
+
+// get returns counter of the window, slot holding another window is treated as empty
+func (s *CacheStore) get(ctx context.Context, key string, window int64) (int64, error) {
+	val, err := s.impl.Get(ctx, s.slotKey(key, window))
+	if errors.Is(err, cacheErrors.ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	str, ok := val.(string)
+	if !ok {
+		return 0, cacheErrors.NewErrInvalidValue(val, cacheErrors.ErrMalformedData, "CacheStore/get")
+	}
+
+	w, count, ok := strings.Cut(str, ":")
+	if !ok || w != strconv.FormatInt(window, 10) {
+		return 0, nil
+	}
+
+	n, err := strconv.ParseInt(count, 10, 64)
+	if err != nil {
+		return 0, cacheErrors.NewErrInvalidValue(str, cacheErrors.ErrMalformedData, "CacheStore/get")
+	}
+
+	return n, nil
+}
+
+func (s *CacheStore) slotKey(key string, window int64) string {
+	slot := window % cacheStoreSlots
+	if slot < 0 {
+		slot += cacheStoreSlots
+	}
+
+	return s.prefix + key + ":" + strconv.FormatInt(slot, 10)
+}
+
+func (s *CacheStore) Close(ctx context.Context) error {
+	return s.impl.Close(ctx)
+}
@@ -0,0 +1,256 @@
+package gin_rate_limiter
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KennyMacCormik/otel/backend/pkg/cache/impl/sync_map"
+	"github.com/KennyMacCormik/otel/backend/pkg/resp"
+)
+
+func testStore(t *testing.T, store Store) {
+	ctx := context.Background()
+
+	curr, prev, err := store.Add(ctx, "k", 10, 3, time.Minute)
+	require.NoError(t, err, "expect counter to be added")
+	assert.Equal(t, int64(3), curr, "expect current counter")
+	assert.Equal(t, int64(0), prev, "expect empty previous counter")
+
+	curr, _, err = store.Add(ctx, "k", 10, 2, time.Minute)
+	require.NoError(t, err, "expect counter to be added")
+	assert.Equal(t, int64(5), curr, "expect counter to accumulate")
+
+	curr, prev, err = store.Add(ctx, "k", 11, 0, time.Minute)
+	require.NoError(t, err, "expect counter to be read")
+	assert.Equal(t, int64(0), curr, "expect empty next window")
+	assert.Equal(t, int64(5), prev, "expect previous window counter")
+
+	curr, prev, err = store.Add(ctx, "k", 13, 1, time.Minute)
+	require.NoError(t, err, "expect counter to be added")
+	assert.Equal(t, int64(1), curr, "expect stale window to be dropped")
+	assert.Equal(t, int64(0), prev, "expect stale previous window to be dropped")
+
+	curr, _, err = store.Add(ctx, "other", 13, 1, time.Minute)
+	require.NoError(t, err, "expect counter to be added")
+	assert.Equal(t, int64(1), curr, "expect keys to be counted separately")
+
+	assert.NoError(t, store.Close(ctx), "expect store to close")
+}
+
+func TestLocalStore(t *testing.T) {
+	testStore(t, NewLocalStore())
+}
+
+func TestCacheStore(t *testing.T) {
+	testStore(t, NewCacheStore(sync_map.NewSyncMapCache(), "rl:"))
+}
+
+// counterHandler is an in-process stand-in of a RESP server supporting commands used by RespStore
+func counterHandler() resp.HandlerFunc {
+	var mtx sync.Mutex
+	counters := map[string]int64{}
+
+	return func(_ context.Context, w *resp.Writer, args []string) {
+		mtx.Lock()
+		defer mtx.Unlock()
+
+		switch args[0] {
+		case "INCRBY":
+			n, _ := strconv.ParseInt(args[2], 10, 64)
+			counters[args[1]] += n
+			w.WriteInteger(counters[args[1]])
+		case "PEXPIRE":
+			w.WriteInteger(1)
+		case "GET":
+			n, ok := counters[args[1]]
+			if !ok {
+				w.WriteNull()
+				return
+			}
+			w.WriteBulkString(strconv.FormatInt(n, 10))
+		default:
+			w.WriteError("ERR unknown command")
+		}
+	}
+}
+
+func TestRespStore(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err, "expect free port")
+	endpoint := lis.Addr().String()
+	require.NoError(t, lis.Close(), "expect listener to close")
+
+	server := resp.NewServer(endpoint, counterHandler(), time.Minute)
+	go func() { _ = server.Start() }()
+	defer func() { _ = server.Close(5 * time.Second) }()
+
+	store, err := NewStore(StoreResp, endpoint, "rl:", time.Second)
+	require.NoError(t, err, "expect store to be created")
+
+	require.Eventually(t, func() bool {
+		_, _, err = store.Add(context.Background(), "probe", 1, 0, time.Minute)
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond, "expect server to accept connections")
+
+	counts, err := store.(BatchStore).AddBatch(context.Background(), []Increment{
+		{Key: "a", Window: 1, Delta: 2, TTL: time.Minute},
+		{Key: "b", Window: 1, Delta: 3, TTL: time.Minute},
+		{Key: "a", Window: 2, Delta: 1, TTL: time.Minute},
+	})
+	require.NoError(t, err, "expect batch to be added")
+	assert.Equal(t, []Counts{{Curr: 2}, {Curr: 3}, {Curr: 1, Prev: 2}}, counts, "expect counts in order")
+
+	testStore(t, store)
+}
+
+func TestNewStore(t *testing.T) {
+	store, err := NewStore(StoreNone, "", "", time.Second)
+	assert.NoError(t, err, "expect no error")
+	assert.Nil(t, store, "expect no store")
+
+	store, err = NewStore(StoreLocal, "", "", time.Second)
+	assert.NoError(t, err, "expect no error")
+	assert.IsType(t, &LocalStore{}, store, "expect local store")
+
+	_, err = NewStore("unknown", "", "", time.Second)
+	assert.Error(t, err, "expect unknown store to be rejected")
+}
+
+func waitSynced(t *testing.T, c *syncedCounter) {
+	require.Eventually(t, func() bool {
+		c.mtx.Lock()
+		defer c.mtx.Unlock()
+		return !c.queued && c.pending == 0 && c.inflight == 0
+	}, 5*time.Second, 5*time.Millisecond, "expect counter to be synced")
+}
+
+func TestSyncedCounter(t *testing.T) {
+	store := NewLocalStore()
+	noErr := func(err error) { t.Errorf("unexpected sync error: %v", err) }
+
+	// two instances sharing the store
+	syncA := newStoreSync(store, 5*time.Millisecond, time.Second, noErr)
+	syncB := newStoreSync(store, 5*time.Millisecond, time.Second, noErr)
+	defer func() { _ = syncA.close(context.Background()) }()
+	defer func() { _ = syncB.close(context.Background()) }()
+
+	a := newSyncedCounter("k", 10, time.Hour, syncA)
+	b := newSyncedCounter("k", 10, time.Hour, syncB)
+
+	for i := 0; i < 6; i++ {
+		require.True(t, a.allow(time.Now()).allowed, "expect request within limit to be allowed")
+	}
+	waitSynced(t, a)
+
+	require.True(t, b.allow(time.Now()).allowed, "expect request within limit to be allowed")
+	waitSynced(t, b)
+
+	for i := 0; i < 3; i++ {
+		require.True(t, b.allow(time.Now()).allowed, "expect request within global limit to be allowed")
+	}
+
+	d := b.allow(time.Now())
+	assert.False(t, d.allowed, "expect global limit to be enforced")
+	assert.Positive(t, d.retryAfter, "expect retry after")
+}
+
+type failingStore struct {
+	Store
+	fail atomic.Bool
+}
+
+func (f *failingStore) Add(ctx context.Context, key string, window, delta int64, ttl time.Duration) (int64, int64, error) {
+	if f.fail.Load() {
+		return 0, 0, errors.New("unavailable")
+	}
+	return f.Store.Add(ctx, key, window, delta, ttl)
+}
+
+func TestSyncedCounter_StoreErrors(t *testing.T) {
+	store := &failingStore{Store: NewLocalStore()}
+	store.fail.Store(true)
+
+	var errs atomic.Int64
+	s := newStoreSync(store, 5*time.Millisecond, time.Second, func(error) { errs.Add(1) })
+	c := newSyncedCounter("k", 10, time.Hour, s)
+
+	for i := 0; i < 3; i++ {
+		require.True(t, c.allow(time.Now()).allowed, "expect requests to be allowed while store is unavailable")
+	}
+
+	require.Eventually(t, func() bool { return errs.Load() > 1 }, 5*time.Second, 5*time.Millisecond, "expect failed sync to be retried")
+
+	store.fail.Store(false)
+	waitSynced(t, c)
+	require.NoError(t, s.close(context.Background()), "expect sync to close")
+
+	curr, _, err := store.Add(context.Background(), "k", time.Now().UnixNano()/int64(time.Hour), 0, time.Hour)
+	require.NoError(t, err, "expect counter to be read")
+	assert.Equal(t, int64(3), curr, "expect counts to be sent once store is available")
+}
+
+type closingStore struct {
+	Store
+	closed atomic.Bool
+}
+
+func (c *closingStore) Close(ctx context.Context) error {
+	c.closed.Store(true)
+	return c.Store.Close(ctx)
+}
+
+func TestRateLimiterClose_ConcurrencyMode(t *testing.T) {
+	store := &closingStore{Store: NewLocalStore()}
+	rm := NewRateLimiter(1, 1, 1, WithStore(store, time.Second, time.Second), WithRegisterer(prometheus.NewRegistry()))
+
+	require.NoError(t, rm.Close(context.Background()), "expect limiter to close")
+	assert.True(t, store.closed.Load(), "expect unused store to be closed")
+}
+
+type batchStore struct {
+	*LocalStore
+
+	mtx     sync.Mutex
+	batches []int
+}
+
+func (b *batchStore) AddBatch(ctx context.Context, increments []Increment) ([]Counts, error) {
+	b.mtx.Lock()
+	b.batches = append(b.batches, len(increments))
+	b.mtx.Unlock()
+
+	return addBatch(ctx, b.LocalStore, increments)
+}
+
+func TestStoreSync_Batch(t *testing.T) {
+	store := &batchStore{LocalStore: NewLocalStore()}
+	s := newStoreSync(store, 5*time.Millisecond, time.Second, func(err error) { t.Errorf("unexpected sync error: %v", err) })
+
+	counters := make([]*syncedCounter, maxSyncBatch+1)
+	for i := range counters {
+		counters[i] = newSyncedCounter(strconv.Itoa(i), 10, time.Hour, s)
+		require.True(t, counters[i].allow(time.Now()).allowed, "expect request within limit to be allowed")
+	}
+	require.NoError(t, s.close(context.Background()), "expect sync to close")
+
+	store.mtx.Lock()
+	defer store.mtx.Unlock()
+
+	total := 0
+	for _, n := range store.batches {
+		assert.LessOrEqual(t, n, maxSyncBatch, "expect batch to be bounded")
+		total += n
+	}
+	assert.Equal(t, len(counters), total, "expect every counter to be sent once by close")
+	assert.Less(t, len(store.batches), len(counters), "expect counters to be sent in batches")
+}
@@ -0,0 +1,88 @@
+package gin_tenant
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/KennyMacCormik/common/log"
+	"github.com/gin-gonic/gin"
+
+	"github.com/KennyMacCormik/otel/backend/pkg/gin/gin_request_id"
+	httpErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/http"
+	"github.com/KennyMacCormik/otel/backend/pkg/models/tenant"
+)
+
+const bearerPrefix = "Bearer "
+
+var unauthorizedBody = httpErrors.NewErrStatus(http.StatusUnauthorized, httpErrors.CodeUnauthorized, "").GetBody()
+
+// TenantMiddleware resolves tenant from API key in the header and puts it into request context,
+// see tenant.FromContext. Keys maps tenant to its API key, optional "Bearer " prefix of the header value is ignored.
+// Requests without the header belong to tenant.Default, requests with unknown key are rejected with 401.
+func TenantMiddleware(header string, keys map[string]string) gin.HandlerFunc {
+	resolver := tenant.NewResolver(keys)
+
+	return func(c *gin.Context) {
+		key := strings.TrimPrefix(c.GetHeader(header), bearerPrefix)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		t, ok := resolver.Resolve(key)
+		if !ok {
+			requestID, _ := gin_request_id.GetRequestIDFromCtx(c)
+			log.Warn("request rejected: unknown tenant key",
+				"requestID", requestID,
+				"path", c.FullPath(),
+			)
+
+			c.AbortWithStatusJSON(http.StatusUnauthorized, unauthorizedBody)
+			return
+		}
+
+		c.Request = c.Request.WithContext(tenant.NewContext(c.Request.Context(), t))
+		c.Next()
+	}
+}
+
+// ParseKeys parses comma separated list of tenant=key pairs. Both tenants and keys must be unique.
+//
+// Example:
+//
+// search=6f1c0a9e,billing=0b7d51c2
+func ParseKeys(s string) (map[string]string, error) {
+	keys := make(map[string]string)
+	seen := make(map[string]struct{})
+
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		t, key, ok := strings.Cut(entry, "=")
+		t, key = strings.TrimSpace(t), strings.TrimSpace(key)
+		if !ok || t == "" || key == "" {
+			return nil, fmt.Errorf("ParseKeys: invalid entry [%s]", t)
+		}
+
+		if t == tenant.Default {
+			return nil, fmt.Errorf("ParseKeys: tenant [%s] is reserved", t)
+		}
+
+		if _, ok = keys[t]; ok {
+			return nil, fmt.Errorf("ParseKeys: duplicate tenant [%s]", t)
+		}
+
+		if _, ok = seen[key]; ok {
+			return nil, fmt.Errorf("ParseKeys: duplicate key for tenant [%s]", t)
+		}
+
+		keys[t] = key
+		seen[key] = struct{}{}
+	}
+
+	return keys, nil
+}
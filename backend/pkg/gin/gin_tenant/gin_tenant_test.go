@@ -0,0 +1,66 @@
+package gin_tenant
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KennyMacCormik/otel/backend/pkg/models/tenant"
+)
+
+func serve(key string) (int, string) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	var got string
+	r.Use(TenantMiddleware("X-API-Key", map[string]string{"search": "search-key", "billing": "billing-key"}))
+	r.GET("/", func(c *gin.Context) {
+		got = tenant.FromContext(c.Request.Context())
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if key != "" {
+		req.Header.Set("X-API-Key", key)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	return w.Code, got
+}
+
+func TestTenantMiddleware(t *testing.T) {
+	code, got := serve("search-key")
+	assert.Equal(t, http.StatusOK, code, "expect known key to pass")
+	assert.Equal(t, "search", got, "expect tenant of the key")
+
+	code, got = serve("Bearer billing-key")
+	assert.Equal(t, http.StatusOK, code, "expect bearer prefix to be ignored")
+	assert.Equal(t, "billing", got, "expect tenant of the key")
+
+	code, got = serve("")
+	assert.Equal(t, http.StatusOK, code, "expect request without key to pass")
+	assert.Equal(t, tenant.Default, got, "expect default tenant without key")
+
+	code, _ = serve("unknown")
+	assert.Equal(t, http.StatusUnauthorized, code, "expect unknown key to be rejected")
+}
+
+func TestParseKeys(t *testing.T) {
+	keys, err := ParseKeys(" search=k1, billing=k2,")
+	require.NoError(t, err, "expect valid list to be parsed")
+	assert.Equal(t, map[string]string{"search": "k1", "billing": "k2"}, keys, "Unexpected keys")
+
+	keys, err = ParseKeys("")
+	require.NoError(t, err, "expect empty list to be parsed")
+	assert.Empty(t, keys, "expect no keys")
+
+	for _, s := range []string{"search", "search=", "=k1", "default=k1", "search=k1,search=k2", "search=k1,billing=k1"} {
+		_, err = ParseKeys(s)
+		assert.Error(t, err, "expect error for [%s]", s)
+	}
+}
@@ -0,0 +1,60 @@
+package grpc_server
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+)
+
+type GrpcServer struct {
+	endpoint string
+	svr      *grpc.Server
+}
+
+// NewGrpcServer returns gRPC server instrumented with OTel stats handler.
+// register is called to register services before the server starts.
+func NewGrpcServer(endpoint string, register func(grpc.ServiceRegistrar), opts ...grpc.ServerOption) *GrpcServer {
+	opts = append([]grpc.ServerOption{grpc.StatsHandler(otelgrpc.NewServerHandler())}, opts...)
+
+	s := &GrpcServer{
+		endpoint: endpoint,
+		svr:      grpc.NewServer(opts...),
+	}
+
+	register(s.svr)
+
+	return s
+}
+
+// Start listens on the endpoint and serves until Close. It returns nil after Close.
+func (s *GrpcServer) Start() error {
+	lis, err := net.Listen("tcp", s.endpoint)
+	if err != nil {
+		return err
+	}
+
+	return s.svr.Serve(lis)
+}
+
+// Close waits for pending RPCs to finish. Once t passes, the remaining RPCs and streams are cancelled.
+func (s *GrpcServer) Close(t time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), t)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		s.svr.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.svr.Stop()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,69 @@
+package grpc_server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/KennyMacCormik/otel/backend/pkg/test_helpers"
+)
+
+func TestGrpcServer_StartAndClose(t *testing.T) {
+	endpoint := test_helpers.GetFreeEndpoint(t)
+
+	server := NewGrpcServer(endpoint, func(r grpc.ServiceRegistrar) {
+		grpc_health_v1.RegisterHealthServer(r, health.NewServer())
+	})
+
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- server.Start()
+	}()
+
+	conn, err := grpc.NewClient(endpoint, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err, "expect no error creating client")
+	defer func() { _ = conn.Close() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resp, err := grpc_health_v1.NewHealthClient(conn).Check(ctx, &grpc_health_v1.HealthCheckRequest{}, grpc.WaitForReady(true))
+	require.NoError(t, err, "RPC should not return an error")
+	assert.Equal(t, grpc_health_v1.HealthCheckResponse_SERVING, resp.GetStatus(), "expect registered service to respond")
+
+	assert.NoError(t, server.Close(5*time.Second), "Server should close without errors")
+	assert.NoError(t, <-stopped, "Serve should return nil after graceful stop")
+}
+
+func TestGrpcServer_CloseTimeout(t *testing.T) {
+	endpoint := test_helpers.GetFreeEndpoint(t)
+
+	server := NewGrpcServer(endpoint, func(r grpc.ServiceRegistrar) {
+		grpc_health_v1.RegisterHealthServer(r, health.NewServer())
+	})
+
+	go func() { _ = server.Start() }()
+
+	conn, err := grpc.NewClient(endpoint, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err, "expect no error creating client")
+	defer func() { _ = conn.Close() }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Watch stream is never finished by the health server
+	stream, err := grpc_health_v1.NewHealthClient(conn).Watch(ctx, &grpc_health_v1.HealthCheckRequest{}, grpc.WaitForReady(true))
+	require.NoError(t, err, "expect stream to open")
+	_, err = stream.Recv()
+	require.NoError(t, err, "expect initial status")
+
+	err = server.Close(100 * time.Millisecond)
+	assert.ErrorIs(t, err, context.DeadlineExceeded, "expect Close to time out with open stream")
+}
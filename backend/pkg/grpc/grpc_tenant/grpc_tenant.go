@@ -0,0 +1,86 @@
+package grpc_tenant
+
+import (
+	"context"
+	"strings"
+
+	"github.com/KennyMacCormik/common/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	grpcErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/grpc"
+	httpErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/http"
+	"github.com/KennyMacCormik/otel/backend/pkg/models/tenant"
+)
+
+const bearerPrefix = "Bearer "
+
+// UnaryServerInterceptor resolves tenant from API key in the metadata the same way as gin_tenant.TenantMiddleware
+// resolves it from the header. RPCs without the key belong to tenant.Default, RPCs with unknown key fail with
+// codes.Unauthenticated.
+func UnaryServerInterceptor(header string, keys map[string]string) grpc.UnaryServerInterceptor {
+	resolve := newResolveFunc(header, keys)
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := resolve(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor resolves tenant of streams, see UnaryServerInterceptor
+func StreamServerInterceptor(header string, keys map[string]string) grpc.StreamServerInterceptor {
+	resolve := newResolveFunc(header, keys)
+
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := resolve(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &tenantStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// newResolveFunc returns func putting tenant resolved from the metadata into ctx
+func newResolveFunc(header string, keys map[string]string) func(ctx context.Context, method string) (context.Context, error) {
+	resolver := tenant.NewResolver(keys)
+	// metadata keys are lowercase
+	header = strings.ToLower(header)
+
+	return func(ctx context.Context, method string) (context.Context, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+
+		values := md.Get(header)
+		if len(values) == 0 {
+			return ctx, nil
+		}
+
+		key := strings.TrimPrefix(values[0], bearerPrefix)
+		if key == "" {
+			return ctx, nil
+		}
+
+		t, ok := resolver.Resolve(key)
+		if !ok {
+			log.Warn("request rejected: unknown tenant key", "method", method)
+			return nil, grpcErrors.ToStatus(httpErrors.ErrUnauthorized)
+		}
+
+		return tenant.NewContext(ctx, t), nil
+	}
+}
+
+// tenantStream carries context with the resolved tenant
+type tenantStream struct {
+	grpc.ServerStream
+
+	ctx context.Context
+}
+
+func (s *tenantStream) Context() context.Context {
+	return s.ctx
+}
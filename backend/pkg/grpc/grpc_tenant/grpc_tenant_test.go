@@ -0,0 +1,90 @@
+package grpc_tenant
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/KennyMacCormik/otel/backend/pkg/models/tenant"
+)
+
+var testKeys = map[string]string{"search": "search-key", "billing": "billing-key"}
+
+func incomingCtx(key string) context.Context {
+	if key == "" {
+		return context.Background()
+	}
+
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-api-key", key))
+}
+
+type testStream struct {
+	grpc.ServerStream
+
+	ctx context.Context
+}
+
+func (s *testStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	interceptor := UnaryServerInterceptor("X-API-Key", testKeys)
+
+	call := func(key string) (string, error) {
+		var got string
+		_, err := interceptor(incomingCtx(key), nil, &grpc.UnaryServerInfo{FullMethod: "/test"},
+			func(ctx context.Context, _ any) (any, error) {
+				got = tenant.FromContext(ctx)
+				return nil, nil
+			})
+
+		return got, err
+	}
+
+	got, err := call("search-key")
+	assert.NoError(t, err, "expect known key to pass")
+	assert.Equal(t, "search", got, "expect tenant of the key")
+
+	got, err = call("Bearer billing-key")
+	assert.NoError(t, err, "expect bearer prefix to be ignored")
+	assert.Equal(t, "billing", got, "expect tenant of the key")
+
+	got, err = call("")
+	assert.NoError(t, err, "expect RPC without key to pass")
+	assert.Equal(t, tenant.Default, got, "expect default tenant without key")
+
+	_, err = call("unknown")
+	assert.Equal(t, codes.Unauthenticated, status.Code(err), "expect unknown key to be rejected")
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	interceptor := StreamServerInterceptor("X-API-Key", testKeys)
+
+	call := func(key string) (string, error) {
+		var got string
+		err := interceptor(nil, &testStream{ctx: incomingCtx(key)}, &grpc.StreamServerInfo{FullMethod: "/test"},
+			func(_ any, ss grpc.ServerStream) error {
+				got = tenant.FromContext(ss.Context())
+				return nil
+			})
+
+		return got, err
+	}
+
+	got, err := call("search-key")
+	assert.NoError(t, err, "expect known key to pass")
+	assert.Equal(t, "search", got, "expect tenant of the key in stream context")
+
+	got, err = call("")
+	assert.NoError(t, err, "expect stream without key to pass")
+	assert.Equal(t, tenant.Default, got, "expect default tenant without key")
+
+	_, err = call("unknown")
+	assert.Equal(t, codes.Unauthenticated, status.Code(err), "expect unknown key to be rejected")
+}
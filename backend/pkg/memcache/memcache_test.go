@@ -0,0 +1,71 @@
+package memcache
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	memcacheErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/memcache"
+)
+
+func TestReader_ReadRequest(t *testing.T) {
+	r := NewReader(strings.NewReader("SET key 5 0 4 noreply\r\nv\r\nl\r\nget a b\r\n\r\ndelete a noreply\r\n"))
+
+	req, err := r.ReadRequest()
+	require.NoError(t, err, "expect storage request")
+	assert.Equal(t, "set", req.Command, "expect lowercase command")
+	assert.Equal(t, []string{"key", "5", "0", "4"}, req.Args, "expect args without noreply")
+	assert.Equal(t, "v\r\nl", req.Data, "expect binary safe data block")
+	assert.True(t, req.NoReply, "expect noreply")
+	assert.True(t, r.Buffered(), "expect pipelined requests to be buffered")
+
+	req, err = r.ReadRequest()
+	require.NoError(t, err, "expect retrieval request")
+	assert.Equal(t, []string{"a", "b"}, req.Args, "expect keys")
+	assert.False(t, req.NoReply, "expect reply")
+
+	req, err = r.ReadRequest()
+	require.NoError(t, err, "expect empty lines to be skipped")
+	assert.Equal(t, "delete", req.Command, "expect delete")
+	assert.True(t, req.NoReply, "expect noreply")
+
+	_, err = r.ReadRequest()
+	assert.ErrorIs(t, err, io.EOF, "expect EOF once input is consumed")
+}
+
+func TestReader_ReadRequest_Malformed(t *testing.T) {
+	_, err := NewReader(strings.NewReader("set a 0 0\r\nab\r\n")).ReadRequest()
+	assert.ErrorIs(t, err, memcacheErrors.ErrBadDataChunk, "expect missing length to be rejected")
+
+	_, err = NewReader(strings.NewReader("set a 0 0 x\r\nab\r\n")).ReadRequest()
+	assert.ErrorIs(t, err, memcacheErrors.ErrBadDataChunk, "expect invalid length to be rejected")
+
+	r := NewReader(strings.NewReader("set a 0 0 2000000\r\n" + strings.Repeat("x", 2000000) + "\r\nget a\r\n"))
+	_, err = r.ReadRequest()
+	assert.ErrorIs(t, err, memcacheErrors.ErrTooLarge, "expect large value to be rejected")
+
+	req, err := r.ReadRequest()
+	require.NoError(t, err, "expect large value to be skipped")
+	assert.Equal(t, "get", req.Command, "expect the next request")
+
+	_, err = NewReader(strings.NewReader("set a 0 0 1\r\nab\r\n")).ReadRequest()
+	assert.ErrorIs(t, err, memcacheErrors.ErrBadDataChunk, "expect data block of wrong length to be rejected")
+}
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+
+	w.WriteValue("a", 3, "xy", 7, false)
+	w.WriteValue("b", 0, "", 8, true)
+	w.WriteLine("END")
+	w.discard = true
+	w.WriteLine("STORED")
+	require.NoError(t, w.Flush(), "expect flush to succeed")
+
+	assert.Equal(t, "VALUE a 3 2\r\nxy\r\nVALUE b 0 0 8\r\n\r\nEND\r\n", buf.String(), "expect replies")
+}
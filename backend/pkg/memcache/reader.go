@@ -0,0 +1,124 @@
+package memcache
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	memcacheErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/memcache"
+)
+
+const (
+	maxLineLen = 2048
+	// MaxValueSize is the default item size limit of memcached
+	MaxValueSize = 1024 * 1024
+)
+
+// storageCommands are followed by data block, their length argument index is given
+var storageCommands = map[string]int{
+	"set":     3,
+	"add":     3,
+	"replace": 3,
+	"append":  3,
+	"prepend": 3,
+	"cas":     3,
+}
+
+// noReplyCommands accept noreply as the last argument
+var noReplyCommands = map[string]struct{}{
+	"set": {}, "add": {}, "replace": {}, "append": {}, "prepend": {}, "cas": {},
+	"delete": {}, "incr": {}, "decr": {}, "touch": {},
+}
+
+// Request is a single text protocol command
+type Request struct {
+	// Command is lowercase command name
+	Command string
+	// Args doesn't include the command name and noreply
+	Args []string
+	// Data is the data block of storage commands
+	Data    string
+	NoReply bool
+}
+
+// Reader reads requests sent by clients
+type Reader struct {
+	r *bufio.Reader
+}
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReaderSize(r, maxLineLen)}
+}
+
+// Buffered reports whether the next request is already received, so replies of pipelined requests can be flushed at once
+func (r *Reader) Buffered() bool {
+	return r.r.Buffered() > 0
+}
+
+// ReadRequest reads the next request. The connection can still be used after memcacheErrors.ErrTooLarge,
+// as the request is consumed. Other errors are fatal, a storage command without valid data length
+// returns memcacheErrors.ErrBadDataChunk, as the end of its data block is unknown.
+func (r *Reader) ReadRequest() (*Request, error) {
+	for {
+		line, err := r.r.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			return nil, fmt.Errorf("%w: line too long", memcacheErrors.ErrBadDataChunk)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		fields := strings.Fields(string(line))
+		if len(fields) == 0 {
+			continue
+		}
+
+		req := &Request{Command: strings.ToLower(fields[0]), Args: fields[1:]}
+
+		if _, ok := noReplyCommands[req.Command]; ok && len(req.Args) > 0 && req.Args[len(req.Args)-1] == "noreply" {
+			req.NoReply = true
+			req.Args = req.Args[:len(req.Args)-1]
+		}
+
+		if i, ok := storageCommands[req.Command]; ok {
+			if err = r.readData(req, i); err != nil {
+				return req, err
+			}
+		}
+
+		return req, nil
+	}
+}
+
+func (r *Reader) readData(req *Request, lenIdx int) error {
+	if len(req.Args) <= lenIdx {
+		return fmt.Errorf("%w: missing data length", memcacheErrors.ErrBadDataChunk)
+	}
+
+	n, err := strconv.Atoi(req.Args[lenIdx])
+	if err != nil || n < 0 {
+		return fmt.Errorf("%w: invalid data length", memcacheErrors.ErrBadDataChunk)
+	}
+
+	if n > MaxValueSize {
+		if _, err = io.CopyN(io.Discard, r.r, int64(n)+2); err != nil {
+			return err
+		}
+		return memcacheErrors.ErrTooLarge
+	}
+
+	buf := make([]byte, n+2)
+	if _, err = io.ReadFull(r.r, buf); err != nil {
+		return err
+	}
+
+	if buf[n] != '\r' || buf[n+1] != '\n' {
+		return memcacheErrors.ErrBadDataChunk
+	}
+
+	req.Data = string(buf[:n])
+
+	return nil
+}
@@ -0,0 +1,67 @@
+package memcache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	memcacheErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/memcache"
+	"github.com/KennyMacCormik/otel/backend/pkg/tcp/tcp_server"
+)
+
+// Version is reported by the version command
+const Version = "1.6.0"
+
+// HandlerFunc handles single request. Reply must be written to w, it is discarded for noreply requests
+// and flushed by the server.
+type HandlerFunc func(ctx context.Context, w *Writer, req *Request)
+
+// Server serves memcached text protocol clients over TCP. Commands version and quit are handled by the server,
+// all others are passed to the handler. Requests of a single connection are handled sequentially.
+type Server struct {
+	*tcp_server.TcpServer
+
+	handler HandlerFunc
+}
+
+// NewServer returns server listening on endpoint. Connections idle for more than idleTimeout are closed.
+func NewServer(endpoint string, handler HandlerFunc, idleTimeout time.Duration) *Server {
+	s := &Server{handler: handler}
+	s.TcpServer = tcp_server.NewTcpServer(endpoint, s.serve, idleTimeout)
+
+	return s
+}
+
+func (s *Server) serve(ctx context.Context, conn *tcp_server.Conn) {
+	r, w := NewReader(conn), NewWriter(conn)
+
+	for conn.Wait() {
+		req, err := r.ReadRequest()
+		w.discard = false
+
+		switch {
+		case errors.Is(err, memcacheErrors.ErrTooLarge):
+			w.WriteLine("SERVER_ERROR " + memcacheErrors.ErrTooLarge.Error())
+		case errors.Is(err, memcacheErrors.ErrBadDataChunk):
+			w.WriteLine("CLIENT_ERROR " + err.Error())
+			_ = w.Flush()
+			return
+		case err != nil:
+			return
+		case req.Command == "quit":
+			_ = w.Flush()
+			return
+		case req.Command == "version":
+			w.WriteLine("VERSION " + Version)
+		default:
+			w.discard = req.NoReply
+			s.handler(ctx, w, req)
+		}
+
+		if !r.Buffered() {
+			if err = w.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package memcache
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KennyMacCormik/otel/backend/pkg/test_helpers"
+)
+
+func TestServer_Requests(t *testing.T) {
+	endpoint := test_helpers.GetFreeEndpoint(t)
+	server := NewServer(endpoint, func(_ context.Context, w *Writer, req *Request) {
+		if req.Command != "set" {
+			w.WriteLine("ERROR")
+			return
+		}
+		w.WriteLine("STORED")
+	}, time.Minute)
+
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- server.Start()
+	}()
+
+	var conn net.Conn
+	var err error
+	require.Eventually(t, func() bool {
+		conn, err = net.Dial("tcp", endpoint)
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond, "expect server to accept connections")
+	defer func() { _ = conn.Close() }()
+	require.NoError(t, conn.SetDeadline(time.Now().Add(5*time.Second)), "expect deadline to be set")
+	r := bufio.NewReader(conn)
+
+	_, err = conn.Write([]byte("set a 0 0 1 noreply\r\nx\r\nset a 0 0 1\r\nx\r\nfoo\r\nversion\r\nquit\r\n"))
+	require.NoError(t, err, "expect requests to be sent")
+
+	for _, expected := range []string{"STORED", "ERROR", "VERSION " + Version} {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err, "expect reply")
+		assert.Equal(t, expected+"\r\n", line, "expect reply in order, noreply request is not answered")
+	}
+
+	_, err = r.ReadString('\n')
+	assert.Error(t, err, "expect quit to close connection")
+
+	malformed, err := net.Dial("tcp", endpoint)
+	require.NoError(t, err, "expect server to accept connections")
+	defer func() { _ = malformed.Close() }()
+	require.NoError(t, malformed.SetDeadline(time.Now().Add(5*time.Second)), "expect deadline to be set")
+	r = bufio.NewReader(malformed)
+
+	_, err = malformed.Write([]byte("set a 0 0\r\nx\r\nversion\r\n"))
+	require.NoError(t, err, "expect requests to be sent")
+
+	line, err := r.ReadString('\n')
+	require.NoError(t, err, "expect reply")
+	assert.Equal(t, "CLIENT_ERROR bad data chunk: missing data length\r\n", line, "expect data length to be required")
+
+	_, err = r.ReadString('\n')
+	assert.Error(t, err, "expect storage command without data length to close connection")
+
+	assert.NoError(t, server.Close(5*time.Second), "Server should close without errors")
+	assert.NoError(t, <-stopped, "Start should return nil after Close")
+}
@@ -0,0 +1,52 @@
+package memcache
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+)
+
+// Writer writes replies, which are buffered until Flush
+type Writer struct {
+	w       *bufio.Writer
+	discard bool
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: bufio.NewWriter(w)}
+}
+
+// WriteLine writes single line reply, e.g. STORED
+func (w *Writer) WriteLine(s string) {
+	if w.discard {
+		return
+	}
+
+	_, _ = w.w.WriteString(s)
+	_, _ = w.w.WriteString("\r\n")
+}
+
+// WriteValue writes single item of get or gets reply, cas unique is only written by gets
+func (w *Writer) WriteValue(key string, flags uint32, data string, cas uint64, withCas bool) {
+	if w.discard {
+		return
+	}
+
+	_, _ = w.w.WriteString("VALUE ")
+	_, _ = w.w.WriteString(key)
+	_, _ = w.w.WriteString(" ")
+	_, _ = w.w.WriteString(strconv.FormatUint(uint64(flags), 10))
+	_, _ = w.w.WriteString(" ")
+	_, _ = w.w.WriteString(strconv.Itoa(len(data)))
+	if withCas {
+		_, _ = w.w.WriteString(" ")
+		_, _ = w.w.WriteString(strconv.FormatUint(cas, 10))
+	}
+	_, _ = w.w.WriteString("\r\n")
+	_, _ = w.w.WriteString(data)
+	_, _ = w.w.WriteString("\r\n")
+}
+
+func (w *Writer) Flush() error {
+	return w.w.Flush()
+}
@@ -0,0 +1,18 @@
+package compressed_cache
+
+type Stats struct {
+	CompressedWrites   int64
+	UncompressedWrites int64
+	// OriginalBytes and CompressedBytes account compressed writes only
+	OriginalBytes   int64
+	CompressedBytes int64
+}
+
+// Ratio returns CompressedBytes to OriginalBytes ratio. It equals 1 if nothing was compressed yet.
+func (s Stats) Ratio() float64 {
+	if s.OriginalBytes == 0 {
+		return 1
+	}
+
+	return float64(s.CompressedBytes) / float64(s.OriginalBytes)
+}
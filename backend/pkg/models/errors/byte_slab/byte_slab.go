@@ -0,0 +1,6 @@
+package byte_slab
+
+import "errors"
+
+var ErrEntryTooLarge = errors.New("entry exceeds shard capacity")
+var ErrKeyTooLong = errors.New("key exceeds maximum length")
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"time"
 )
 
 var ErrNilFunc = errors.New("nil function")
@@ -15,6 +16,11 @@ var ErrCacheClosed = errors.New("cache closed")
 var ErrNotFound = errors.New("not found")
 var ErrNilCtx = errors.New("nil context")
 var ErrTypeCast = errors.New("internal type cast error")
+var ErrUnsupportedType = errors.New("unsupported value type")
+var ErrMalformedData = errors.New("malformed serialized data")
+var ErrWriteRateLimited = errors.New("key write rate limit exceeded")
+var ErrValueTooLarge = errors.New("value too large")
+var ErrQuotaExceeded = errors.New("tenant quota exceeded")
 
 type ErrTypeCastFailed struct {
 	key           any
@@ -146,6 +152,39 @@ func (e *ErrKeyNotFound) GetKey() string {
 	return e.key
 }
 
+type ErrWriteLimited struct {
+	key        string
+	retryAfter time.Duration
+	err        error
+}
+
+func NewErrWriteLimited(key string, retryAfter time.Duration) *ErrWriteLimited {
+	return &ErrWriteLimited{key: key, retryAfter: retryAfter, err: ErrWriteRateLimited}
+}
+
+func (e *ErrWriteLimited) Error() string {
+	return fmt.Errorf("key %s: %w: retry after %s", e.key, e.err, e.retryAfter).Error()
+}
+
+// Is function only checks for an ErrWriteLimited type and don't compare for an underlying key
+func (e *ErrWriteLimited) Is(target error) bool {
+	_, ok := target.(*ErrWriteLimited)
+	return ok
+}
+
+func (e *ErrWriteLimited) Unwrap() error {
+	return e.err
+}
+
+func (e *ErrWriteLimited) GetKey() string {
+	return e.key
+}
+
+// GetRetryAfter returns time until the key may be written again
+func (e *ErrWriteLimited) GetRetryAfter() time.Duration {
+	return e.retryAfter
+}
+
 type ErrCtx struct {
 	callerInfo string
 	ctxErr     error
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -236,6 +237,19 @@ func TestErrKeyNotFound_Is(t *testing.T) {
 	assert.True(t, err.Is(target), "Is should return true for ErrKeyNotFound type")
 }
 
+func TestNewErrWriteLimited(t *testing.T) {
+	key := "hotKey"
+
+	err := NewErrWriteLimited(key, time.Second)
+
+	require.NotNil(t, err, "NewErrWriteLimited should return a non-nil error")
+	assert.Equal(t, key, err.GetKey(), "Key should match the input")
+	assert.Equal(t, time.Second, err.GetRetryAfter(), "RetryAfter should match the input")
+	assert.ErrorIs(t, err, ErrWriteRateLimited, "Error should wrap ErrWriteRateLimited")
+	assert.ErrorIs(t, fmt.Errorf("set: %w", err), &ErrWriteLimited{}, "Is should return true for ErrWriteLimited type")
+	assert.Contains(t, err.Error(), key, "Error message should include the key")
+}
+
 func TestNewErrNilOrErrCtx(t *testing.T) {
 	const callerInfo = "TestCaller"
 
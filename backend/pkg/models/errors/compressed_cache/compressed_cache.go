@@ -0,0 +1,5 @@
+package compressed_cache
+
+import "errors"
+
+var ErrUnknownAlgorithm = errors.New("unknown compression algorithm")
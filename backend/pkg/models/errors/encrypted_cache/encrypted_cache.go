@@ -0,0 +1,8 @@
+package encrypted_cache
+
+import "errors"
+
+var ErrEmptyKeyring = errors.New("keyring has no keys")
+var ErrInvalidKey = errors.New("invalid encryption key")
+var ErrUnknownKeyID = errors.New("unknown key id")
+var ErrDecryptionFailed = errors.New("decryption failed")
@@ -0,0 +1,91 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	httpErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/http"
+)
+
+// errorDomain is the domain of errdetails.ErrorInfo, its reason is the httpErrors code
+const errorDomain = "otel.backend"
+
+// ToStatus converts err to gRPC status error. Code and message follow httpErrors.FromError,
+// so both transports report the same errors. httpErrors code is attached as errdetails.ErrorInfo,
+// since several codes share a gRPC code.
+func ToStatus(err error) error {
+	errStatus := httpErrors.FromError(err)
+
+	var code codes.Code
+	switch errStatus.GetCode() {
+	case httpErrors.CodeBadRequest:
+		code = codes.InvalidArgument
+	case httpErrors.CodeNotFound:
+		code = codes.NotFound
+	case httpErrors.CodeUnauthorized:
+		code = codes.Unauthenticated
+	case httpErrors.CodeRateLimited, httpErrors.CodeWriteLimited, httpErrors.CodeQuotaExceeded:
+		code = codes.ResourceExhausted
+	case httpErrors.CodeUnavailable:
+		code = codes.Unavailable
+	case httpErrors.CodeTimeout:
+		code = codes.DeadlineExceeded
+	default:
+		code = codes.Internal
+	}
+
+	st := status.New(code, errStatus.GetMessage())
+	if withInfo, err := st.WithDetails(&errdetails.ErrorInfo{Reason: errStatus.GetCode(), Domain: errorDomain}); err == nil {
+		st = withInfo
+	}
+
+	return st.Err()
+}
+
+// FromStatus converts gRPC status error to httpErrors.ErrStatus, so callers handle both transports alike.
+// httpErrors code is taken from errdetails.ErrorInfo if the status has one.
+// Cancellation is returned as context.Canceled, errors without status are returned as is.
+func FromStatus(err error) error {
+	st, ok := status.FromError(err)
+	if !ok || err == nil {
+		return err
+	}
+
+	var httpStatus int
+	switch st.Code() {
+	case codes.Canceled:
+		return fmt.Errorf("%w: %s", context.Canceled, st.Message())
+	case codes.InvalidArgument:
+		httpStatus = http.StatusBadRequest
+	case codes.NotFound:
+		httpStatus = http.StatusNotFound
+	case codes.Unauthenticated:
+		httpStatus = http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		httpStatus = http.StatusTooManyRequests
+	case codes.Unavailable:
+		httpStatus = http.StatusServiceUnavailable
+	case codes.DeadlineExceeded:
+		httpStatus = http.StatusGatewayTimeout
+	default:
+		httpStatus = http.StatusInternalServerError
+	}
+
+	return httpErrors.NewErrStatus(httpStatus, errorCode(st), st.Message())
+}
+
+// errorCode returns httpErrors code attached by ToStatus, empty code is derived from the status
+func errorCode(st *status.Status) string {
+	for _, d := range st.Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok && info.GetDomain() == errorDomain {
+			return info.GetReason()
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,99 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	cacheErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/cache"
+	httpErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/http"
+)
+
+func TestToStatus(t *testing.T) {
+	testCases := []struct {
+		name    string
+		err     error
+		code    codes.Code
+		message string
+	}{
+		{"not found", fmt.Errorf("get: %w", cacheErrors.ErrNotFound), codes.NotFound, "not found"},
+		{"bad request", fmt.Errorf("%w: no key provided", httpErrors.ErrBadRequest), codes.InvalidArgument, "bad request: no key provided"},
+		{"unauthorized", httpErrors.ErrUnauthorized, codes.Unauthenticated, "unauthorized"},
+		{"rate limited", httpErrors.ErrRateLimited, codes.ResourceExhausted, "too many requests"},
+		{"quota exceeded", cacheErrors.ErrQuotaExceeded, codes.ResourceExhausted, "tenant quota exceeded"},
+		{"cache closed", cacheErrors.ErrCacheClosed, codes.Unavailable, "service unavailable"},
+		{"deadline", context.DeadlineExceeded, codes.DeadlineExceeded, "gateway timeout"},
+		{"other", errors.New("secret details"), codes.Internal, "internal server error"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			st, ok := status.FromError(ToStatus(tc.err))
+
+			assert.True(t, ok, "expect gRPC status error")
+			assert.Equal(t, tc.code, st.Code(), "Unexpected code")
+			assert.Equal(t, tc.message, st.Message(), "Unexpected message")
+		})
+	}
+}
+
+func TestStatusRoundTrip(t *testing.T) {
+	testCases := []struct {
+		name     string
+		err      error
+		code     string
+		sentinel error
+	}{
+		{"unauthorized", httpErrors.ErrUnauthorized, httpErrors.CodeUnauthorized, httpErrors.ErrUnauthorized},
+		{"rate limited", httpErrors.ErrRateLimited, httpErrors.CodeRateLimited, httpErrors.ErrRateLimited},
+		{"write limited", cacheErrors.NewErrWriteLimited("key", time.Second), httpErrors.CodeWriteLimited, cacheErrors.ErrWriteRateLimited},
+		{"quota exceeded", cacheErrors.ErrQuotaExceeded, httpErrors.CodeQuotaExceeded, cacheErrors.ErrQuotaExceeded},
+		{"not found", cacheErrors.ErrNotFound, httpErrors.CodeNotFound, cacheErrors.ErrNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := FromStatus(ToStatus(tc.err))
+
+			var errStatus *httpErrors.ErrStatus
+			assert.ErrorAs(t, err, &errStatus, "expect ErrStatus")
+			assert.Equal(t, tc.code, errStatus.GetCode(), "expect code to survive the transport")
+			assert.ErrorIs(t, err, tc.sentinel, "Unexpected error")
+		})
+	}
+}
+
+func TestFromStatus(t *testing.T) {
+	testCases := []struct {
+		name     string
+		err      error
+		sentinel error
+	}{
+		{"not found", status.Error(codes.NotFound, "not found"), cacheErrors.ErrNotFound},
+		{"invalid argument", status.Error(codes.InvalidArgument, "no key provided"), httpErrors.ErrBadRequest},
+		{"unauthenticated", status.Error(codes.Unauthenticated, "unauthorized"), httpErrors.ErrUnauthorized},
+		{"resource exhausted", status.Error(codes.ResourceExhausted, "too many requests"), httpErrors.ErrRateLimited},
+		{"unavailable", status.Error(codes.Unavailable, "connection refused"), httpErrors.ErrUnavailable},
+		{"deadline exceeded", status.Error(codes.DeadlineExceeded, "deadline exceeded"), httpErrors.ErrTimeout},
+		{"internal", status.Error(codes.Unknown, "boom"), httpErrors.ErrInternal},
+		{"canceled", status.Error(codes.Canceled, "canceled"), context.Canceled},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.ErrorIs(t, FromStatus(tc.err), tc.sentinel, "Unexpected error")
+		})
+	}
+
+	t.Run("not a status", func(t *testing.T) {
+		err := errors.New("boom")
+		assert.Equal(t, err, FromStatus(err), "expect error without status to be returned as is")
+		assert.NoError(t, FromStatus(nil), "expect nil for nil error")
+	})
+}
@@ -0,0 +1,170 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	netHttp "net/http"
+	"strings"
+
+	cacheErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/cache"
+	httpModels "github.com/KennyMacCormik/otel/backend/pkg/models/http"
+)
+
+// Error codes of httpModels.ErrorBody
+const (
+	CodeBadRequest    = "bad_request"
+	CodeNotFound      = "not_found"
+	CodeUnauthorized  = "unauthorized"
+	CodeRateLimited   = "rate_limited"
+	CodeWriteLimited  = "write_limited"
+	CodeQuotaExceeded = "quota_exceeded"
+	CodeInternal      = "internal"
+	CodeUnavailable   = "unavailable"
+	CodeTimeout       = "timeout"
+	CodeUnknown       = "unknown"
+)
+
+var ErrBadRequest = errors.New("bad request")
+var ErrRateLimited = errors.New("rate limited")
+var ErrUnauthorized = errors.New("unauthorized")
+var ErrInternal = errors.New("internal server error")
+var ErrUnavailable = errors.New("service unavailable")
+var ErrTimeout = errors.New("timeout")
+var ErrUnexpectedStatus = errors.New("unexpected status")
+
+// ErrStatus is an error response of the storage API.
+// It unwraps to the sentinel error of its code, so callers can use errors.Is
+// without knowing the status, e.g. errors.Is(err, cacheErrors.ErrNotFound).
+type ErrStatus struct {
+	status  int
+	code    string
+	message string
+}
+
+func NewErrStatus(status int, code, message string) *ErrStatus {
+	if code == "" {
+		code = CodeFromStatus(status)
+	}
+
+	if message == "" {
+		message = strings.ToLower(netHttp.StatusText(status))
+	}
+
+	return &ErrStatus{status: status, code: code, message: message}
+}
+
+// ParseErrStatus returns ErrStatus from the response status and httpModels.ErrorBody.
+// Code is derived from the status if body is empty or malformed.
+func ParseErrStatus(status int, body []byte) *ErrStatus {
+	var b httpModels.ErrorBody
+	if len(body) > 0 {
+		_ = json.Unmarshal(body, &b)
+	}
+
+	return NewErrStatus(status, b.Code, b.Message)
+}
+
+func (e *ErrStatus) GetStatus() int {
+	return e.status
+}
+
+func (e *ErrStatus) GetCode() string {
+	return e.code
+}
+
+func (e *ErrStatus) GetMessage() string {
+	return e.message
+}
+
+// GetBody returns error body to be sent with the status
+func (e *ErrStatus) GetBody() httpModels.ErrorBody {
+	return httpModels.ErrorBody{Code: e.code, Message: e.message}
+}
+
+func (e *ErrStatus) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.status, e.code, e.message)
+}
+
+func (e *ErrStatus) Unwrap() error {
+	switch e.code {
+	case CodeBadRequest:
+		return ErrBadRequest
+	case CodeNotFound:
+		return cacheErrors.ErrNotFound
+	case CodeRateLimited:
+		return ErrRateLimited
+	case CodeWriteLimited:
+		return cacheErrors.ErrWriteRateLimited
+	case CodeQuotaExceeded:
+		return cacheErrors.ErrQuotaExceeded
+	case CodeUnauthorized:
+		return ErrUnauthorized
+	case CodeInternal:
+		return ErrInternal
+	case CodeUnavailable:
+		return ErrUnavailable
+	case CodeTimeout:
+		return ErrTimeout
+	default:
+		return ErrUnexpectedStatus
+	}
+}
+
+// CodeFromStatus returns error code matching the status
+func CodeFromStatus(status int) string {
+	switch {
+	case status == netHttp.StatusNotFound:
+		return CodeNotFound
+	case status == netHttp.StatusTooManyRequests:
+		return CodeRateLimited
+	case status == netHttp.StatusUnauthorized || status == netHttp.StatusForbidden:
+		return CodeUnauthorized
+	case status == netHttp.StatusServiceUnavailable || status == netHttp.StatusBadGateway:
+		return CodeUnavailable
+	case status == netHttp.StatusGatewayTimeout || status == netHttp.StatusRequestTimeout:
+		return CodeTimeout
+	case status >= 400 && status < 500:
+		return CodeBadRequest
+	case status >= 500 && status < 600:
+		return CodeInternal
+	default:
+		return CodeUnknown
+	}
+}
+
+// FromError maps err to ErrStatus. ErrStatus found in the chain is returned as is,
+// sentinel errors are mapped to their statuses, any other error results in 500.
+// Only bad request messages expose err, as they explain what is wrong with the request.
+// Quota and key write limit rejections have their own codes, as unlike CodeRateLimited they don't indicate
+// an overloaded backend and aren't resolved by retrying.
+func FromError(err error) *ErrStatus {
+	var errStatus *ErrStatus
+	if errors.As(err, &errStatus) {
+		return errStatus
+	}
+
+	switch {
+	case errors.Is(err, cacheErrors.ErrNotFound):
+		return NewErrStatus(netHttp.StatusNotFound, CodeNotFound, "")
+	case errors.Is(err, ErrBadRequest):
+		return NewErrStatus(netHttp.StatusBadRequest, CodeBadRequest, err.Error())
+	case errors.Is(err, ErrRateLimited):
+		return NewErrStatus(netHttp.StatusTooManyRequests, CodeRateLimited, "")
+	case errors.Is(err, cacheErrors.ErrWriteRateLimited):
+		return NewErrStatus(netHttp.StatusTooManyRequests, CodeWriteLimited, cacheErrors.ErrWriteRateLimited.Error())
+	case errors.Is(err, cacheErrors.ErrQuotaExceeded):
+		return NewErrStatus(netHttp.StatusTooManyRequests, CodeQuotaExceeded, cacheErrors.ErrQuotaExceeded.Error())
+	case errors.Is(err, cacheErrors.ErrValueTooLarge):
+		return NewErrStatus(netHttp.StatusRequestEntityTooLarge, CodeBadRequest, cacheErrors.ErrValueTooLarge.Error())
+	case errors.Is(err, ErrUnauthorized):
+		return NewErrStatus(netHttp.StatusUnauthorized, CodeUnauthorized, "")
+	case errors.Is(err, ErrUnavailable), errors.Is(err, cacheErrors.ErrCacheClosed):
+		return NewErrStatus(netHttp.StatusServiceUnavailable, CodeUnavailable, "")
+	case errors.Is(err, ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+		return NewErrStatus(netHttp.StatusGatewayTimeout, CodeTimeout, "")
+	default:
+		return NewErrStatus(netHttp.StatusInternalServerError, CodeInternal, "")
+	}
+}
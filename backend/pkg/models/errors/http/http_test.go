@@ -0,0 +1,94 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	netHttp "net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	cacheErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/cache"
+	httpModels "github.com/KennyMacCormik/otel/backend/pkg/models/http"
+)
+
+func TestNewErrStatus(t *testing.T) {
+	err := NewErrStatus(netHttp.StatusServiceUnavailable, "", "")
+
+	assert.Equal(t, netHttp.StatusServiceUnavailable, err.GetStatus(), "Status should match the input")
+	assert.Equal(t, CodeUnavailable, err.GetCode(), "Code should be derived from status")
+	assert.Equal(t, "service unavailable", err.GetMessage(), "Message should be derived from status")
+	assert.Equal(t, "503 unavailable: service unavailable", err.Error(), "Error should include status, code and message")
+	assert.Equal(t, httpModels.ErrorBody{Code: CodeUnavailable, Message: "service unavailable"}, err.GetBody(), "Body should include code and message")
+}
+
+func TestParseErrStatus(t *testing.T) {
+	testCases := []struct {
+		name     string
+		status   int
+		body     string
+		code     string
+		message  string
+		sentinel error
+	}{
+		{"error body", netHttp.StatusBadRequest, `{"code":"bad_request","message":"key must be URL-encoded"}`, CodeBadRequest, "key must be URL-encoded", ErrBadRequest},
+		{"empty body", netHttp.StatusNotFound, "", CodeNotFound, "not found", cacheErrors.ErrNotFound},
+		{"malformed body", netHttp.StatusTooManyRequests, "too many requests", CodeRateLimited, "too many requests", ErrRateLimited},
+		{"bad gateway", netHttp.StatusBadGateway, "", CodeUnavailable, "bad gateway", ErrUnavailable},
+		{"gateway timeout", netHttp.StatusGatewayTimeout, "", CodeTimeout, "gateway timeout", ErrTimeout},
+		{"internal", netHttp.StatusInternalServerError, "", CodeInternal, "internal server error", ErrInternal},
+		{"quota exceeded", netHttp.StatusTooManyRequests, `{"code":"quota_exceeded","message":"tenant quota exceeded"}`, CodeQuotaExceeded, "tenant quota exceeded", cacheErrors.ErrQuotaExceeded},
+		{"write limited", netHttp.StatusTooManyRequests, `{"code":"write_limited","message":"key write rate limit exceeded"}`, CodeWriteLimited, "key write rate limit exceeded", cacheErrors.ErrWriteRateLimited},
+		{"unauthorized", netHttp.StatusForbidden, "", CodeUnauthorized, "forbidden", ErrUnauthorized},
+		{"other client error", netHttp.StatusConflict, "", CodeBadRequest, "conflict", ErrBadRequest},
+		{"unknown code", netHttp.StatusInternalServerError, `{"code":"new_code","message":"msg"}`, "new_code", "msg", ErrUnexpectedStatus},
+		{"unexpected status", netHttp.StatusFound, "", CodeUnknown, "found", ErrUnexpectedStatus},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ParseErrStatus(tc.status, []byte(tc.body))
+
+			assert.Equal(t, tc.status, err.GetStatus(), "Status should match the input")
+			assert.Equal(t, tc.code, err.GetCode(), "Unexpected code")
+			assert.Equal(t, tc.message, err.GetMessage(), "Unexpected message")
+			assert.ErrorIs(t, err, tc.sentinel, "Error should unwrap to the sentinel of its code")
+		})
+	}
+}
+
+func TestFromError(t *testing.T) {
+	errStatus := NewErrStatus(netHttp.StatusTooManyRequests, CodeRateLimited, "slow down")
+
+	testCases := []struct {
+		name    string
+		err     error
+		status  int
+		code    string
+		message string
+	}{
+		{"wrapped ErrStatus", fmt.Errorf("client: %w", errStatus), netHttp.StatusTooManyRequests, CodeRateLimited, "slow down"},
+		{"not found", fmt.Errorf("get: %w", cacheErrors.ErrNotFound), netHttp.StatusNotFound, CodeNotFound, "not found"},
+		{"bad request", fmt.Errorf("%w: no key provided", ErrBadRequest), netHttp.StatusBadRequest, CodeBadRequest, "bad request: no key provided"},
+		{"rate limited", ErrRateLimited, netHttp.StatusTooManyRequests, CodeRateLimited, "too many requests"},
+		{"write rate limited", cacheErrors.NewErrWriteLimited("key", time.Second), netHttp.StatusTooManyRequests, CodeWriteLimited, "key write rate limit exceeded"},
+		{"quota exceeded", fmt.Errorf("tenant a: %w", cacheErrors.ErrQuotaExceeded), netHttp.StatusTooManyRequests, CodeQuotaExceeded, "tenant quota exceeded"},
+		{"value too large", fmt.Errorf("set: %w: 2048 bytes", cacheErrors.ErrValueTooLarge), netHttp.StatusRequestEntityTooLarge, CodeBadRequest, "value too large"},
+		{"unauthorized", ErrUnauthorized, netHttp.StatusUnauthorized, CodeUnauthorized, "unauthorized"},
+		{"cache closed", cacheErrors.ErrCacheClosed, netHttp.StatusServiceUnavailable, CodeUnavailable, "service unavailable"},
+		{"deadline", context.DeadlineExceeded, netHttp.StatusGatewayTimeout, CodeTimeout, "gateway timeout"},
+		{"other", errors.New("secret details"), netHttp.StatusInternalServerError, CodeInternal, "internal server error"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := FromError(tc.err)
+
+			assert.Equal(t, tc.status, err.GetStatus(), "Unexpected status")
+			assert.Equal(t, tc.code, err.GetCode(), "Unexpected code")
+			assert.Equal(t, tc.message, err.GetMessage(), "Unexpected message")
+		})
+	}
+}
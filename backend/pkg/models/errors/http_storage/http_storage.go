@@ -0,0 +1,7 @@
+package http_storage
+
+import "errors"
+
+var ErrUnexpectedStatus = errors.New("unexpected response status")
+var ErrNotSupported = errors.New("operation not supported by storage protocol")
+var ErrInvalidEndpoint = errors.New("invalid storage endpoint url")
@@ -0,0 +1,8 @@
+package memcache
+
+import "errors"
+
+var (
+	ErrBadDataChunk = errors.New("bad data chunk")
+	ErrTooLarge     = errors.New("object too large for cache")
+)
@@ -0,0 +1,9 @@
+package rate_limiter
+
+import "errors"
+
+var ErrUnknownStore = errors.New("unknown rate limiter store")
+var ErrUnexpectedReply = errors.New("unexpected rate limiter store reply")
+var ErrQueueFull = errors.New("rate limiter queue is full")
+var ErrShed = errors.New("request shed by rate limiter")
+var ErrInvalidSettings = errors.New("invalid rate limiter settings")
@@ -0,0 +1,5 @@
+package resp
+
+import "errors"
+
+var ErrProtocol = errors.New("protocol error")
@@ -0,0 +1,5 @@
+package tiered_cache
+
+import "errors"
+
+var ErrUnknownWritePolicy = errors.New("unknown write policy")
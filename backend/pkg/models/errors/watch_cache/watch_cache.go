@@ -0,0 +1,5 @@
+package watch_cache
+
+import "errors"
+
+var ErrWatcherFellBehind = errors.New("watcher fell behind")
@@ -4,3 +4,9 @@ type Body struct {
 	Key string `json:"key" binding:"required"`
 	Val string `json:"value" binding:"required"`
 }
+
+// ErrorBody is returned with every error status. Code is one of the codes in pkg/models/errors/http.
+type ErrorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
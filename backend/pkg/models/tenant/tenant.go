@@ -0,0 +1,47 @@
+package tenant
+
+import (
+	"context"
+	"crypto/sha256"
+)
+
+// Default tenant owns requests without a resolved tenant
+const Default = "default"
+
+type ctxKey struct{}
+
+// NewContext returns ctx carrying tenant
+func NewContext(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, tenant)
+}
+
+// FromContext returns tenant carried by ctx, it is Default if ctx carries none
+func FromContext(ctx context.Context) string {
+	if t, ok := ctx.Value(ctxKey{}).(string); ok && t != "" {
+		return t
+	}
+
+	return Default
+}
+
+// Resolver resolves tenants from their API keys, so every transport authenticates tenants alike
+type Resolver struct {
+	// keys are looked up by digest, so lookup time doesn't depend on how much of the key matches
+	tenants map[[sha256.Size]byte]string
+}
+
+// NewResolver returns resolver of keys, which maps tenant to its API key
+func NewResolver(keys map[string]string) *Resolver {
+	tenants := make(map[[sha256.Size]byte]string, len(keys))
+	for t, key := range keys {
+		tenants[sha256.Sum256([]byte(key))] = t
+	}
+
+	return &Resolver{tenants: tenants}
+}
+
+// Resolve returns tenant of the key, false if the key is unknown
+func (r *Resolver) Resolve(key string) (string, bool) {
+	t, ok := r.tenants[sha256.Sum256([]byte(key))]
+	return t, ok
+}
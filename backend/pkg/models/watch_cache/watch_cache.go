@@ -0,0 +1,15 @@
+package watch_cache
+
+type EventType int
+
+const (
+	EventSet EventType = iota + 1
+	EventDelete
+)
+
+// Event describes successful change of the key. Value is nil for EventDelete.
+type Event struct {
+	Type  EventType
+	Key   string
+	Value any
+}
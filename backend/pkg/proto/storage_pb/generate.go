@@ -0,0 +1,4 @@
+// Package storage_pb contains the protobuf schema of the storage gRPC service shared by the backend and its clients.
+package storage_pb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative storage.proto
@@ -0,0 +1,97 @@
+package resp
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+)
+
+const defaultClientTimeout = time.Second
+
+// Client is a minimal RESP2 client sending pipelined commands over a single connection.
+// The connection is dropped on failure and re-established by the next call.
+type Client struct {
+	endpoint string
+	timeout  time.Duration
+
+	mtx  sync.Mutex
+	conn net.Conn
+	r    *Reader
+	w    *Writer
+}
+
+// NewClient returns Client for the endpoint, timeout bounds every call without context deadline
+func NewClient(endpoint string, timeout time.Duration) *Client {
+	if timeout <= 0 {
+		timeout = defaultClientTimeout
+	}
+
+	return &Client{endpoint: endpoint, timeout: timeout}
+}
+
+// Do sends commands in a single round trip and returns their replies in order, see Reader.ReadReply
+func (c *Client) Do(ctx context.Context, cmds ...[]string) ([]any, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(c.timeout)
+	}
+
+	if c.conn == nil {
+		d := net.Dialer{Deadline: deadline}
+		conn, err := d.DialContext(ctx, "tcp", c.endpoint)
+		if err != nil {
+			return nil, err
+		}
+		c.conn, c.r, c.w = conn, NewReader(conn), NewWriter(conn)
+	}
+
+	replies, err := c.do(deadline, cmds)
+	if err != nil {
+		_ = c.conn.Close()
+		c.conn = nil
+	}
+
+	return replies, err
+}
+
+func (c *Client) do(deadline time.Time, cmds [][]string) ([]any, error) {
+	if err := c.conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	for _, cmd := range cmds {
+		c.w.WriteStrings(cmd)
+	}
+	if err := c.w.Flush(); err != nil {
+		return nil, err
+	}
+
+	replies := make([]any, 0, len(cmds))
+	for range cmds {
+		reply, err := c.r.ReadReply()
+		if err != nil {
+			return nil, err
+		}
+		replies = append(replies, reply)
+	}
+
+	return replies, nil
+}
+
+func (c *Client) Close() error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.conn == nil {
+		return nil
+	}
+
+	err := c.conn.Close()
+	c.conn = nil
+
+	return err
+}
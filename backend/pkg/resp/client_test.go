@@ -0,0 +1,58 @@
+package resp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KennyMacCormik/otel/backend/pkg/test_helpers"
+)
+
+func replyHandler(_ context.Context, w *Writer, args []string) {
+	switch args[0] {
+	case "INT":
+		w.WriteInteger(42)
+	case "NULL":
+		w.WriteNull()
+	case "ARR":
+		w.WriteStrings(args[1:])
+	default:
+		w.WriteError("ERR unknown command")
+	}
+}
+
+func TestClient_Do(t *testing.T) {
+	endpoint := test_helpers.GetFreeEndpoint(t)
+	server := NewServer(endpoint, replyHandler, time.Minute)
+
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- server.Start()
+	}()
+
+	c := NewClient(endpoint, time.Second)
+
+	var replies []any
+	require.Eventually(t, func() bool {
+		var err error
+		replies, err = c.Do(context.Background(), []string{"INT"}, []string{"NULL"}, []string{"ARR", "a", "b"}, []string{"ECHO", "x"})
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond, "expect pipelined commands to succeed")
+
+	assert.Equal(t, []any{
+		int64(42),
+		nil,
+		[]any{"a", "b"},
+		ReplyError("ERR unknown command"),
+	}, replies, "expect replies in order")
+
+	require.NoError(t, server.Close(5*time.Second), "Server should close without errors")
+	require.NoError(t, <-stopped, "Start should return nil after Close")
+
+	_, err := c.Do(context.Background(), []string{"INT"})
+	assert.Error(t, err, "expect error after server is closed")
+	assert.NoError(t, c.Close(), "expect client to close without errors")
+}
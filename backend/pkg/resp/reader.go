@@ -0,0 +1,187 @@
+package resp
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	respErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/resp"
+)
+
+const (
+	maxArgs      = 1024 * 1024
+	maxBulkLen   = 16 * 1024 * 1024
+	maxInlineLen = 64 * 1024
+)
+
+// Reader reads commands sent by clients
+type Reader struct {
+	r *bufio.Reader
+}
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r)}
+}
+
+// Buffered reports whether the next command is already received, so replies of pipelined commands can be flushed at once
+func (r *Reader) Buffered() bool {
+	return r.r.Buffered() > 0
+}
+
+// ReadCommand reads command sent either as RESP array of bulk strings or as inline command.
+// Malformed input results in respErrors.ErrProtocol, the connection can't be used after it.
+func (r *Reader) ReadCommand() ([]string, error) {
+	for {
+		line, err := r.readLine()
+		if err != nil {
+			return nil, err
+		}
+
+		if len(line) == 0 {
+			continue
+		}
+
+		if line[0] != '*' {
+			return strings.Fields(line), nil
+		}
+
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n > maxArgs {
+			return nil, fmt.Errorf("%w: invalid multibulk length", respErrors.ErrProtocol)
+		}
+
+		if n <= 0 {
+			continue
+		}
+
+		args := make([]string, 0, n)
+		for range n {
+			arg, err := r.readBulk()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+		}
+
+		return args, nil
+	}
+}
+
+// ReplyError is an error reply sent by server
+type ReplyError string
+
+func (e ReplyError) Error() string {
+	return string(e)
+}
+
+// ReadReply reads a single reply. Simple and bulk strings are returned as string, integers as int64,
+// arrays as []any and null as nil. Error replies are returned as ReplyError value along with nil error.
+func (r *Reader) ReadReply() (any, error) {
+	line, err := r.readLine()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(line) == 0 {
+		return nil, fmt.Errorf("%w: empty reply", respErrors.ErrProtocol)
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return ReplyError(line[1:]), nil
+	case ':':
+		n, err := strconv.ParseInt(line[1:], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid integer", respErrors.ErrProtocol)
+		}
+		return n, nil
+	case '_':
+		return nil, nil
+	case '$':
+		if line == "$-1" {
+			return nil, nil
+		}
+		return r.readBulkBody(line)
+	case '*':
+		if line == "*-1" {
+			return nil, nil
+		}
+
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 || n > maxArgs {
+			return nil, fmt.Errorf("%w: invalid multibulk length", respErrors.ErrProtocol)
+		}
+
+		arr := make([]any, 0, n)
+		for range n {
+			v, err := r.ReadReply()
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+
+		return arr, nil
+	default:
+		return nil, fmt.Errorf("%w: unexpected reply type '%c'", respErrors.ErrProtocol, line[0])
+	}
+}
+
+func (r *Reader) readBulk() (string, error) {
+	line, err := r.readLine()
+	if err != nil {
+		return "", err
+	}
+
+	if len(line) == 0 || line[0] != '$' {
+		return "", fmt.Errorf("%w: expected '$', got '%s'", respErrors.ErrProtocol, line)
+	}
+
+	return r.readBulkBody(line)
+}
+
+// readBulkBody reads bulk string content following the length line
+func (r *Reader) readBulkBody(line string) (string, error) {
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n < 0 || n > maxBulkLen {
+		return "", fmt.Errorf("%w: invalid bulk length", respErrors.ErrProtocol)
+	}
+
+	buf := make([]byte, n+2)
+	if _, err = io.ReadFull(r.r, buf); err != nil {
+		return "", err
+	}
+
+	if buf[n] != '\r' || buf[n+1] != '\n' {
+		return "", fmt.Errorf("%w: bulk string is not terminated by CRLF", respErrors.ErrProtocol)
+	}
+
+	return string(buf[:n]), nil
+}
+
+// readLine returns line without CRLF. Inline commands may be terminated by LF only.
+func (r *Reader) readLine() (string, error) {
+	line, err := r.r.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {
+		// lines longer than the buffer are only valid for inline commands
+		var b strings.Builder
+		b.Write(line)
+		for err == bufio.ErrBufferFull && b.Len() <= maxInlineLen {
+			line, err = r.r.ReadSlice('\n')
+			b.Write(line)
+		}
+		if err == bufio.ErrBufferFull {
+			return "", fmt.Errorf("%w: too big inline request", respErrors.ErrProtocol)
+		}
+		line = []byte(b.String())
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSuffix(strings.TrimSuffix(string(line), "\n"), "\r"), nil
+}
@@ -0,0 +1,66 @@
+package resp
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	respErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/resp"
+)
+
+func TestReader_ReadCommand(t *testing.T) {
+	r := NewReader(strings.NewReader("*3\r\n$3\r\nSET\r\n$3\r\nkey\r\n$5\r\nva\r\nl\r\nPING hello\n"))
+
+	args, err := r.ReadCommand()
+	require.NoError(t, err, "expect multibulk command")
+	assert.Equal(t, []string{"SET", "key", "va\r\nl"}, args, "expect binary safe bulk strings")
+	assert.True(t, r.Buffered(), "expect pipelined commands to be buffered")
+
+	args, err = r.ReadCommand()
+	require.NoError(t, err, "expect inline command")
+	assert.Equal(t, []string{"PING", "hello"}, args, "expect inline command split by spaces")
+
+	_, err = r.ReadCommand()
+	assert.ErrorIs(t, err, io.EOF, "expect EOF once input is consumed")
+}
+
+func TestReader_ReadCommand_Malformed(t *testing.T) {
+	tests := map[string]string{
+		"invalid multibulk length": "*x\r\n",
+		"missing bulk prefix":      "*1\r\n:1\r\n",
+		"invalid bulk length":      "*1\r\n$-1\r\n",
+		"bulk not terminated":      "*1\r\n$2\r\nabcd\r\n",
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewReader(strings.NewReader(input)).ReadCommand()
+			assert.ErrorIs(t, err, respErrors.ErrProtocol, "expect protocol error")
+		})
+	}
+}
+
+func TestWriter_Proto(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+
+	w.WriteNull()
+	w.WriteMapHeader(1)
+	w.WriteSimpleString("OK")
+	w.WriteInteger(-2)
+	w.WriteError("ERR boom")
+	w.WriteStrings([]string{"a", ""})
+	require.NoError(t, w.Flush(), "expect flush to succeed")
+	assert.Equal(t, "$-1\r\n*2\r\n+OK\r\n:-2\r\n-ERR boom\r\n*2\r\n$1\r\na\r\n$0\r\n\r\n", buf.String(), "expect RESP2 replies")
+
+	buf.Reset()
+	w.SetProto(Resp3)
+	w.WriteNull()
+	w.WriteMapHeader(1)
+	require.NoError(t, w.Flush(), "expect flush to succeed")
+	assert.Equal(t, "_\r\n%1\r\n", buf.String(), "expect RESP3 null and map")
+}
@@ -0,0 +1,187 @@
+package resp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	respErrors "github.com/KennyMacCormik/otel/backend/pkg/models/errors/resp"
+	"github.com/KennyMacCormik/otel/backend/pkg/tcp/tcp_server"
+)
+
+// HandlerFunc handles single command. args[0] is the command name as sent by the client.
+// Reply must be written to w, it is flushed by the server.
+type HandlerFunc func(ctx context.Context, w *Writer, args []string)
+
+// AuthFunc authenticates connection with password of AUTH or HELLO AUTH. Returned context is passed to the handler
+// with the following commands of the connection, false rejects the password.
+type AuthFunc func(ctx context.Context, password string) (context.Context, bool)
+
+// Server serves RESP2 and RESP3 clients over TCP. Connection level commands HELLO, AUTH and QUIT are handled by the server,
+// all others are passed to the handler. Commands of a single connection are handled sequentially.
+type Server struct {
+	*tcp_server.TcpServer
+
+	handler HandlerFunc
+	auth    AuthFunc
+}
+
+type InitOptions func(s *Server)
+
+// WithAuth authenticates connections with auth. Username is ignored and connections are not required to authenticate,
+// commands of unauthenticated connections are passed to the handler with the server context.
+func WithAuth(auth AuthFunc) InitOptions {
+	return func(s *Server) {
+		s.auth = auth
+	}
+}
+
+// NewServer returns server listening on endpoint. Connections idle for more than idleTimeout are closed.
+func NewServer(endpoint string, handler HandlerFunc, idleTimeout time.Duration, opts ...InitOptions) *Server {
+	s := &Server{handler: handler}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.TcpServer = tcp_server.NewTcpServer(endpoint, s.serve, idleTimeout)
+
+	return s
+}
+
+func (s *Server) serve(ctx context.Context, conn *tcp_server.Conn) {
+	r, w := NewReader(conn), NewWriter(conn)
+	// connCtx carries the result of the last successful authentication
+	connCtx := ctx
+
+	for conn.Wait() {
+		args, err := r.ReadCommand()
+		if err != nil {
+			if errors.Is(err, respErrors.ErrProtocol) {
+				w.WriteError("ERR " + err.Error())
+				_ = w.Flush()
+			}
+			return
+		}
+
+		if len(args) == 0 {
+			continue
+		}
+
+		switch strings.ToUpper(args[0]) {
+		case "HELLO":
+			connCtx = s.hello(ctx, connCtx, w, conn.ID(), args)
+		case "AUTH":
+			connCtx = s.authCommand(ctx, connCtx, w, args)
+		case "QUIT":
+			w.WriteSimpleString("OK")
+			_ = w.Flush()
+			return
+		default:
+			s.handler(connCtx, w, args)
+		}
+
+		if !r.Buffered() {
+			if err = w.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// authCommand handles AUTH [username] password and returns context of the connection
+func (s *Server) authCommand(ctx, connCtx context.Context, w *Writer, args []string) context.Context {
+	if len(args) < 2 || len(args) > 3 {
+		WrongArgs(w, args[0])
+		return connCtx
+	}
+
+	authCtx, ok := s.authenticate(ctx, w, args[len(args)-1])
+	if !ok {
+		return connCtx
+	}
+
+	w.WriteSimpleString("OK")
+
+	return authCtx
+}
+
+// authenticate returns context of the connection authenticated with password, it writes error reply on failure
+func (s *Server) authenticate(ctx context.Context, w *Writer, password string) (context.Context, bool) {
+	if s.auth == nil {
+		w.WriteError("ERR AUTH called without any password configured for the default user")
+		return nil, false
+	}
+
+	authCtx, ok := s.auth(ctx, password)
+	if !ok {
+		w.WriteError("WRONGPASS invalid username-password pair or user is disabled.")
+		return nil, false
+	}
+
+	return authCtx, true
+}
+
+// hello switches protocol version and authenticates the connection with AUTH option, client name is accepted but ignored.
+// It returns context of the connection, the protocol is kept if any option fails.
+func (s *Server) hello(ctx, connCtx context.Context, w *Writer, id int64, args []string) context.Context {
+	proto := w.Proto()
+	if len(args) > 1 {
+		var err error
+		proto, err = strconv.Atoi(args[1])
+		if err != nil {
+			w.WriteError("ERR Protocol version is not an integer or out of range")
+			return connCtx
+		}
+
+		if proto != Resp2 && proto != Resp3 {
+			w.WriteError("NOPROTO unsupported protocol version")
+			return connCtx
+		}
+	}
+
+	helloCtx := connCtx
+	for i := 2; i < len(args); i++ {
+		switch opt := strings.ToUpper(args[i]); {
+		case opt == "AUTH" && i+2 < len(args):
+			authCtx, ok := s.authenticate(ctx, w, args[i+2])
+			if !ok {
+				return connCtx
+			}
+			helloCtx = authCtx
+			i += 2
+		case opt == "SETNAME" && i+1 < len(args):
+			i++
+		default:
+			w.WriteError(fmt.Sprintf("ERR Syntax error in HELLO option '%s'", args[i]))
+			return connCtx
+		}
+	}
+
+	w.SetProto(proto)
+
+	w.WriteMapHeader(7)
+	w.WriteBulkString("server")
+	w.WriteBulkString("otel-backend")
+	w.WriteBulkString("version")
+	w.WriteBulkString("7.0.0")
+	w.WriteBulkString("proto")
+	w.WriteInteger(int64(w.Proto()))
+	w.WriteBulkString("id")
+	w.WriteInteger(id)
+	w.WriteBulkString("mode")
+	w.WriteBulkString("standalone")
+	w.WriteBulkString("role")
+	w.WriteBulkString("master")
+	w.WriteBulkString("modules")
+	w.WriteArrayHeader(0)
+
+	return helloCtx
+}
+
+// WrongArgs writes standard reply to the command called with wrong number of arguments
+func WrongArgs(w *Writer, cmd string) {
+	w.WriteError(fmt.Sprintf("ERR wrong number of arguments for '%s' command", strings.ToLower(cmd)))
+}
@@ -0,0 +1,151 @@
+package resp
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KennyMacCormik/otel/backend/pkg/test_helpers"
+)
+
+func dial(t *testing.T, endpoint string) (net.Conn, *bufio.Reader) {
+	var conn net.Conn
+	var err error
+
+	require.Eventually(t, func() bool {
+		conn, err = net.Dial("tcp", endpoint)
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond, "expect server to accept connections")
+	require.NoError(t, conn.SetDeadline(time.Now().Add(5*time.Second)), "expect deadline to be set")
+
+	return conn, bufio.NewReader(conn)
+}
+
+func readReply(t *testing.T, r *bufio.Reader, lines int) string {
+	var reply string
+	for range lines {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err, "expect reply")
+		reply += line
+	}
+
+	return reply
+}
+
+func echoHandler(_ context.Context, w *Writer, args []string) {
+	if len(args) < 2 {
+		WrongArgs(w, args[0])
+		return
+	}
+	w.WriteBulkString(args[1])
+}
+
+func TestServer_Commands(t *testing.T) {
+	endpoint := test_helpers.GetFreeEndpoint(t)
+	server := NewServer(endpoint, echoHandler, time.Minute)
+
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- server.Start()
+	}()
+
+	conn, r := dial(t, endpoint)
+	defer func() { _ = conn.Close() }()
+
+	_, err := conn.Write([]byte("*2\r\n$4\r\nECHO\r\n$2\r\nhi\r\nECHO\r\n"))
+	require.NoError(t, err, "expect pipelined commands to be sent")
+	assert.Equal(t, "$2\r\nhi\r\n", readReply(t, r, 2), "expect handler reply")
+	assert.Equal(t, "-ERR wrong number of arguments for 'echo' command\r\n", readReply(t, r, 1), "expect error reply")
+
+	_, err = conn.Write([]byte("HELLO 3\r\n"))
+	require.NoError(t, err, "expect HELLO to be sent")
+	reply := readReply(t, r, 26)
+	assert.Contains(t, reply, "%7\r\n", "expect RESP3 map reply")
+	assert.Contains(t, reply, "$5\r\nproto\r\n:3\r\n", "expect protocol version 3")
+
+	_, err = conn.Write([]byte("HELLO 4\r\nQUIT\r\n"))
+	require.NoError(t, err, "expect commands to be sent")
+	assert.Equal(t, "-NOPROTO unsupported protocol version\r\n+OK\r\n", readReply(t, r, 2), "expect NOPROTO and QUIT reply")
+
+	assert.NoError(t, server.Close(5*time.Second), "Server should close without errors")
+	assert.NoError(t, <-stopped, "Start should return nil after Close")
+}
+
+type authKey struct{}
+
+// whoamiHandler replies with the name authenticated by testAuth
+func whoamiHandler(ctx context.Context, w *Writer, _ []string) {
+	name, ok := ctx.Value(authKey{}).(string)
+	if !ok {
+		name = "anonymous"
+	}
+	w.WriteBulkString(name)
+}
+
+func testAuth(ctx context.Context, password string) (context.Context, bool) {
+	if password != "secret" {
+		return nil, false
+	}
+
+	return context.WithValue(ctx, authKey{}, "tenant"), true
+}
+
+func TestServer_Auth(t *testing.T) {
+	endpoint := test_helpers.GetFreeEndpoint(t)
+	server := NewServer(endpoint, whoamiHandler, time.Minute, WithAuth(testAuth))
+
+	go func() { _ = server.Start() }()
+	defer func() { _ = server.Close(5 * time.Second) }()
+
+	conn, r := dial(t, endpoint)
+	defer func() { _ = conn.Close() }()
+
+	send := func(cmd string) {
+		_, err := conn.Write([]byte(cmd + "\r\n"))
+		require.NoError(t, err, "expect %s to be sent", cmd)
+	}
+
+	send("WHOAMI")
+	assert.Equal(t, "$9\r\nanonymous\r\n", readReply(t, r, 2), "expect unauthenticated connection to be served")
+
+	send("AUTH wrong")
+	assert.Equal(t, "-WRONGPASS invalid username-password pair or user is disabled.\r\n", readReply(t, r, 1), "expect wrong password to be rejected")
+	send("AUTH")
+	assert.Equal(t, "-ERR wrong number of arguments for 'auth' command\r\n", readReply(t, r, 1), "expect AUTH without password to fail")
+
+	send("AUTH default secret")
+	assert.Equal(t, "+OK\r\n", readReply(t, r, 1), "expect password to be accepted")
+	send("WHOAMI")
+	assert.Equal(t, "$6\r\ntenant\r\n", readReply(t, r, 2), "expect commands to carry authentication")
+
+	send("HELLO 3 AUTH default wrong")
+	assert.Equal(t, "-WRONGPASS invalid username-password pair or user is disabled.\r\n", readReply(t, r, 1), "expect HELLO to reject wrong password")
+	send("HELLO 3 SETNAME")
+	assert.Equal(t, "-ERR Syntax error in HELLO option 'SETNAME'\r\n", readReply(t, r, 1), "expect HELLO option without value to fail")
+	send("WHOAMI")
+	assert.Equal(t, "$6\r\ntenant\r\n", readReply(t, r, 2), "expect failed HELLO to keep authentication and protocol")
+
+	send("HELLO 2 AUTH default secret SETNAME client")
+	assert.Contains(t, readReply(t, r, 26), "*14\r\n", "expect RESP2 HELLO reply")
+}
+
+func TestServer_AuthDisabled(t *testing.T) {
+	endpoint := test_helpers.GetFreeEndpoint(t)
+	server := NewServer(endpoint, whoamiHandler, time.Minute)
+
+	go func() { _ = server.Start() }()
+	defer func() { _ = server.Close(5 * time.Second) }()
+
+	conn, r := dial(t, endpoint)
+	defer func() { _ = conn.Close() }()
+
+	_, err := conn.Write([]byte("AUTH secret\r\nWHOAMI\r\n"))
+	require.NoError(t, err, "expect commands to be sent")
+	assert.Equal(t, "-ERR AUTH called without any password configured for the default user\r\n", readReply(t, r, 1), "expect AUTH to fail")
+	assert.Equal(t, "$9\r\nanonymous\r\n", readReply(t, r, 2), "expect connection to stay usable")
+}
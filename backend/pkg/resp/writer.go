@@ -0,0 +1,94 @@
+package resp
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+)
+
+const (
+	Resp2 = 2
+	Resp3 = 3
+)
+
+// Writer writes replies in the protocol version negotiated by HELLO, RESP2 by default.
+// Replies are buffered until Flush.
+type Writer struct {
+	w     *bufio.Writer
+	proto int
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: bufio.NewWriter(w), proto: Resp2}
+}
+
+func (w *Writer) Proto() int {
+	return w.proto
+}
+
+func (w *Writer) SetProto(proto int) {
+	w.proto = proto
+}
+
+func (w *Writer) WriteSimpleString(s string) {
+	w.line('+', s)
+}
+
+// WriteError writes error reply. msg starts with the error code, e.g. "ERR syntax error".
+func (w *Writer) WriteError(msg string) {
+	w.line('-', msg)
+}
+
+func (w *Writer) WriteInteger(n int64) {
+	w.line(':', strconv.FormatInt(n, 10))
+}
+
+func (w *Writer) WriteBulkString(s string) {
+	w.line('$', strconv.Itoa(len(s)))
+	_, _ = w.w.WriteString(s)
+	_, _ = w.w.WriteString("\r\n")
+}
+
+// WriteNull writes null bulk string in RESP2 and null in RESP3
+func (w *Writer) WriteNull() {
+	if w.proto == Resp3 {
+		_, _ = w.w.WriteString("_\r\n")
+		return
+	}
+
+	_, _ = w.w.WriteString("$-1\r\n")
+}
+
+// WriteArrayHeader starts array of n elements, which are written next
+func (w *Writer) WriteArrayHeader(n int) {
+	w.line('*', strconv.Itoa(n))
+}
+
+// WriteMapHeader starts map of n pairs, which are written next as key followed by value.
+// In RESP2 map is written as flat array.
+func (w *Writer) WriteMapHeader(n int) {
+	if w.proto == Resp3 {
+		w.line('%', strconv.Itoa(n))
+		return
+	}
+
+	w.WriteArrayHeader(2 * n)
+}
+
+// WriteStrings writes array of bulk strings
+func (w *Writer) WriteStrings(s []string) {
+	w.WriteArrayHeader(len(s))
+	for _, v := range s {
+		w.WriteBulkString(v)
+	}
+}
+
+func (w *Writer) Flush() error {
+	return w.w.Flush()
+}
+
+func (w *Writer) line(prefix byte, s string) {
+	_ = w.w.WriteByte(prefix)
+	_, _ = w.w.WriteString(s)
+	_, _ = w.w.WriteString("\r\n")
+}
@@ -0,0 +1,173 @@
+package tcp_server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ConnHandler serves single connection until it returns. It must call Conn.Wait before reading every request.
+// ctx is cancelled once Close times out.
+type ConnHandler func(ctx context.Context, conn *Conn)
+
+// Conn is a connection accepted by TcpServer
+type Conn struct {
+	net.Conn
+
+	id int64
+	s  *TcpServer
+}
+
+// ID returns connection id unique within the server
+func (c *Conn) ID() int64 {
+	return c.id
+}
+
+// Wait prepares connection to read the next request and reports whether it should be served.
+// Read of a connection waiting for the next request fails once Close is called.
+func (c *Conn) Wait() bool {
+	if c.s.idleTimeout > 0 {
+		_ = c.SetReadDeadline(time.Now().Add(c.s.idleTimeout))
+	}
+
+	// checked after the deadline is set, so it can't override the one set by Close
+	return !c.s.closed.Load()
+}
+
+// TcpServer accepts connections and tracks them for graceful shutdown
+type TcpServer struct {
+	endpoint    string
+	handler     ConnHandler
+	idleTimeout time.Duration
+
+	mtx    sync.Mutex
+	lis    net.Listener
+	conns  map[*Conn]struct{}
+	wg     sync.WaitGroup
+	closed atomic.Bool
+	nextID atomic.Int64
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewTcpServer returns server listening on endpoint. Connections idle for more than idleTimeout are closed.
+func NewTcpServer(endpoint string, handler ConnHandler, idleTimeout time.Duration) *TcpServer {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &TcpServer{
+		endpoint:    endpoint,
+		handler:     handler,
+		idleTimeout: idleTimeout,
+		conns:       make(map[*Conn]struct{}),
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+}
+
+// Start listens on the endpoint and serves until Close. It returns nil after Close.
+func (s *TcpServer) Start() error {
+	lis, err := net.Listen("tcp", s.endpoint)
+	if err != nil {
+		return err
+	}
+
+	s.mtx.Lock()
+	if s.closed.Load() {
+		s.mtx.Unlock()
+		_ = lis.Close()
+		return nil
+	}
+	s.lis = lis
+	s.mtx.Unlock()
+
+	for {
+		netConn, err := lis.Accept()
+		if err != nil {
+			if s.closed.Load() {
+				return nil
+			}
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				continue
+			}
+			return err
+		}
+
+		conn := &Conn{Conn: netConn, id: s.nextID.Add(1), s: s}
+		if !s.track(conn) {
+			_ = netConn.Close()
+			return nil
+		}
+
+		go s.serve(conn)
+	}
+}
+
+// Close stops accepting connections and closes idle ones. Requests in flight are allowed to finish until t passes,
+// then the remaining connections are closed and their contexts cancelled.
+func (s *TcpServer) Close(t time.Duration) error {
+	s.mtx.Lock()
+	s.closed.Store(true)
+	if s.lis != nil {
+		_ = s.lis.Close()
+	}
+	// unblocks connections waiting for the next request, busy ones exit after the reply
+	for conn := range s.conns {
+		_ = conn.SetReadDeadline(time.Now())
+	}
+	s.mtx.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), t)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		s.cancel()
+		return nil
+	case <-ctx.Done():
+		s.cancel()
+		s.mtx.Lock()
+		for conn := range s.conns {
+			_ = conn.Close()
+		}
+		s.mtx.Unlock()
+		return ctx.Err()
+	}
+}
+
+func (s *TcpServer) track(conn *Conn) bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.closed.Load() {
+		return false
+	}
+
+	s.conns[conn] = struct{}{}
+	s.wg.Add(1)
+
+	return true
+}
+
+func (s *TcpServer) serve(conn *Conn) {
+	defer func() {
+		s.mtx.Lock()
+		delete(s.conns, conn)
+		s.mtx.Unlock()
+
+		_ = conn.Close()
+		s.wg.Done()
+	}()
+
+	s.handler(s.ctx, conn)
+}
@@ -0,0 +1,95 @@
+package tcp_server
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KennyMacCormik/otel/backend/pkg/test_helpers"
+)
+
+func dial(t *testing.T, endpoint string) (net.Conn, *bufio.Reader) {
+	var conn net.Conn
+	var err error
+
+	require.Eventually(t, func() bool {
+		conn, err = net.Dial("tcp", endpoint)
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond, "expect server to accept connections")
+	require.NoError(t, conn.SetDeadline(time.Now().Add(5*time.Second)), "expect deadline to be set")
+
+	return conn, bufio.NewReader(conn)
+}
+
+func echoHandler(_ context.Context, conn *Conn) {
+	r := bufio.NewReader(conn)
+	for conn.Wait() {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		if _, err = conn.Write([]byte(line)); err != nil {
+			return
+		}
+	}
+}
+
+func TestTcpServer_StartAndClose(t *testing.T) {
+	endpoint := test_helpers.GetFreeEndpoint(t)
+	server := NewTcpServer(endpoint, echoHandler, time.Minute)
+
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- server.Start()
+	}()
+
+	conn, r := dial(t, endpoint)
+	defer func() { _ = conn.Close() }()
+
+	_, err := conn.Write([]byte("hello\n"))
+	require.NoError(t, err, "expect request to be sent")
+	line, err := r.ReadString('\n')
+	require.NoError(t, err, "expect reply")
+	assert.Equal(t, "hello\n", line, "expect handler to serve connection")
+
+	assert.NoError(t, server.Close(5*time.Second), "expect idle connection not to delay Close")
+	assert.NoError(t, <-stopped, "Start should return nil after Close")
+
+	_, err = r.ReadString('\n')
+	assert.Error(t, err, "expect connection to be closed")
+}
+
+func TestTcpServer_IdleTimeout(t *testing.T) {
+	endpoint := test_helpers.GetFreeEndpoint(t)
+	server := NewTcpServer(endpoint, echoHandler, 100*time.Millisecond)
+	go func() { _ = server.Start() }()
+	defer func() { _ = server.Close(time.Second) }()
+
+	conn, r := dial(t, endpoint)
+	defer func() { _ = conn.Close() }()
+
+	_, err := r.ReadString('\n')
+	assert.Error(t, err, "expect idle connection to be closed")
+}
+
+func TestTcpServer_CloseTimeout(t *testing.T) {
+	endpoint := test_helpers.GetFreeEndpoint(t)
+	started := make(chan struct{})
+	server := NewTcpServer(endpoint, func(ctx context.Context, conn *Conn) {
+		close(started)
+		<-ctx.Done()
+	}, time.Minute)
+	go func() { _ = server.Start() }()
+
+	conn, _ := dial(t, endpoint)
+	defer func() { _ = conn.Close() }()
+	<-started
+
+	err := server.Close(100 * time.Millisecond)
+	assert.ErrorIs(t, err, context.DeadlineExceeded, "expect Close to time out with request in flight")
+}
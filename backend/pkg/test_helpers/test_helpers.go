@@ -0,0 +1,19 @@
+package test_helpers
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// GetFreeEndpoint returns local endpoint with a port nobody listens on at the moment
+func GetFreeEndpoint(t testing.TB) string {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err, "expect free port")
+	defer func() { _ = lis.Close() }()
+
+	return lis.Addr().String()
+}